@@ -0,0 +1,98 @@
+// Pasfmt formats Pascal programs.
+//
+// Without an explicit path it processes the standard input. Given a file,
+// it operates on that file; given a directory, it operates on all .pas
+// files in that directory, recursively.
+//
+// Usage:
+//
+//	pasfmt [flags] [path ...]
+//
+// The flags are:
+//
+//	-d	Do not print reformatted sources to standard output.
+//		If a file's formatting is different than pasfmt's, print diffs
+//		to standard output.
+//	-l	Do not print reformatted sources to standard output.
+//		If a file's formatting is different from pasfmt's, print its name
+//		to standard output.
+//	-w	Do not print reformatted sources to standard output.
+//		If a file's formatting is different from pasfmt's, overwrite it
+//		with pasfmt's version.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/format"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/interpreter"
+)
+
+var options format.Options
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: pasfmt [flags] [path ...]\n")
+	flag.PrintDefaults()
+}
+
+func processFile(path string, input io.Reader, output io.Writer) error {
+	src, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+
+	if err := format.File(path, src, output, options); err != nil {
+		// positioned errors read path:line:col: kind error: message
+		if _, ok := interpreter.ErrorPosition(err); ok {
+			return fmt.Errorf("%s:%w", path, err)
+		}
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func processPath(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return processFile(path, file, os.Stdout)
+}
+
+func main() {
+	options.RegisterFlags(flag.CommandLine)
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if options.Write {
+			fmt.Fprintln(os.Stderr, "error: cannot use -w with standard input")
+			os.Exit(2)
+		}
+		if err := processFile("<standard input>", os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	exitCode := 0
+	for _, path := range flag.Args() {
+		err := format.Walk(path, func(path string) {
+			if err := processPath(path); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exitCode = 2
+			}
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 2
+		}
+	}
+	os.Exit(exitCode)
+}
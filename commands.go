@@ -0,0 +1,302 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/format"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/interpreter"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/repl"
+)
+
+func newFlagSet(name string, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: simple-interpreter %s %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// symbolsFlag collects the conditional symbols defined by repeated -d flags
+type symbolsFlag []string
+
+func (r *symbolsFlag) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *symbolsFlag) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
+
+// definesFlag adds the flag defining conditional symbols for {$IFDEF}
+func definesFlag(flags *flag.FlagSet) *symbolsFlag {
+	var symbols symbolsFlag
+	flags.Var(&symbols, "d", "define the conditional `symbol` checked by {$IFDEF}, may be repeated")
+	return &symbols
+}
+
+// parseArgs parses the flags of a command expecting a single file argument
+func parseArgs(flags *flag.FlagSet, args []string) (string, bool) {
+	if err := flags.Parse(args); err != nil {
+		return "", false
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return "", false
+	}
+	return flags.Arg(0), true
+}
+
+func readSource(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// parseFile returns the tree of the program at path, which is either
+// Pascal source, in which the conditional symbols defines are defined, or,
+// if fromJSON is set, a tree encoded by `ast -format json`
+func parseFile(path string, fromJSON bool, defines []string) (ast.Node, []lexer.Comment, error) {
+	source, err := readSource(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if fromJSON {
+		tree, err := ast.UnmarshalJSON(source)
+		return tree, nil, err
+	}
+
+	lxr := lexer.NewLexer(string(source))
+	lxr.Define(defines...)
+	parser, err := interpreter.NewParser(lxr)
+	if err != nil {
+		return nil, nil, err
+	}
+	tree, err := parser.Parse()
+	if err != nil {
+		return nil, nil, err
+	}
+	return tree, parser.Lexer.GetComments(), nil
+}
+
+// unitsFlag adds the flag setting the directories searched for units
+func unitsFlag(flags *flag.FlagSet) *string {
+	return flags.String("path", "", "search the `directories` in the list separated by "+string(os.PathListSeparator)+" for units instead of the one of the program")
+}
+
+// newUnits returns the units found in the directories of the list
+// searchPath, the directory of the program at path if it is empty, parsed
+// with the conditional symbols defines
+func newUnits(searchPath string, path string, defines []string) *interpreter.Units {
+	units := interpreter.NewUnits(filepath.Dir(path))
+	if searchPath != "" {
+		units.SearchPath = filepath.SplitList(searchPath)
+	}
+	units.Defines = defines
+	return units
+}
+
+func runCommand(args []string) int {
+	flags := newFlagSet("run", "[-json] [-leaks] [-path directories] [-d symbol] file.pas")
+	fromJSON := flags.Bool("json", false, "read a JSON encoded parse tree instead of Pascal source")
+	leaks := flags.Bool("leaks", false, "report variables allocated by New and never disposed")
+	searchPath := unitsFlag(flags)
+	defines := definesFlag(flags)
+	path, ok := parseArgs(flags, args)
+	if !ok {
+		return exitUsage
+	}
+
+	tree, _, err := parseFile(path, *fromJSON, *defines)
+	if err != nil {
+		return report(path, err)
+	}
+	if _, isUnit := tree.(ast.Unit); isUnit {
+		fmt.Fprintf(os.Stderr, "%s: file is a unit, not a program\n", path)
+		return exitUsage
+	}
+
+	units := newUnits(*searchPath, path, *defines)
+	analyzer := interpreter.NewSemanticAnalyzer()
+	analyzer.Units = units
+	evaluator := interpreter.NewEvaluatorVisitor(os.Stdin, os.Stdout)
+	evaluator.Units = units
+	if *leaks {
+		evaluator.Leaks = os.Stderr
+	}
+	basicInterpreter := interpreter.BasicInterpreter{
+		Analyzer:  analyzer,
+		Evaluator: &evaluator,
+	}
+	code, err := basicInterpreter.Evaluate(tree)
+	if err != nil {
+		return report(path, err)
+	}
+	return code
+}
+
+func replCommand(args []string) int {
+	flags := newFlagSet("repl", "")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return exitUsage
+	}
+
+	r := repl.NewRepl(os.Stdin, os.Stdout)
+	code, err := r.Run()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	return code
+}
+
+func checkCommand(args []string) int {
+	flags := newFlagSet("check", "[-json] [-path directories] [-d symbol] file.pas")
+	fromJSON := flags.Bool("json", false, "read a JSON encoded parse tree instead of Pascal source")
+	searchPath := unitsFlag(flags)
+	defines := definesFlag(flags)
+	path, ok := parseArgs(flags, args)
+	if !ok {
+		return exitUsage
+	}
+
+	tree, _, err := parseFile(path, *fromJSON, *defines)
+	if err != nil {
+		return report(path, err)
+	}
+
+	analyzer := interpreter.NewSemanticAnalyzer()
+	analyzer.Units = newUnits(*searchPath, path, *defines)
+	if err := analyzer.Analyze(tree); err != nil {
+		return report(path, err)
+	}
+	return exitSuccess
+}
+
+func tokensCommand(args []string) int {
+	flags := newFlagSet("tokens", "[-d symbol] file.pas")
+	defines := definesFlag(flags)
+	path, ok := parseArgs(flags, args)
+	if !ok {
+		return exitUsage
+	}
+
+	source, err := readSource(path)
+	if err != nil {
+		return report(path, err)
+	}
+
+	lxr := lexer.NewLexer(string(source))
+	lxr.Define(*defines...)
+	for {
+		token, err := lxr.NextToken()
+		if err != nil {
+			return report(path, err)
+		}
+
+		fmt.Printf("%d:%d\t%v\t%s\n", token.Pos.Line, token.Pos.Column, token.TokenType, token.Text())
+		if token.TokenType == lexer.EOF {
+			return exitSuccess
+		}
+	}
+}
+
+func astCommand(args []string) int {
+	flags := newFlagSet("ast", "[-format text|json|dot] [-o file] [-d symbol] file.pas")
+	outputFormat := flags.String("format", "text", "output `format`: text, json or dot")
+	outputPath := flags.String("o", "-", "write the tree to `file` instead of the standard output")
+	defines := definesFlag(flags)
+	path, ok := parseArgs(flags, args)
+	if !ok {
+		return exitUsage
+	}
+
+	var write func(io.Writer, ast.Node) error
+	switch *outputFormat {
+	case "text":
+		write = ast.Fprint
+	case "dot":
+		write = ast.WriteDOT
+	case "json":
+		write = func(output io.Writer, node ast.Node) error {
+			encoded, err := ast.MarshalJSON(node)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(output, "%s\n", encoded)
+			return err
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *outputFormat)
+		return exitUsage
+	}
+
+	tree, _, err := parseFile(path, false, *defines)
+	if err != nil {
+		return report(path, err)
+	}
+
+	var output io.Writer = os.Stdout
+	if *outputPath != "-" {
+		file, err := os.Create(*outputPath)
+		if err != nil {
+			return report(*outputPath, err)
+		}
+		defer file.Close()
+		output = file
+	}
+
+	if err := write(output, tree); err != nil {
+		return report(*outputPath, err)
+	}
+	return exitSuccess
+}
+
+func fmtCommand(args []string) int {
+	flags := newFlagSet("fmt", "[-l] [-w] [-d] path...")
+	var options format.Options
+	options.RegisterFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	if options.Write && slices.Contains(flags.Args(), "-") {
+		fmt.Fprintln(os.Stderr, "cannot use -w with the standard input")
+		return exitUsage
+	}
+
+	result := exitSuccess
+	formatFile := func(path string) {
+		source, err := readSource(path)
+		if err == nil {
+			err = format.File(path, source, os.Stdout, options)
+		}
+		if err != nil {
+			result = max(result, report(path, err))
+		}
+	}
+	for _, path := range flags.Args() {
+		if path == "-" {
+			formatFile(path)
+			continue
+		}
+		if err := format.Walk(path, formatFile); err != nil {
+			result = max(result, report(path, err))
+		}
+	}
+	return result
+}
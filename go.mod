@@ -2,9 +2,12 @@ module github.com/anuarkaliyev23/simple-interpreter-go
 
 go 1.23.0
 
+require (
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/stretchr/testify v1.9.0
+)
+
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.9.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
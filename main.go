@@ -1,17 +1,104 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
-	"github.com/anuarkaliyev23/simple-interpreter-go/public/repl"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/interpreter"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
 )
 
+// Exit codes, distinguishing at which stage a program was rejected
+const (
+	exitSuccess       = 0
+	exitRuntimeError  = 1
+	exitUsage         = 2
+	exitSyntaxError   = 3
+	exitSemanticError = 4
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+var commands = []command{
+	{"run", "parse, check and execute a program", runCommand},
+	{"repl", "evaluate statements and expressions interactively", replCommand},
+	{"check", "parse and semantically analyze a program without running it", checkCommand},
+	{"tokens", "print the tokens produced by the lexer", tokensCommand},
+	{"ast", "print the parse tree of a program", astCommand},
+	{"fmt", "format programs", fmtCommand},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: simple-interpreter <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "The commands are:")
+	fmt.Fprintln(os.Stderr)
+	for _, v := range commands {
+		fmt.Fprintf(os.Stderr, "\t%-8s %s\n", v.name, v.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Without a command the REPL is started. A file argument of - reads the standard input.")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Exit codes: 0 success, 1 runtime error, 2 usage or I/O error, 3 syntax error, 4 semantic error.")
+	fmt.Fprintln(os.Stderr, "A program stopped by Halt(code) exits with code.")
+}
+
+func exitCode(err error) int {
+	var lexerError lexer.LexerError
+	var parserError interpreter.ParserError
+	var semanticError interpreter.SemanticError
+	var runtimeError interpreter.RuntimeError
+
+	switch {
+	case errors.As(err, &lexerError), errors.As(err, &parserError):
+		return exitSyntaxError
+	case errors.As(err, &semanticError):
+		return exitSemanticError
+	case errors.As(err, &runtimeError):
+		return exitRuntimeError
+	}
+	return exitUsage
+}
+
+// report prints err prefixed with the path of the program or unit it
+// occurred in and returns the matching exit code
+func report(path string, err error) int {
+	var unitError interpreter.UnitError
+	if errors.As(err, &unitError) && unitError.Path != "" {
+		path = unitError.Path
+	}
+	if _, ok := interpreter.ErrorPosition(err); ok {
+		fmt.Fprintf(os.Stderr, "%s:%v\n", path, err)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+	}
+	return exitCode(err)
+}
+
 func main() {
-	repl := repl.NewRepl()
-	for {
-		err := repl.Iter()
-		if err != nil {
-			os.Exit(1)
+	args := os.Args[1:]
+	if len(args) == 0 {
+		os.Exit(replCommand(nil))
+	}
+
+	for _, v := range commands {
+		if v.name == args[0] {
+			os.Exit(v.run(args[1:]))
 		}
 	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage()
+		os.Exit(exitSuccess)
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	usage()
+	os.Exit(exitUsage)
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeProgram writes source to a file of a temporary directory and
+// returns its path
+func writeProgram(t *testing.T, source string) string {
+	path := filepath.Join(t.TempDir(), "program.pas")
+	require.NoError(t, os.WriteFile(path, []byte(source), 0o644))
+	return path
+}
+
+func TestCommands_exitCodes(t *testing.T) {
+	t.Run("A lexer error after a semicolon is a syntax error", func(t *testing.T) {
+		path := writeProgram(t, "program P; var x, y: integer; begin x := 1; } y := 2 end.")
+		for _, v := range commands {
+			if v.name == "repl" {
+				continue
+			}
+			require.Equal(t, exitSyntaxError, v.run([]string{path}), v.name)
+		}
+	})
+
+	t.Run("run rejects units", func(t *testing.T) {
+		path := writeProgram(t, "UNIT U; INTERFACE IMPLEMENTATION END.")
+		require.Equal(t, exitUsage, runCommand([]string{path}))
+	})
+
+	t.Run("fmt -w rejects the standard input", func(t *testing.T) {
+		require.Equal(t, exitUsage, fmtCommand([]string{"-w", "-"}))
+	})
+
+	t.Run("fmt formats the programs of a directory", func(t *testing.T) {
+		path := writeProgram(t, "program P; begin end.")
+		require.Equal(t, exitSuccess, fmtCommand([]string{"-w", filepath.Dir(path)}))
+		text, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "PROGRAM P;\nBEGIN\nEND.\n", string(text))
+
+		require.NoError(t, os.WriteFile(path, []byte("program P; begin"), 0o644))
+		require.Equal(t, exitSyntaxError, fmtCommand([]string{"-d", filepath.Dir(path)}))
+	})
+}
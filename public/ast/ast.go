@@ -67,6 +67,26 @@ func NewRealNode(t lexer.BasicToken) (RealNode, error) {
 	}, nil
 }
 
+// StringNode is a string literal, Value holds the decoded text. A literal
+// of a single character is a CHAR constant.
+type StringNode struct {
+	BasicNode
+	Value string
+}
+
+func NewStringNode(t lexer.BasicToken) (StringNode, error) {
+	if t.TokenType != lexer.STRING_LITERAL {
+		return StringNode{}, fmt.Errorf("Cannot parse token %v to String AST node", t)
+	}
+
+	return StringNode{
+		Value: t.TokenValue,
+		BasicNode: BasicNode{
+			token: t,
+		},
+	}, nil
+}
+
 type BinaryOperation struct {
 	BasicNode
 	Left  Node
@@ -128,6 +148,8 @@ func NewVar(token lexer.BasicToken) (Var, error) {
 type Compound struct {
 	BasicNode
 	Children []Node
+	// End is the position right after the closing END keyword
+	End lexer.Position
 }
 
 func NewCompound(children []Node, token lexer.BasicToken) Compound {
@@ -167,13 +189,15 @@ func NewTypeSpec(token lexer.BasicToken) TypeSpec {
 	}
 }
 
+// VarDeclaration declares Variable of the type described by TypeSpec, a
+// TypeSpec naming the type or a type constructor such as ArrayType.
 type VarDeclaration struct {
 	BasicNode
 	Variable Var
-	TypeSpec TypeSpec
+	TypeSpec Node
 }
 
-func NewVarDeclaration(variable Var, typeSpec TypeSpec, token lexer.BasicToken) VarDeclaration {
+func NewVarDeclaration(variable Var, typeSpec Node, token lexer.BasicToken) VarDeclaration {
 	return VarDeclaration {
 		BasicNode: BasicNode{
 			token: token,
@@ -183,13 +207,131 @@ func NewVarDeclaration(variable Var, typeSpec TypeSpec, token lexer.BasicToken)
 	}
 }
 
+// TypeDeclaration declares Name as the type described by TypeSpec. Its
+// token is the EQUAL between them.
+type TypeDeclaration struct {
+	BasicNode
+	Name     Var
+	TypeSpec Node
+}
+
+func NewTypeDeclaration(name Var, typeSpec Node, token lexer.BasicToken) TypeDeclaration {
+	return TypeDeclaration{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Name:     name,
+		TypeSpec: typeSpec,
+	}
+}
+
+// ConstDeclaration declares Name as the constant Value. TypeSpec is nil
+// unless the constant is typed, e.g. Limit: REAL = 10. Its token is the
+// EQUAL before the value.
+type ConstDeclaration struct {
+	BasicNode
+	Name     Var
+	TypeSpec Node
+	Value    Node
+}
+
+func NewConstDeclaration(name Var, typeSpec Node, value Node, token lexer.BasicToken) ConstDeclaration {
+	return ConstDeclaration{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Name:     name,
+		TypeSpec: typeSpec,
+		Value:    value,
+	}
+}
+
+// Param is a formal parameter of a routine. Its token is the VAR keyword
+// for a VAR parameter and the colon for a value parameter. A procedural
+// parameter written ISO style, as in PROCEDURE Apply(FUNCTION f(x: REAL):
+// REAL), has the PROCEDURE or FUNCTION keyword as its token and a
+// ProceduralType as TypeSpec.
+type Param struct {
+	BasicNode
+	Variable Var
+	TypeSpec Node
+}
+
+func NewParam(variable Var, typeSpec Node, token lexer.BasicToken) Param {
+	return Param{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Variable: variable,
+		TypeSpec: typeSpec,
+	}
+}
+
+// IsVar reports whether the parameter is passed by reference
+func (r Param) IsVar() bool {
+	return r.token.TokenType == lexer.VAR
+}
+
+// ProceduralType is PROCEDURE(params) or FUNCTION(params): Result, the
+// type of variables holding a routine. Result is nil for procedures. Its
+// token is the PROCEDURE or FUNCTION keyword.
+type ProceduralType struct {
+	BasicNode
+	Params []Param
+	Result Node
+}
+
+func NewProceduralType(params []Param, result Node, token lexer.BasicToken) ProceduralType {
+	return ProceduralType{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Params: params,
+		Result: result,
+	}
+}
+
+// RoutineDeclaration declares the procedure or function Name, Result
+// being nil for procedures. The implementation of a method has the
+// TypeSpec of its class as Class, which is nil for other routines. Its
+// token is the PROCEDURE, FUNCTION, CONSTRUCTOR or DESTRUCTOR keyword.
+type RoutineDeclaration struct {
+	BasicNode
+	Class  Node
+	Name   Var
+	Params []Param
+	Result Node
+	Block  Block
+}
+
+func NewRoutineDeclaration(name Var, params []Param, result Node, block Block, token lexer.BasicToken) RoutineDeclaration {
+	return RoutineDeclaration{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Name:   name,
+		Params: params,
+		Result: result,
+		Block:  block,
+	}
+}
+
+// IsFunction reports whether the routine returns a value
+func (r RoutineDeclaration) IsFunction() bool {
+	return r.token.TokenType == lexer.FUNCTION
+}
+
+// Block holds the labels of the LABEL section, which comes first, then the
+// declarations of the CONST, TYPE and VAR sections and the routines in
+// source order, followed by the statements of Compound
 type Block struct {
 	BasicNode
-	Declarations []VarDeclaration
+	Labels []LabelDeclaration
+	Declarations []Node
 	Compound Compound
 }
 
-func NewBlock(declarations []VarDeclaration, compound Compound, token lexer.BasicToken) Block {
+func NewBlock(declarations []Node, compound Compound, token lexer.BasicToken) Block {
 	return Block{
 		BasicNode: BasicNode{
 			token: token,
@@ -199,9 +341,42 @@ func NewBlock(declarations []VarDeclaration, compound Compound, token lexer.Basi
 	}
 }
 
+// Constants returns the declarations of the CONST sections of the block
+func (r Block) Constants() []ConstDeclaration {
+	return declarationsOf[ConstDeclaration](r)
+}
+
+// Types returns the declarations of the TYPE sections of the block
+func (r Block) Types() []TypeDeclaration {
+	return declarationsOf[TypeDeclaration](r)
+}
+
+// Vars returns the declarations of the VAR sections of the block
+func (r Block) Vars() []VarDeclaration {
+	return declarationsOf[VarDeclaration](r)
+}
+
+// Routines returns the routines declared in the block
+func (r Block) Routines() []RoutineDeclaration {
+	return declarationsOf[RoutineDeclaration](r)
+}
+
+func declarationsOf[T Node](block Block) []T {
+	var result []T
+	for _, v := range block.Declarations {
+		if declaration, ok := v.(T); ok {
+			result = append(result, declaration)
+		}
+	}
+	return result
+}
+
+// Program is the main file of a program. Uses lists the units named by its
+// USES clause, if any, in order.
 type Program struct {
 	BasicNode
 	Name string
+	Uses []Var
 	Block Block
 }
 
@@ -214,3 +389,616 @@ func NewProgram(name string, block Block, token lexer.BasicToken) Program {
 		Name: name,
 	}
 }
+
+// ProcedureCall is a call statement, e.g. WRITELN(a, b). Its token is the
+// procedure name.
+type ProcedureCall struct {
+	BasicNode
+	Name      string
+	Arguments []Node
+}
+
+func NewProcedureCall(arguments []Node, token lexer.BasicToken) ProcedureCall {
+	return ProcedureCall{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Name:      token.TokenValue,
+		Arguments: arguments,
+	}
+}
+
+// FunctionCall calls a function inside an expression, e.g. Length(s). Its
+// token is the function name.
+type FunctionCall struct {
+	BasicNode
+	Name      string
+	Arguments []Node
+}
+
+func NewFunctionCall(arguments []Node, token lexer.BasicToken) FunctionCall {
+	return FunctionCall{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Name:      token.TokenValue,
+		Arguments: arguments,
+	}
+}
+
+// FormattedArgument is an output argument with a field width and an
+// optional precision, value:width:precision. Precision is nil if omitted.
+// Its token is the first colon.
+type FormattedArgument struct {
+	BasicNode
+	Value     Node
+	Width     Node
+	Precision Node
+}
+
+func NewFormattedArgument(value Node, width Node, precision Node, token lexer.BasicToken) FormattedArgument {
+	return FormattedArgument{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Value:     value,
+		Width:     width,
+		Precision: precision,
+	}
+}
+
+// Index selects an element of Value, e.g. s[i] or a[i, j]. Its token is
+// the opening bracket.
+type Index struct {
+	BasicNode
+	Value   Node
+	Indices []Node
+}
+
+func NewIndex(value Node, indices []Node, token lexer.BasicToken) Index {
+	return Index{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Value:   value,
+		Indices: indices,
+	}
+}
+
+// Subrange is the range of ordinal values Low..High, e.g. the index range
+// of an array. Its token is the DOTDOT between the bounds.
+type Subrange struct {
+	BasicNode
+	Low  Node
+	High Node
+}
+
+func NewSubrange(low Node, high Node, token lexer.BasicToken) Subrange {
+	return Subrange{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Low:  low,
+		High: high,
+	}
+}
+
+// ArrayType is ARRAY[range, ...] OF Element, where every range is an
+// ordinal type, e.g. a Subrange or a TypeSpec naming an enumeration. Arrays of several ranges are arrays of arrays. Its token is the
+// ARRAY keyword.
+type ArrayType struct {
+	BasicNode
+	Ranges  []Node
+	Element Node
+}
+
+func NewArrayType(ranges []Node, element Node, token lexer.BasicToken) ArrayType {
+	return ArrayType{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Ranges:  ranges,
+		Element: element,
+	}
+}
+
+// EnumType is the enumeration (Value, ...), the values are constants
+// numbered from 0. Its token is the opening parenthesis.
+type EnumType struct {
+	BasicNode
+	Values []Var
+}
+
+func NewEnumType(values []Var, token lexer.BasicToken) EnumType {
+	return EnumType{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Values: values,
+	}
+}
+
+// SetType is SET OF Element, Element being an ordinal type. Its token is
+// the SET keyword.
+type SetType struct {
+	BasicNode
+	Element Node
+}
+
+func NewSetType(element Node, token lexer.BasicToken) SetType {
+	return SetType{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Element: element,
+	}
+}
+
+// SetConstructor is the set [element, ...], an element being an
+// expression or a Subrange of them, e.g. ['a'..'z', '_']. Its token is the
+// opening bracket.
+type SetConstructor struct {
+	BasicNode
+	Elements []Node
+	// End is the position right after the closing bracket
+	End lexer.Position
+}
+
+func NewSetConstructor(elements []Node, token lexer.BasicToken) SetConstructor {
+	return SetConstructor{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Elements: elements,
+	}
+}
+
+// PointerType is ^Target, Target being a TypeSpec that may name a type
+// declared later in the same TYPE section. Its token is the caret.
+type PointerType struct {
+	BasicNode
+	Target Node
+}
+
+func NewPointerType(target Node, token lexer.BasicToken) PointerType {
+	return PointerType{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Target: target,
+	}
+}
+
+// Dereference is Value^, the variable the pointer Value points to. Its
+// token is the caret.
+type Dereference struct {
+	BasicNode
+	Value Node
+}
+
+func NewDereference(value Node, token lexer.BasicToken) Dereference {
+	return Dereference{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Value: value,
+	}
+}
+
+// NilNode is the pointer constant NIL, pointing nowhere
+type NilNode struct {
+	BasicNode
+}
+
+func NewNilNode(token lexer.BasicToken) NilNode {
+	return NilNode{
+		BasicNode: BasicNode{
+			token: token,
+		},
+	}
+}
+
+// RecordType is RECORD field; ... END, every field is declared like a
+// variable. Its token is the RECORD keyword.
+type RecordType struct {
+	BasicNode
+	Fields []VarDeclaration
+	// End is the position right after the closing END keyword
+	End lexer.Position
+}
+
+func NewRecordType(fields []VarDeclaration, token lexer.BasicToken) RecordType {
+	return RecordType{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Fields: fields,
+	}
+}
+
+// FieldAccess selects the field Field of the record Value, e.g. p.x. Its
+// token is the field name.
+type FieldAccess struct {
+	BasicNode
+	Value Node
+	Field string
+}
+
+func NewFieldAccess(value Node, token lexer.BasicToken) FieldAccess {
+	return FieldAccess{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Value: value,
+		Field: token.TokenValue,
+	}
+}
+
+// WithStatement is WITH record, ... DO Body, the fields of the records can
+// be used without qualification inside Body. Its token is the WITH keyword.
+type WithStatement struct {
+	BasicNode
+	Records []Node
+	Body    Node
+}
+
+func NewWithStatement(records []Node, body Node, token lexer.BasicToken) WithStatement {
+	return WithStatement{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Records: records,
+		Body:    body,
+	}
+}
+
+// MethodCall calls the method Name of Value, e.g. Exception.Create('text').
+// Its token is the method name.
+type MethodCall struct {
+	BasicNode
+	Value     Node
+	Name      string
+	Arguments []Node
+}
+
+func NewMethodCall(value Node, arguments []Node, token lexer.BasicToken) MethodCall {
+	return MethodCall{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Value:     value,
+		Name:      token.TokenValue,
+		Arguments: arguments,
+	}
+}
+
+// RaiseStatement is RAISE Value. A bare RAISE, whose Value is nil,
+// raises the exception being handled again. Its token is the RAISE
+// keyword.
+type RaiseStatement struct {
+	BasicNode
+	Value Node
+}
+
+func NewRaiseStatement(value Node, token lexer.BasicToken) RaiseStatement {
+	return RaiseStatement{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Value: value,
+	}
+}
+
+// ExceptionHandler is ON Variable: Class DO Body, Variable being nil if
+// the exception is not named. Its token is the ON keyword.
+type ExceptionHandler struct {
+	BasicNode
+	Variable Node
+	Class    TypeSpec
+	Body     Node
+}
+
+func NewExceptionHandler(variable Node, class TypeSpec, body Node, token lexer.BasicToken) ExceptionHandler {
+	return ExceptionHandler{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Variable: variable,
+		Class:    class,
+		Body:     body,
+	}
+}
+
+// TryExcept is TRY Body EXCEPT Handlers ELSE Default END. Without
+// handlers Default holds all the statements following EXCEPT, which handle
+// any exception. Its token is the TRY keyword.
+type TryExcept struct {
+	BasicNode
+	Body     []Node
+	Handlers []ExceptionHandler
+	Default  []Node
+	// End is the position right after the closing END keyword
+	End lexer.Position
+}
+
+func NewTryExcept(body []Node, handlers []ExceptionHandler, defaultStatements []Node, token lexer.BasicToken) TryExcept {
+	return TryExcept{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Body:     body,
+		Handlers: handlers,
+		Default:  defaultStatements,
+	}
+}
+
+// TryFinally is TRY Body FINALLY Finally END, Finally runs however Body
+// is left. Its token is the TRY keyword.
+type TryFinally struct {
+	BasicNode
+	Body    []Node
+	Finally []Node
+	// End is the position right after the closing END keyword
+	End lexer.Position
+}
+
+func NewTryFinally(body []Node, finally []Node, token lexer.BasicToken) TryFinally {
+	return TryFinally{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Body:    body,
+		Finally: finally,
+	}
+}
+
+// IfStatement is IF Condition THEN Then ELSE Else, Else being nil if
+// there is no ELSE part. Its token is the IF keyword.
+type IfStatement struct {
+	BasicNode
+	Condition Node
+	Then      Node
+	Else      Node
+}
+
+func NewIfStatement(condition Node, then Node, otherwise Node, token lexer.BasicToken) IfStatement {
+	return IfStatement{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Condition: condition,
+		Then:      then,
+		Else:      otherwise,
+	}
+}
+
+// WhileStatement is WHILE Condition DO Body. Its token is the WHILE
+// keyword.
+type WhileStatement struct {
+	BasicNode
+	Condition Node
+	Body      Node
+}
+
+func NewWhileStatement(condition Node, body Node, token lexer.BasicToken) WhileStatement {
+	return WhileStatement{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Condition: condition,
+		Body:      body,
+	}
+}
+
+// RepeatStatement is REPEAT Body UNTIL Condition. Its token is the REPEAT
+// keyword.
+type RepeatStatement struct {
+	BasicNode
+	Body      []Node
+	Condition Node
+}
+
+func NewRepeatStatement(body []Node, condition Node, token lexer.BasicToken) RepeatStatement {
+	return RepeatStatement{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Body:      body,
+		Condition: condition,
+	}
+}
+
+// ForStatement is FOR Variable := Start TO Stop DO Body, counting down
+// to Stop if Downto is set. Its token is the FOR keyword.
+type ForStatement struct {
+	BasicNode
+	Variable Node
+	Start    Node
+	Stop     Node
+	Downto   bool
+	Body     Node
+}
+
+func NewForStatement(variable Node, start Node, stop Node, downto bool, body Node, token lexer.BasicToken) ForStatement {
+	return ForStatement{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Variable: variable,
+		Start:    start,
+		Stop:     stop,
+		Downto:   downto,
+		Body:     body,
+	}
+}
+
+// LabelDeclaration declares the label Name in a LABEL section. A label is
+// a number, as in ISO Pascal, or an identifier. Its token is the label.
+type LabelDeclaration struct {
+	BasicNode
+	Name string
+}
+
+func NewLabelDeclaration(token lexer.BasicToken) LabelDeclaration {
+	return LabelDeclaration{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Name: token.Text(),
+	}
+}
+
+// LabeledStatement is Label: Statement, the target of GOTO Label. Its
+// token is the label.
+type LabeledStatement struct {
+	BasicNode
+	Label     string
+	Statement Node
+}
+
+func NewLabeledStatement(statement Node, token lexer.BasicToken) LabeledStatement {
+	return LabeledStatement{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Label:     token.Text(),
+		Statement: statement,
+	}
+}
+
+// GotoStatement is GOTO Label. Its token is the GOTO keyword.
+type GotoStatement struct {
+	BasicNode
+	Label string
+}
+
+func NewGotoStatement(label string, token lexer.BasicToken) GotoStatement {
+	return GotoStatement{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Label: label,
+	}
+}
+
+// ClassType is CLASS(Parent) fields and method headings END, Parent being
+// nil if it is omitted. Its token is the CLASS keyword.
+type ClassType struct {
+	BasicNode
+	Parent  Node
+	Fields  []VarDeclaration
+	Methods []MethodHeading
+	// End is the position right after the closing END keyword
+	End lexer.Position
+}
+
+func NewClassType(parent Node, fields []VarDeclaration, methods []MethodHeading, token lexer.BasicToken) ClassType {
+	return ClassType{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Parent:  parent,
+		Fields:  fields,
+		Methods: methods,
+	}
+}
+
+// MethodHeading declares the method Name of a class, e.g. FUNCTION Area:
+// REAL; VIRTUAL. Result is nil unless the method is a function. Its token
+// is the PROCEDURE, FUNCTION, CONSTRUCTOR or DESTRUCTOR keyword.
+type MethodHeading struct {
+	BasicNode
+	Name     Var
+	Params   []Param
+	Result   Node
+	Virtual  bool
+	Override bool
+}
+
+func NewMethodHeading(name Var, params []Param, result Node, token lexer.BasicToken) MethodHeading {
+	return MethodHeading{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Name:   name,
+		Params: params,
+		Result: result,
+	}
+}
+
+// InheritedCall is INHERITED Name(Arguments), calling the method Name as
+// declared by the ancestors of the class whose method is running. Its
+// token is the INHERITED keyword.
+type InheritedCall struct {
+	BasicNode
+	Name      string
+	Arguments []Node
+}
+
+func NewInheritedCall(name string, arguments []Node, token lexer.BasicToken) InheritedCall {
+	return InheritedCall{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Name:      name,
+		Arguments: arguments,
+	}
+}
+
+// RoutineHeading declares the routine Name in the INTERFACE section of a
+// unit, leaving its body to the IMPLEMENTATION section. Result is nil
+// unless the routine is a function. Its token is the PROCEDURE or FUNCTION
+// keyword.
+type RoutineHeading struct {
+	BasicNode
+	Name   Var
+	Params []Param
+	Result Node
+}
+
+func NewRoutineHeading(name Var, params []Param, result Node, token lexer.BasicToken) RoutineHeading {
+	return RoutineHeading{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Name:   name,
+		Params: params,
+		Result: result,
+	}
+}
+
+// IsFunction reports whether the routine returns a value
+func (r RoutineHeading) IsFunction() bool {
+	return r.token.TokenType == lexer.FUNCTION
+}
+
+// Unit is a separately compiled file named by the USES clause of a program
+// or another unit. The CONST, TYPE and VAR sections of Interface and the
+// Headings are visible to its users, everything declared by Implementation
+// is private. The Compound of Implementation holds the INITIALIZATION
+// statements, Finalization the FINALIZATION ones. Its token is the UNIT
+// keyword.
+type Unit struct {
+	BasicNode
+	Name               string
+	InterfaceUses      []Var
+	Interface          Block
+	Headings           []RoutineHeading
+	ImplementationUses []Var
+	Implementation     Block
+	Finalization       []Node
+	// End is the position right after the closing END keyword
+	End lexer.Position
+}
+
+func NewUnit(name string, interfaceSection Block, headings []RoutineHeading, implementation Block, token lexer.BasicToken) Unit {
+	return Unit{
+		BasicNode: BasicNode{
+			token: token,
+		},
+		Name:           name,
+		Interface:      interfaceSection,
+		Headings:       headings,
+		Implementation: implementation,
+	}
+}
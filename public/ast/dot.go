@@ -0,0 +1,44 @@
+package ast
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func dotQuote(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(text) + `"`
+}
+
+// WriteDOT renders the tree rooted at node as a Graphviz digraph. Nodes are
+// labelled with their kind and token, children are ordered left to right.
+func WriteDOT(output io.Writer, node Node) error {
+	writer := bufio.NewWriter(output)
+	fmt.Fprintln(writer, "digraph AST {")
+	fmt.Fprintln(writer, "\tordering=out;")
+	fmt.Fprintln(writer, "\tnode [shape=box, fontname=\"Helvetica\"];")
+
+	var parents []int
+	next := 0
+	Inspect(node, func(n Node) bool {
+		if n == nil {
+			parents = parents[:len(parents)-1]
+			return false
+		}
+
+		id := next
+		next++
+		fmt.Fprintf(writer, "\tn%d [label=%s];\n", id, dotQuote(label(n)))
+		if len(parents) > 0 {
+			fmt.Fprintf(writer, "\tn%d -> n%d;\n", parents[len(parents)-1], id)
+		}
+
+		parents = append(parents, id)
+		return true
+	})
+
+	fmt.Fprintln(writer, "}")
+	return writer.Flush()
+}
@@ -0,0 +1,52 @@
+package ast
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDOT(t *testing.T) {
+	t.Run("Expression tree", func(t *testing.T) {
+		assign := NewAssignt(testVar("x"), NewBinaryOperation(
+			testInt(1),
+			testVar("y"),
+			lexer.BasicToken{TokenType: lexer.PLUS},
+		), lexer.BasicToken{TokenType: lexer.ASSIGN})
+
+		var output bytes.Buffer
+		require.NoError(t, WriteDOT(&output, assign))
+		require.Equal(t, `digraph AST {
+	ordering=out;
+	node [shape=box, fontname="Helvetica"];
+	n0 [label="AssignOperation ':='"];
+	n1 [label="Var 'x'"];
+	n0 -> n1;
+	n2 [label="BinaryOperation '+'"];
+	n0 -> n2;
+	n3 [label="IntNode '1'"];
+	n2 -> n3;
+	n4 [label="Var 'y'"];
+	n2 -> n4;
+}
+`, output.String())
+	})
+
+	t.Run("Every node of a program is exported", func(t *testing.T) {
+		var output bytes.Buffer
+		require.NoError(t, WriteDOT(&output, testProgram()))
+
+		dot := output.String()
+		require.Contains(t, dot, `n0 [label="Program 'test'"];`)
+		require.Contains(t, dot, `[label="TypeSpec 'INTEGER'"];`)
+		require.Contains(t, dot, `[label="UnaryOperation '-'"];`)
+		require.Contains(t, dot, `n12 [label="NoOp"];`)
+		require.Equal(t, 12, bytes.Count(output.Bytes(), []byte("->")))
+	})
+
+	t.Run("Labels are escaped", func(t *testing.T) {
+		require.Equal(t, `"a \"b\" \\ c"`, dotQuote(`a "b" \ c`))
+	})
+}
@@ -0,0 +1,363 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+)
+
+// Node types that can be encoded, the kind of a node is its type name.
+var jsonNodes = []Node{
+	IntNode{},
+	RealNode{},
+	StringNode{},
+	NilNode{},
+	BinaryOperation{},
+	UnaryOperation{},
+	AssignOperation{},
+	Var{},
+	Compound{},
+	NoOp{},
+	TypeSpec{},
+	VarDeclaration{},
+	ConstDeclaration{},
+	TypeDeclaration{},
+	Param{},
+	ProceduralType{},
+	RoutineDeclaration{},
+	Block{},
+	Program{},
+	ProcedureCall{},
+	FunctionCall{},
+	FormattedArgument{},
+	Index{},
+	Subrange{},
+	ArrayType{},
+	EnumType{},
+	SetType{},
+	SetConstructor{},
+	PointerType{},
+	Dereference{},
+	RecordType{},
+	FieldAccess{},
+	WithStatement{},
+	MethodCall{},
+	RaiseStatement{},
+	ExceptionHandler{},
+	TryExcept{},
+	TryFinally{},
+	IfStatement{},
+	WhileStatement{},
+	RepeatStatement{},
+	ForStatement{},
+	LabelDeclaration{},
+	LabeledStatement{},
+	GotoStatement{},
+	ClassType{},
+	MethodHeading{},
+	InheritedCall{},
+	RoutineHeading{},
+	Unit{},
+}
+
+var nodeKinds = map[string]reflect.Type{}
+
+// optionalFields lists the fields holding a node that may be nil, keyed by
+// the kind of the node. Every other field holding a node is required.
+var optionalFields = map[string][]string{
+	"ConstDeclaration":   {"TypeSpec"},
+	"ProceduralType":     {"Result"},
+	"RoutineDeclaration": {"Class", "Result"},
+	"RoutineHeading":     {"Result"},
+	"ClassType":          {"Parent"},
+	"MethodHeading":      {"Result"},
+	"RaiseStatement":     {"Value"},
+	"ExceptionHandler":   {"Variable"},
+	"IfStatement":        {"Else"},
+	"FormattedArgument":  {"Precision"},
+}
+
+var nodeInterface = reflect.TypeOf((*Node)(nil)).Elem()
+
+func init() {
+	for _, v := range jsonNodes {
+		t := reflect.TypeOf(v)
+		nodeKinds[t.Name()] = t
+	}
+}
+
+type jsonToken struct {
+	Type  string          `json:"type"`
+	Value string          `json:"value,omitempty"`
+	Pos   *lexer.Position `json:"pos,omitempty"`
+	End   *lexer.Position `json:"end,omitempty"`
+}
+
+type jsonSpan struct {
+	Start lexer.Position `json:"start"`
+	End   lexer.Position `json:"end"`
+}
+
+// jsonObject keeps the order of its keys when encoded
+type jsonObject struct {
+	keys   []string
+	values []any
+}
+
+func (r *jsonObject) add(key string, value any) {
+	r.keys = append(r.keys, key)
+	r.values = append(r.values, value)
+}
+
+func (r jsonObject) MarshalJSON() ([]byte, error) {
+	var output bytes.Buffer
+	output.WriteByte('{')
+	for i, key := range r.keys {
+		if i > 0 {
+			output.WriteByte(',')
+		}
+
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		encodedValue, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+
+		output.Write(encodedKey)
+		output.WriteByte(':')
+		output.Write(encodedValue)
+	}
+	output.WriteByte('}')
+	return output.Bytes(), nil
+}
+
+func jsonFieldName(name string) string {
+	return string(unicode.ToLower(rune(name[0]))) + name[1:]
+}
+
+// jsonFields returns the exported fields of a node type, including the ones
+// promoted from embedded nodes
+func jsonFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for _, v := range reflect.VisibleFields(t) {
+		if v.Anonymous || !v.IsExported() {
+			continue
+		}
+		fields = append(fields, v)
+	}
+	return fields
+}
+
+func isNodeType(t reflect.Type) bool {
+	return t == nodeInterface || (t.Kind() == reflect.Struct && t.Implements(nodeInterface))
+}
+
+func encodeToken(token lexer.BasicToken) jsonToken {
+	result := jsonToken{
+		Type:  token.TokenType.String(),
+		Value: token.TokenValue,
+	}
+	if token.Pos.IsValid() {
+		result.Pos = &token.Pos
+	}
+	if token.End.IsValid() {
+		result.End = &token.End
+	}
+	return result
+}
+
+func encodeNode(node Node) (any, error) {
+	if node == nil {
+		return nil, nil
+	}
+
+	t := reflect.TypeOf(node)
+	if _, ok := nodeKinds[t.Name()]; !ok || nodeKinds[t.Name()] != t {
+		return nil, fmt.Errorf("Cannot encode node of unknown type %T", node)
+	}
+
+	object := jsonObject{}
+	object.add("kind", t.Name())
+	object.add("token", encodeToken(node.GetToken()))
+
+	if start, end := Pos(node), End(node); start.IsValid() && end.IsValid() {
+		object.add("span", jsonSpan{Start: start, End: end})
+	}
+
+	value := reflect.ValueOf(node)
+	for _, field := range jsonFields(t) {
+		encoded, err := encodeField(value.FieldByIndex(field.Index))
+		if err != nil {
+			return nil, err
+		}
+		object.add(jsonFieldName(field.Name), encoded)
+	}
+	return object, nil
+}
+
+func encodeField(value reflect.Value) (any, error) {
+	t := value.Type()
+	if isNodeType(t) {
+		node, _ := value.Interface().(Node)
+		return encodeNode(node)
+	}
+
+	if t.Kind() == reflect.Slice && isNodeType(t.Elem()) {
+		result := []any{}
+		for i := 0; i < value.Len(); i++ {
+			encoded, err := encodeField(value.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, encoded)
+		}
+		return result, nil
+	}
+
+	return value.Interface(), nil
+}
+
+// MarshalJSON encodes the tree rooted at node. Every node is an object
+// holding its "kind" (the node type name), its "token", the "span" of
+// source it was parsed from, if known, and its fields.
+func MarshalJSON(node Node) ([]byte, error) {
+	encoded, err := encodeNode(node)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encoded)
+}
+
+func decodeToken(data json.RawMessage) (lexer.BasicToken, error) {
+	var token jsonToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return lexer.BasicToken{}, err
+	}
+
+	tokenType, ok := lexer.TokenTypeByName(token.Type)
+	if !ok {
+		return lexer.BasicToken{}, fmt.Errorf("Unknown token type %q", token.Type)
+	}
+
+	result := lexer.BasicToken{
+		TokenType:  tokenType,
+		TokenValue: token.Value,
+	}
+	if token.Pos != nil {
+		result.Pos = *token.Pos
+	}
+	if token.End != nil {
+		result.End = *token.End
+	}
+	return result, nil
+}
+
+func decodeNode(data json.RawMessage) (Node, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	var kind string
+	if err := json.Unmarshal(fields["kind"], &kind); err != nil {
+		return nil, fmt.Errorf("Node kind expected: %w", err)
+	}
+
+	t, ok := nodeKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("Cannot decode node of unknown kind %q", kind)
+	}
+
+	value := reflect.New(t).Elem()
+	if rawToken, ok := fields["token"]; ok {
+		token, err := decodeToken(rawToken)
+		if err != nil {
+			return nil, err
+		}
+		value.FieldByName("BasicNode").Addr().Interface().(*BasicNode).token = token
+	}
+
+	for _, field := range jsonFields(t) {
+		raw, ok := fields[jsonFieldName(field.Name)]
+		required := isNodeType(field.Type) && !slices.Contains(optionalFields[kind], field.Name)
+		if required && (!ok || string(raw) == "null") {
+			return nil, fmt.Errorf("%s.%s: Node expected", kind, field.Name)
+		}
+		if !ok {
+			continue
+		}
+		if err := decodeField(raw, value.FieldByIndex(field.Index)); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", kind, field.Name, err)
+		}
+	}
+
+	return value.Interface().(Node), nil
+}
+
+func decodeField(data json.RawMessage, target reflect.Value) error {
+	t := target.Type()
+	if isNodeType(t) {
+		node, err := decodeNode(data)
+		if err != nil {
+			return err
+		}
+		if node == nil {
+			return nil
+		}
+		if !reflect.TypeOf(node).AssignableTo(t) {
+			return fmt.Errorf("Cannot use %T as %v", node, t)
+		}
+		target.Set(reflect.ValueOf(node))
+		return nil
+	}
+
+	if t.Kind() == reflect.Slice && isNodeType(t.Elem()) {
+		var elements []json.RawMessage
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return err
+		}
+
+		if len(elements) == 0 {
+			return nil
+		}
+
+		result := reflect.MakeSlice(t, 0, len(elements))
+		for _, v := range elements {
+			element := reflect.New(t.Elem()).Elem()
+			if err := decodeField(v, element); err != nil {
+				return err
+			}
+			result = reflect.Append(result, element)
+		}
+		target.Set(result)
+		return nil
+	}
+
+	return json.Unmarshal(data, target.Addr().Interface())
+}
+
+// UnmarshalJSON decodes a tree encoded by MarshalJSON. Spans are derived
+// from the tokens and ignored on input.
+func UnmarshalJSON(data []byte) (Node, error) {
+	node, err := decodeNode(json.RawMessage(strings.TrimSpace(string(data))))
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, fmt.Errorf("Node expected, got null")
+	}
+	return node, nil
+}
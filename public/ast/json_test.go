@@ -0,0 +1,126 @@
+package ast
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	t.Run("Nodes carry kind, token, span and fields", func(t *testing.T) {
+		left, _ := NewIntNode(lexer.BasicToken{
+			TokenType:  lexer.INTEGER,
+			TokenValue: "1",
+			Pos:        lexer.Position{Line: 1, Column: 1},
+			End:        lexer.Position{Line: 1, Column: 2},
+		})
+		right, _ := NewVar(lexer.BasicToken{
+			TokenType:  lexer.ID,
+			TokenValue: "x",
+			Pos:        lexer.Position{Line: 1, Column: 5},
+			End:        lexer.Position{Line: 1, Column: 6},
+		})
+		node := NewBinaryOperation(left, right, lexer.BasicToken{
+			TokenType: lexer.PLUS,
+			Pos:       lexer.Position{Line: 1, Column: 3},
+			End:       lexer.Position{Line: 1, Column: 4},
+		})
+
+		encoded, err := MarshalJSON(node)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"kind": "BinaryOperation",
+			"token": {"type": "PLUS", "pos": {"line": 1, "column": 3}, "end": {"line": 1, "column": 4}},
+			"span": {"start": {"line": 1, "column": 1}, "end": {"line": 1, "column": 6}},
+			"left": {
+				"kind": "IntNode",
+				"token": {"type": "INTEGER", "value": "1", "pos": {"line": 1, "column": 1}, "end": {"line": 1, "column": 2}},
+				"span": {"start": {"line": 1, "column": 1}, "end": {"line": 1, "column": 2}},
+				"value": 1
+			},
+			"right": {
+				"kind": "Var",
+				"token": {"type": "ID", "value": "x", "pos": {"line": 1, "column": 5}, "end": {"line": 1, "column": 6}},
+				"span": {"start": {"line": 1, "column": 5}, "end": {"line": 1, "column": 6}},
+				"value": "x"
+			}
+		}`, string(encoded))
+	})
+
+	t.Run("Kind is the first key", func(t *testing.T) {
+		encoded, err := MarshalJSON(NewNoOp())
+		require.NoError(t, err)
+		require.Equal(t, `{"kind":"NoOp","token":{"type":"SEMICOLON"}}`, string(encoded))
+	})
+
+	t.Run("Promoted fields of embedded nodes are encoded", func(t *testing.T) {
+		encoded, err := MarshalJSON(NewAssignt(testVar("a"), testInt(1), lexer.BasicToken{TokenType: lexer.ASSIGN}))
+		require.NoError(t, err)
+
+		var fields map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(encoded, &fields))
+		require.Contains(t, fields, "left")
+		require.Contains(t, fields, "right")
+	})
+
+	t.Run("Unknown node types are rejected", func(t *testing.T) {
+		_, err := MarshalJSON(BasicNode{})
+		require.Error(t, err)
+	})
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	t.Run("Round-trips a program", func(t *testing.T) {
+		program := testProgram()
+		encoded, err := MarshalJSON(program)
+		require.NoError(t, err)
+
+		decoded, err := UnmarshalJSON(encoded)
+		require.NoError(t, err)
+		require.Equal(t, program, decoded)
+	})
+
+	t.Run("Round-trips positions", func(t *testing.T) {
+		compound := NewCompound(nil, lexer.BasicToken{TokenType: lexer.BEGIN, Pos: lexer.Position{Line: 2, Column: 1}})
+		compound.End = lexer.Position{Line: 3, Column: 4}
+
+		encoded, err := MarshalJSON(compound)
+		require.NoError(t, err)
+		decoded, err := UnmarshalJSON(encoded)
+		require.NoError(t, err)
+		require.Equal(t, compound, decoded)
+	})
+
+	t.Run("Accepts trees written by hand", func(t *testing.T) {
+		decoded, err := UnmarshalJSON([]byte(`{
+			"kind": "UnaryOperation",
+			"token": {"type": "MINUS"},
+			"right": {"kind": "IntNode", "token": {"type": "INTEGER", "value": "5"}, "value": 5}
+		}`))
+		require.NoError(t, err)
+		require.Equal(t, NewUnaryOperation(testInt(5), lexer.BasicToken{TokenType: lexer.MINUS}), decoded)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		cases := map[string]string{
+			"unknown kind":       `{"kind": "Goto"}`,
+			"missing kind":       `{"token": {"type": "PLUS"}}`,
+			"unknown token type": `{"kind": "NoOp", "token": {"type": "SPACESHIP"}}`,
+			"wrong field type":   `{"kind": "Program", "block": {"kind": "NoOp", "token": {"type": "SEMICOLON"}}}`,
+			"missing child":      `{"kind": "Program", "token": {"type": "PROGRAM"}, "name": "x"}`,
+			"null child":         `{"kind": "UnaryOperation", "token": {"type": "MINUS"}, "right": null}`,
+			"null":               `null`,
+			"malformed":          `{"kind":`,
+		}
+
+		for name, data := range cases {
+			_, err := UnmarshalJSON([]byte(data))
+			require.Error(t, err, name)
+		}
+
+		_, err := UnmarshalJSON([]byte(cases["missing child"]))
+		require.ErrorContains(t, err, "Program.Block: Node expected")
+	})
+}
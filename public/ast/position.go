@@ -0,0 +1,58 @@
+package ast
+
+import "github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+
+// Pos returns the position of the first token of node. The position is not
+// valid if the tree was built without source information.
+func Pos(node Node) lexer.Position {
+	var start lexer.Position
+	Inspect(node, func(n Node) bool {
+		if n == nil {
+			return false
+		}
+
+		pos := n.GetToken().Pos
+		if pos.IsValid() && (!start.IsValid() || pos.Before(start)) {
+			start = pos
+		}
+		return true
+	})
+	return start
+}
+
+// End returns the position right after the last token of node. The
+// position is not valid if the tree was built without source information.
+func End(node Node) lexer.Position {
+	var end lexer.Position
+	extend := func(pos lexer.Position) {
+		if pos.IsValid() && (!end.IsValid() || end.Before(pos)) {
+			end = pos
+		}
+	}
+
+	Inspect(node, func(n Node) bool {
+		if n == nil {
+			return false
+		}
+
+		extend(n.GetToken().End)
+		switch block := n.(type) {
+		case Compound:
+			extend(block.End)
+		case RecordType:
+			extend(block.End)
+		case ClassType:
+			extend(block.End)
+		case SetConstructor:
+			extend(block.End)
+		case TryExcept:
+			extend(block.End)
+		case TryFinally:
+			extend(block.End)
+		case Unit:
+			extend(block.End)
+		}
+		return true
+	})
+	return end
+}
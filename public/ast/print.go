@@ -0,0 +1,62 @@
+package ast
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// label describes a single node by its kind and, where it is meaningful,
+// its token, e.g. BinaryOperation '+' or Var 'x'
+func label(node Node) string {
+	kind := reflect.TypeOf(node).Name()
+
+	var text string
+	switch n := node.(type) {
+	case Program:
+		text = n.Name
+	case Unit:
+		text = n.Name
+	case ProcedureCall:
+		text = n.Name
+	case FunctionCall:
+		text = n.Name
+	case FieldAccess:
+		text = n.Field
+	case IntNode, RealNode, StringNode, Var, TypeSpec, UnaryOperation, BinaryOperation, AssignOperation:
+		text = n.GetToken().Text()
+	}
+
+	if text == "" {
+		return kind
+	}
+	return fmt.Sprintf("%s '%s'", kind, text)
+}
+
+// Fprint writes an indented outline of the tree rooted at node to output,
+// one node per line followed by its source position, if known. It is meant
+// for debugging; use the printer package to get Pascal source back.
+func Fprint(output io.Writer, node Node) error {
+	writer := bufio.NewWriter(output)
+
+	depth := 0
+	Inspect(node, func(n Node) bool {
+		if n == nil {
+			depth--
+			return false
+		}
+
+		fmt.Fprint(writer, strings.Repeat("  ", depth), label(n))
+		if pos := Pos(n); pos.IsValid() {
+			fmt.Fprintf(writer, " %v:%v", pos.Line, pos.Column)
+		}
+		fmt.Fprintln(writer)
+
+		depth++
+		return true
+	})
+
+	return writer.Flush()
+}
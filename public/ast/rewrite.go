@@ -0,0 +1,415 @@
+package ast
+
+import "fmt"
+
+// ApplyFunc is called by Rewrite for every node it encounters. The cursor
+// describes the node and its position inside the parent and allows the
+// callback to modify the tree.
+type ApplyFunc func(cursor *Cursor) bool
+
+type iterator struct {
+	base   int
+	before []Node
+	after  []Node
+}
+
+// Cursor describes the node currently visited by Rewrite.
+type Cursor struct {
+	parent  Node
+	name    string
+	iter    *iterator
+	node    Node
+	deleted bool
+}
+
+// Node returns the current node, taking previous Replace calls into account.
+func (r *Cursor) Node() Node {
+	return r.node
+}
+
+// Parent returns the node whose field holds the current node, or nil for
+// the root. Since nodes are values, the parent is the copy that is being
+// rebuilt, its children past the current one are not rewritten yet.
+func (r *Cursor) Parent() Node {
+	return r.parent
+}
+
+// Name returns the name of the parent field holding the current node, e.g.
+// "Left" or "Children". It is empty for the root.
+func (r *Cursor) Name() string {
+	return r.name
+}
+
+// Index returns the position of the current node inside the rewritten
+// slice of its parent, or -1 if the node is not a slice element.
+func (r *Cursor) Index() int {
+	if r.iter == nil {
+		return -1
+	}
+	return r.iter.base + len(r.iter.before)
+}
+
+// Replace substitutes the current node with node. The children of the
+// replacement are visited instead of the original ones when Replace is
+// called from the pre function. Replacing a slice element with nil deletes
+// it, like Delete; other nodes can't be removed, Replace panics if node is
+// nil for them.
+func (r *Cursor) Replace(node Node) {
+	if node == nil {
+		if r.iter == nil {
+			panic(fmt.Sprintf("ast.Rewrite: cannot replace %s.%s with nil, only slice elements can be removed", kindOf(r.parent), r.name))
+		}
+		r.deleted = true
+		return
+	}
+	r.node = node
+}
+
+// Delete removes the current node from the slice holding it. It panics if
+// the node is not a slice element.
+func (r *Cursor) Delete() {
+	if r.iter == nil {
+		panic(fmt.Sprintf("ast.Rewrite: cannot delete %s.%s, it is not a slice element", kindOf(r.parent), r.name))
+	}
+	r.deleted = true
+}
+
+// InsertBefore inserts node before the current one in the slice holding
+// it. Inserted nodes are not visited. It panics if the current node is not
+// a slice element.
+func (r *Cursor) InsertBefore(node Node) {
+	if r.iter == nil {
+		panic(fmt.Sprintf("ast.Rewrite: cannot insert into %s.%s, it is not a slice", kindOf(r.parent), r.name))
+	}
+	r.iter.before = append(r.iter.before, node)
+}
+
+// InsertAfter inserts node after the current one in the slice holding it.
+// Inserted nodes are not visited. It panics if the current node is not a
+// slice element.
+func (r *Cursor) InsertAfter(node Node) {
+	if r.iter == nil {
+		panic(fmt.Sprintf("ast.Rewrite: cannot insert into %s.%s, it is not a slice", kindOf(r.parent), r.name))
+	}
+	r.iter.after = append(r.iter.after, node)
+}
+
+type application struct {
+	pre     ApplyFunc
+	post    ApplyFunc
+	aborted bool
+}
+
+// Rewrite traverses the tree rooted at root in depth-first order and
+// returns the rewritten tree. For every node pre is called before its
+// children and post after them; either may be nil.
+//
+// If pre returns false the children of the node and the post call are
+// skipped. If post returns false the traversal stops and the tree is
+// returned with the modifications made so far. The original tree is never
+// modified.
+func Rewrite(root Node, pre, post ApplyFunc) Node {
+	a := application{pre: pre, post: post}
+	result, _ := a.apply(nil, "", nil, root)
+	return result
+}
+
+func (r *application) apply(parent Node, name string, iter *iterator, node Node) (Node, bool) {
+	if r.aborted {
+		return node, false
+	}
+
+	cursor := Cursor{parent: parent, name: name, iter: iter, node: node}
+	if r.pre != nil && !r.pre(&cursor) {
+		return cursor.node, cursor.deleted
+	}
+	if cursor.deleted {
+		return nil, true
+	}
+
+	cursor.node = r.applyChildren(cursor.node)
+	if r.aborted {
+		return cursor.node, false
+	}
+
+	if r.post != nil && !r.post(&cursor) {
+		r.aborted = true
+	}
+	return cursor.node, cursor.deleted
+}
+
+func applyField[T Node](a *application, parent Node, name string, node T) T {
+	result, _ := a.apply(parent, name, nil, node)
+	return castRewritten[T](result, parent, name)
+}
+
+func applyList[T Node](a *application, parent Node, name string, nodes []T) []T {
+	var result []T
+	for _, node := range nodes {
+		iter := &iterator{base: len(result)}
+		rewritten, deleted := a.apply(parent, name, iter, node)
+
+		for _, v := range iter.before {
+			result = append(result, castRewritten[T](v, parent, name))
+		}
+		if !deleted {
+			result = append(result, castRewritten[T](rewritten, parent, name))
+		}
+		for _, v := range iter.after {
+			result = append(result, castRewritten[T](v, parent, name))
+		}
+	}
+	return result
+}
+
+func castRewritten[T Node](node Node, parent Node, name string) T {
+	casted, ok := node.(T)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot use %s as %s.%s", kindOf(node), kindOf(parent), name))
+	}
+	return casted
+}
+
+func kindOf(node Node) string {
+	return fmt.Sprintf("%T", node)
+}
+
+func (r *application) applyChildren(node Node) Node {
+	switch n := node.(type) {
+	case IntNode, RealNode, StringNode, NilNode, Var, NoOp, TypeSpec, LabelDeclaration, GotoStatement:
+		return n
+
+	case UnaryOperation:
+		n.Right = applyField(r, n, "Right", n.Right)
+		return n
+
+	case BinaryOperation:
+		n.Left = applyField(r, n, "Left", n.Left)
+		n.Right = applyField(r, n, "Right", n.Right)
+		return n
+
+	case AssignOperation:
+		n.Left = applyField(r, n, "Left", n.Left)
+		n.Right = applyField(r, n, "Right", n.Right)
+		return n
+
+	case Compound:
+		n.Children = applyList(r, n, "Children", n.Children)
+		return n
+
+	case VarDeclaration:
+		n.Variable = applyField(r, n, "Variable", n.Variable)
+		n.TypeSpec = applyField(r, n, "TypeSpec", n.TypeSpec)
+		return n
+
+	case ConstDeclaration:
+		n.Name = applyField(r, n, "Name", n.Name)
+		if n.TypeSpec != nil {
+			n.TypeSpec = applyField(r, n, "TypeSpec", n.TypeSpec)
+		}
+		n.Value = applyField(r, n, "Value", n.Value)
+		return n
+
+	case TypeDeclaration:
+		n.Name = applyField(r, n, "Name", n.Name)
+		n.TypeSpec = applyField(r, n, "TypeSpec", n.TypeSpec)
+		return n
+
+	case Block:
+		n.Labels = applyList(r, n, "Labels", n.Labels)
+		n.Declarations = applyList(r, n, "Declarations", n.Declarations)
+		n.Compound = applyField(r, n, "Compound", n.Compound)
+		return n
+
+	case Param:
+		n.Variable = applyField(r, n, "Variable", n.Variable)
+		n.TypeSpec = applyField(r, n, "TypeSpec", n.TypeSpec)
+		return n
+
+	case ProceduralType:
+		n.Params = applyList(r, n, "Params", n.Params)
+		if n.Result != nil {
+			n.Result = applyField(r, n, "Result", n.Result)
+		}
+		return n
+
+	case RoutineDeclaration:
+		if n.Class != nil {
+			n.Class = applyField(r, n, "Class", n.Class)
+		}
+		n.Name = applyField(r, n, "Name", n.Name)
+		n.Params = applyList(r, n, "Params", n.Params)
+		if n.Result != nil {
+			n.Result = applyField(r, n, "Result", n.Result)
+		}
+		n.Block = applyField(r, n, "Block", n.Block)
+		return n
+
+	case Program:
+		n.Uses = applyList(r, n, "Uses", n.Uses)
+		n.Block = applyField(r, n, "Block", n.Block)
+		return n
+
+	case RoutineHeading:
+		n.Name = applyField(r, n, "Name", n.Name)
+		n.Params = applyList(r, n, "Params", n.Params)
+		if n.Result != nil {
+			n.Result = applyField(r, n, "Result", n.Result)
+		}
+		return n
+
+	case Unit:
+		n.InterfaceUses = applyList(r, n, "InterfaceUses", n.InterfaceUses)
+		n.Interface = applyField(r, n, "Interface", n.Interface)
+		n.Headings = applyList(r, n, "Headings", n.Headings)
+		n.ImplementationUses = applyList(r, n, "ImplementationUses", n.ImplementationUses)
+		n.Implementation = applyField(r, n, "Implementation", n.Implementation)
+		n.Finalization = applyList(r, n, "Finalization", n.Finalization)
+		return n
+
+	case ProcedureCall:
+		n.Arguments = applyList(r, n, "Arguments", n.Arguments)
+		return n
+
+	case FunctionCall:
+		n.Arguments = applyList(r, n, "Arguments", n.Arguments)
+		return n
+
+	case Index:
+		n.Value = applyField(r, n, "Value", n.Value)
+		n.Indices = applyList(r, n, "Indices", n.Indices)
+		return n
+
+	case Subrange:
+		n.Low = applyField(r, n, "Low", n.Low)
+		n.High = applyField(r, n, "High", n.High)
+		return n
+
+	case ArrayType:
+		n.Ranges = applyList(r, n, "Ranges", n.Ranges)
+		n.Element = applyField(r, n, "Element", n.Element)
+		return n
+
+	case EnumType:
+		n.Values = applyList(r, n, "Values", n.Values)
+		return n
+
+	case SetType:
+		n.Element = applyField(r, n, "Element", n.Element)
+		return n
+
+	case SetConstructor:
+		n.Elements = applyList(r, n, "Elements", n.Elements)
+		return n
+
+	case PointerType:
+		n.Target = applyField(r, n, "Target", n.Target)
+		return n
+
+	case Dereference:
+		n.Value = applyField(r, n, "Value", n.Value)
+		return n
+
+	case RecordType:
+		n.Fields = applyList(r, n, "Fields", n.Fields)
+		return n
+
+	case FieldAccess:
+		n.Value = applyField(r, n, "Value", n.Value)
+		return n
+
+	case ClassType:
+		if n.Parent != nil {
+			n.Parent = applyField(r, n, "Parent", n.Parent)
+		}
+		n.Fields = applyList(r, n, "Fields", n.Fields)
+		n.Methods = applyList(r, n, "Methods", n.Methods)
+		return n
+
+	case MethodHeading:
+		n.Name = applyField(r, n, "Name", n.Name)
+		n.Params = applyList(r, n, "Params", n.Params)
+		if n.Result != nil {
+			n.Result = applyField(r, n, "Result", n.Result)
+		}
+		return n
+
+	case InheritedCall:
+		n.Arguments = applyList(r, n, "Arguments", n.Arguments)
+		return n
+
+	case WithStatement:
+		n.Records = applyList(r, n, "Records", n.Records)
+		n.Body = applyField(r, n, "Body", n.Body)
+		return n
+
+	case MethodCall:
+		n.Value = applyField(r, n, "Value", n.Value)
+		n.Arguments = applyList(r, n, "Arguments", n.Arguments)
+		return n
+
+	case RaiseStatement:
+		if n.Value != nil {
+			n.Value = applyField(r, n, "Value", n.Value)
+		}
+		return n
+
+	case ExceptionHandler:
+		if n.Variable != nil {
+			n.Variable = applyField(r, n, "Variable", n.Variable)
+		}
+		n.Class = applyField(r, n, "Class", n.Class)
+		n.Body = applyField(r, n, "Body", n.Body)
+		return n
+
+	case TryExcept:
+		n.Body = applyList(r, n, "Body", n.Body)
+		n.Handlers = applyList(r, n, "Handlers", n.Handlers)
+		n.Default = applyList(r, n, "Default", n.Default)
+		return n
+
+	case IfStatement:
+		n.Condition = applyField(r, n, "Condition", n.Condition)
+		n.Then = applyField(r, n, "Then", n.Then)
+		if n.Else != nil {
+			n.Else = applyField(r, n, "Else", n.Else)
+		}
+		return n
+
+	case WhileStatement:
+		n.Condition = applyField(r, n, "Condition", n.Condition)
+		n.Body = applyField(r, n, "Body", n.Body)
+		return n
+
+	case RepeatStatement:
+		n.Body = applyList(r, n, "Body", n.Body)
+		n.Condition = applyField(r, n, "Condition", n.Condition)
+		return n
+
+	case LabeledStatement:
+		n.Statement = applyField(r, n, "Statement", n.Statement)
+		return n
+
+	case ForStatement:
+		n.Variable = applyField(r, n, "Variable", n.Variable)
+		n.Start = applyField(r, n, "Start", n.Start)
+		n.Stop = applyField(r, n, "Stop", n.Stop)
+		n.Body = applyField(r, n, "Body", n.Body)
+		return n
+
+	case TryFinally:
+		n.Body = applyList(r, n, "Body", n.Body)
+		n.Finally = applyList(r, n, "Finally", n.Finally)
+		return n
+
+	case FormattedArgument:
+		n.Value = applyField(r, n, "Value", n.Value)
+		n.Width = applyField(r, n, "Width", n.Width)
+		if n.Precision != nil {
+			n.Precision = applyField(r, n, "Precision", n.Precision)
+		}
+		return n
+	}
+
+	panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", node))
+}
@@ -0,0 +1,166 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+	"github.com/stretchr/testify/require"
+)
+
+func foldConstants(cursor *Cursor) bool {
+	operation, ok := cursor.Node().(BinaryOperation)
+	if !ok {
+		return true
+	}
+
+	left, leftOk := operation.Left.(IntNode)
+	right, rightOk := operation.Right.(IntNode)
+	if leftOk && rightOk && operation.GetToken().TokenType == lexer.PLUS {
+		cursor.Replace(testInt(left.Value + right.Value))
+	}
+	return true
+}
+
+func TestRewrite(t *testing.T) {
+	t.Run("Nil callbacks return an equal tree", func(t *testing.T) {
+		program := testProgram()
+		require.Equal(t, program, Rewrite(program, nil, nil))
+	})
+
+	t.Run("Replace in post folds constants bottom-up", func(t *testing.T) {
+		// 1 + (2 + 3)
+		expression := NewBinaryOperation(
+			testInt(1),
+			NewBinaryOperation(testInt(2), testInt(3), lexer.BasicToken{TokenType: lexer.PLUS}),
+			lexer.BasicToken{TokenType: lexer.PLUS},
+		)
+
+		result := Rewrite(expression, nil, foldConstants)
+		require.Equal(t, testInt(6), result)
+	})
+
+	t.Run("Original tree is not modified", func(t *testing.T) {
+		program := testProgram()
+		Rewrite(program, func(cursor *Cursor) bool {
+			if _, ok := cursor.Node().(UnaryOperation); ok {
+				cursor.Replace(testInt(-2))
+			}
+			return true
+		}, nil)
+
+		require.Equal(t, testProgram(), program)
+	})
+
+	t.Run("Cursor reports parent, field name and index", func(t *testing.T) {
+		type position struct {
+			parent string
+			name   string
+			index  int
+		}
+		var positions []position
+
+		Rewrite(testProgram(), func(cursor *Cursor) bool {
+			if cursor.Parent() == nil {
+				require.Equal(t, "", cursor.Name())
+				return true
+			}
+			positions = append(positions, position{kindOf(cursor.Parent()), cursor.Name(), cursor.Index()})
+			return true
+		}, nil)
+
+		require.Contains(t, positions, position{"ast.Program", "Block", -1})
+		require.Contains(t, positions, position{"ast.Block", "Declarations", 0})
+		require.Contains(t, positions, position{"ast.Compound", "Children", 1})
+		require.Contains(t, positions, position{"ast.UnaryOperation", "Right", -1})
+	})
+
+	t.Run("Delete and insert slice elements", func(t *testing.T) {
+		result := Rewrite(testProgram(), func(cursor *Cursor) bool {
+			switch cursor.Node().(type) {
+			case NoOp:
+				cursor.Delete()
+			case AssignOperation:
+				cursor.InsertBefore(testVar("before"))
+				require.Equal(t, 1, cursor.Index())
+				cursor.InsertAfter(testVar("after"))
+			}
+			return true
+		}, nil)
+
+		children := result.(Program).Block.Compound.Children
+		require.Len(t, children, 3)
+		require.Equal(t, testVar("before"), children[0])
+		require.IsType(t, AssignOperation{}, children[1])
+		require.Equal(t, testVar("after"), children[2])
+	})
+
+	t.Run("Returning false from pre skips children", func(t *testing.T) {
+		visited := 0
+		Rewrite(testProgram(), func(cursor *Cursor) bool {
+			visited++
+			_, isBlock := cursor.Node().(Block)
+			return !isBlock
+		}, nil)
+
+		require.Equal(t, 2, visited)
+	})
+
+	t.Run("Returning false from post aborts and keeps earlier changes", func(t *testing.T) {
+		result := Rewrite(testProgram(), nil, func(cursor *Cursor) bool {
+			if _, ok := cursor.Node().(IntNode); ok {
+				cursor.Replace(testInt(42))
+				return false
+			}
+			return true
+		})
+
+		operation := result.(Program).Block.Compound.Children[0].(AssignOperation).Right.(BinaryOperation)
+		require.Equal(t, testInt(42), operation.Left)
+		require.Equal(t, testInt(2), operation.Right.(UnaryOperation).Right)
+	})
+
+	t.Run("Delete outside of a slice panics", func(t *testing.T) {
+		require.Panics(t, func() {
+			Rewrite(testProgram(), func(cursor *Cursor) bool {
+				if _, ok := cursor.Node().(Block); ok {
+					cursor.Delete()
+				}
+				return true
+			}, nil)
+		})
+	})
+
+	t.Run("Replacing a slice element with nil deletes it", func(t *testing.T) {
+		result := Rewrite(testProgram(), func(cursor *Cursor) bool {
+			if _, ok := cursor.Node().(AssignOperation); ok {
+				cursor.Replace(nil)
+			}
+			return true
+		}, nil)
+		children := result.(Program).Block.Compound.Children
+		require.Len(t, children, 1)
+		require.IsType(t, NoOp{}, children[0])
+	})
+
+	t.Run("Replacing a field with nil panics", func(t *testing.T) {
+		require.PanicsWithValue(t, "ast.Rewrite: cannot replace ast.Program.Block with nil, only slice elements can be removed", func() {
+			Rewrite(testProgram(), func(cursor *Cursor) bool {
+				if _, ok := cursor.Node().(Block); ok {
+					cursor.Replace(nil)
+				}
+				return true
+			}, nil)
+		})
+	})
+
+	t.Run("Replacing a typed field with a wrong node panics", func(t *testing.T) {
+		require.Panics(t, func() {
+			Rewrite(testProgram(), func(cursor *Cursor) bool {
+				if _, ok := cursor.Node().(Block); ok {
+					cursor.Replace(NewNoOp())
+				}
+				return true
+			}, nil)
+		})
+	})
+}
@@ -0,0 +1,241 @@
+package ast
+
+import "fmt"
+
+// Visitor's Visit method is invoked by Walk for each node it encounters.
+// If the returned visitor is not nil, Walk visits every child of the node
+// with it and finishes with a call to Visit(nil).
+type Visitor interface {
+	Visit(node Node) Visitor
+}
+
+func walkList[T Node](v Visitor, nodes []T) {
+	for _, node := range nodes {
+		Walk(v, node)
+	}
+}
+
+// Walk traverses the tree rooted at node in depth-first order, calling
+// v.Visit(node) first and descending into the children only if the
+// visitor it returns is not nil.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case IntNode, RealNode, StringNode, NilNode, Var, NoOp, TypeSpec, LabelDeclaration, GotoStatement:
+		// leaves, nothing to descend into
+
+	case UnaryOperation:
+		Walk(v, n.Right)
+
+	case BinaryOperation:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+
+	case AssignOperation:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+
+	case Compound:
+		walkList(v, n.Children)
+
+	case VarDeclaration:
+		Walk(v, n.Variable)
+		Walk(v, n.TypeSpec)
+
+	case ConstDeclaration:
+		Walk(v, n.Name)
+		if n.TypeSpec != nil {
+			Walk(v, n.TypeSpec)
+		}
+		Walk(v, n.Value)
+
+	case TypeDeclaration:
+		Walk(v, n.Name)
+		Walk(v, n.TypeSpec)
+
+	case Block:
+		walkList(v, n.Labels)
+		walkList(v, n.Declarations)
+		Walk(v, n.Compound)
+
+	case Param:
+		Walk(v, n.Variable)
+		Walk(v, n.TypeSpec)
+
+	case ProceduralType:
+		walkList(v, n.Params)
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+
+	case RoutineDeclaration:
+		if n.Class != nil {
+			Walk(v, n.Class)
+		}
+		Walk(v, n.Name)
+		walkList(v, n.Params)
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+		Walk(v, n.Block)
+
+	case Program:
+		walkList(v, n.Uses)
+		Walk(v, n.Block)
+
+	case RoutineHeading:
+		Walk(v, n.Name)
+		walkList(v, n.Params)
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+
+	case Unit:
+		walkList(v, n.InterfaceUses)
+		Walk(v, n.Interface)
+		walkList(v, n.Headings)
+		walkList(v, n.ImplementationUses)
+		Walk(v, n.Implementation)
+		walkList(v, n.Finalization)
+
+	case ProcedureCall:
+		walkList(v, n.Arguments)
+
+	case FunctionCall:
+		walkList(v, n.Arguments)
+
+	case Index:
+		Walk(v, n.Value)
+		walkList(v, n.Indices)
+
+	case Subrange:
+		Walk(v, n.Low)
+		Walk(v, n.High)
+
+	case ArrayType:
+		walkList(v, n.Ranges)
+		Walk(v, n.Element)
+
+	case EnumType:
+		walkList(v, n.Values)
+
+	case SetType:
+		Walk(v, n.Element)
+
+	case SetConstructor:
+		walkList(v, n.Elements)
+
+	case PointerType:
+		Walk(v, n.Target)
+
+	case Dereference:
+		Walk(v, n.Value)
+
+	case RecordType:
+		walkList(v, n.Fields)
+
+	case FieldAccess:
+		Walk(v, n.Value)
+
+	case ClassType:
+		if n.Parent != nil {
+			Walk(v, n.Parent)
+		}
+		walkList(v, n.Fields)
+		walkList(v, n.Methods)
+
+	case MethodHeading:
+		Walk(v, n.Name)
+		walkList(v, n.Params)
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+
+	case InheritedCall:
+		walkList(v, n.Arguments)
+
+	case WithStatement:
+		walkList(v, n.Records)
+		Walk(v, n.Body)
+
+	case MethodCall:
+		Walk(v, n.Value)
+		walkList(v, n.Arguments)
+
+	case RaiseStatement:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case ExceptionHandler:
+		if n.Variable != nil {
+			Walk(v, n.Variable)
+		}
+		Walk(v, n.Class)
+		Walk(v, n.Body)
+
+	case TryExcept:
+		walkList(v, n.Body)
+		walkList(v, n.Handlers)
+		walkList(v, n.Default)
+
+	case IfStatement:
+		Walk(v, n.Condition)
+		Walk(v, n.Then)
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+
+	case WhileStatement:
+		Walk(v, n.Condition)
+		Walk(v, n.Body)
+
+	case RepeatStatement:
+		walkList(v, n.Body)
+		Walk(v, n.Condition)
+
+	case LabeledStatement:
+		Walk(v, n.Statement)
+
+	case ForStatement:
+		Walk(v, n.Variable)
+		Walk(v, n.Start)
+		Walk(v, n.Stop)
+		Walk(v, n.Body)
+
+	case TryFinally:
+		walkList(v, n.Body)
+		walkList(v, n.Finally)
+
+	case FormattedArgument:
+		Walk(v, n.Value)
+		Walk(v, n.Width)
+		if n.Precision != nil {
+			Walk(v, n.Precision)
+		}
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (r inspector) Visit(node Node) Visitor {
+	if r(node) {
+		return r
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in depth-first order, calling
+// f for every node. If f returns false the children of that node are
+// skipped. Once all children of a node are visited f is called with nil.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,136 @@
+package ast
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+	"github.com/stretchr/testify/require"
+)
+
+func testVar(name string) Var {
+	v, _ := NewVar(lexer.BasicToken{TokenType: lexer.ID, TokenValue: name})
+	return v
+}
+
+func testInt(value int) IntNode {
+	n, _ := NewIntNode(lexer.BasicToken{TokenType: lexer.INTEGER, TokenValue: fmt.Sprint(value)})
+	return n
+}
+
+// PROGRAM test; VAR a : INTEGER; BEGIN a := 1 + -2; END.
+func testProgram() Program {
+	declaration := NewVarDeclaration(
+		testVar("a"),
+		NewTypeSpec(lexer.BasicToken{TokenType: lexer.INTEGER_DECLARAION, TokenValue: "INTEGER"}),
+		lexer.BasicToken{TokenType: lexer.COLON},
+	)
+
+	sum := NewBinaryOperation(
+		testInt(1),
+		NewUnaryOperation(testInt(2), lexer.BasicToken{TokenType: lexer.MINUS}),
+		lexer.BasicToken{TokenType: lexer.PLUS},
+	)
+	assign := NewAssignt(testVar("a"), sum, lexer.BasicToken{TokenType: lexer.ASSIGN})
+	compound := NewCompound([]Node{assign, NewNoOp()}, lexer.BasicToken{TokenType: lexer.BEGIN})
+
+	block := NewBlock([]Node{declaration}, compound, lexer.BasicToken{TokenType: lexer.VAR})
+	return NewProgram("test", block, lexer.BasicToken{TokenType: lexer.PROGRAM})
+}
+
+func nodeKind(node Node) string {
+	return fmt.Sprintf("%T", node)
+}
+
+func TestInspect(t *testing.T) {
+	t.Run("Visits every node in depth-first order", func(t *testing.T) {
+		var visited []string
+		Inspect(testProgram(), func(node Node) bool {
+			if node != nil {
+				visited = append(visited, nodeKind(node))
+			}
+			return true
+		})
+
+		require.Equal(t, []string{
+			"ast.Program",
+			"ast.Block",
+			"ast.VarDeclaration",
+			"ast.Var",
+			"ast.TypeSpec",
+			"ast.Compound",
+			"ast.AssignOperation",
+			"ast.Var",
+			"ast.BinaryOperation",
+			"ast.IntNode",
+			"ast.UnaryOperation",
+			"ast.IntNode",
+			"ast.NoOp",
+		}, visited)
+	})
+
+	t.Run("Returning false prunes the subtree", func(t *testing.T) {
+		var visited []string
+		Inspect(testProgram(), func(node Node) bool {
+			if node == nil {
+				return false
+			}
+			visited = append(visited, nodeKind(node))
+			_, isAssign := node.(AssignOperation)
+			_, isDeclaration := node.(VarDeclaration)
+			return !isAssign && !isDeclaration
+		})
+
+		require.Equal(t, []string{
+			"ast.Program",
+			"ast.Block",
+			"ast.VarDeclaration",
+			"ast.Compound",
+			"ast.AssignOperation",
+			"ast.NoOp",
+		}, visited)
+	})
+
+	t.Run("Calls f with nil after the children", func(t *testing.T) {
+		depth, maxDepth := 0, 0
+		Inspect(testProgram(), func(node Node) bool {
+			if node == nil {
+				depth--
+				return false
+			}
+			depth++
+			maxDepth = max(maxDepth, depth)
+			return true
+		})
+
+		require.Equal(t, 0, depth)
+		require.Equal(t, 7, maxDepth)
+	})
+}
+
+type countingVisitor map[string]int
+
+func (r countingVisitor) Visit(node Node) Visitor {
+	if node != nil {
+		r[nodeKind(node)]++
+	}
+	return r
+}
+
+func TestWalk(t *testing.T) {
+	t.Run("Visitor receives every node", func(t *testing.T) {
+		counts := countingVisitor{}
+		Walk(counts, testProgram())
+
+		require.Equal(t, 2, counts["ast.Var"])
+		require.Equal(t, 2, counts["ast.IntNode"])
+		require.Equal(t, 1, counts["ast.BinaryOperation"])
+		require.Equal(t, 1, counts["ast.NoOp"])
+	})
+
+	t.Run("Unknown node types panic", func(t *testing.T) {
+		require.Panics(t, func() {
+			Walk(countingVisitor{}, BasicNode{})
+		})
+	})
+}
@@ -0,0 +1,143 @@
+// Package format implements the canonical formatting of Pascal source.
+package format
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/interpreter"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/printer"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Source parses src as a Pascal program and returns it canonically
+// formatted, keeping its comments.
+func Source(src []byte) ([]byte, error) {
+	parser, err := interpreter.NewParser(lexer.NewLexer(string(src)))
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	var output bytes.Buffer
+	commented := printer.CommentedNode{
+		Node:     node,
+		Comments: parser.Lexer.GetComments(),
+		Source:   src,
+	}
+	if err := printer.Fprint(&output, commented); err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
+
+func splitLines(text []byte) []string {
+	lines := strings.SplitAfter(string(text), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Diff returns the unified diff between the original and the formatted
+// version of the file at path.
+func Diff(path string, original []byte, formatted []byte) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(original),
+		B:        splitLines(formatted),
+		FromFile: path + ".orig",
+		ToFile:   path,
+		Context:  3,
+	})
+}
+
+// Options tells File what to do with a file whose formatting differs from
+// the canonical one. Without any, the formatted source is written instead.
+type Options struct {
+	// List writes the path of the file
+	List bool
+	// Write rewrites the file, keeping its mode
+	Write bool
+	// Diff writes the diff between the file and its formatted version
+	Diff bool
+}
+
+// RegisterFlags defines the -l, -w and -d flags of the commands formatting
+// files, which set the options
+func (r *Options) RegisterFlags(flags *flag.FlagSet) {
+	flags.BoolVar(&r.List, "l", false, "list files whose formatting differs from the canonical one")
+	flags.BoolVar(&r.Write, "w", false, "write the result to the source file instead of the standard output")
+	flags.BoolVar(&r.Diff, "d", false, "display diffs instead of rewriting files")
+}
+
+// Walk calls fn with path if it is a file and, if it is a directory, with
+// every .pas file in it and its subdirectories, in lexical order. It
+// returns the error of reading path or one of the directories.
+func Walk(path string, fn func(path string)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		fn(path)
+		return nil
+	}
+	return filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(path), ".pas") {
+			fn(path)
+		}
+		return nil
+	})
+}
+
+// File formats src, the content of the file at path, and writes the result
+// to output, or does what options ask for if its formatting differs.
+func File(path string, src []byte, output io.Writer, options Options) error {
+	formatted, err := Source(src)
+	if err != nil {
+		return err
+	}
+
+	if !options.List && !options.Write && !options.Diff {
+		_, err = output.Write(formatted)
+		return err
+	}
+	if bytes.Equal(src, formatted) {
+		return nil
+	}
+
+	if options.List {
+		fmt.Fprintln(output, path)
+	}
+	if options.Write {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if options.Diff {
+		text, err := Diff(path, src, formatted)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(output, text)
+	}
+	return nil
+}
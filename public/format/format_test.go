@@ -0,0 +1,139 @@
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSource(t *testing.T) {
+	t.Run("Keywords are upper-cased and code is re-indented", func(t *testing.T) {
+		formatted, err := Source([]byte(`program Demo;  var x:integer;
+begin    x:=(1+2)*3 {done} end.`))
+		require.NoError(t, err)
+		require.Equal(t, `PROGRAM Demo;
+VAR
+  x: INTEGER;
+BEGIN
+  x := (1 + 2) * 3 {done}
+END.
+`, string(formatted))
+	})
+
+	t.Run("Formatted source is left untouched", func(t *testing.T) {
+		source := "PROGRAM Demo;\nBEGIN\nEND.\n"
+		formatted, err := Source([]byte(source))
+		require.NoError(t, err)
+		require.Equal(t, source, string(formatted))
+	})
+
+	t.Run("Syntax errors are reported", func(t *testing.T) {
+		_, err := Source([]byte("PROGRAM Demo; BEGIN x := END."))
+		require.Error(t, err)
+	})
+
+	t.Run("Excluded conditional source is kept verbatim", func(t *testing.T) {
+		formatted, err := Source([]byte(`program Demo;
+begin
+  {$IFDEF DEBUG} writeln( 'debug' ); {$ELSE} writeln( 'release' ) {$ENDIF}
+end.`))
+		require.NoError(t, err)
+		require.Equal(t, `PROGRAM Demo;
+BEGIN
+  {$IFDEF DEBUG}
+  writeln( 'debug' );
+  {$ELSE}
+  writeln('release') {$ENDIF}
+END.
+`, string(formatted))
+	})
+
+	t.Run("Unterminated comments are reported", func(t *testing.T) {
+		_, err := Source([]byte("PROGRAM Demo; BEGIN { END."))
+		require.Error(t, err)
+	})
+}
+
+func TestDiff(t *testing.T) {
+	t.Run("Unified diff between original and formatted source", func(t *testing.T) {
+		diff, err := Diff("demo.pas", []byte("program demo;\nbegin\nend.\n"), []byte("PROGRAM demo;\nBEGIN\nEND.\n"))
+		require.NoError(t, err)
+		require.Equal(t, `--- demo.pas.orig
++++ demo.pas
+@@ -1,3 +1,3 @@
+-program demo;
+-begin
+-end.
++PROGRAM demo;
++BEGIN
++END.
+`, diff)
+	})
+}
+
+func TestFile(t *testing.T) {
+	src := []byte("program demo;\nbegin\nend.\n")
+	formatted := "PROGRAM demo;\nBEGIN\nEND.\n"
+
+	t.Run("Formatted source is written to output", func(t *testing.T) {
+		var output strings.Builder
+		require.NoError(t, File("demo.pas", src, &output, Options{}))
+		require.Equal(t, formatted, output.String())
+	})
+
+	t.Run("Files are listed and rewritten keeping their mode", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "demo.pas")
+		require.NoError(t, os.WriteFile(path, src, 0o600))
+
+		var output strings.Builder
+		require.NoError(t, File(path, src, &output, Options{List: true, Write: true}))
+		require.Equal(t, path+"\n", output.String())
+
+		text, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, formatted, string(text))
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("Formatted files are left alone", func(t *testing.T) {
+		var output strings.Builder
+		require.NoError(t, File("demo.pas", []byte(formatted), &output, Options{List: true, Diff: true}))
+		require.Empty(t, output.String())
+	})
+}
+
+func TestWalk(t *testing.T) {
+	t.Run("Directories are walked for .pas files", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+		for _, name := range []string{"a.pas", "notes.txt", filepath.Join("sub", "b.PAS")} {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+		}
+
+		var paths []string
+		require.NoError(t, Walk(dir, func(path string) {
+			paths = append(paths, path)
+		}))
+		require.Equal(t, []string{filepath.Join(dir, "a.pas"), filepath.Join(dir, "sub", "b.PAS")}, paths)
+	})
+
+	t.Run("Files are passed as they are", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "notes.txt")
+		require.NoError(t, os.WriteFile(path, nil, 0o644))
+
+		var paths []string
+		require.NoError(t, Walk(path, func(path string) {
+			paths = append(paths, path)
+		}))
+		require.Equal(t, []string{path}, paths)
+	})
+
+	t.Run("Missing paths are reported", func(t *testing.T) {
+		require.Error(t, Walk(filepath.Join(t.TempDir(), "missing"), func(string) {}))
+	})
+}
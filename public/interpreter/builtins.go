@@ -0,0 +1,447 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+)
+
+// builtinRoutine is a procedure or function provided by the interpreter.
+// Arguments are passed to call evaluated, a VAR parameter as a *reference
+// and a value:width:precision argument as a formattedValue.
+type builtinRoutine struct {
+	function bool
+	// bounds of the number of arguments, maxArgs is -1 if unbounded
+	minArgs int
+	maxArgs int
+	// positions of the VAR parameters
+	varParams []int
+	// positions of the arguments accepting a width and precision
+	formatParams []int
+	// overloads the semantic analyzer chooses from, routines without any
+	// only have their number of arguments checked
+	signatures []signature
+	// result type of functions without signatures
+	result string
+	call   func(args []any) (any, error)
+}
+
+// Type names in signatures matching more than a single type
+const (
+	// anyOrdinal matches INTEGER, CHAR and BOOLEAN
+	anyOrdinal = "<ordinal>"
+	// sameAsArgument as the result is the type of the first argument
+	sameAsArgument = "<argument>"
+	// anyReference matches pointers, classes and procedural types
+	anyReference = "<reference>"
+)
+
+// signature lists the parameter type names of an overload and, for
+// functions, the result type name
+type signature struct {
+	params []string
+	result string
+}
+
+func returns(result string, params ...string) signature {
+	return signature{params: params, result: result}
+}
+
+func takes(params ...string) signature {
+	return signature{params: params}
+}
+
+func (r builtinRoutine) isVarParam(position int) bool {
+	for _, v := range r.varParams {
+		if v == position {
+			return true
+		}
+	}
+	return false
+}
+
+func (r builtinRoutine) isFormatParam(position int) bool {
+	for _, v := range r.formatParams {
+		if v == position {
+			return true
+		}
+	}
+	return false
+}
+
+// formattedValue is an evaluated value:width:precision argument, missing
+// specifiers are negative
+type formattedValue struct {
+	value     any
+	width     int
+	precision int
+}
+
+// builtinRoutines holds the routines besides WRITE, WRITELN, READ and
+// READLN, which take arguments of any number and type, keyed by upper-case
+// name
+var builtinRoutines = map[string]builtinRoutine{
+	"LENGTH": {function: true, minArgs: 1, maxArgs: 1, call: builtinLength,
+		signatures: []signature{returns("INTEGER", "STRING")}},
+	"COPY": {function: true, minArgs: 3, maxArgs: 3, call: builtinCopy,
+		signatures: []signature{returns("STRING", "STRING", "INTEGER", "INTEGER")}},
+	"POS": {function: true, minArgs: 2, maxArgs: 2, call: builtinPos,
+		signatures: []signature{returns("INTEGER", "STRING", "STRING")}},
+	"CONCAT": {function: true, minArgs: 1, maxArgs: -1, call: builtinConcat,
+		result: "STRING"},
+	"UPPERCASE": {function: true, minArgs: 1, maxArgs: 1, call: builtinUpperCase,
+		signatures: []signature{returns("STRING", "STRING")}},
+	"INTTOSTR": {function: true, minArgs: 1, maxArgs: 1, call: builtinIntToStr,
+		signatures: []signature{returns("STRING", "INTEGER")}},
+	"STRTOINT": {function: true, minArgs: 1, maxArgs: 1, call: builtinStrToInt,
+		signatures: []signature{returns("INTEGER", "STRING")}},
+	"DELETE": {minArgs: 3, maxArgs: 3, varParams: []int{0}, call: builtinDelete,
+		signatures: []signature{takes("STRING", "INTEGER", "INTEGER")}},
+	"INSERT": {minArgs: 3, maxArgs: 3, varParams: []int{1}, call: builtinInsert,
+		signatures: []signature{takes("STRING", "STRING", "INTEGER")}},
+	"VAL": {minArgs: 3, maxArgs: 3, varParams: []int{1, 2}, call: builtinVal,
+		signatures: []signature{takes("STRING", "INTEGER", "INTEGER"), takes("STRING", "REAL", "INTEGER")}},
+	"STR": {minArgs: 2, maxArgs: 2, varParams: []int{1}, formatParams: []int{0}, call: builtinStr,
+		signatures: []signature{takes("INTEGER", "STRING"), takes("REAL", "STRING")}},
+
+	"ABS": {function: true, minArgs: 1, maxArgs: 1, call: builtinAbs,
+		signatures: []signature{returns("INTEGER", "INTEGER"), returns("REAL", "REAL")}},
+	"SQR": {function: true, minArgs: 1, maxArgs: 1, call: builtinSqr,
+		signatures: []signature{returns("INTEGER", "INTEGER"), returns("REAL", "REAL")}},
+	"SQRT": {function: true, minArgs: 1, maxArgs: 1, call: builtinSqrt,
+		signatures: []signature{returns("REAL", "REAL")}},
+	"SIN": {function: true, minArgs: 1, maxArgs: 1, call: realFunction(math.Sin),
+		signatures: []signature{returns("REAL", "REAL")}},
+	"COS": {function: true, minArgs: 1, maxArgs: 1, call: realFunction(math.Cos),
+		signatures: []signature{returns("REAL", "REAL")}},
+	"ARCTAN": {function: true, minArgs: 1, maxArgs: 1, call: realFunction(math.Atan),
+		signatures: []signature{returns("REAL", "REAL")}},
+	"EXP": {function: true, minArgs: 1, maxArgs: 1, call: realFunction(math.Exp),
+		signatures: []signature{returns("REAL", "REAL")}},
+	"LN": {function: true, minArgs: 1, maxArgs: 1, call: builtinLn,
+		signatures: []signature{returns("REAL", "REAL")}},
+	"ROUND": {function: true, minArgs: 1, maxArgs: 1, call: builtinRound,
+		signatures: []signature{returns("INTEGER", "REAL")}},
+	"TRUNC": {function: true, minArgs: 1, maxArgs: 1, call: builtinTrunc,
+		signatures: []signature{returns("INTEGER", "REAL")}},
+	"ODD": {function: true, minArgs: 1, maxArgs: 1, call: builtinOdd,
+		signatures: []signature{returns("BOOLEAN", "INTEGER")}},
+	"SUCC": {function: true, minArgs: 1, maxArgs: 1, call: builtinSucc,
+		signatures: []signature{returns(sameAsArgument, anyOrdinal)}},
+	"PRED": {function: true, minArgs: 1, maxArgs: 1, call: builtinPred,
+		signatures: []signature{returns(sameAsArgument, anyOrdinal)}},
+	"ORD": {function: true, minArgs: 1, maxArgs: 1, call: builtinOrd,
+		signatures: []signature{returns("INTEGER", anyOrdinal)}},
+	"CHR": {function: true, minArgs: 1, maxArgs: 1, call: builtinChr,
+		signatures: []signature{returns("CHAR", "INTEGER")}},
+
+	"ASSIGNED": {function: true, minArgs: 1, maxArgs: 1, varParams: []int{0}, call: builtinAssigned,
+		signatures: []signature{returns("BOOLEAN", anyReference)}},
+}
+
+func stringArg(args []any, position int) (string, error) {
+	value, ok := toString(args[position])
+	if !ok {
+		return "", fmt.Errorf("Incompatible type for argument %d: got %v expected STRING", position+1, typeName(args[position]))
+	}
+	return value, nil
+}
+
+func intArg(args []any, position int) (int, error) {
+	value, ok := args[position].(int)
+	if !ok {
+		return 0, fmt.Errorf("Incompatible type for argument %d: got %v expected INTEGER", position+1, typeName(args[position]))
+	}
+	return value, nil
+}
+
+// Length(s): the number of characters in s
+func builtinLength(args []any) (any, error) {
+	s, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return len(s), nil
+}
+
+// clamp limits a count of characters starting at the 1-based index to a
+// string of the given length and returns them as a 0-based slice range
+func clamp(length int, index int, count int) (int, int) {
+	if index > length || count <= 0 {
+		return length, length
+	}
+	return index - 1, min(length, index-1+count)
+}
+
+// Copy(s, index, count): count characters of s starting at index, fewer
+// if s is shorter
+func builtinCopy(args []any) (any, error) {
+	s, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	index, err := intArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	count, err := intArg(args, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	// unlike Delete, Copy starts at the first character if index is below 1
+	start, end := clamp(len(s), max(1, index), count)
+	return s[start:end], nil
+}
+
+// Pos(substr, s): the index of the first occurrence of substr in s, 0 if
+// there is none
+func builtinPos(args []any) (any, error) {
+	substr, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	s, err := stringArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if substr == "" {
+		return 0, nil
+	}
+	return strings.Index(s, substr) + 1, nil
+}
+
+// Concat(s1, s2, ...): the arguments joined
+func builtinConcat(args []any) (any, error) {
+	var result strings.Builder
+	for i := range args {
+		s, err := stringArg(args, i)
+		if err != nil {
+			return nil, err
+		}
+		result.WriteString(s)
+	}
+	return result.String(), nil
+}
+
+func builtinUpperCase(args []any) (any, error) {
+	s, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToUpper(s), nil
+}
+
+func builtinIntToStr(args []any) (any, error) {
+	value, err := intArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return strconv.Itoa(value), nil
+}
+
+func builtinStrToInt(args []any) (any, error) {
+	s, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return nil, kindError(ConvertError, "'%v' is not a valid integer value", s)
+	}
+	return value, nil
+}
+
+// Delete(var s, index, count): removes count characters of s starting at
+// index, nothing if index is not within s
+func builtinDelete(args []any) (any, error) {
+	variable := args[0].(*reference)
+	value, err := variable.get()
+	if err != nil {
+		return nil, err
+	}
+	s, err := stringArg([]any{value}, 0)
+	if err != nil {
+		return nil, err
+	}
+	index, err := intArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	count, err := intArg(args, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	if index < 1 {
+		return nil, nil
+	}
+	start, end := clamp(len(s), index, count)
+	return nil, variable.set(s[:start] + s[end:])
+}
+
+// Insert(source, var s, index): inserts source into s before index,
+// appends it if index is past the end of s
+func builtinInsert(args []any) (any, error) {
+	source, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	variable := args[1].(*reference)
+	value, err := variable.get()
+	if err != nil {
+		return nil, err
+	}
+	s, err := stringArg([]any{value}, 0)
+	if err != nil {
+		return nil, err
+	}
+	index, err := intArg(args, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	position := min(max(index, 1), len(s)+1) - 1
+	return nil, variable.set(s[:position] + source + s[position:])
+}
+
+// numberLength returns the length of the longest prefix of s that is an
+// INTEGER, or a REAL if isReal is set
+func numberLength(s string, isReal bool) int {
+	digits := func(i int) int {
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return i
+	}
+
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	end := digits(i)
+	if end == i || !isReal {
+		return end
+	}
+
+	if end+1 < len(s) && s[end] == '.' {
+		if fraction := digits(end + 1); fraction > end+1 {
+			end = fraction
+		}
+	}
+	if end < len(s) && (s[end] == 'e' || s[end] == 'E') {
+		i = end + 1
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if exponent := digits(i); exponent > i {
+			end = exponent
+		}
+	}
+	return end
+}
+
+// Val(s, var v, var code): converts s to the type of v. On success code
+// is 0, otherwise the position of the first offending character and v is
+// left unchanged. Leading blanks are skipped.
+func builtinVal(args []any) (any, error) {
+	s, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	variable, code := args[1].(*reference), args[2].(*reference)
+
+	trimmed := strings.TrimLeft(s, " \t")
+	offset := len(s) - len(trimmed)
+
+	var value any
+	switch variable.typeName {
+	case "INTEGER":
+		length := numberLength(trimmed, false)
+		if length == len(trimmed) && length > 0 {
+			value, err = strconv.Atoi(trimmed)
+		}
+		if value == nil || err != nil {
+			return nil, code.set(offset + length + 1)
+		}
+	case "REAL":
+		length := numberLength(trimmed, true)
+		if length == len(trimmed) && length > 0 {
+			value, err = strconv.ParseFloat(trimmed, 64)
+		}
+		if value == nil || err != nil {
+			return nil, code.set(offset + length + 1)
+		}
+	default:
+		return nil, fmt.Errorf("Cannot convert a STRING to %v", variable.typeName)
+	}
+
+	if err := variable.set(value); err != nil {
+		return nil, err
+	}
+	return nil, code.set(0)
+}
+
+// Str(x:width:precision, var s): stores x in s formatted as WRITE does
+func builtinStr(args []any) (any, error) {
+	argument, ok := args[0].(formattedValue)
+	if !ok {
+		argument = formattedValue{value: args[0], width: -1, precision: -1}
+	}
+
+	if _, isNumber := toReal(argument.value); !isNumber {
+		return nil, fmt.Errorf("Incompatible type for argument 1: got %v expected a number", typeName(argument.value))
+	}
+	text, err := formatValue(argument.value, argument.width, argument.precision)
+	if err != nil {
+		return nil, err
+	}
+	return nil, args[1].(*reference).set(text)
+}
+
+// callBuiltin evaluates the arguments of a call to routine and calls it.
+// Errors of the routine are reported at node.
+func (r *EvaluatorVisitor) callBuiltin(node ast.Node, routine builtinRoutine, arguments []ast.Node) (any, error) {
+	var args []any
+	for i, v := range arguments {
+		if routine.isVarParam(i) {
+			variable, err := r.referenceTo(v)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, variable)
+			continue
+		}
+
+		if _, isFormatted := v.(ast.FormattedArgument); isFormatted {
+			argument, err := r.evaluateFormatted(v)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, argument)
+			continue
+		}
+
+		value, err := r.Visit(v)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+
+	result, err := routine.call(args)
+	if err != nil {
+		var runtimeError RuntimeError
+		if !errors.As(err, &runtimeError) || !runtimeError.Pos.IsValid() {
+			err = runtimeErrorAt(node, err)
+		}
+		return nil, err
+	}
+	return result, nil
+}
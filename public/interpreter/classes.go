@@ -0,0 +1,542 @@
+package interpreter
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+)
+
+// classType is a CLASS, Parent is nil for TObject only. Fields holds the
+// fields of the ancestors followed by the ones the class declares,
+// Methods the methods it declares by upper case name.
+type classType struct {
+	Name    string
+	Parent  *classType
+	Fields  []recordField
+	Methods map[string]*classMethod
+}
+
+func (r *classType) String() string {
+	return r.Name
+}
+
+// descends reports whether the class is ancestor or derived from it
+func (r *classType) descends(ancestor *classType) bool {
+	for c := r; c != nil; c = c.Parent {
+		if c == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// field returns the position of the named field, -1 if there is none
+func (r *classType) field(name string) int {
+	for i, v := range r.Fields {
+		if strings.EqualFold(v.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// method returns the named method declared by the class or, if it does
+// not declare one, by its closest ancestor declaring it
+func (r *classType) method(name string) (*classMethod, bool) {
+	for c := r; c != nil; c = c.Parent {
+		if method, ok := c.Methods[strings.ToUpper(name)]; ok {
+			return method, true
+		}
+	}
+	return nil, false
+}
+
+// declare adds a method to the methods the class declares
+func (r *classType) declare(method *classMethod) {
+	method.Class = r
+	r.Methods[strings.ToUpper(method.Name)] = method
+}
+
+// classMethod is a method declared by Class, Kind being the keyword it is
+// declared with. Declaration is the implementation, whose Frame is the
+// one the class was declared in. The methods of the predeclared classes
+// have no Declaration, the interpreter provides them.
+type classMethod struct {
+	Name        string
+	Kind        lexer.TokenType
+	Type        procType
+	Virtual     bool
+	Class       *classType
+	Declaration *ast.RoutineDeclaration
+	Frame       *frame
+}
+
+// objectValue is an instance of Class, created by a constructor. Objects
+// are shared rather than copied on assignment, a variable of a class type
+// holding an object or NIL. Destroyed is set once a destructor ran.
+type objectValue struct {
+	Class     *classType
+	Fields    []any
+	Destroyed bool
+}
+
+func (r *objectValue) String() string {
+	if r.Class.descends(exceptionType) {
+		return r.Class.Name + ": " + r.message()
+	}
+	return r.Class.Name
+}
+
+// message returns the Message of an exception
+func (r *objectValue) message() string {
+	message, _ := r.Fields[r.Class.field("Message")].(string)
+	return message
+}
+
+// boundMethod is a method of Object, named inside the methods of its
+// class without Self
+type boundMethod struct {
+	Object *objectValue
+	Method *classMethod
+}
+
+var objectType = &classType{Name: "TObject", Methods: map[string]*classMethod{}}
+
+// predeclaredClasses holds TObject and the exception types by upper case
+// name
+var predeclaredClasses = map[string]*classType{}
+
+func init() {
+	objectType.declare(&classMethod{Name: "Create", Kind: lexer.CONSTRUCTOR})
+	objectType.declare(&classMethod{Name: "Destroy", Kind: lexer.DESTRUCTOR, Virtual: true})
+	objectType.declare(&classMethod{Name: "Free", Kind: lexer.PROCEDURE})
+	objectType.declare(&classMethod{Name: "ClassName", Kind: lexer.FUNCTION, Type: procType{Result: simpleType("STRING")}})
+	predeclaredClasses[strings.ToUpper(objectType.Name)] = objectType
+}
+
+// predeclaredClass returns a class the interpreter provides that declares
+// no fields or methods of its own
+func predeclaredClass(name string, parent *classType) *classType {
+	return &classType{Name: name, Parent: parent, Fields: parent.Fields, Methods: map[string]*classMethod{}}
+}
+
+// newObject returns an object of class whose fields, unlike variables,
+// start with the zero values of their types
+func newObject(class *classType) *objectValue {
+	object := &objectValue{Class: class, Fields: make([]any, len(class.Fields))}
+	for i, v := range class.Fields {
+		object.Fields[i] = zeroValue(v.Type)
+	}
+	return object
+}
+
+// zeroValue returns the value of type t that is all zeroes: 0, the empty
+// string, FALSE, NIL or the first value of an enumeration
+func zeroValue(t dataType) any {
+	switch v := t.(type) {
+	case *enumType:
+		return enumValue{Type: v}
+	case subrangeType:
+		value, _ := withOrdinal(ordinalSample(v.Base), v.Low)
+		return value
+	case setType:
+		return setValue{Element: v.Element}
+	case pointerType:
+		return pointerValue{Type: v}
+	case procType:
+		return routineValue{Type: v}
+	case *classType:
+		return pointerValue{}
+
+	case arrayType:
+		value := &arrayValue{Type: v, Elements: make([]any, v.High-v.Low+1)}
+		for i := range value.Elements {
+			value.Elements[i] = zeroValue(v.Element)
+		}
+		return value
+
+	case recordType:
+		value := &recordValue{Type: v, Fields: make([]any, len(v.Fields))}
+		for i, field := range v.Fields {
+			value.Fields[i] = zeroValue(field.Type)
+		}
+		return value
+	}
+
+	switch t {
+	case simpleType("INTEGER"):
+		return 0
+	case simpleType("REAL"):
+		return 0.0
+	case simpleType("CHAR"):
+		return byte(0)
+	case simpleType("BOOLEAN"):
+		return false
+	case simpleType("STRING"):
+		return ""
+	}
+	return nil
+}
+
+// isNilObject reports whether value is NIL, which is what a variable of a
+// class type holds when it refers to no object
+func isNilObject(value any) bool {
+	pointer, ok := value.(pointerValue)
+	return ok && pointer.Address == 0 && pointer.Type.Target == ""
+}
+
+// classNamed returns the class called name, declared in a TYPE section or
+// predeclared
+func (r *EvaluatorVisitor) classNamed(name string) (*classType, bool) {
+	name = strings.ToUpper(name)
+	if declared, ok := r.typeDef(name); ok {
+		class, isClass := declared.(*classType)
+		return class, isClass
+	}
+	class, ok := predeclaredClasses[name]
+	return class, ok
+}
+
+// className returns the class node names, unless a variable hides it
+func (r *EvaluatorVisitor) className(node ast.Node) (*classType, bool) {
+	variable, ok := node.(ast.Var)
+	if !ok || r.frameOf(strings.ToUpper(variable.Value)).declares(strings.ToUpper(variable.Value)) {
+		return nil, false
+	}
+	return r.classNamed(variable.Value)
+}
+
+// declareClass makes name denote the class node describes before its
+// fields and methods are resolved, so that they can refer to the class
+func (r *EvaluatorVisitor) declareClass(name string, node ast.ClassType) error {
+	parent := objectType
+	if node.Parent != nil {
+		declared, err := r.resolveType(node.Parent)
+		if err != nil {
+			return err
+		}
+		class, ok := declared.(*classType)
+		if !ok {
+			return newRuntimeError(node.Parent, "Class type expected, got %v", declared)
+		}
+		parent = class
+	}
+
+	class := &classType{Name: name, Parent: parent, Fields: slices.Clone(parent.Fields), Methods: map[string]*classMethod{}}
+	r.scope().typeDefs[strings.ToUpper(name)] = class
+	for _, v := range node.Fields {
+		fieldType, err := r.resolveType(v.TypeSpec)
+		if err != nil {
+			return err
+		}
+		class.Fields = append(class.Fields, recordField{Name: v.Variable.Value, Type: fieldType})
+	}
+	for _, v := range node.Methods {
+		methodType, err := r.routineType(v.Params, v.Result)
+		if err != nil {
+			return err
+		}
+		class.declare(&classMethod{Name: v.Name.Value, Kind: v.GetToken().TokenType, Type: methodType, Virtual: v.Virtual || v.Override})
+	}
+	return nil
+}
+
+// implementMethod attaches the implementation of a method to the method
+// its class declares
+func (r *EvaluatorVisitor) implementMethod(node ast.RoutineDeclaration) error {
+	declared, err := r.resolveType(node.Class)
+	if err != nil {
+		return err
+	}
+	class, ok := declared.(*classType)
+	if !ok {
+		return newRuntimeError(node.Class, "Class type expected, got %v", declared)
+	}
+
+	method, ok := class.Methods[strings.ToUpper(node.Name.Value)]
+	if !ok || predeclaredClasses[strings.ToUpper(class.Name)] == class {
+		return newRuntimeError(node.Name, "Method '%v' is not declared by %v", node.Name.Value, class)
+	}
+	method.Declaration, method.Frame = &node, r.frame
+	return nil
+}
+
+// visitMethodCall calls a constructor of a class, creating an object, or
+// a method of an object
+func (r *EvaluatorVisitor) visitMethodCall(node ast.MethodCall) (any, error) {
+	if class, ok := r.className(node.Value); ok {
+		return r.construct(node, class, node.Name, node.Arguments)
+	}
+
+	value, err := r.Visit(node.Value)
+	if err != nil {
+		return nil, err
+	}
+	return r.callOn(node, value, node.Name, node.Arguments)
+}
+
+// construct creates an object of class and initializes it with the
+// constructor name
+func (r *EvaluatorVisitor) construct(node ast.Node, class *classType, name string, arguments []ast.Node) (any, error) {
+	constructor, ok := class.method(name)
+	if !ok {
+		return nil, newRuntimeError(node, "Unknown method '%v'", name)
+	}
+	if constructor.Kind != lexer.CONSTRUCTOR {
+		return nil, newRuntimeError(node, "'%v' is not a constructor", name)
+	}
+
+	object := newObject(class)
+	if _, err := r.callMethod(node, object, constructor, arguments); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// callOn calls the method name of the object value. Free does nothing if
+// value is NIL.
+func (r *EvaluatorVisitor) callOn(node ast.Node, value any, name string, arguments []ast.Node) (any, error) {
+	if isNilObject(value) && strings.EqualFold(name, "FREE") {
+		return nil, nil
+	}
+	object, err := r.object(node, value)
+	if err != nil {
+		return nil, err
+	}
+	method, ok := object.Class.method(name)
+	if !ok {
+		return nil, newRuntimeError(node, "Unknown method '%v'", name)
+	}
+	return r.invoke(node, object, method, arguments)
+}
+
+// object returns value as an object whose members can be used
+func (r *EvaluatorVisitor) object(node ast.Node, value any) (*objectValue, error) {
+	object, ok := value.(*objectValue)
+	switch {
+	case isNilObject(value):
+		return nil, newKindError(node, AccessViolation, "Access of NIL object")
+	case !ok:
+		return nil, newRuntimeError(node, "Object expected, got %v", typeName(value))
+	case object.Destroyed:
+		return nil, newKindError(node, AccessViolation, "Access of destroyed object")
+	}
+	return object, nil
+}
+
+// invoke calls the method of object, which is looked up in the class of
+// the object if it is virtual. A destructor leaves the object destroyed.
+func (r *EvaluatorVisitor) invoke(node ast.Node, object *objectValue, method *classMethod, arguments []ast.Node) (any, error) {
+	if method.Kind == lexer.CONSTRUCTOR {
+		return nil, newRuntimeError(node, "Constructor '%v' has to be called on a class", method.Name)
+	}
+
+	result, err := r.callMethod(node, object, method, arguments)
+	if err == nil && method.Kind == lexer.DESTRUCTOR {
+		object.Destroyed = true
+	}
+	return result, err
+}
+
+// callMethod runs method with object as Self
+func (r *EvaluatorVisitor) callMethod(node ast.Node, object *objectValue, method *classMethod, arguments []ast.Node) (any, error) {
+	if method.Declaration == nil {
+		return r.callPredeclaredMethod(node, object, method, arguments)
+	}
+
+	callee := newFrame(r.memberFrame(node, object, method.Class, method.Frame))
+	callee.self, callee.method = object, method
+	callee.values["SELF"] = object
+	callee.types["SELF"] = method.Class
+	return r.run(node, method.Name, method.Declaration, method.Type, callee, arguments)
+}
+
+// callPredeclaredMethod runs a method of TObject or Exception
+func (r *EvaluatorVisitor) callPredeclaredMethod(node ast.Node, object *objectValue, method *classMethod, arguments []ast.Node) (any, error) {
+	if len(arguments) != len(method.Type.Params) {
+		return nil, newRuntimeError(node, "Wrong number of arguments for '%v'", method.Name)
+	}
+
+	switch strings.ToUpper(method.Name) {
+	case "CREATE":
+		if method.Class != exceptionType {
+			return nil, nil
+		}
+		value, err := r.Visit(arguments[0])
+		if err != nil {
+			return nil, err
+		}
+		message, err := r.convert(arguments[0], value, simpleType("STRING"))
+		if err != nil {
+			return nil, err
+		}
+		object.Fields[object.Class.field("Message")] = message
+	case "FREE":
+		destructor, _ := object.Class.method("DESTROY")
+		_, err := r.invoke(node, object, destructor, nil)
+		return nil, err
+	case "CLASSNAME":
+		return object.Class.Name, nil
+	}
+	return nil, nil
+}
+
+// memberFrame returns a frame holding the fields and methods of class,
+// which its methods use without naming Self, as references to those of
+// object. Virtual methods are the ones of the class of the object.
+func (r *EvaluatorVisitor) memberFrame(node ast.Node, object *objectValue, class *classType, parent *frame) *frame {
+	members := newFrame(parent)
+	for i, v := range class.Fields {
+		members.values[strings.ToUpper(v.Name)] = r.objectFieldReference(node, object, i)
+	}
+	for c := class; c != nil; c = c.Parent {
+		for name, method := range c.Methods {
+			if _, ok := members.values[name]; ok {
+				continue
+			}
+			if method.Virtual {
+				method, _ = object.Class.method(name)
+			}
+			members.values[name] = boundMethod{Object: object, Method: method}
+		}
+	}
+	return members
+}
+
+// visitInheritedCall calls the method name of Self as the ancestors of
+// the class of the running method declare it
+func (r *EvaluatorVisitor) visitInheritedCall(node ast.InheritedCall) (any, error) {
+	for f := r.frame; f != nil; f = f.parent {
+		if f.method == nil {
+			continue
+		}
+		method, ok := f.method.Class.Parent.method(node.Name)
+		if !ok {
+			return nil, newRuntimeError(node, "Unknown method '%v'", node.Name)
+		}
+		return r.callMethod(node, f.self, method, node.Arguments)
+	}
+	return nil, newRuntimeError(node, "INHERITED outside of a method")
+}
+
+// member returns the named field of the object value or calls its method
+// name, which takes no arguments then
+func (r *EvaluatorVisitor) member(node ast.Node, value any, name string) (any, error) {
+	object, ok := value.(*objectValue)
+	if !ok {
+		return r.callOn(node, value, name, nil)
+	}
+
+	position := object.Class.field(name)
+	if position < 0 {
+		if _, isMethod := object.Class.method(name); !isMethod {
+			return nil, newRuntimeError(node, "Unknown field '%v' of %v", name, object.Class)
+		}
+		return r.callOn(node, value, name, nil)
+	}
+	return r.objectFieldReference(node, object, position).get()
+}
+
+// memberReference returns a reference to the named field of the object
+// value
+func (r *EvaluatorVisitor) memberReference(node ast.Node, value any, name string) (*reference, error) {
+	object, err := r.object(node, value)
+	if err != nil {
+		return nil, err
+	}
+	position := object.Class.field(name)
+	if position < 0 {
+		return nil, newRuntimeError(node, "Unknown field '%v' of %v", name, object.Class)
+	}
+	return r.objectFieldReference(node, object, position), nil
+}
+
+// objectFieldReference returns a reference to the field of object at
+// position, which cannot be used once the object is destroyed
+func (r *EvaluatorVisitor) objectFieldReference(node ast.Node, object *objectValue, position int) *reference {
+	field := object.Class.Fields[position]
+	return &reference{
+		get: func() (any, error) {
+			if object.Destroyed {
+				return nil, newKindError(node, AccessViolation, "Access of destroyed object")
+			}
+			return object.Fields[position], nil
+		},
+		set: func(value any) error {
+			if object.Destroyed {
+				return newKindError(node, AccessViolation, "Access of destroyed object")
+			}
+			converted, err := r.convert(node, value, field.Type)
+			if err != nil {
+				return err
+			}
+			object.Fields[position] = converted
+			return nil
+		},
+		declared: field.Type,
+		typeName: baseType(field.Type).String(),
+	}
+}
+
+// typeTest evaluates object IS Class, whether the object is of a class
+// descending from Class, and object AS Class, the object as one of Class.
+// NIL is of no class, but can be cast to any.
+func (r *EvaluatorVisitor) typeTest(node ast.BinaryOperation) (any, error) {
+	value, err := r.Visit(node.Left)
+	if err != nil {
+		return nil, err
+	}
+	class, ok := r.className(node.Right)
+	if !ok {
+		return nil, newRuntimeError(node.Right, "Class type expected, got %v", node.Right.GetToken().Text())
+	}
+
+	object, isObject := value.(*objectValue)
+	if !isObject && !isNilObject(value) {
+		return nil, newRuntimeError(node, "Operator %v is not defined for %v and %v", node.GetToken().Text(), typeName(value), class)
+	}
+	if node.GetToken().TokenType == lexer.IS {
+		return isObject && object.Class.descends(class), nil
+	}
+	if isObject && !object.Class.descends(class) {
+		return nil, newKindError(node, InvalidCast, "Invalid type cast from %v to %v", object.Class, class)
+	}
+	return value, nil
+}
+
+// compareObjects evaluates = and <> on objects and NIL, two objects being
+// equal if they are the same
+func (r *EvaluatorVisitor) compareObjects(node ast.BinaryOperation, left any, right any) (any, error) {
+	_, leftIsObject := left.(*objectValue)
+	_, rightIsObject := right.(*objectValue)
+	if (leftIsObject || isNilObject(left)) && (rightIsObject || isNilObject(right)) {
+		switch node.GetToken().TokenType {
+		case lexer.EQUAL:
+			return left == right, nil
+		case lexer.NOT_EQUAL:
+			return left != right, nil
+		}
+	}
+	return nil, newRuntimeError(node, "Operator %v is not defined for %v and %v", node.GetToken().Text(), typeName(left), typeName(right))
+}
+
+// convertObject makes the object value fit a variable of the declared
+// class, which it does if its class descends from it. NIL fits any class.
+func (r *EvaluatorVisitor) convertObject(node ast.Node, value any, declared *classType) (any, error) {
+	if object, ok := value.(*objectValue); ok && object.Class.descends(declared) {
+		return object, nil
+	}
+	if isNilObject(value) {
+		return pointerValue{}, nil
+	}
+	return nil, newRuntimeError(node, "Incompatible types: got %v expected %v", typeName(value), declared)
+}
+
+// isObject reports whether value is an object or NIL, whose members are
+// used like the ones of an object
+func isObject(value any) bool {
+	_, ok := value.(*objectValue)
+	return ok || isNilObject(value)
+}
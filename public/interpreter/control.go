@@ -0,0 +1,227 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+)
+
+// breakSignal is returned by Break to leave the innermost loop
+type breakSignal struct{}
+
+func (r breakSignal) Error() string {
+	return "Break outside of a loop"
+}
+
+// continueSignal is returned by Continue to start the next iteration of
+// the innermost loop
+type continueSignal struct{}
+
+func (r continueSignal) Error() string {
+	return "Continue outside of a loop"
+}
+
+// exitSignal is returned by Exit to leave the running routine, or the
+// program in the main block
+type exitSignal struct{}
+
+func (r exitSignal) Error() string {
+	return "Exit outside of a routine"
+}
+
+// haltSignal is returned by Halt to stop the program with an exit code
+type haltSignal struct {
+	code int
+}
+
+func (r haltSignal) Error() string {
+	return fmt.Sprintf("Program halted with exit code %d", r.code)
+}
+
+// HaltCode returns the exit code passed to Halt if err is the signal of a
+// call of it, for callers visiting statements one by one like the REPL
+func HaltCode(err error) (int, bool) {
+	var halt haltSignal
+	if !errors.As(err, &halt) {
+		return 0, false
+	}
+	return halt.code, true
+}
+
+// isControlProcedure reports whether name is one of the procedures
+// leaving a loop, a routine or the program
+func isControlProcedure(name string) bool {
+	switch strings.ToUpper(name) {
+	case "BREAK", "CONTINUE", "EXIT", "HALT":
+		return true
+	}
+	return false
+}
+
+// controlProcedure runs Break, Continue, Exit or Halt, which all stop the
+// statements being run by returning a signal
+func (r *EvaluatorVisitor) controlProcedure(node ast.ProcedureCall) error {
+	name := strings.ToUpper(node.Name)
+	switch {
+	case name == "HALT" && len(node.Arguments) <= 1:
+		code := 0
+		if len(node.Arguments) == 1 {
+			value, err := r.Visit(node.Arguments[0])
+			if err != nil {
+				return err
+			}
+			n, ok := value.(int)
+			if !ok {
+				return newRuntimeError(node.Arguments[0], "Incompatible types: got %v expected INTEGER", typeName(value))
+			}
+			code = n
+		}
+		return haltSignal{code: code}
+
+	case name == "EXIT" && len(node.Arguments) <= 1:
+		if len(node.Arguments) == 1 {
+			if err := r.setResult(node, node.Arguments[0]); err != nil {
+				return err
+			}
+		}
+		return exitSignal{}
+
+	case name == "BREAK" && len(node.Arguments) == 0:
+		return breakSignal{}
+	case name == "CONTINUE" && len(node.Arguments) == 0:
+		return continueSignal{}
+	}
+	return newRuntimeError(node, "Wrong number of arguments for '%v'", node.Name)
+}
+
+// setResult assigns the value of Exit(value) to the result variable of
+// the running function
+func (r *EvaluatorVisitor) setResult(node ast.Node, value ast.Node) error {
+	for f := r.frame; f != nil; f = f.parent {
+		if f.result == "" {
+			continue
+		}
+
+		result, err := r.valueFor(value, f.types[f.result])
+		if err != nil {
+			return err
+		}
+		converted, err := r.convert(value, result, f.types[f.result])
+		if err != nil {
+			return err
+		}
+		f.values[f.result] = converted
+		return nil
+	}
+	return newRuntimeError(node, "Exit with a value outside of a function")
+}
+
+// condition evaluates the BOOLEAN expression controlling a statement
+func (r *EvaluatorVisitor) condition(node ast.Node) (bool, error) {
+	value, err := r.Visit(node)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, newRuntimeError(node, "Incompatible types: got %v expected BOOLEAN", typeName(value))
+	}
+	return result, nil
+}
+
+// loopBody runs the body of a loop and reports whether the loop goes on,
+// which it does unless the body fails or calls Break
+func (r *EvaluatorVisitor) loopBody(body ...ast.Node) (bool, error) {
+	switch err := r.statements(body); err.(type) {
+	case nil, continueSignal:
+		return true, nil
+	case breakSignal:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (r *EvaluatorVisitor) visitIfStatement(node ast.IfStatement) (any, error) {
+	condition, err := r.condition(node.Condition)
+	if err != nil {
+		return nil, err
+	}
+	if condition {
+		return r.Visit(node.Then)
+	}
+	if node.Else != nil {
+		return r.Visit(node.Else)
+	}
+	return nil, nil
+}
+
+func (r *EvaluatorVisitor) visitWhileStatement(node ast.WhileStatement) (any, error) {
+	for {
+		condition, err := r.condition(node.Condition)
+		if err != nil || !condition {
+			return nil, err
+		}
+		if next, err := r.loopBody(node.Body); !next {
+			return nil, err
+		}
+	}
+}
+
+func (r *EvaluatorVisitor) visitRepeatStatement(node ast.RepeatStatement) (any, error) {
+	for {
+		if next, err := r.loopBody(node.Body...); !next {
+			return nil, err
+		}
+		condition, err := r.condition(node.Condition)
+		if err != nil || condition {
+			return nil, err
+		}
+	}
+}
+
+// visitForStatement assigns the values from Start to Stop to the control
+// variable in turn, both bounds being evaluated once
+func (r *EvaluatorVisitor) visitForStatement(node ast.ForStatement) (any, error) {
+	variable, err := r.referenceTo(node.Variable)
+	if err != nil {
+		return nil, err
+	}
+	start, err := r.Visit(node.Start)
+	if err != nil {
+		return nil, err
+	}
+	stop, err := r.Visit(node.Stop)
+	if err != nil {
+		return nil, err
+	}
+
+	first, err := ordinalValue(start)
+	if err != nil {
+		return nil, runtimeErrorAt(node.Start, err)
+	}
+	last, err := ordinalValue(stop)
+	if err != nil {
+		return nil, runtimeErrorAt(node.Stop, err)
+	}
+	step := 1
+	if node.Downto {
+		step = -1
+	}
+
+	for n := first; n*step <= last*step; n += step {
+		value, err := withOrdinal(start, n)
+		if err != nil {
+			return nil, runtimeErrorAt(node, err)
+		}
+		if err := variable.set(value); err != nil {
+			return nil, err
+		}
+		if next, err := r.loopBody(node.Body); !next {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,141 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+)
+
+func formatError(pos lexer.Position, kind string, message string) string {
+	if !pos.IsValid() {
+		return fmt.Sprintf("%v: %v", kind, message)
+	}
+	return fmt.Sprintf("%v:%v: %v: %v", pos.Line, pos.Column, kind, message)
+}
+
+// ParserError reports source text that does not follow the grammar.
+type ParserError struct {
+	Pos     lexer.Position
+	Message string
+}
+
+func (r ParserError) Error() string {
+	return formatError(r.Pos, "syntax error", r.Message)
+}
+
+// SemanticError reports a syntactically valid program that is rejected by
+// the SemanticAnalyzer, e.g. because it uses an undeclared identifier.
+type SemanticError struct {
+	Pos     lexer.Position
+	Message string
+}
+
+func (r SemanticError) Error() string {
+	return formatError(r.Pos, "semantic error", r.Message)
+}
+
+func newSemanticError(node ast.Node, format string, args ...any) SemanticError {
+	return SemanticError{
+		Pos:     ast.Pos(node),
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// ErrorKind classifies runtime errors by their cause, which tells the
+// class of the exception a runtime error is raised as
+type ErrorKind int
+
+const (
+	// GeneralError is raised as Exception
+	GeneralError ErrorKind = iota
+	DivisionByZero
+	RangeCheckError
+	InvalidFloatOperation
+	AccessViolation
+	InvalidPointer
+	StackOverflow
+	ConvertError
+	InvalidCast
+)
+
+// RuntimeError reports a failure while the program is being evaluated.
+type RuntimeError struct {
+	Pos     lexer.Position
+	Kind    ErrorKind
+	Message string
+	// exception raised by RAISE, nil for errors of the interpreter
+	exception *objectValue
+}
+
+func (r RuntimeError) Error() string {
+	return formatError(r.Pos, "runtime error", r.Message)
+}
+
+func newRuntimeError(node ast.Node, format string, args ...any) RuntimeError {
+	return newKindError(node, GeneralError, format, args...)
+}
+
+func newKindError(node ast.Node, kind ErrorKind, format string, args ...any) RuntimeError {
+	return RuntimeError{
+		Pos:     ast.Pos(node),
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// kindError returns a runtime error of kind without a position, for the
+// helpers of builtin routines that don't know the node being evaluated
+func kindError(kind ErrorKind, format string, args ...any) RuntimeError {
+	return RuntimeError{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// runtimeErrorAt returns err as a runtime error at node, keeping the kind
+// of a runtime error that has no position yet
+func runtimeErrorAt(node ast.Node, err error) RuntimeError {
+	var runtimeError RuntimeError
+	if errors.As(err, &runtimeError) && !runtimeError.Pos.IsValid() {
+		runtimeError.Pos = ast.Pos(node)
+		return runtimeError
+	}
+	return newRuntimeError(node, "%v", err)
+}
+
+// UnitError is an error in the file of a unit at Path, Err being the error
+// itself. Path is empty for runtime errors of the program raised while it
+// uses units, so that they aren't attributed to a unit calling back into
+// the program.
+type UnitError struct {
+	Path string
+	Err  error
+}
+
+func (r UnitError) Error() string {
+	return r.Err.Error()
+}
+
+func (r UnitError) Unwrap() error {
+	return r.Err
+}
+
+// ErrorPosition returns the source position err refers to, if it is known.
+func ErrorPosition(err error) (lexer.Position, bool) {
+	var lexerError lexer.LexerError
+	var parserError ParserError
+	var semanticError SemanticError
+	var runtimeError RuntimeError
+
+	var pos lexer.Position
+	switch {
+	case errors.As(err, &lexerError):
+		pos = lexerError.Pos
+	case errors.As(err, &parserError):
+		pos = parserError.Pos
+	case errors.As(err, &semanticError):
+		pos = semanticError.Pos
+	case errors.As(err, &runtimeError):
+		pos = runtimeError.Pos
+	}
+	return pos, pos.IsValid()
+}
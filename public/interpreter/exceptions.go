@@ -0,0 +1,181 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+)
+
+// Exception is the class every exception descends from, the other
+// exception types are the ones runtime errors are raised as
+var (
+	exceptionType = &classType{
+		Name:    "Exception",
+		Parent:  objectType,
+		Fields:  []recordField{{Name: "Message", Type: simpleType("STRING")}},
+		Methods: map[string]*classMethod{},
+	}
+	intErrorType        = predeclaredClass("EIntError", exceptionType)
+	mathErrorType       = predeclaredClass("EMathError", exceptionType)
+	divByZeroType       = predeclaredClass("EDivByZero", intErrorType)
+	rangeErrorType      = predeclaredClass("ERangeError", intErrorType)
+	invalidOpType       = predeclaredClass("EInvalidOp", mathErrorType)
+	accessViolationType = predeclaredClass("EAccessViolation", exceptionType)
+	invalidPointerType  = predeclaredClass("EInvalidPointer", exceptionType)
+	stackOverflowType   = predeclaredClass("EStackOverflow", exceptionType)
+	convertErrorType    = predeclaredClass("EConvertError", exceptionType)
+	invalidCastType     = predeclaredClass("EInvalidCast", exceptionType)
+)
+
+func init() {
+	exceptionType.declare(&classMethod{
+		Name: "Create",
+		Kind: lexer.CONSTRUCTOR,
+		Type: procType{Params: []procParam{{Name: "Msg", Type: simpleType("STRING")}}},
+	})
+	for _, v := range []*classType{
+		exceptionType, intErrorType, mathErrorType, divByZeroType, rangeErrorType, invalidOpType,
+		accessViolationType, invalidPointerType, stackOverflowType, convertErrorType, invalidCastType,
+	} {
+		predeclaredClasses[strings.ToUpper(v.Name)] = v
+	}
+}
+
+// runtimeErrorClasses maps the kinds of runtime errors to the exception
+// types they are raised as, GeneralError being raised as Exception
+var runtimeErrorClasses = map[ErrorKind]*classType{
+	GeneralError:          exceptionType,
+	DivisionByZero:        divByZeroType,
+	RangeCheckError:       rangeErrorType,
+	InvalidFloatOperation: invalidOpType,
+	AccessViolation:       accessViolationType,
+	InvalidPointer:        invalidPointerType,
+	StackOverflow:         stackOverflowType,
+	ConvertError:          convertErrorType,
+	InvalidCast:           invalidCastType,
+}
+
+// newException returns an exception of class with message
+func newException(class *classType, message string) *objectValue {
+	exception := newObject(class)
+	exception.Fields[class.field("Message")] = message
+	return exception
+}
+
+// exceptionOf returns the exception err is raised as. Only runtime errors
+// can be caught.
+func exceptionOf(err error) (*objectValue, bool) {
+	var runtimeError RuntimeError
+	if !errors.As(err, &runtimeError) {
+		return nil, false
+	}
+	if runtimeError.exception != nil {
+		return runtimeError.exception, true
+	}
+
+	return newException(runtimeErrorClasses[runtimeError.Kind], runtimeError.Message), true
+}
+
+// statements runs a statement list, stopping at the first error. A GOTO
+// to a label of the list continues at the labeled statement.
+func (r *EvaluatorVisitor) statements(nodes []ast.Node) error {
+	for i := 0; i < len(nodes); i++ {
+		_, err := r.Visit(nodes[i])
+		if jump, ok := err.(gotoSignal); ok {
+			if target := labelIndex(nodes, jump.label); target >= 0 {
+				i = target - 1
+				continue
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visitTryExcept runs the handler of the first ON clause matching the
+// exception raised by the body, the ELSE part if none does. Exceptions
+// no handler matches are raised again.
+func (r *EvaluatorVisitor) visitTryExcept(node ast.TryExcept) (any, error) {
+	raised := r.statements(node.Body)
+	exception, ok := exceptionOf(raised)
+	if !ok {
+		return nil, raised
+	}
+
+	for _, v := range node.Handlers {
+		class, ok := r.classNamed(v.Class.Value)
+		if !ok {
+			return nil, newRuntimeError(v.Class, "Exception class type expected, got %v", v.Class.Value)
+		}
+		if exception.Class.descends(class) {
+			return nil, r.handle(raised, exception, v.Variable, []ast.Node{v.Body})
+		}
+	}
+	if len(node.Handlers) == 0 || len(node.Default) > 0 {
+		return nil, r.handle(raised, exception, nil, node.Default)
+	}
+	return nil, raised
+}
+
+// handle runs the statements handling the exception raised as err, with
+// variable, if it is set, holding the exception
+func (r *EvaluatorVisitor) handle(err error, exception *objectValue, variable ast.Node, statements []ast.Node) error {
+	enclosing, depth := r.frame, len(r.handling)
+	r.handling = append(r.handling, err)
+	defer func() {
+		r.frame, r.handling = enclosing, r.handling[:depth]
+	}()
+
+	if variable != nil {
+		name := strings.ToUpper(variable.GetToken().Text())
+		r.frame = newFrame(r.frame)
+		r.frame.types[name] = exception.Class
+		r.frame.values[name] = exception
+	}
+	return r.statements(statements)
+}
+
+// visitTryFinally runs the FINALLY part whether the body fails or not, an
+// error in the FINALLY part replacing the one of the body. Halt stops the
+// program without running it.
+func (r *EvaluatorVisitor) visitTryFinally(node ast.TryFinally) (any, error) {
+	raised := r.statements(node.Body)
+	if _, halted := raised.(haltSignal); halted {
+		return nil, raised
+	}
+	if err := r.statements(node.Finally); err != nil {
+		return nil, err
+	}
+	return nil, raised
+}
+
+// visitRaiseStatement raises an exception value, a bare RAISE raising the
+// exception being handled again
+func (r *EvaluatorVisitor) visitRaiseStatement(node ast.RaiseStatement) (any, error) {
+	if node.Value == nil {
+		if len(r.handling) == 0 {
+			return nil, newRuntimeError(node, "RAISE without an exception outside of an exception handler")
+		}
+		return nil, r.handling[len(r.handling)-1]
+	}
+
+	value, err := r.Visit(node.Value)
+	if err != nil {
+		return nil, err
+	}
+	exception, ok := value.(*objectValue)
+	if !ok || !exception.Class.descends(exceptionType) {
+		return nil, newRuntimeError(node.Value, "Exception expected, got %v", typeName(value))
+	}
+
+	return nil, RuntimeError{
+		Pos:       ast.Pos(node),
+		Message:   fmt.Sprintf("Unhandled exception %v: %v", exception.Class, exception.message()),
+		exception: exception,
+	}
+}
@@ -0,0 +1,161 @@
+package interpreter
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+)
+
+func isInputSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isNumber reports whether text only consists of characters Pascal allows
+// in numbers, strconv accepts more, e.g. "Inf" or hexadecimal floats
+func isNumber(text string) bool {
+	return strings.Trim(text, "0123456789+-.eE") == ""
+}
+
+// readWord skips blanks and line ends and returns the characters up to the
+// next one. It returns an empty word at the end of the input.
+func (r *EvaluatorVisitor) readWord() (string, error) {
+	var word []byte
+	for {
+		c, err := r.Input.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return string(word), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if isInputSpace(c) {
+			if len(word) == 0 {
+				continue
+			}
+			return string(word), r.Input.UnreadByte()
+		}
+		word = append(word, c)
+	}
+}
+
+// readLine returns the rest of the current line, leaving the line end to be
+// read.
+func (r *EvaluatorVisitor) readLine() (string, error) {
+	var line []byte
+	for {
+		c, err := r.Input.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return strings.TrimSuffix(string(line), "\r"), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if c == '\n' {
+			if err := r.Input.UnreadByte(); err != nil {
+				return "", err
+			}
+			return strings.TrimSuffix(string(line), "\r"), nil
+		}
+		line = append(line, c)
+	}
+}
+
+// skipLine reads up to and including the next line end
+func (r *EvaluatorVisitor) skipLine() error {
+	_, err := r.Input.ReadString('\n')
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// readValue reads a value of the named type the way Pascal does: numbers
+// after any blanks and line ends, a CHAR as the very next character, line
+// ends included, and a STRING as the rest of the line.
+func (r *EvaluatorVisitor) readValue(node ast.Node, declaredType string) (any, error) {
+	switch declaredType {
+	case "INTEGER", "REAL":
+		word, err := r.readWord()
+		if err != nil {
+			return nil, err
+		}
+		if word == "" {
+			return nil, newRuntimeError(node, "Unexpected end of input")
+		}
+
+		if declaredType == "INTEGER" {
+			value, err := strconv.Atoi(word)
+			if err != nil {
+				return nil, newKindError(node, ConvertError, "Invalid numeric input '%v'", word)
+			}
+			return value, nil
+		}
+
+		value, err := strconv.ParseFloat(word, 64)
+		if err != nil || !isNumber(word) {
+			return nil, newKindError(node, ConvertError, "Invalid numeric input '%v'", word)
+		}
+		return value, nil
+
+	case "CHAR":
+		c, err := r.Input.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return nil, newRuntimeError(node, "Unexpected end of input")
+		}
+		return c, err
+
+	case "STRING":
+		return r.readLine()
+	}
+
+	if declaredType == "" {
+		return nil, newRuntimeError(node, "Cannot read a value of unknown type")
+	}
+	return nil, newRuntimeError(node, "Cannot read values of type %v", declaredType)
+}
+
+// variableType returns the declared type of variable or, if it was never
+// declared as in the REPL, the type of its current value
+func (r *EvaluatorVisitor) variableType(variable ast.Var) string {
+	name := strings.ToUpper(variable.Value)
+	scope := r.frameOf(name)
+	if declaredType, ok := scope.types[name]; ok {
+		return baseType(declaredType).String()
+	}
+	if parameter, ok := scope.values[name].(*reference); ok {
+		return parameter.typeName
+	}
+	if value, ok := scope.values[name]; ok {
+		return typeName(value)
+	}
+	return ""
+}
+
+// read implements READ and READLN, every argument is a variable receiving
+// the next value. READLN skips the rest of the line afterwards.
+func (r *EvaluatorVisitor) read(arguments []ast.Node, skipLine bool) error {
+	for _, v := range arguments {
+		variable, err := r.referenceTo(v)
+		if err != nil {
+			return err
+		}
+
+		value, err := r.readValue(v, variable.typeName)
+		if err != nil {
+			return err
+		}
+		if err := variable.set(value); err != nil {
+			return err
+		}
+	}
+
+	if skipLine {
+		return r.skipLine()
+	}
+	return nil
+}
@@ -1,6 +1,12 @@
 package interpreter
 
-import "github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+import (
+	"errors"
+	"io"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+)
 
 const ErrorCode int = 1
 
@@ -10,11 +16,17 @@ type Parser interface {
 }
 
 type NodeVisitor interface {
-	Visit(node ast.Node) (int, error)
+	Visit(node ast.Node) (any, error)
+}
+
+type Analyzer interface {
+	Analyze(node ast.Node) error
 }
 
 type BasicInterpreter struct {
 	Parser Parser
+	// Analyzer is optional, the tree is evaluated unchecked without it
+	Analyzer  Analyzer
 	Evaluator NodeVisitor
 }
 
@@ -24,10 +36,46 @@ func (r BasicInterpreter) Interpret() (int, error) {
 		return ErrorCode, err
 	}
 
-	result, err := r.Evaluator.Visit(astTree)
-	if err != nil {
+	return r.Evaluate(astTree)
+}
+
+// Evaluate checks and evaluates an already parsed tree and returns the exit
+// code of the program, the one passed to Halt if it is called. Evaluation
+// failures are always reported as RuntimeError.
+func (r BasicInterpreter) Evaluate(astTree ast.Node) (int, error) {
+	if r.Analyzer != nil {
+		if err := r.Analyzer.Analyze(astTree); err != nil {
+			return ErrorCode, err
+		}
+	}
+
+	if _, err := r.Evaluator.Visit(astTree); err != nil {
+		if code, halted := HaltCode(err); halted {
+			return code, nil
+		}
+
+		var runtimeError RuntimeError
+		if !errors.As(err, &runtimeError) {
+			err = RuntimeError{Message: err.Error()}
+		}
 		return ErrorCode, err
 	}
 
-	return result, nil
+	return 0, nil
+}
+
+// NewInterpreter creates an interpreter for the program read by lxr, the
+// program reads input and writes to output.
+func NewInterpreter(lxr lexer.BasicLexer, input io.Reader, output io.Writer) (*BasicInterpreter, error) {
+	parser, err := NewParser(lxr)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator := NewEvaluatorVisitor(input, output)
+	return &BasicInterpreter{
+		Parser:    parser,
+		Analyzer:  NewSemanticAnalyzer(),
+		Evaluator: &evaluator,
+	}, nil
 }
@@ -0,0 +1,1877 @@
+package interpreter
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+	"github.com/stretchr/testify/require"
+)
+
+func interpret(t *testing.T, text string) (*BasicInterpreter, error) {
+	t.Helper()
+	basicInterpreter, err := NewInterpreter(lexer.NewLexer(text), strings.NewReader(""), io.Discard)
+	require.NoError(t, err)
+	_, err = basicInterpreter.Interpret()
+	return basicInterpreter, err
+}
+
+// output interprets text and returns what it writes
+func output(t *testing.T, text string) (string, error) {
+	t.Helper()
+	return outputWithInput(t, text, "")
+}
+
+// outputWithInput interprets text reading input and returns what it writes
+func outputWithInput(t *testing.T, text string, input string) (string, error) {
+	t.Helper()
+	var buffer bytes.Buffer
+	basicInterpreter, err := NewInterpreter(lexer.NewLexer(text), strings.NewReader(input), &buffer)
+	require.NoError(t, err)
+	_, err = basicInterpreter.Interpret()
+	return buffer.String(), err
+}
+
+func TestBasicInterpreter_Interpret(t *testing.T) {
+	t.Run("Assignments", func(t *testing.T) {
+		basicInterpreter, err := interpret(t, `
+			PROGRAM test;
+			VAR a, b: INTEGER;
+			BEGIN
+				a := 2;
+				B := a * 3 + 1
+			END.
+		`)
+		require.NoError(t, err)
+
+		evaluator := basicInterpreter.Evaluator.(*EvaluatorVisitor)
+		require.Equal(t, 2, evaluator.GloabalScope["A"])
+		require.Equal(t, 7, evaluator.GloabalScope["B"])
+	})
+
+	t.Run("Syntax error", func(t *testing.T) {
+		_, err := interpret(t, "PROGRAM test; BEGIN a := END.")
+		require.ErrorAs(t, err, &ParserError{})
+	})
+
+	t.Run("Undeclared variable", func(t *testing.T) {
+		_, err := interpret(t, "PROGRAM test; BEGIN a := 1 END.")
+		var semanticError SemanticError
+		require.ErrorAs(t, err, &semanticError)
+		require.Equal(t, lexer.Position{Line: 1, Column: 21}, semanticError.Pos)
+	})
+
+	t.Run("Duplicate declaration", func(t *testing.T) {
+		_, err := interpret(t, "PROGRAM test; VAR a: INTEGER; a: REAL; BEGIN END.")
+		require.ErrorAs(t, err, &SemanticError{})
+	})
+
+	t.Run("Division by zero", func(t *testing.T) {
+		_, err := interpret(t, "PROGRAM test; VAR a: INTEGER; BEGIN a := 1 DIV 0 END.")
+		var runtimeError RuntimeError
+		require.ErrorAs(t, err, &runtimeError)
+		pos, ok := ErrorPosition(err)
+		require.True(t, ok)
+		require.Equal(t, 1, pos.Line)
+	})
+}
+
+func TestBasicInterpreter_write(t *testing.T) {
+	t.Run("Values and field widths", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR i: INTEGER; x: REAL;
+			BEGIN
+				i := 42;
+				x := i / 8;
+				WRITE(i, i:5, i:1);
+				WRITELN;
+				WRITELN(x:0:2, -x:8:3, x:6:0);
+				WRITELN(x);
+				WRITELN(x:10, i + 0.5:12)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "42   4242\n"+
+			"5.25  -5.250     5\n"+
+			" 5.25000000000000E+000\n"+
+			" 5.25E+000 4.2500E+001\n", text)
+	})
+
+	t.Run("INTEGER is converted when assigned to REAL", func(t *testing.T) {
+		text, err := output(t, "PROGRAM test; VAR x: REAL; BEGIN x := 3; WRITELN(x:0:1, 7 / 2:4:1) END.")
+		require.NoError(t, err)
+		require.Equal(t, "3.0 3.5\n", text)
+	})
+
+	t.Run("Precision of an INTEGER", func(t *testing.T) {
+		text, err := output(t, "PROGRAM test; BEGIN WRITE(1); WRITE(1:2:1) END.")
+		require.ErrorAs(t, err, &RuntimeError{})
+		require.Equal(t, "1", text)
+	})
+
+	t.Run("Incompatible values assigned to INTEGER", func(t *testing.T) {
+		cases := map[string]string{
+			"i := 1 / 2":   "1:42: semantic error: Incompatible types: got REAL expected INTEGER",
+			"i := 2.5":     "1:42: semantic error: Incompatible types: got REAL expected INTEGER",
+			"i := 'abc'":   "1:42: semantic error: Incompatible types: got STRING expected INTEGER",
+			"i := Sqrt(4)": "1:42: semantic error: Incompatible types: got REAL expected INTEGER",
+		}
+
+		for statement, message := range cases {
+			_, err := output(t, "PROGRAM test; VAR i: INTEGER; BEGIN "+statement+" END.")
+			require.ErrorAs(t, err, &SemanticError{}, statement)
+			require.ErrorContains(t, err, message, statement)
+		}
+	})
+
+	t.Run("Unknown procedure", func(t *testing.T) {
+		_, err := output(t, "PROGRAM test; BEGIN PRINT(1) END.")
+		require.ErrorAs(t, err, &SemanticError{})
+		require.ErrorContains(t, err, "Identifier not found 'PRINT'")
+	})
+
+	t.Run("Variable called as procedure", func(t *testing.T) {
+		_, err := output(t, "PROGRAM test; VAR i: INTEGER; BEGIN i END.")
+		require.ErrorContains(t, err, "'i' is not a procedure")
+	})
+}
+
+func TestBasicInterpreter_read(t *testing.T) {
+	t.Run("Numbers span lines, CHAR and STRING do not skip blanks", func(t *testing.T) {
+		text, err := outputWithInput(t, `
+			PROGRAM test;
+			VAR i, j: INTEGER; x: REAL; c: CHAR; s, rest: STRING;
+			BEGIN
+				READ(i, j);
+				READLN(x);
+				READ(c);
+				READLN(s);
+				READLN;
+				READLN(rest);
+				WRITELN(i + j, x:6:2, c, s, rest)
+			END.
+		`, "  12\n-5 3.5 ignored\n ab c \nskipped\r\nlast\r\n")
+		require.NoError(t, err)
+		require.Equal(t, "7  3.50 ab c last\n", text)
+	})
+
+	t.Run("Malformed number", func(t *testing.T) {
+		for _, input := range []string{"12abc", "1.5", "0x10"} {
+			_, err := outputWithInput(t, "PROGRAM test; VAR i: INTEGER; BEGIN READ(i) END.", input)
+			require.ErrorContains(t, err, "Invalid numeric input", input)
+		}
+
+		_, err := outputWithInput(t, "PROGRAM test; VAR x: REAL; BEGIN READ(x) END.", "Inf")
+		require.ErrorAs(t, err, &RuntimeError{})
+	})
+
+	t.Run("End of input", func(t *testing.T) {
+		_, err := outputWithInput(t, "PROGRAM test; VAR i: INTEGER; BEGIN READ(i) END.", " \n")
+		require.ErrorContains(t, err, "Unexpected end of input")
+
+		text, err := outputWithInput(t, "PROGRAM test; VAR s: STRING; BEGIN READLN(s); READLN(s); WRITE(s) END.", "one")
+		require.NoError(t, err)
+		require.Equal(t, "", text)
+	})
+
+	t.Run("Arguments must be variables", func(t *testing.T) {
+		_, err := outputWithInput(t, "PROGRAM test; VAR i: INTEGER; BEGIN READ(i + 1) END.", "1")
+		require.ErrorAs(t, err, &SemanticError{})
+	})
+}
+
+func TestBasicInterpreter_strings(t *testing.T) {
+	t.Run("Concatenation, CHAR and indexing", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR s, t: STRING; c: CHAR;
+			BEGIN
+				c := 'x';
+				s := 'it''s ' + c;
+				t := c;
+				WRITELN(s, '|', s[1], s[6], '|', t:3, '|', '':2, '|'#33)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "it's x|ix|  x|  |!\n", text)
+	})
+
+	t.Run("Comparisons", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR b: BOOLEAN; s: STRING;
+			BEGIN
+				s := 'abc';
+				b := s < 'abd';
+				WRITELN(b, ' ', s = 'abc', ' ', 'b' > s, ' ', s[2] = 'b', ' ', 'a' <> 'a');
+				WRITELN(1 < 1.5, ' ', 2 >= 2, ' ', 1 + 2 = 3, ' ', b = (1 > 2), ' ', b:6)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "TRUE TRUE TRUE TRUE FALSE\nTRUE TRUE TRUE FALSE   TRUE\n", text)
+	})
+
+	t.Run("Index out of range", func(t *testing.T) {
+		_, err := output(t, "PROGRAM test; VAR s: STRING; c: CHAR; BEGIN s := 'ab'; c := s[3] END.")
+		require.ErrorContains(t, err, "1:63: runtime error: Index 3 out of range 1..2")
+	})
+
+	t.Run("Mismatched operands", func(t *testing.T) {
+		cases := map[string]string{
+			"s := 'a' + 1":   "Operator + is not defined for CHAR and INTEGER",
+			"b := 'a' < 1":   "Operator < is not defined for CHAR and INTEGER",
+			"s := 'a' * 'b'": "Operator * is not defined for CHAR and CHAR",
+			"i := 'ab'":      "Incompatible types: got STRING expected INTEGER",
+		}
+		for statement, message := range cases {
+			_, err := output(t, "PROGRAM test; VAR s: STRING; b: BOOLEAN; i: INTEGER; BEGIN "+statement+" END.")
+			require.ErrorAs(t, err, &SemanticError{}, statement)
+			require.ErrorContains(t, err, message, statement)
+		}
+	})
+}
+
+func TestBasicInterpreter_stringLibrary(t *testing.T) {
+	t.Run("Functions", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR s: STRING;
+			BEGIN
+				s := 'Hello, world';
+				WRITELN(Length(s), ' ', Length(''), ' ', Length('x'));
+				WRITELN(Copy(s, 8, 5), '|', Copy(s, 0, 2), '|', Copy(s, 11, 10), '|', Copy(s, 20, 1), '|');
+				WRITELN(Pos('o', s), ' ', Pos('world', s), ' ', Pos('x', s), ' ', Pos('', s));
+				WRITELN(Concat(s, '!', '?'), ' ', UpperCase(s));
+				WRITELN(IntToStr(-42) + '!', ' ', StrToInt(' 17 ') + 1)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "12 0 1\nworld|He|ld||\n5 8 0 0\nHello, world!? HELLO, WORLD\n-42! 18\n", text)
+	})
+
+	t.Run("Procedures modify VAR arguments", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR s, t: STRING; i, code: INTEGER; x: REAL;
+			BEGIN
+				s := 'Hello, world';
+				Delete(s, 6, 7);
+				t := 'abc';
+				Delete(t, 0, 1);
+				Delete(t, 2, 100);
+				WRITELN(s, '|', t);
+
+				Insert(' there', s, 6);
+				Insert('>', s, -3);
+				Insert('<', s, 100);
+				WRITELN(s);
+
+				Val('123', i, code);
+				WRITELN(i, ' ', code);
+				Val('12a', i, code);
+				WRITELN(i, ' ', code);
+				Val(' 2.5e1', x, code);
+				WRITELN(x:0:1, ' ', code);
+
+				Str(i, s);
+				Str(x:8:2, t);
+				WRITELN(s, '|', t, '|')
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "Hello|a\n>Hello there<\n123 0\n123 3\n25.0 0\n123|   25.00|\n", text)
+	})
+
+	t.Run("Invalid conversion", func(t *testing.T) {
+		_, err := output(t, "PROGRAM test; VAR i: INTEGER; BEGIN i := StrToInt('12x') END.")
+		require.ErrorContains(t, err, "1:42: runtime error: '12x' is not a valid integer value")
+	})
+
+	t.Run("Calls are checked", func(t *testing.T) {
+		cases := map[string]string{
+			"i := Length(s, s)":    "Wrong number of arguments for 'Length'",
+			"Delete('abc', 1, 1)":  "Variable identifier expected",
+			"i := Delete(s, 1)":    "'Delete' is not a function",
+			"Length(s)":            "'Length' is not a procedure",
+			"s := Copy(s:2, 1, 1)": "Format specifiers are only allowed",
+		}
+
+		for statement, message := range cases {
+			_, err := output(t, "PROGRAM test; VAR s: STRING; i: INTEGER; BEGIN "+statement+" END.")
+			require.ErrorAs(t, err, &SemanticError{}, statement)
+			require.ErrorContains(t, err, message, statement)
+		}
+	})
+}
+
+func TestBasicInterpreter_mathLibrary(t *testing.T) {
+	t.Run("Functions keep INTEGER and REAL apart", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			BEGIN
+				WRITELN(Abs(-3), ' ', Abs(-2.5):0:1, ' ', Sqr(4), ' ', Sqr(1.5):0:2);
+				WRITELN(Sqrt(16):0:1, ' ', Exp(0):0:1, ' ', Ln(1):0:1, ' ', Sin(0):0:1, ' ', Cos(0):0:1, ' ', ArcTan(1) * 4:0:4);
+				WRITELN(Round(2.5), ' ', Round(3.5), ' ', Round(-2.7), ' ', Trunc(-2.7), ' ', Trunc(2.7));
+				WRITELN(Odd(3), ' ', Odd(-4), ' ', Succ(1), ' ', Pred('b'), ' ', Succ(1 > 2));
+				WRITELN(Ord('A'), ' ', Ord(1 = 1), ' ', Chr(72), Chr(105))
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "3 2.5 16 2.25\n4.0 1.0 0.0 0.0 1.0 3.1416\n2 4 -3 -2 2\nTRUE FALSE 2 a TRUE\n65 1 Hi\n", text)
+	})
+
+	t.Run("Runtime errors", func(t *testing.T) {
+		cases := map[string]string{
+			"x := Sqrt(-1)":     "1:72: runtime error: Invalid floating point operation: Sqrt of negative number -1",
+			"x := Ln(0)":        "1:72: runtime error: Invalid floating point operation: Ln of non-positive number 0",
+			"c := Chr(256)":     "1:72: runtime error: Range check error: 256 is not a CHAR",
+			"b := Succ(1 = 1)":  "1:72: runtime error: Range check error: 2 is not a BOOLEAN",
+			"c := Pred(Chr(0))": "1:72: runtime error: Range check error: -1 is not a CHAR",
+		}
+
+		for statement, message := range cases {
+			_, err := output(t, "PROGRAM test; VAR x: REAL; i: INTEGER; c: CHAR; b: BOOLEAN; BEGIN "+statement+" END.")
+			require.ErrorAs(t, err, &RuntimeError{}, statement)
+			require.ErrorContains(t, err, message, statement)
+		}
+	})
+
+	t.Run("Overloads are resolved by argument types", func(t *testing.T) {
+		cases := map[string]string{
+			"b := Odd(1.5)":            "Incompatible types in call to 'Odd': got (REAL)",
+			"x := Sqrt('a')":           "Incompatible types in call to 'Sqrt': got (CHAR)",
+			"i := Ord(x)":              "Incompatible types in call to 'Ord': got (REAL)",
+			"c := Chr(Abs(-1.0))":      "Incompatible types in call to 'Chr': got (REAL)",
+			"i := Round(Abs(2) + 0.5)": "",
+		}
+
+		for statement, message := range cases {
+			_, err := output(t, "PROGRAM test; VAR x: REAL; i: INTEGER; c: CHAR; b: BOOLEAN; BEGIN "+statement+" END.")
+			if message == "" {
+				require.NoError(t, err, statement)
+				continue
+			}
+			require.ErrorAs(t, err, &SemanticError{}, statement)
+			require.ErrorContains(t, err, message, statement)
+		}
+	})
+}
+
+func TestBasicInterpreter_arrays(t *testing.T) {
+	t.Run("Elements are assigned and read", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR a: ARRAY[1..3] OF INTEGER; grid: ARRAY[-1..0, 'a'..'b'] OF CHAR; i: INTEGER;
+			BEGIN
+				a[1] := 10; a[2] := 20; a[1 + 2] := a[1] + a[2];
+				grid[-1, 'a'] := 'x'; grid[-1]['b'] := 'y'; grid[0, Chr(97)] := grid[-1, 'b'];
+				i := 2;
+				WRITELN(a[1], ' ', a[i], ' ', a[i + 1]);
+				WRITELN(grid[-1, 'a'], grid[-1, 'b'], grid[0]['a'])
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "10 20 30\nxyy\n", text)
+	})
+
+	t.Run("Assignment copies the elements", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR a, b: ARRAY[1..2] OF ARRAY[1..2] OF INTEGER; row: ARRAY[1..2] OF INTEGER;
+			BEGIN
+				a[1, 1] := 1; a[1, 2] := 2; a[2, 1] := 3; a[2, 2] := 4;
+				b := a;
+				row := a[2];
+				a[1, 1] := 100;
+				row[1] := 300;
+				WRITELN(a[1, 1], ' ', b[1, 1], ' ', a[2, 1], ' ', row[1], ' ', b[2][2])
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "100 1 3 300 4\n", text)
+	})
+
+	t.Run("Elements are VAR arguments", func(t *testing.T) {
+		text, err := outputWithInput(t, `
+			PROGRAM test;
+			VAR words: ARRAY[1..2] OF STRING; n: ARRAY[0..1] OF INTEGER; s: STRING;
+			BEGIN
+				READ(n[0], n[1]);
+				READLN;
+				READLN(words[1]);
+				Delete(words[1], 1, 1);
+				s := 'cat';
+				s[1] := 'b';
+				WRITELN(n[0] + n[1], ' ', words[1], ' ', s)
+			END.
+		`, "3 4\nfoo\n")
+		require.NoError(t, err)
+		require.Equal(t, "7 oo bat\n", text)
+	})
+
+	t.Run("Runtime errors", func(t *testing.T) {
+		cases := map[string]string{
+			"i := 4; a[i] := 1":      "1:117: runtime error: Index 4 out of range 1..3",
+			"c := 'z'; g[1, c] := 1": "1:122: runtime error: Index 'z' out of range 'a'..'c'",
+			"i := a[2]":              "1:114: runtime error: Element 2 is not initialized",
+		}
+
+		for statement, message := range cases {
+			_, err := output(t, "PROGRAM test; VAR a: ARRAY[1..3] OF INTEGER; g: ARRAY[1..2, 'a'..'c'] OF REAL; i: INTEGER; c: CHAR; BEGIN "+statement+" END.")
+			require.ErrorAs(t, err, &RuntimeError{}, statement)
+			require.ErrorContains(t, err, message, statement)
+		}
+	})
+
+	t.Run("Declarations and indices are checked", func(t *testing.T) {
+		cases := map[string]string{
+			"VAR a: ARRAY[3..1] OF INTEGER; BEGIN END.":                  "High range limit < low range limit",
+			"VAR a: ARRAY[1..'c'] OF INTEGER; BEGIN END.":                "Incompatible types: got CHAR expected INTEGER",
+			"VAR a: ARRAY[1.5..2] OF INTEGER; BEGIN END.":                "Ordinal expression expected, got REAL",
+			"VAR i: INTEGER; a: ARRAY[1..i] OF INTEGER; BEGIN END.":      "Constant expression expected",
+			"VAR a: ARRAY[1..3] OF INTEGER; BEGIN a['x'] := 1 END.":      "Incompatible types: got CHAR expected INTEGER",
+			"VAR a: ARRAY[1..3] OF INTEGER; BEGIN a[1, 2] := 1 END.":     "Cannot index a value of type INTEGER",
+			"VAR a: ARRAY[1..3] OF INTEGER; BEGIN WRITE(Length(a)) END.": "Incompatible types in call to 'Length': got (ARRAY[1..3] OF INTEGER)",
+		}
+
+		for source, message := range cases {
+			_, err := output(t, "PROGRAM test; "+source)
+			require.ErrorAs(t, err, &SemanticError{}, source)
+			require.ErrorContains(t, err, message, source)
+		}
+	})
+}
+
+func TestBasicInterpreter_records(t *testing.T) {
+	t.Run("Fields are assigned and read", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR
+				p: RECORD x, y: INTEGER; name: STRING END;
+				line: RECORD from, till: RECORD x, y: REAL END END;
+				points: ARRAY[1..2] OF RECORD x: INTEGER END;
+			BEGIN
+				p.x := 1; p.y := p.x + 1; p.name := 'p';
+				line.from.x := 0.5; line.till.y := 2;
+				points[2].x := 7;
+				WRITELN(p.name, ' ', p.x, ' ', p.y, ' ', line.from.x:0:1, ' ', line.till.y:0:1, ' ', points[2].x)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "p 1 2 0.5 2.0 7\n", text)
+	})
+
+	t.Run("Assignment copies the fields", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR a, b: RECORD n: INTEGER; inner: RECORD s: STRING END END;
+			BEGIN
+				a.n := 1; a.inner.s := 'one';
+				b := a;
+				a.n := 2; a.inner.s := 'two';
+				WRITELN(a.n, a.inner.s, ' ', b.n, b.inner.s)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "2two 1one\n", text)
+	})
+
+	t.Run("WITH opens the fields", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR
+				x: INTEGER;
+				p: RECORD x, y: INTEGER END;
+				r: RECORD y: INTEGER; inner: RECORD z: INTEGER END END;
+				points: ARRAY[1..2] OF RECORD x: INTEGER END;
+			BEGIN
+				x := 100;
+				WITH p DO
+				BEGIN
+					x := 1;
+					y := x + 1
+				END;
+				WITH p, r DO
+				BEGIN
+					y := 20;
+					inner.z := x + y
+				END;
+				WITH r.inner DO z := z + 1;
+				x := 2;
+				WITH points[x] DO x := 9;
+				WRITELN(x, ' ', p.x, ' ', p.y, ' ', r.y, ' ', r.inner.z, ' ', points[2].x)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "2 1 2 20 22 9\n", text)
+	})
+
+	t.Run("Uninitialized field", func(t *testing.T) {
+		_, err := output(t, "PROGRAM test; VAR p: RECORD x: INTEGER END; i: INTEGER; BEGIN i := p.x END.")
+		require.ErrorContains(t, err, "1:68: runtime error: Field 'x' is not initialized")
+	})
+
+	t.Run("Declarations and access are checked", func(t *testing.T) {
+		cases := map[string]string{
+			"VAR p: RECORD x: INTEGER; x: REAL END; BEGIN END.":         "Duplicate identifier 'x' found",
+			"VAR p: RECORD x: INTEGER END; BEGIN p.y := 1 END.":         "Unknown record field 'y'",
+			"VAR i: INTEGER; BEGIN i.x := 1 END.":                       "Cannot access field 'x' of a value of type INTEGER",
+			"VAR i: INTEGER; BEGIN WITH i DO i := 1 END.":               "Record type expected",
+			"VAR p: RECORD x: INTEGER END; BEGIN WITH p DO y := 1 END.": "Identifier not found 'y'",
+			"VAR p: RECORD s: STRING END; BEGIN WRITE(Abs(p.s)) END.":   "Incompatible types in call to 'Abs': got (STRING)",
+			"VAR p: RECORD s: STRING END; BEGIN WRITE(Length(p)) END.":  "got (RECORD s: STRING END)",
+		}
+
+		for source, message := range cases {
+			_, err := output(t, "PROGRAM test; "+source)
+			require.ErrorAs(t, err, &SemanticError{}, source)
+			require.ErrorContains(t, err, message, source)
+		}
+	})
+}
+
+func TestBasicInterpreter_types(t *testing.T) {
+	t.Run("Enumerations are ordinal", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			TYPE Color = (Red, Green, Blue);
+			VAR c: Color; counts: ARRAY[Color] OF INTEGER; s: (Alpha, Beta);
+			BEGIN
+				c := Succ(Red);
+				counts[Blue] := 3;
+				s := Beta;
+				WRITELN(c, ' ', Ord(c), ' ', Pred(c), ' ', c < Blue, ' ', counts[Succ(c)], ' ', s)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "Green 1 Red TRUE 3 Beta\n", text)
+	})
+
+	t.Run("Aliases and named types", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			TYPE
+				Number = INTEGER;
+				Point = RECORD x, y: Number END;
+				Position = Point;
+				Letters = ARRAY['a'..'c'] OF Number;
+			VAR p: Point; q: Position; l: Letters;
+			BEGIN
+				p.x := 1; p.y := 2;
+				q := p;
+				l['b'] := q.x + q.y;
+				WRITELN(l['b'])
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "3\n", text)
+	})
+
+	t.Run("Subranges are checked on assignment", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			TYPE Digit = 0..9; Color = (Red, Green, Blue); Warm = Red..Green;
+			VAR d: Digit; w: Warm; letter: 'a'..'z'; digits: ARRAY[Digit] OF CHAR;
+			BEGIN
+				d := 9; w := Green; letter := 'q';
+				digits[d] := letter;
+				WRITELN(d, ' ', w, ' ', digits[9], ' ', Succ(d))
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "9 Green q 10\n", text)
+
+		_, err = output(t, "PROGRAM test; TYPE Digit = 0..9; VAR d: Digit; BEGIN d := 9; d := d + 1 END.")
+		require.ErrorContains(t, err, "1:62: runtime error: Range check error: 10 out of range 0..9")
+
+		_, err = output(t, "PROGRAM test; TYPE Color = (Red, Green); VAR c: Color; BEGIN c := Succ(Green) END.")
+		require.ErrorContains(t, err, "1:67: runtime error: Range check error: 2 is not a Color")
+	})
+
+	t.Run("Declarations are checked", func(t *testing.T) {
+		cases := map[string]string{
+			"TYPE T = (A, B); U = (B, C); BEGIN END.":                     "Duplicate identifier 'B' found",
+			"TYPE T = INTEGER; T = REAL; BEGIN END.":                      "Duplicate identifier 'T' found",
+			"TYPE T = X; BEGIN END.":                                      "Unknown type 'X'",
+			"TYPE T = 9..0; BEGIN END.":                                   "High range limit < low range limit",
+			"TYPE T = 0..'a'; BEGIN END.":                                 "Incompatible types: got CHAR expected INTEGER",
+			"TYPE T = (A, B); BEGIN A := B END.":                          "Cannot assign to A",
+			"TYPE T = INTEGER; VAR i: T; BEGIN i := T END.":               "'T' is not a variable",
+			"VAR a: ARRAY[INTEGER] OF CHAR; BEGIN END.":                   "Index range expected",
+			"TYPE T = (A, B); VAR v: ARRAY[T] OF T; BEGIN v[1] := A END.": "Incompatible types: got INTEGER expected T",
+		}
+
+		for source, message := range cases {
+			_, err := output(t, "PROGRAM test; "+source)
+			require.ErrorAs(t, err, &SemanticError{}, source)
+			require.ErrorContains(t, err, message, source)
+		}
+	})
+}
+
+func TestBasicInterpreter_constants(t *testing.T) {
+	t.Run("Constant expressions", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			CONST
+				Size = 3;
+				Last = Size - 1;
+				Letter = Chr(Ord('a') + Last);
+				Ratio: REAL = Size;
+				Title = 'n' + 'ame';
+				Verbose = FALSE = FALSE;
+			VAR a: ARRAY[0..Last] OF INTEGER;
+			BEGIN
+				a[Last] := Size;
+				WRITELN(a[2], ' ', Letter, ' ', Ratio:0:1, ' ', Title, ' ', Verbose, ' ', TRUE = (1 = 1))
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "3 c 3.0 name TRUE TRUE\n", text)
+	})
+
+	t.Run("Sections are declared in source order", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			TYPE
+				Small = 1..9;
+				TCounter = CLASS
+					Count: Small;
+					PROCEDURE Tick;
+				END;
+			CONST Max: Small = 5;
+			PROCEDURE TCounter.Tick;
+			BEGIN
+				Count := Count + 1
+			END;
+			VAR c: TCounter;
+			BEGIN
+				c := TCounter.Create;
+				c.Count := Max;
+				c.Tick;
+				WRITELN(c.Count);
+				c.Free
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "6\n", text)
+
+		_, err = output(t, "PROGRAM test; VAR i: Small; TYPE Small = 1..9; BEGIN END.")
+		require.ErrorAs(t, err, &SemanticError{})
+		require.ErrorContains(t, err, "Small")
+	})
+
+	t.Run("Constants are checked", func(t *testing.T) {
+		cases := map[string]string{
+			"CONST Max = 5; BEGIN Max := 6 END.":               "Cannot assign to Max",
+			"BEGIN TRUE := FALSE END.":                         "Cannot assign to TRUE",
+			"CONST Max = 5; BEGIN READ(Max) END.":              "Variable identifier expected",
+			"CONST Max = 5; Max = 6; BEGIN END.":               "Duplicate identifier 'Max' found",
+			"CONST Max: INTEGER = 'a'; BEGIN END.":             "Incompatible types: got CHAR expected INTEGER",
+			"CONST Letter = Chr(300); BEGIN END.":              "Range check error: 300 is not a CHAR",
+			"CONST Max = 5; Twice = Max * Unknown; BEGIN END.": "Constant expression expected",
+		}
+
+		for source, message := range cases {
+			_, err := output(t, "PROGRAM test; "+source)
+			require.ErrorAs(t, err, &SemanticError{}, source)
+			require.ErrorContains(t, err, message, source)
+		}
+	})
+
+	t.Run("Operands and assigned values are checked", func(t *testing.T) {
+		cases := map[string]string{
+			"i := 1 + TRUE":  "Operator + is not defined for INTEGER and BOOLEAN",
+			"i := 5 DIV 2.0": "Operator DIV is not defined for INTEGER and REAL",
+			"b := c < 'a'":   "Operator < is not defined for TColor and CHAR",
+			"b := c = 1":     "Operator = is not defined for TColor and INTEGER",
+			"b := 1 IN l":    "Operator IN is not defined for INTEGER and TLetters",
+			"i := -'a'":      "Operator - is not defined for CHAR",
+			"i := Red":       "Incompatible types: got TColor expected INTEGER",
+			"c := 1":         "Incompatible types: got INTEGER expected TColor",
+			"a := b3":        "Incompatible types: got ARRAY[1..3] OF INTEGER expected ARRAY[1..2] OF INTEGER",
+			"l := [1, 2]":    "Incompatible types: got SET OF INTEGER expected TLetters",
+			"s := i":         "Incompatible types: got INTEGER expected TSmall",
+		}
+
+		for statement, message := range cases {
+			_, err := output(t, `PROGRAM test;
+				TYPE TColor = (Red, Green); TLetters = SET OF 'a'..'z'; TSmall = 'a'..'z';
+				VAR i: INTEGER; b: BOOLEAN; c: TColor; l: TLetters; s: TSmall;
+					a: ARRAY[1..2] OF INTEGER; b3: ARRAY[1..3] OF INTEGER;
+				BEGIN `+statement+` END.`)
+			require.ErrorAs(t, err, &SemanticError{}, statement)
+			require.ErrorContains(t, err, message, statement)
+		}
+	})
+}
+
+func TestBasicInterpreter_sets(t *testing.T) {
+	t.Run("Membership and set operators", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			CONST Vowels = ['a', 'e', 'i', 'o', 'u'];
+			TYPE Color = (Red, Green, Blue); Colors = SET OF Color;
+			VAR ch: CHAR; letters: SET OF CHAR; warm, cold: Colors; digits: SET OF 0..9;
+			BEGIN
+				ch := 'q';
+				letters := ['a'..'f'] - Vowels + ['x'];
+				warm := [Red, Green];
+				cold := [Blue] * warm;
+				digits := [1, 3..5];
+				WRITELN(ch IN ['a'..'z'], ' ', 'b' IN letters, ' ', 'e' IN letters, ' ', Blue IN warm + [Blue]);
+				WRITELN(cold = [], ' ', warm <> [Green, Red], ' ', [Red] <= warm, ' ', warm >= [Blue], ' ', 4 IN digits)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "TRUE TRUE FALSE TRUE\nTRUE FALSE TRUE FALSE TRUE\n", text)
+	})
+
+	t.Run("Set values are formatted like constructors", func(t *testing.T) {
+		set := setValue{Element: simpleType("CHAR")}
+		for _, v := range "acdex" {
+			set.add(int(v))
+		}
+		require.Equal(t, "['a', 'c'..'e', 'x']", FormatValue(set))
+		require.Equal(t, "[]", FormatValue(setValue{}))
+	})
+
+	t.Run("Elements are checked at runtime", func(t *testing.T) {
+		_, err := output(t, "PROGRAM test; VAR digits: SET OF 0..9; i: INTEGER; BEGIN i := 10; digits := [1, i] END.")
+		require.ErrorContains(t, err, "runtime error: Range check error: set element 10 out of range 0..9")
+
+		_, err = output(t, "PROGRAM test; VAR i: INTEGER; b: BOOLEAN; BEGIN i := 300; b := 1 IN [i] END.")
+		require.ErrorContains(t, err, "runtime error: Range check error: set elements must be in 0..255")
+	})
+
+	t.Run("Set types and constructors are checked", func(t *testing.T) {
+		cases := map[string]string{
+			"VAR s: SET OF INTEGER; BEGIN END.":                 "Illegal set element type INTEGER",
+			"VAR s: SET OF 0..300; BEGIN END.":                  "Illegal set element type 0..300",
+			"VAR b: BOOLEAN; BEGIN b := 'a' IN ['a', 1] END.":   "Incompatible types: got INTEGER expected CHAR",
+			"VAR b: BOOLEAN; BEGIN b := ['a'] = [1] END.":       "Incompatible types: got SET OF INTEGER expected SET OF CHAR",
+			"VAR s: SET OF CHAR; BEGIN s := ['a'] + [1.5] END.": "Ordinal expression expected",
+			"CONST d: SET OF 0..9 = [10]; BEGIN END.":           "Range check error: set element 10 out of range 0..9",
+		}
+
+		for source, message := range cases {
+			_, err := output(t, "PROGRAM test; "+source)
+			require.ErrorAs(t, err, &SemanticError{}, source)
+			require.ErrorContains(t, err, message, source)
+		}
+	})
+}
+
+func TestBasicInterpreter_pointers(t *testing.T) {
+	t.Run("Linked list on the heap", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			TYPE
+				PNode = ^TNode;
+				TNode = RECORD value: INTEGER; next: PNode END;
+			VAR head, node: PNode; count: ^INTEGER;
+			BEGIN
+				head := NIL;
+				New(node); node^.value := 1; node^.next := head; head := node;
+				New(node); node^.value := 2; node^.next := head; head := node;
+				New(count);
+				count^ := head^.value + head^.next^.value;
+				WITH head^.next^ DO value := 10;
+				WRITELN(count^, ' ', node^.next^.value, ' ', head^.next^.next = NIL, ' ', head <> node);
+				Dispose(head^.next); Dispose(head); Dispose(count)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "3 10 TRUE FALSE\n", text)
+	})
+
+	t.Run("Invalid pointer use is a runtime error", func(t *testing.T) {
+		cases := map[string]string{
+			"VAR p: ^INTEGER; BEGIN p := NIL; p^ := 1 END.":                      "Dereference of NIL pointer",
+			"VAR p, q: ^INTEGER; BEGIN New(p); q := p; Dispose(p); q^ := 1 END.": "Dereference of disposed pointer",
+			"VAR p: ^INTEGER; BEGIN New(p); Dispose(p); Dispose(p) END.":         "Pointer disposed twice",
+			"VAR p: ^INTEGER; i: INTEGER; BEGIN New(p); i := p^ END.":            "Pointer target is not initialized",
+		}
+
+		for source, message := range cases {
+			_, err := output(t, "PROGRAM test; "+source)
+			require.ErrorAs(t, err, &RuntimeError{}, source)
+			require.ErrorContains(t, err, message, source)
+		}
+	})
+
+	t.Run("Leaked allocations are reported", func(t *testing.T) {
+		cases := map[string]string{
+			"New(p); New(q); Dispose(p)": "1 unfreed allocation:\n  4:11: INTEGER\n",
+			"New(p); New(q)":             "2 unfreed allocations:\n  4:3: INTEGER\n  4:11: INTEGER\n",
+		}
+
+		for statements, report := range cases {
+			var leaks bytes.Buffer
+			basicInterpreter, err := NewInterpreter(lexer.NewLexer(`PROGRAM test;
+VAR p, q: ^INTEGER;
+BEGIN
+  `+statements+`
+END.`), strings.NewReader(""), io.Discard)
+			require.NoError(t, err)
+			basicInterpreter.Evaluator.(*EvaluatorVisitor).Leaks = &leaks
+
+			_, err = basicInterpreter.Interpret()
+			require.NoError(t, err)
+			require.Equal(t, report, leaks.String(), statements)
+		}
+	})
+
+	t.Run("Pointer use is checked", func(t *testing.T) {
+		cases := map[string]string{
+			"TYPE P = ^Missing; BEGIN END.":                                "Unknown type 'Missing'",
+			"VAR i: INTEGER; BEGIN New(i) END.":                            "Pointer type expected, got INTEGER",
+			"VAR p: ^INTEGER; BEGIN New(p, p) END.":                        "Wrong number of arguments for 'New'",
+			"BEGIN New(NIL) END.":                                          "Variable identifier expected",
+			"VAR i: INTEGER; BEGIN i := i^ END.":                           "Cannot dereference a value of type INTEGER",
+			"VAR p: ^INTEGER; q: ^CHAR; b: BOOLEAN; BEGIN b := p = q END.": "Incompatible types: got ^CHAR expected ^INTEGER",
+			"VAR p: ^CHAR; q: ^INTEGER; BEGIN q := NIL; p := q END.":       "Incompatible types: got ^INTEGER expected ^CHAR",
+			"VAR p: ^INTEGER; r: REAL; BEGIN r := p END.":                  "Incompatible types: got ^INTEGER expected REAL",
+			"VAR p: ^INTEGER; q: ^REAL; BEGIN New(q); p := q END.":         "Incompatible types: got ^REAL expected ^INTEGER",
+			"VAR p: ^INTEGER; b: BOOLEAN; BEGIN b := p < NIL END.":         "Operator < is not defined for ^INTEGER and Pointer",
+		}
+
+		for source, message := range cases {
+			_, err := output(t, "PROGRAM test; "+source)
+			require.ErrorAs(t, err, &SemanticError{}, source)
+			require.ErrorContains(t, err, message, source)
+		}
+	})
+}
+
+func TestBasicInterpreter_routines(t *testing.T) {
+	t.Run("Procedures and functions", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR a, b, total: INTEGER;
+
+			FUNCTION Sum(x, y: INTEGER): INTEGER;
+			BEGIN
+				Sum := x + y
+			END;
+
+			PROCEDURE Swap(VAR x, y: INTEGER);
+			VAR t: INTEGER;
+			BEGIN
+				t := x; x := y; y := t
+			END;
+
+			PROCEDURE Count;
+			VAR step: INTEGER;
+				PROCEDURE Add;
+				BEGIN
+					total := total + step
+				END;
+			BEGIN
+				step := 5; Add; Add
+			END;
+
+			FUNCTION Answer: INTEGER;
+			BEGIN
+				Answer := 42
+			END;
+
+			BEGIN
+				a := 1; b := 2; total := 0;
+				Swap(a, b);
+				Count;
+				WRITELN(a, ' ', b, ' ', Sum(a, b * 10), ' ', total, ' ', Answer + 1)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "2 1 12 10 43\n", text)
+	})
+
+	t.Run("Procedural variables and parameters", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			TYPE TCompare = FUNCTION(a, b: INTEGER): INTEGER;
+			VAR compare: TCompare;
+
+			FUNCTION Ascending(a, b: INTEGER): INTEGER;
+			BEGIN
+				Ascending := a - b
+			END;
+
+			FUNCTION Descending(x, y: INTEGER): INTEGER;
+			BEGIN
+				Descending := y - x
+			END;
+
+			PROCEDURE Report(compare: TCompare);
+			BEGIN
+				WRITE(compare(1, 2), ' ')
+			END;
+
+			PROCEDURE Apply(FUNCTION f(a, b: INTEGER): INTEGER);
+			BEGIN
+				WRITE(f(5, 3), ' ')
+			END;
+
+			BEGIN
+				compare := Ascending;
+				Report(compare);
+				compare := Descending;
+				Report(compare);
+				Report(Ascending);
+				Apply(Descending);
+				WRITELN(compare(10, 1))
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "-1 1 -1 -2 -9\n", text)
+	})
+
+	t.Run("Procedural variables are compared and tested for NIL", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			TYPE
+				TCompare = FUNCTION(a, b: INTEGER): INTEGER;
+				TCount = FUNCTION: INTEGER;
+				PNumber = ^INTEGER;
+				TThing = CLASS END;
+			VAR compare, other: TCompare; count: TCount; p: PNumber; thing: TThing;
+
+			FUNCTION Ascending(a, b: INTEGER): INTEGER;
+			BEGIN
+				Ascending := a - b
+			END;
+
+			FUNCTION Fail: INTEGER;
+			BEGIN
+				RAISE Exception.Create('called')
+			END;
+
+			BEGIN
+				compare := NIL;
+				WRITELN(compare = NIL, ' ', NIL <> compare, ' ', Assigned(compare));
+				compare := Ascending;
+				other := Ascending;
+				WRITELN(compare = NIL, ' ', compare = other, ' ', Assigned(compare));
+				other := NIL;
+				WRITELN(compare <> other);
+				count := Fail;
+				WRITELN(Assigned(count));
+				p := NIL;
+				thing := NIL;
+				WRITELN(Assigned(p), ' ', Assigned(thing));
+				New(p);
+				thing := TThing.Create;
+				WRITELN(Assigned(p), ' ', Assigned(thing));
+				Dispose(p);
+				thing.Free
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "TRUE FALSE FALSE\nFALSE TRUE TRUE\nTRUE\nTRUE\nFALSE FALSE\nTRUE TRUE\n", text)
+
+		cases := map[string]string{
+			"b := compare < NIL":   "Operator < is not defined for TCompare and Pointer",
+			"b := compare = 1":     "Operator = is not defined for TCompare and INTEGER",
+			"b := compare = apply": "Operator = is not defined for TCompare and TApply",
+			"b := Assigned(i)":     "Incompatible types in call to 'Assigned': got (INTEGER)",
+			"b := Assigned(NIL)":   "Variable identifier expected",
+		}
+		for statement, message := range cases {
+			_, err := output(t, `PROGRAM test;
+				TYPE TCompare = FUNCTION(a, b: INTEGER): INTEGER; TApply = PROCEDURE(x: INTEGER);
+				VAR compare: TCompare; apply: TApply; i: INTEGER; b: BOOLEAN;
+				BEGIN `+statement+` END.`)
+			require.ErrorAs(t, err, &SemanticError{}, statement)
+			require.ErrorContains(t, err, message, statement)
+		}
+	})
+
+	t.Run("Runaway recursion is a stack overflow", func(t *testing.T) {
+		_, err := output(t, `
+			PROGRAM test;
+			FUNCTION Loop(n: INTEGER): INTEGER;
+			BEGIN
+				Loop := Loop(n + 1)
+			END;
+			BEGIN
+				WRITELN(Loop(0))
+			END.
+		`)
+		require.ErrorAs(t, err, &RuntimeError{})
+		require.ErrorContains(t, err, "Stack overflow")
+	})
+
+	t.Run("Invalid calls are runtime errors", func(t *testing.T) {
+		cases := map[string]string{
+			"TYPE TProc = PROCEDURE; VAR p: TProc; BEGIN p := NIL; p END.": "Call through NIL procedural variable 'p'",
+			"FUNCTION F: INTEGER; BEGIN END; BEGIN WRITELN(F) END.":        "Result of function 'F' is not set",
+		}
+
+		for source, message := range cases {
+			_, err := output(t, "PROGRAM test; "+source)
+			require.ErrorAs(t, err, &RuntimeError{}, source)
+			require.ErrorContains(t, err, message, source)
+		}
+	})
+
+	t.Run("Calls are checked", func(t *testing.T) {
+		cases := map[string]string{
+			"PROCEDURE P(x: INTEGER); BEGIN END; BEGIN P END.":                                         "Wrong number of arguments for 'P'",
+			"PROCEDURE P(VAR x: INTEGER); BEGIN END; BEGIN P(1) END.":                                  "Variable identifier expected",
+			"PROCEDURE P(x: INTEGER); BEGIN END; BEGIN P('text') END.":                                 "Incompatible types: got STRING expected INTEGER",
+			"VAR i: INTEGER; PROCEDURE P; BEGIN END; BEGIN i := P(1) END.":                             "'P' is not a function",
+			"PROCEDURE P(x: INTEGER); BEGIN END; BEGIN P(Sqrt(4)) END.":                                "Incompatible types: got REAL expected INTEGER",
+			"FUNCTION F: INTEGER; BEGIN F := 2.5 END; BEGIN END.":                                      "Incompatible types: got REAL expected INTEGER",
+			"PROCEDURE P(x, x: INTEGER); BEGIN END; BEGIN END.":                                        "Duplicate identifier 'x' found",
+			"TYPE TFunc = FUNCTION: INTEGER; VAR f: TFunc; PROCEDURE P; BEGIN END; BEGIN f := P END.":  "Incompatible types: got PROCEDURE expected TFunc",
+			"PROCEDURE Q(PROCEDURE p(x: INTEGER)); BEGIN END; PROCEDURE P; BEGIN END; BEGIN Q(P) END.": "Incompatible types: got PROCEDURE expected PROCEDURE(INTEGER)",
+			"PROCEDURE P; BEGIN END; BEGIN P := 1 END.":                                                "Cannot assign to P",
+			"PROCEDURE P; VAR x: INTEGER; BEGIN END; BEGIN x := 1 END.":                                "Identifier not found 'x'",
+		}
+
+		for source, message := range cases {
+			_, err := output(t, "PROGRAM test; "+source)
+			require.ErrorAs(t, err, &SemanticError{}, source)
+			require.ErrorContains(t, err, message, source)
+		}
+	})
+}
+
+func TestBasicInterpreter_exceptions(t *testing.T) {
+	t.Run("Runtime errors are caught as exceptions", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR a, b: INTEGER;
+			BEGIN
+				a := 1; b := 0;
+				TRY
+					WRITELN(a DIV b)
+				EXCEPT
+					ON E: EDivByZero DO WRITELN(E.ClassName, ': ', E.Message)
+				END;
+				TRY
+					WRITELN(StrToInt('x'))
+				EXCEPT
+					ON E: EDivByZero DO WRITELN('wrong');
+					ON E: Exception DO WRITELN(E.ClassName)
+				END
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "EDivByZero: Division by zero\nEConvertError\n", text)
+	})
+
+	t.Run("The class of a runtime error depends on its kind, not its message", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR i: INTEGER;
+			BEGIN
+				TRY
+					i := StrToInt('Division by zero')
+				EXCEPT
+					ON E: Exception DO WRITELN(E.ClassName)
+				END
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "EConvertError\n", text)
+	})
+
+	t.Run("Handlers match derived exceptions", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			TYPE TSmall = 1..5;
+			VAR s: TSmall; i: INTEGER;
+			BEGIN
+				i := 9;
+				TRY
+					s := i
+				EXCEPT
+					ON EIntError DO WRITELN('int error')
+				END;
+				TRY
+					RAISE ERangeError.Create('custom')
+				EXCEPT
+					ON E: EDivByZero DO WRITELN('wrong')
+				ELSE
+					WRITELN('else')
+				END;
+				TRY
+					RAISE Exception.Create('plain')
+				EXCEPT
+					WRITELN('caught')
+				END
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "int error\nelse\ncaught\n", text)
+	})
+
+	t.Run("Indexes out of range are caught as ERangeError", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR a: ARRAY[1..2] OF INTEGER; s: STRING; c: CHAR; i: INTEGER;
+			BEGIN
+				i := 3; s := 'ab';
+				TRY
+					a[i] := 1
+				EXCEPT
+					ON E: ERangeError DO WRITELN(E.Message)
+				END;
+				TRY
+					c := s[i]
+				EXCEPT
+					ON E: ERangeError DO WRITELN(E.Message)
+				END
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "Index 3 out of range 1..2\nIndex 3 out of range 1..2\n", text)
+	})
+
+	t.Run("Finally runs on both paths", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR zero: INTEGER;
+			BEGIN
+				zero := 0;
+				TRY
+					WRITE('body ')
+				FINALLY
+					WRITELN('finally')
+				END;
+				TRY
+					TRY
+						WRITE(1 DIV zero)
+					FINALLY
+						WRITE('finally ')
+					END
+				EXCEPT
+					ON E: Exception DO WRITELN(E.Message)
+				END
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "body finally\nfinally Division by zero\n", text)
+	})
+
+	t.Run("Exceptions are raised again", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			PROCEDURE Fail;
+			BEGIN
+				RAISE EConvertError.Create('bad input')
+			END;
+			BEGIN
+				TRY
+					TRY
+						Fail
+					EXCEPT
+						ON E: EConvertError DO
+						BEGIN
+							WRITELN('inner ', E.Message);
+							RAISE
+						END
+					END
+				EXCEPT
+					ON E: Exception DO WRITELN('outer ', E.ClassName)
+				END;
+				Fail
+			END.
+		`)
+		require.Equal(t, "inner bad input\nouter EConvertError\n", text)
+		require.ErrorAs(t, err, &RuntimeError{})
+		require.ErrorContains(t, err, "Unhandled exception EConvertError: bad input")
+	})
+
+	t.Run("Exceptions are checked", func(t *testing.T) {
+		cases := map[string]string{
+			"BEGIN RAISE END.":   "RAISE without an exception outside of an exception handler",
+			"BEGIN RAISE 1 END.": "Exception expected, got INTEGER",
+			"TYPE TNumber = INTEGER; BEGIN TRY EXCEPT ON E: TNumber DO END END.":        "Exception class type expected, got INTEGER",
+			"BEGIN TRY EXCEPT ON E: Exception DO E := NIL END; WRITELN(E.Message) END.": "Identifier not found 'E'",
+			"BEGIN RAISE Exception.Create(1) END.":                                      "Incompatible types: got INTEGER expected STRING",
+			"BEGIN RAISE Exception.Make('x') END.":                                      "Unknown method 'Make'",
+			"BEGIN TRY EXCEPT ON E: Exception DO WRITELN(E.Code) END END.":              "Unknown field 'Code' of Exception",
+		}
+
+		for source, message := range cases {
+			_, err := output(t, "PROGRAM test; "+source)
+			require.ErrorAs(t, err, &SemanticError{}, source)
+			require.ErrorContains(t, err, message, source)
+		}
+	})
+}
+
+func TestBasicInterpreter_controlFlow(t *testing.T) {
+	t.Run("Conditions and loops", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			TYPE TColor = (red, green, blue);
+			VAR i, total: INTEGER; c: TColor; ch: CHAR;
+			BEGIN
+				total := 0;
+				FOR i := 1 TO 10 DO
+					IF i < 5 THEN total := total + i ELSE IF i = 5 THEN total := total * 10;
+				WRITE(total, ' ');
+				FOR c := blue DOWNTO red DO WRITE(Ord(c));
+				FOR ch := 'a' TO 'c' DO WRITE(ch);
+				i := 1;
+				WHILE i < 100 DO i := i * 3;
+				REPEAT
+					i := i - 50
+				UNTIL i < 0;
+				WRITELN(' ', i)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "100 210abc -7\n", text)
+	})
+
+	t.Run("Break and Continue", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR i, j: INTEGER;
+			BEGIN
+				FOR i := 1 TO 10 DO
+				BEGIN
+					IF Odd(i) = FALSE THEN Continue;
+					IF i > 7 THEN Break;
+					FOR j := 1 TO 10 DO
+						IF j > 1 THEN Break;
+					WRITE(i, j, ' ')
+				END;
+				i := 0;
+				REPEAT
+					i := i + 1;
+					TRY
+						IF i = 2 THEN Continue;
+						WRITE(i)
+					FINALLY
+						WRITE('.')
+					END
+				UNTIL i = 3;
+				WRITELN
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "12 32 52 72 1..3.\n", text)
+	})
+
+	t.Run("Exit leaves routines and the program", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			VAR i: INTEGER;
+
+			FUNCTION Find(n: INTEGER): INTEGER;
+			VAR i: INTEGER;
+			BEGIN
+				FOR i := 1 TO 100 DO
+					IF i * i >= n THEN Exit(i);
+				Find := -1
+			END;
+
+			PROCEDURE Report(n: INTEGER);
+			BEGIN
+				TRY
+					IF n < 0 THEN Exit;
+					WRITE(n, ' ')
+				FINALLY
+					WRITE('; ')
+				END
+			END;
+
+			BEGIN
+				Report(Find(50));
+				Report(-1);
+				Report(Find(9999));
+				WRITELN;
+				Exit;
+				WRITELN('unreachable')
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "8 ; ; 100 ; \n", text)
+	})
+
+	t.Run("Halt sets the exit code", func(t *testing.T) {
+		var buffer bytes.Buffer
+		basicInterpreter, err := NewInterpreter(lexer.NewLexer(`
+			PROGRAM test;
+			PROCEDURE Stop;
+			BEGIN
+				TRY
+					Halt(3)
+				FINALLY
+					WRITELN('skipped')
+				END
+			END;
+			BEGIN
+				WRITE('before ');
+				TRY
+					Stop
+				EXCEPT
+					WRITELN('not caught')
+				END;
+				WRITELN('after')
+			END.
+		`), strings.NewReader(""), &buffer)
+		require.NoError(t, err)
+		code, err := basicInterpreter.Interpret()
+		require.NoError(t, err)
+		require.Equal(t, 3, code)
+		require.Equal(t, "before ", buffer.String())
+	})
+
+	t.Run("Control flow is checked", func(t *testing.T) {
+		cases := map[string]string{
+			"BEGIN Break END.": "Break outside of a loop",
+			"VAR i: INTEGER; PROCEDURE P; BEGIN Continue END; BEGIN FOR i := 1 TO 2 DO P END.": "Continue outside of a loop",
+			"PROCEDURE P; BEGIN Exit(1) END; BEGIN P END.":                                     "Exit with a value outside of a function",
+			"FUNCTION F: INTEGER; BEGIN Exit('a') END; BEGIN END.":                             "Incompatible types: got CHAR expected INTEGER",
+			"BEGIN Halt('a') END.":                            "Incompatible types: got CHAR expected INTEGER",
+			"BEGIN Halt(1, 2) END.":                           "Wrong number of arguments for 'Halt'",
+			"BEGIN IF 1 THEN END.":                            "Incompatible types: got INTEGER expected BOOLEAN",
+			"VAR r: REAL; BEGIN FOR r := 1 TO 2 DO END.":      "Ordinal type expected, got REAL",
+			"VAR i: INTEGER; BEGIN FOR i := 'a' TO 2 DO END.": "Incompatible types: got CHAR expected INTEGER",
+		}
+
+		for source, message := range cases {
+			_, err := output(t, "PROGRAM test; "+source)
+			require.ErrorAs(t, err, &SemanticError{}, source)
+			require.ErrorContains(t, err, message, source)
+		}
+	})
+}
+
+func TestBasicInterpreter_labels(t *testing.T) {
+	t.Run("GOTO jumps forward and backward", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			LABEL 10, 020, done;
+			VAR i, j: INTEGER;
+
+			PROCEDURE Search;
+			LABEL found;
+			VAR k: INTEGER;
+			BEGIN
+				FOR k := 1 TO 10 DO
+					IF k * k > 20 THEN GOTO found;
+				WRITE('none');
+				found: WRITE(k, ' ')
+			END;
+
+			BEGIN
+				i := 0;
+				10: i := i + 1;
+				IF i < 3 THEN GOTO 10;
+				WRITE(i, ' ');
+				GOTO 20;
+				WRITE('skipped');
+				20:
+				FOR i := 1 TO 3 DO
+					FOR j := 1 TO 3 DO
+						IF i * j = 4 THEN GOTO done;
+				done: WRITE(i, j, ' ');
+				Search;
+				WRITELN
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "3 22 5 \n", text)
+	})
+
+	t.Run("GOTO inside a compound statement", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			LABEL again;
+			VAR i: INTEGER;
+			BEGIN
+				i := 0;
+				BEGIN
+					again: i := i + 1;
+					WRITE(i);
+					TRY
+						IF i < 3 THEN GOTO again
+					FINALLY
+						WRITE('.')
+					END
+				END;
+				WRITELN
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "1.2.3.\n", text)
+	})
+
+	t.Run("Labels are checked", func(t *testing.T) {
+		cases := map[string]string{
+			"BEGIN GOTO 10 END.":                                          "Label '10' is not declared",
+			"LABEL 10; BEGIN GOTO 10 END.":                                "Label '10' is declared but not defined",
+			"LABEL 10, 10; BEGIN 10: END.":                                "Duplicate label '10' found",
+			"BEGIN 10: END.":                                              "Label '10' is not declared",
+			"LABEL 10; BEGIN 10: ; 10: END.":                              "Label '10' is defined more than once",
+			"VAR x: INTEGER; BEGIN x: END.":                               "'x' is not a label",
+			"LABEL 10; VAR b: BOOLEAN; BEGIN GOTO 10; IF b THEN 10: END.": "GOTO '10' jumps into a structured statement",
+			"LABEL 10; PROCEDURE P; BEGIN GOTO 10 END; BEGIN 10: P END.":  "GOTO '10' leaves the block the label is declared in",
+		}
+
+		for source, message := range cases {
+			_, err := output(t, "PROGRAM test; "+source)
+			require.ErrorAs(t, err, &SemanticError{}, source)
+			require.ErrorContains(t, err, message, source)
+		}
+	})
+}
+
+func TestBasicInterpreter_classes(t *testing.T) {
+	t.Run("Inheritance, virtual methods and Self", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			TYPE
+				TShape = CLASS
+					Name: STRING;
+					CONSTRUCTOR Create(AName: STRING);
+					FUNCTION Area: REAL; VIRTUAL;
+					PROCEDURE Describe;
+				END;
+				TRect = CLASS(TShape)
+					W, H: REAL;
+					CONSTRUCTOR Create(AW, AH: REAL);
+					FUNCTION Area: REAL; OVERRIDE;
+				END;
+				TSquare = CLASS(TRect)
+					CONSTRUCTOR Create(S: REAL);
+				END;
+			VAR s: TShape; r: TRect;
+
+			CONSTRUCTOR TShape.Create(AName: STRING);
+			BEGIN
+				Name := AName
+			END;
+
+			FUNCTION TShape.Area: REAL;
+			BEGIN
+				Area := 0
+			END;
+
+			PROCEDURE TShape.Describe;
+			BEGIN
+				WRITELN(Name, ' ', ClassName, ' ', Area:0:1)
+			END;
+
+			CONSTRUCTOR TRect.Create(AW, AH: REAL);
+			BEGIN
+				INHERITED Create('rect');
+				W := AW;
+				Self.H := AH
+			END;
+
+			FUNCTION TRect.Area: REAL;
+			BEGIN
+				Area := INHERITED Area + W * H
+			END;
+
+			CONSTRUCTOR TSquare.Create(S: REAL);
+			BEGIN
+				INHERITED Create(S, S);
+				Name := 'square'
+			END;
+
+			BEGIN
+				s := TShape.Create('blob');
+				s.Describe;
+				s.Free;
+				s := TRect.Create(2, 3);
+				s.Describe;
+				WRITELN(s IS TRect, ' ', s IS TSquare, ' ', NIL IS TObject);
+				s := TSquare.Create(4);
+				r := s AS TRect;
+				(s AS TRect).W := 5;
+				WRITELN(r.Area:0:1, ' ', s = r, ' ', s <> NIL);
+				s.Free;
+				s := NIL;
+				s.Free
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "blob TShape 0.0\nrect TRect 6.0\nTRUE FALSE FALSE\n20.0 TRUE TRUE\n", text)
+	})
+
+	t.Run("Objects are shared and destroyed by Free", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			TYPE
+				TNode = CLASS
+					Value: INTEGER;
+					Next: TNode;
+					CONSTRUCTOR Create(V: INTEGER; N: TNode);
+					DESTRUCTOR Destroy; OVERRIDE;
+					FUNCTION Sum: INTEGER;
+				END;
+			VAR list, second: TNode;
+
+			CONSTRUCTOR TNode.Create(V: INTEGER; N: TNode);
+			BEGIN
+				Value := V;
+				Next := N
+			END;
+
+			DESTRUCTOR TNode.Destroy;
+			BEGIN
+				WRITE('~', Value, ' ');
+				Next.Free;
+				INHERITED Destroy
+			END;
+
+			FUNCTION TNode.Sum: INTEGER;
+			BEGIN
+				IF Next = NIL THEN Sum := Value ELSE Sum := Value + Next.Sum
+			END;
+
+			BEGIN
+				list := TNode.Create(1, TNode.Create(2, TNode.Create(3, NIL)));
+				second := list.Next;
+				second.Value := 10;
+				WRITE(list.Sum, ' ');
+				list.Free;
+				WRITELN;
+				WRITELN(second.Value)
+			END.
+		`)
+		require.Equal(t, "14 ~1 ~10 ~3 \n", text)
+		require.ErrorAs(t, err, &RuntimeError{})
+		require.ErrorContains(t, err, "Access of destroyed object")
+	})
+
+	t.Run("Exception classes and failed casts", func(t *testing.T) {
+		text, err := output(t, `
+			PROGRAM test;
+			TYPE
+				EShape = CLASS(Exception)
+					Code: INTEGER;
+				END;
+			VAR o: TObject; e: EShape;
+			BEGIN
+				TRY
+					e := EShape.Create('custom');
+					e.Code := 7;
+					RAISE e
+				EXCEPT
+					ON E: EShape DO WRITELN(E.Message, ' ', E.Code)
+				END;
+				o := TObject.Create;
+				TRY
+					e := o AS EShape
+				EXCEPT
+					ON E: EInvalidCast DO WRITELN(E.ClassName, ': ', E.Message)
+				END;
+				o := NIL;
+				o.ClassName
+			END.
+		`)
+		require.Equal(t, "custom 7\nEInvalidCast: Invalid type cast from TObject to EShape\n", text)
+		require.ErrorAs(t, err, &RuntimeError{})
+		require.ErrorContains(t, err, "Access of NIL object")
+	})
+
+	t.Run("Classes are checked", func(t *testing.T) {
+		cases := map[string]string{
+			"TYPE T = CLASS PROCEDURE P; END; BEGIN END.":                                                     "Method 'P' of T is not implemented",
+			"TYPE T = CLASS X: INTEGER; X: REAL; END; BEGIN END.":                                             "Duplicate identifier 'X' found",
+			"TYPE T = CLASS PROCEDURE Free; END; BEGIN END.":                                                  "Duplicate identifier 'Free' found",
+			"TYPE T = CLASS FUNCTION F: INTEGER; OVERRIDE; END; BEGIN END.":                                   "There is no virtual method 'F' to override",
+			"TYPE T = CLASS CONSTRUCTOR C; VIRTUAL; END; BEGIN END.":                                          "Constructor 'C' cannot be virtual",
+			"TYPE N = INTEGER; T = CLASS(N) END; BEGIN END.":                                                  "Class type expected, got INTEGER",
+			"TYPE T = CLASS PROCEDURE P; END; PROCEDURE T.P(X: INTEGER); BEGIN END; BEGIN END.":               "Implementation of 'P' does not match its declaration in T",
+			"TYPE T = CLASS END; PROCEDURE T.P; BEGIN END; BEGIN END.":                                        "Method 'P' is not declared by T",
+			"VAR x: CLASS END; BEGIN END.":                                                                    "Class types have to be declared in a TYPE section",
+			"VAR x: INTEGER; BEGIN WRITELN(x IS TObject) END.":                                                "Operator IS is not defined for INTEGER and TObject",
+			"VAR x: TObject; BEGIN x := x AS x END.":                                                          "Class type expected, got x",
+			"BEGIN INHERITED Create END.":                                                                     "INHERITED outside of a method",
+			"TYPE T = CLASS PROCEDURE P; END; PROCEDURE T.P; BEGIN INHERITED P END; BEGIN END.":               "Unknown method 'P'",
+			"VAR x: TObject; BEGIN x := TObject.Make END.":                                                    "Unknown method 'Make'",
+			"VAR x: TObject; BEGIN x := TObject.Free END.":                                                    "'Free' is not a constructor",
+			"VAR x: TObject; BEGIN x.Create END.":                                                             "Constructor 'Create' has to be called on a class",
+			"VAR x: TObject; BEGIN WRITELN(x.Size) END.":                                                      "Unknown field 'Size' of TObject",
+			"TYPE T = CLASS PROCEDURE P(o: T); END; PROCEDURE T.P(o: T); BEGIN END; BEGIN T.Create.P(1) END.": "Incompatible types: got INTEGER expected T",
+			"TYPE A = CLASS END; B = CLASS END; VAR x: A; BEGIN x := B.Create END.":                           "Incompatible types: got B expected A",
+			"TYPE A = CLASS END; B = CLASS(A) END; VAR x: A; y: B; BEGIN x := B.Create; y := x END.":          "Incompatible types: got A expected B",
+		}
+
+		for source, message := range cases {
+			_, err := output(t, "PROGRAM test; "+source)
+			require.ErrorAs(t, err, &SemanticError{}, source)
+			require.ErrorContains(t, err, message, source)
+		}
+	})
+}
+
+// outputWithUnits interprets text, which uses the units whose sources are
+// given by file name, and returns what it writes
+func outputWithUnits(t *testing.T, units map[string]string, text string) (string, error) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, source := range units {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(source), 0644))
+	}
+
+	var buffer bytes.Buffer
+	basicInterpreter, err := NewInterpreter(lexer.NewLexer(text), strings.NewReader(""), &buffer)
+	require.NoError(t, err)
+	search := NewUnits(dir)
+	basicInterpreter.Analyzer.(*SemanticAnalyzer).Units = search
+	basicInterpreter.Evaluator.(*EvaluatorVisitor).Units = search
+	_, err = basicInterpreter.Interpret()
+	return buffer.String(), err
+}
+
+func TestBasicInterpreter_units(t *testing.T) {
+	counter := `
+		UNIT Counter;
+		INTERFACE
+		VAR Count: INTEGER;
+		PROCEDURE Tick;
+		IMPLEMENTATION
+		VAR ticks: INTEGER;
+		PROCEDURE Tick;
+		BEGIN
+			ticks := ticks + 1;
+			Count := ticks
+		END;
+		INITIALIZATION
+			ticks := 0;
+			Count := 0;
+			WRITELN('counter init')
+		FINALIZATION
+			WRITELN('counter final ', ticks)
+		END.
+	`
+
+	t.Run("Interface declarations are exported", func(t *testing.T) {
+		text, err := outputWithUnits(t, map[string]string{
+			"counter.pas": counter,
+			"Shapes.pas": `
+				UNIT Shapes;
+				INTERFACE
+				USES Counter;
+				CONST Sides = 4;
+				TYPE
+					TColor = (Red, Green);
+					TSquare = CLASS
+						Side: INTEGER;
+						CONSTRUCTOR Create(s: INTEGER);
+						FUNCTION Area: INTEGER;
+					END;
+				FUNCTION Perimeter(s: TSquare): INTEGER;
+				IMPLEMENTATION
+				CONSTRUCTOR TSquare.Create(s: INTEGER);
+				BEGIN
+					Side := s;
+					Tick
+				END;
+				FUNCTION TSquare.Area: INTEGER;
+				BEGIN
+					Area := Side * Side
+				END;
+				FUNCTION Perimeter(s: TSquare): INTEGER;
+				BEGIN
+					Perimeter := Sides * s.Side
+				END;
+				BEGIN
+					WRITELN('shapes init')
+				END.
+			`,
+		}, `
+			PROGRAM test;
+			USES Shapes, Counter;
+			VAR s: TSquare; c: TColor;
+			BEGIN
+				s := TSquare.Create(3);
+				c := Green;
+				WRITELN(s.Area, ' ', Perimeter(s), ' ', Ord(c), ' ', Count);
+				Count := 10;
+				Tick;
+				WRITELN(Count)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "counter init\nshapes init\n9 12 1 1\n2\ncounter final 2\n", text)
+	})
+
+	t.Run("Implementation declarations are private", func(t *testing.T) {
+		_, err := outputWithUnits(t, map[string]string{"counter.pas": counter}, `
+			PROGRAM test;
+			USES Counter;
+			BEGIN
+				ticks := 1
+			END.
+		`)
+		var semanticError SemanticError
+		require.ErrorAs(t, err, &semanticError)
+		require.Contains(t, semanticError.Message, "ticks")
+	})
+
+	t.Run("Program declarations hide the ones of units", func(t *testing.T) {
+		text, err := outputWithUnits(t, map[string]string{"counter.pas": counter}, `
+			PROGRAM test;
+			USES Counter;
+			VAR Count: STRING;
+			BEGIN
+				Count := 'mine';
+				Tick;
+				WRITELN(Count)
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "counter init\nmine\ncounter final 1\n", text)
+	})
+
+	t.Run("Units are finalized when the program halts", func(t *testing.T) {
+		text, err := outputWithUnits(t, map[string]string{"counter.pas": counter}, `
+			PROGRAM test;
+			USES Counter;
+			BEGIN
+				Halt(3);
+				WRITELN('unreachable')
+			END.
+		`)
+		require.NoError(t, err)
+		require.Equal(t, "counter init\ncounter final 0\n", text)
+	})
+
+	t.Run("Circular references are rejected", func(t *testing.T) {
+		_, err := outputWithUnits(t, map[string]string{
+			"a.pas": `UNIT A; INTERFACE USES B; IMPLEMENTATION END.`,
+			"b.pas": `UNIT B; INTERFACE USES A; IMPLEMENTATION END.`,
+		}, `PROGRAM test; USES A; BEGIN END.`)
+		var unitError UnitError
+		require.ErrorAs(t, err, &unitError)
+		require.Equal(t, "b.pas", filepath.Base(unitError.Path))
+		require.ErrorContains(t, err, "Circular unit reference to 'A'")
+	})
+
+	t.Run("Implementation sections may use units using theirs", func(t *testing.T) {
+		units := map[string]string{
+			"a.pas": `
+				UNIT A;
+				INTERFACE
+				USES B;
+				FUNCTION Twice(x: TNumber): TNumber;
+				IMPLEMENTATION
+				FUNCTION Twice(x: TNumber): TNumber;
+				BEGIN
+					Twice := x * 2
+				END;
+				INITIALIZATION
+					WRITELN('a init')
+				END.
+			`,
+			"b.pas": `
+				UNIT B;
+				INTERFACE
+				TYPE TNumber = INTEGER;
+				FUNCTION Quadruple(x: TNumber): TNumber;
+				IMPLEMENTATION
+				USES A;
+				FUNCTION Quadruple(x: TNumber): TNumber;
+				BEGIN
+					Quadruple := Twice(Twice(x))
+				END;
+				INITIALIZATION
+					WRITELN('b init ', Twice(1))
+				END.
+			`,
+		}
+		cases := map[string]string{
+			"A, B": "b init 2\na init\n6 12\n",
+			"B, A": "a init\nb init 2\n6 12\n",
+		}
+		for uses, expected := range cases {
+			text, err := outputWithUnits(t, units, "PROGRAM test; USES "+uses+"; BEGIN WRITELN(Twice(3), ' ', Quadruple(3)) END.")
+			require.NoError(t, err, uses)
+			require.Equal(t, expected, text, uses)
+		}
+	})
+
+	t.Run("Units can't be used twice", func(t *testing.T) {
+		_, err := outputWithUnits(t, map[string]string{"counter.pas": counter}, `PROGRAM test; USES Counter, counter; BEGIN END.`)
+		require.ErrorAs(t, err, &SemanticError{})
+		require.ErrorContains(t, err, "Duplicate identifier 'counter' found")
+
+		_, err = outputWithUnits(t, map[string]string{
+			"counter.pas": counter,
+			"u.pas":       `UNIT U; INTERFACE USES Counter; IMPLEMENTATION USES Counter; END.`,
+		}, `PROGRAM test; USES U; BEGIN END.`)
+		var unitError UnitError
+		require.ErrorAs(t, err, &unitError)
+		require.Equal(t, "u.pas", filepath.Base(unitError.Path))
+		require.ErrorContains(t, err, "Duplicate identifier 'Counter' found")
+	})
+
+	t.Run("Missing units are reported at the USES clause", func(t *testing.T) {
+		_, err := outputWithUnits(t, nil, `PROGRAM test; USES Missing; BEGIN END.`)
+		var semanticError SemanticError
+		require.ErrorAs(t, err, &semanticError)
+		require.Equal(t, lexer.Position{Line: 1, Column: 20}, semanticError.Pos)
+	})
+
+	t.Run("Interface routines have to be implemented", func(t *testing.T) {
+		_, err := outputWithUnits(t, map[string]string{
+			"u.pas": `
+				UNIT U;
+				INTERFACE
+				FUNCTION F(x: INTEGER): INTEGER;
+				PROCEDURE P;
+				IMPLEMENTATION
+				FUNCTION F(x: INTEGER): INTEGER;
+				BEGIN
+					F := x
+				END;
+				END.
+			`,
+		}, `PROGRAM test; USES U; BEGIN END.`)
+		require.ErrorContains(t, err, "Routine 'P' of unit U is not implemented")
+
+		_, err = outputWithUnits(t, map[string]string{
+			"u.pas": `
+				UNIT U;
+				INTERFACE
+				FUNCTION F(x: INTEGER): INTEGER;
+				IMPLEMENTATION
+				FUNCTION F(x: REAL): INTEGER;
+				BEGIN
+					F := 1
+				END;
+				END.
+			`,
+		}, `PROGRAM test; USES U; BEGIN END.`)
+		require.ErrorContains(t, err, "Implementation of 'F' does not match its declaration in the interface")
+	})
+
+	t.Run("Runtime errors are attributed to the unit", func(t *testing.T) {
+		_, err := outputWithUnits(t, map[string]string{
+			"u.pas": `
+				UNIT U;
+				INTERFACE
+				FUNCTION Half(x: INTEGER): INTEGER;
+				IMPLEMENTATION
+				FUNCTION Half(x: INTEGER): INTEGER;
+				BEGIN
+					Half := x DIV 0
+				END;
+				END.
+			`,
+		}, `PROGRAM test; USES U; BEGIN WRITELN(Half(1)) END.`)
+		var unitError UnitError
+		require.ErrorAs(t, err, &unitError)
+		require.Equal(t, "u.pas", filepath.Base(unitError.Path))
+		require.ErrorAs(t, err, &RuntimeError{})
+	})
+}
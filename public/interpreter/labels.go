@@ -0,0 +1,194 @@
+package interpreter
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+)
+
+// labelKey returns the label as it is looked up, numeric labels being
+// compared by value and others ignoring case
+func labelKey(label string) string {
+	if n, err := strconv.Atoi(label); err == nil {
+		return strconv.Itoa(n)
+	}
+	return strings.ToUpper(label)
+}
+
+// gotoSignal is returned by GOTO to continue at the statement labeled
+// label, a label key. The statement lists and the labeled statements
+// enclosing the GOTO catch it.
+type gotoSignal struct {
+	label string
+}
+
+func (r gotoSignal) Error() string {
+	return "GOTO " + r.label + " outside of the block of the label"
+}
+
+// labelIndex returns the position of the statement labeled label in
+// nodes, -1 if there is none
+func labelIndex(nodes []ast.Node, label string) int {
+	for i, v := range nodes {
+		if labeled, ok := v.(ast.LabeledStatement); ok && labelKey(labeled.Label) == label {
+			return i
+		}
+	}
+	return -1
+}
+
+// visitLabeledStatement runs the statement again whenever a GOTO inside it
+// jumps to its label
+func (r *EvaluatorVisitor) visitLabeledStatement(node ast.LabeledStatement) (any, error) {
+	for {
+		_, err := r.Visit(node.Statement)
+		if jump, ok := err.(gotoSignal); !ok || jump.label != labelKey(node.Label) {
+			return nil, err
+		}
+	}
+}
+
+func (r *EvaluatorVisitor) visitGotoStatement(node ast.GotoStatement) (any, error) {
+	return nil, gotoSignal{label: labelKey(node.Label)}
+}
+
+// visitLabelDeclaration declares a label of the block being checked
+func (r *SemanticAnalyzer) visitLabelDeclaration(node ast.LabelDeclaration) error {
+	name := labelKey(node.Name)
+	if _, ok := r.CurrentScope.Lookup(name, true); ok {
+		return newSemanticError(node, "Duplicate label '%v' found", node.Name)
+	}
+	r.CurrentScope.Insert(LabelSymbol{Name: name})
+	return nil
+}
+
+// labelChecker collects the labeled statements and the GOTO statements of
+// a block, numbering the statement lists they are found in
+type labelChecker struct {
+	analyzer *SemanticAnalyzer
+	// statement list each label is defined in
+	defined map[string]int
+	gotos   []pendingGoto
+	// statement lists enclosing the statement being checked, outermost
+	// first
+	lists []int
+	count int
+}
+
+// pendingGoto is a GOTO checked once all labels of the block are known
+type pendingGoto struct {
+	node  ast.GotoStatement
+	lists []int
+}
+
+// checkLabels checks that the labels used in the statements of a block
+// are declared by the block and defined once in it, and that no GOTO
+// jumps into a structured statement it is not part of
+func (r *SemanticAnalyzer) checkLabels(node ast.Compound) error {
+	checker := &labelChecker{analyzer: r, defined: map[string]int{}}
+	if err := checker.list(node.Children...); err != nil {
+		return err
+	}
+
+	for _, v := range checker.gotos {
+		name := labelKey(v.node.Label)
+		symbol, _ := r.CurrentScope.Lookup(name, false)
+		if _, isLabel := symbol.(LabelSymbol); !isLabel {
+			return newSemanticError(v.node, "Label '%v' is not declared", v.node.Label)
+		}
+		if _, isLocal := r.CurrentScope.Lookup(name, true); !isLocal {
+			return newSemanticError(v.node, "GOTO '%v' leaves the block the label is declared in", v.node.Label)
+		}
+
+		list, ok := checker.defined[name]
+		if !ok {
+			return newSemanticError(v.node, "Label '%v' is declared but not defined", v.node.Label)
+		}
+		if !contains(v.lists, list) {
+			return newSemanticError(v.node, "GOTO '%v' jumps into a structured statement", v.node.Label)
+		}
+	}
+	return nil
+}
+
+func contains(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// list checks a statement list, a single statement controlled by another
+// one being a list of its own
+func (r *labelChecker) list(statements ...ast.Node) error {
+	r.count++
+	r.lists = append(r.lists, r.count)
+	defer func() {
+		r.lists = r.lists[:len(r.lists)-1]
+	}()
+
+	for _, v := range statements {
+		if err := r.statement(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *labelChecker) statement(node ast.Node) error {
+	switch n := node.(type) {
+	case ast.LabeledStatement:
+		name := labelKey(n.Label)
+		if symbol, ok := r.analyzer.CurrentScope.Lookup(name, true); !ok {
+			return newSemanticError(n, "Label '%v' is not declared", n.Label)
+		} else if _, isLabel := symbol.(LabelSymbol); !isLabel {
+			return newSemanticError(n, "'%v' is not a label", n.Label)
+		}
+		if _, ok := r.defined[name]; ok {
+			return newSemanticError(n, "Label '%v' is defined more than once", n.Label)
+		}
+		r.defined[name] = r.lists[len(r.lists)-1]
+		return r.statement(n.Statement)
+
+	case ast.GotoStatement:
+		r.gotos = append(r.gotos, pendingGoto{node: n, lists: append([]int{}, r.lists...)})
+		return nil
+
+	case ast.Compound:
+		return r.list(n.Children...)
+	case ast.IfStatement:
+		if err := r.list(n.Then); err != nil {
+			return err
+		}
+		if n.Else != nil {
+			return r.list(n.Else)
+		}
+	case ast.WhileStatement:
+		return r.list(n.Body)
+	case ast.RepeatStatement:
+		return r.list(n.Body...)
+	case ast.ForStatement:
+		return r.list(n.Body)
+	case ast.WithStatement:
+		return r.list(n.Body)
+	case ast.TryExcept:
+		if err := r.list(n.Body...); err != nil {
+			return err
+		}
+		for _, v := range n.Handlers {
+			if err := r.list(v.Body); err != nil {
+				return err
+			}
+		}
+		return r.list(n.Default...)
+	case ast.TryFinally:
+		if err := r.list(n.Body...); err != nil {
+			return err
+		}
+		return r.list(n.Finally...)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+)
+
+func realArg(args []any, position int) (float64, error) {
+	value, ok := toReal(args[position])
+	if !ok {
+		return 0, fmt.Errorf("Incompatible type for argument %d: got %v expected REAL", position+1, typeName(args[position]))
+	}
+	return value, nil
+}
+
+// checkedReal reports results that do not fit a REAL
+func checkedReal(value float64) (any, error) {
+	if math.IsInf(value, 0) {
+		return nil, fmt.Errorf("Floating point overflow")
+	}
+	if math.IsNaN(value) {
+		return nil, kindError(InvalidFloatOperation, "Invalid floating point operation")
+	}
+	return value, nil
+}
+
+// realFunction turns f into a routine taking a REAL, INTEGER arguments are
+// converted
+func realFunction(f func(float64) float64) func(args []any) (any, error) {
+	return func(args []any) (any, error) {
+		value, err := realArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return checkedReal(f(value))
+	}
+}
+
+// Abs(x) keeps the type of x
+func builtinAbs(args []any) (any, error) {
+	if value, ok := args[0].(int); ok {
+		if value < 0 {
+			return -value, nil
+		}
+		return value, nil
+	}
+
+	value, err := realArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return math.Abs(value), nil
+}
+
+// Sqr(x) is x * x of the type of x
+func builtinSqr(args []any) (any, error) {
+	if value, ok := args[0].(int); ok {
+		return value * value, nil
+	}
+
+	value, err := realArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return checkedReal(value * value)
+}
+
+func builtinSqrt(args []any) (any, error) {
+	value, err := realArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if value < 0 {
+		return nil, kindError(InvalidFloatOperation, "Invalid floating point operation: Sqrt of negative number %v", FormatValue(args[0]))
+	}
+	return math.Sqrt(value), nil
+}
+
+func builtinLn(args []any) (any, error) {
+	value, err := realArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if value <= 0 {
+		return nil, kindError(InvalidFloatOperation, "Invalid floating point operation: Ln of non-positive number %v", FormatValue(args[0]))
+	}
+	return math.Log(value), nil
+}
+
+// toInteger converts a rounded REAL, failing if it does not fit an INTEGER
+func toInteger(value float64) (any, error) {
+	if math.IsNaN(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+		return nil, kindError(RangeCheckError, "Range check error: %v does not fit an INTEGER", value)
+	}
+	return int(value), nil
+}
+
+// Round(x) rounds halves to even, like Free Pascal does
+func builtinRound(args []any) (any, error) {
+	value, err := realArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return toInteger(math.RoundToEven(value))
+}
+
+func builtinTrunc(args []any) (any, error) {
+	value, err := realArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return toInteger(math.Trunc(value))
+}
+
+func builtinOdd(args []any) (any, error) {
+	value, err := intArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return value%2 != 0, nil
+}
+
+// ordinalValue returns the ordinal number of an INTEGER, CHAR, BOOLEAN or
+// enumeration value
+func ordinalValue(value any) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case enumValue:
+		return v.Ordinal, nil
+	case byte:
+		return int(v), nil
+	case bool:
+		return ordinal(v), nil
+	}
+	return 0, fmt.Errorf("Ordinal expression expected, got %v", typeName(value))
+}
+
+// withOrdinal returns the value of the type of like having the ordinal
+// number n, failing if there is none
+func withOrdinal(like any, n int) (any, error) {
+	switch v := like.(type) {
+	case int:
+		return n, nil
+	case byte:
+		if n < 0 || n > math.MaxUint8 {
+			return nil, kindError(RangeCheckError, "Range check error: %d is not a CHAR", n)
+		}
+		return byte(n), nil
+	case bool:
+		if n < 0 || n > 1 {
+			return nil, kindError(RangeCheckError, "Range check error: %d is not a BOOLEAN", n)
+		}
+		return n == 1, nil
+	case enumValue:
+		if n < 0 || n >= len(v.Type.Values) {
+			return nil, kindError(RangeCheckError, "Range check error: %d is not a %v", n, v.Type)
+		}
+		return enumValue{Type: v.Type, Ordinal: n}, nil
+	}
+	return nil, fmt.Errorf("Ordinal expression expected, got %v", typeName(like))
+}
+
+func builtinSucc(args []any) (any, error) {
+	n, err := ordinalValue(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return withOrdinal(args[0], n+1)
+}
+
+func builtinPred(args []any) (any, error) {
+	n, err := ordinalValue(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return withOrdinal(args[0], n-1)
+}
+
+func builtinOrd(args []any) (any, error) {
+	n, err := ordinalValue(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func builtinChr(args []any) (any, error) {
+	n, err := intArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return withOrdinal(byte(0), n)
+}
@@ -0,0 +1,233 @@
+package interpreter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+)
+
+// Fraction digits of a REAL written in scientific notation without a field
+// width, matching the 22 characters wide default of Pascal.
+const defaultRealDigits = 14
+
+func toReal(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// toString converts STRING and CHAR values
+func toString(value any) (string, bool) {
+	switch v := value.(type) {
+	case byte:
+		return string([]byte{v}), true
+	case string:
+		return v, true
+	}
+	return "", false
+}
+
+func ordinal(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// typeName returns the Pascal name of the type of a runtime value
+func typeName(value any) string {
+	switch v := value.(type) {
+	case int:
+		return "INTEGER"
+	case float64:
+		return "REAL"
+	case byte:
+		return "CHAR"
+	case string:
+		return "STRING"
+	case bool:
+		return "BOOLEAN"
+	case *arrayValue:
+		return v.Type.String()
+	case *recordValue:
+		return v.Type.String()
+	case enumValue:
+		return v.Type.String()
+	case setValue:
+		return setType{Element: v.Element}.String()
+	case pointerValue:
+		if v.Type.Target == "" {
+			return "Pointer"
+		}
+		return v.Type.String()
+	case routineValue:
+		return v.Type.String()
+	case *objectValue:
+		return v.Class.String()
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// formatScientific writes value like Pascal does for REAL values without
+// precision: a sign or a space, one digit, digits fraction digits and a
+// three digit exponent, e.g. " 1.50E+000".
+func formatScientific(value float64, digits int) string {
+	sign := " "
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(value, 'E', digits, 64), "E")
+	exponentSign, exponentDigits := exponent[:1], exponent[1:]
+	for len(exponentDigits) < 3 {
+		exponentDigits = "0" + exponentDigits
+	}
+	return sign + mantissa + "E" + exponentSign + exponentDigits
+}
+
+// formatValue returns the text written for value. A negative width means
+// the width is not given, a negative precision that it is not given.
+func formatValue(value any, width int, precision int) (string, error) {
+	if _, isReal := value.(float64); precision >= 0 && !isReal {
+		return "", fmt.Errorf("Precision is only allowed for REAL values")
+	}
+
+	var text string
+	switch v := value.(type) {
+	case int:
+		text = strconv.Itoa(v)
+	case float64:
+		if precision >= 0 {
+			text = strconv.FormatFloat(v, 'f', precision, 64)
+		} else if width >= 0 {
+			// the space left for the fraction, at least one digit is written
+			text = formatScientific(v, max(1, width-len(" 0.E+000")))
+		} else {
+			text = formatScientific(v, defaultRealDigits)
+		}
+	case byte:
+		text = string([]byte{v})
+	case string:
+		text = v
+	case bool:
+		text = strings.ToUpper(strconv.FormatBool(v))
+	case enumValue:
+		text = v.Type.Values[v.Ordinal]
+	default:
+		return "", fmt.Errorf("Cannot write value of type %v", typeName(value))
+	}
+
+	if len(text) < width {
+		text = strings.Repeat(" ", width-len(text)) + text
+	}
+	return text, nil
+}
+
+// FormatValue returns a runtime value for display, e.g. in the REPL. CHAR
+// and STRING values are quoted like Pascal literals.
+func FormatValue(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case byte:
+		return lexer.QuoteString(string([]byte{v}))
+	case string:
+		return lexer.QuoteString(v)
+	case setValue:
+		return v.String()
+	case pointerValue:
+		return v.String()
+	case routineValue:
+		return v.String()
+	case *objectValue:
+		return v.String()
+	}
+
+	text, err := formatValue(value, -1, -1)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return text
+}
+
+// formatSpecifier evaluates a width or precision expression
+func (r *EvaluatorVisitor) formatSpecifier(node ast.Node) (int, error) {
+	value, err := r.Visit(node)
+	if err != nil {
+		return 0, err
+	}
+
+	specifier, ok := value.(int)
+	if !ok {
+		return 0, newRuntimeError(node, "Incompatible types: got %v expected INTEGER", typeName(value))
+	}
+	return specifier, nil
+}
+
+// evaluateFormatted evaluates an argument that may carry a width and
+// precision
+func (r *EvaluatorVisitor) evaluateFormatted(node ast.Node) (formattedValue, error) {
+	result := formattedValue{width: -1, precision: -1}
+	valueNode := node
+
+	if formatted, ok := node.(ast.FormattedArgument); ok {
+		valueNode = formatted.Value
+
+		var err error
+		if result.width, err = r.formatSpecifier(formatted.Width); err != nil {
+			return result, err
+		}
+		if formatted.Precision != nil {
+			if result.precision, err = r.formatSpecifier(formatted.Precision); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	value, err := r.Visit(valueNode)
+	if err != nil {
+		return result, err
+	}
+	result.value = value
+	return result, nil
+}
+
+func (r *EvaluatorVisitor) formatArgument(node ast.Node) (string, error) {
+	argument, err := r.evaluateFormatted(node)
+	if err != nil {
+		return "", err
+	}
+
+	text, err := formatValue(argument.value, argument.width, argument.precision)
+	if err != nil {
+		return "", runtimeErrorAt(node, err)
+	}
+	return text, nil
+}
+
+// write implements WRITE and WRITELN. Nothing is written if an argument
+// fails to evaluate.
+func (r *EvaluatorVisitor) write(arguments []ast.Node, newline bool) error {
+	var output strings.Builder
+	for _, v := range arguments {
+		text, err := r.formatArgument(v)
+		if err != nil {
+			return err
+		}
+		output.WriteString(text)
+	}
+	if newline {
+		output.WriteString("\n")
+	}
+
+	_, err := fmt.Fprint(r.Output, output.String())
+	return err
+}
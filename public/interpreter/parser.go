@@ -1,6 +1,7 @@
 package interpreter
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
@@ -12,6 +13,7 @@ type Lexer interface {
 	NextToken() (lexer.BasicToken, error)
 	Eat(lexer.TokenType) error
 	GetCurrentToken() *lexer.BasicToken
+	GetComments() []lexer.Comment
 }
 
 type BasicParser struct {
@@ -57,6 +59,12 @@ func (r *BasicParser) factor() (ast.Node, error) {
 			return nil, err
 		}
 		return ast.NewRealNode(*token)
+	} else if token.TokenType == lexer.STRING_LITERAL {
+		err := r.Lexer.Eat(lexer.STRING_LITERAL)
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewStringNode(*token)
 	} else if token.TokenType == lexer.LPAREN {
 		if err := r.Lexer.Eat(lexer.LPAREN); err != nil {
 			return nil, err
@@ -69,22 +77,85 @@ func (r *BasicParser) factor() (ast.Node, error) {
 		if err := r.Lexer.Eat(lexer.RPAREN); err != nil {
 			return nil, err
 		}
-		return result, err
+		return r.selectors(result)
+	} else if token.TokenType == lexer.INHERITED {
+		return r.inheritedCall()
+	} else if token.TokenType == lexer.LBRACKET {
+		return r.setConstructor()
+	} else if token.TokenType == lexer.NIL {
+		if err := r.Lexer.Eat(lexer.NIL); err != nil {
+			return nil, err
+		}
+		return ast.NewNilNode(*token), nil
 	} else if token.TokenType == lexer.ID {
-		return r.variable()
+		node, err := r.variable()
+		if err != nil {
+			return nil, err
+		}
+
+		if r.Lexer.GetCurrentToken().TokenType == lexer.LPAREN {
+			arguments, err := r.arguments()
+			if err != nil {
+				return nil, err
+			}
+			node = ast.NewFunctionCall(arguments, node.GetToken())
+		}
+		return r.selectors(node)
 	}
 
 	return nil, fmt.Errorf("Could not read factor")
 }
 
-// factor((MUL | INTEGER_DIV | FLOAT_DIV) factor)*
+// setConstructor: LBRACKET (setElement (COMMA setElement)*)? RBRACKET
+// setElement: expr (DOTDOT expr)?
+func (r *BasicParser) setConstructor() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.LBRACKET); err != nil {
+		return nil, err
+	}
+
+	var elements []ast.Node
+	for r.Lexer.GetCurrentToken().TokenType != lexer.RBRACKET {
+		if len(elements) > 0 {
+			if err := r.Lexer.Eat(lexer.COMMA); err != nil {
+				return nil, err
+			}
+		}
+
+		element, err := r.Expr()
+		if err != nil {
+			return nil, err
+		}
+		if dotdot := r.Lexer.GetCurrentToken(); dotdot.TokenType == lexer.DOTDOT {
+			if err := r.Lexer.Eat(lexer.DOTDOT); err != nil {
+				return nil, err
+			}
+			high, err := r.Expr()
+			if err != nil {
+				return nil, err
+			}
+			element = ast.NewSubrange(element, high, *dotdot)
+		}
+		elements = append(elements, element)
+	}
+
+	endToken := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.RBRACKET); err != nil {
+		return nil, err
+	}
+	set := ast.NewSetConstructor(elements, *token)
+	set.End = endToken.End
+	return set, nil
+}
+
+// factor((MUL | INTEGER_DIV | FLOAT_DIV | AS) factor)*
 func (r *BasicParser) term() (ast.Node, error) {
 	node, err := r.factor()
 	if err != nil {
 		return nil, err
 	}
 
-	for r.isValidToken(*r.Lexer.GetCurrentToken(), lexer.MUL, lexer.FLOAT_DIV, lexer.INTEGER_DIV) {
+	for r.isValidToken(*r.Lexer.GetCurrentToken(), lexer.MUL, lexer.FLOAT_DIV, lexer.INTEGER_DIV, lexer.AS) {
 		token := r.Lexer.GetCurrentToken()
 
 		if token.TokenType == lexer.MUL {
@@ -102,6 +173,11 @@ func (r *BasicParser) term() (ast.Node, error) {
 			if err != nil {
 				return nil, err
 			}
+		} else if token.TokenType == lexer.AS {
+			err = r.Lexer.Eat(lexer.AS)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		right, err := r.factor()
@@ -124,8 +200,42 @@ func (r *BasicParser) isValidToken(token lexer.BasicToken, types ...lexer.TokenT
 	return false
 }
 
-// term ((PLUS|MINUS) term)*
+// relational operators, binding looser than all others
+var relationalOperators = []lexer.TokenType{
+	lexer.EQUAL,
+	lexer.NOT_EQUAL,
+	lexer.LESS,
+	lexer.LESS_EQUAL,
+	lexer.GREATER,
+	lexer.GREATER_EQUAL,
+	lexer.IN,
+	lexer.IS,
+}
+
+// simpleExpression (relationalOperator simpleExpression)?
 func (r *BasicParser) Expr() (ast.Node, error) {
+	node, err := r.simpleExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	token := r.Lexer.GetCurrentToken()
+	if !r.isValidToken(*token, relationalOperators...) {
+		return node, nil
+	}
+	if err := r.Lexer.Eat(token.TokenType); err != nil {
+		return nil, err
+	}
+
+	right, err := r.simpleExpression()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewBinaryOperation(node, right, *token), nil
+}
+
+// term ((PLUS|MINUS) term)*
+func (r *BasicParser) simpleExpression() (ast.Node, error) {
 	node, err := r.term()
 	if err != nil {
 		return nil, err
@@ -168,19 +278,78 @@ func (r *BasicParser) variable() (ast.Node, error) {
 		return nil, err
 	}
 	
-	r.Lexer.Eat(lexer.ID)
+	if err := r.Lexer.Eat(lexer.ID); err != nil {
+		return nil, err
+	}
 	return node, err
 }
 
-// assignment: variable ASSIGN expr
-func (r *BasicParser) assignment() (ast.Node, error) {
-	left, err := r.variable()
-	if err != nil {
-		return nil, err
+// selectors: (LBRACKET expr (COMMA expr)* RBRACKET | DOT ID arguments? | CARET)*
+func (r *BasicParser) selectors(node ast.Node) (ast.Node, error) {
+	for r.isValidToken(*r.Lexer.GetCurrentToken(), lexer.LBRACKET, lexer.DOT, lexer.CARET) {
+		if token := r.Lexer.GetCurrentToken(); token.TokenType == lexer.CARET {
+			if err := r.Lexer.Eat(lexer.CARET); err != nil {
+				return nil, err
+			}
+			node = ast.NewDereference(node, *token)
+			continue
+		}
+		if r.Lexer.GetCurrentToken().TokenType == lexer.DOT {
+			if err := r.Lexer.Eat(lexer.DOT); err != nil {
+				return nil, err
+			}
+
+			field := r.Lexer.GetCurrentToken()
+			if err := r.Lexer.Eat(lexer.ID); err != nil {
+				return nil, err
+			}
+			if r.Lexer.GetCurrentToken().TokenType == lexer.LPAREN {
+				arguments, err := r.arguments()
+				if err != nil {
+					return nil, err
+				}
+				node = ast.NewMethodCall(node, arguments, *field)
+				continue
+			}
+			node = ast.NewFieldAccess(node, *field)
+			continue
+		}
+
+		token := r.Lexer.GetCurrentToken()
+		if err := r.Lexer.Eat(lexer.LBRACKET); err != nil {
+			return nil, err
+		}
+
+		var indices []ast.Node
+		for {
+			index, err := r.Expr()
+			if err != nil {
+				return nil, err
+			}
+			indices = append(indices, index)
+
+			if r.Lexer.GetCurrentToken().TokenType != lexer.COMMA {
+				break
+			}
+			if err := r.Lexer.Eat(lexer.COMMA); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := r.Lexer.Eat(lexer.RBRACKET); err != nil {
+			return nil, err
+		}
+		node = ast.NewIndex(node, indices, *token)
 	}
+	return node, nil
+}
 
+// assignment: variable selectors ASSIGN expr
+func (r *BasicParser) assignment(left ast.Node) (ast.Node, error) {
 	token := r.Lexer.GetCurrentToken()
-	r.Lexer.Eat(lexer.ASSIGN)
+	if err := r.Lexer.Eat(lexer.ASSIGN); err != nil {
+		return nil, err
+	}
 
 	right, err := r.Expr()
 	if err != nil {
@@ -190,6 +359,105 @@ func (r *BasicParser) assignment() (ast.Node, error) {
 	return node, nil
 }
 
+// argument: expr (COLON expr (COLON expr)?)?
+func (r *BasicParser) argument() (ast.Node, error) {
+	value, err := r.Expr()
+	if err != nil {
+		return nil, err
+	}
+
+	token := r.Lexer.GetCurrentToken()
+	if token.TokenType != lexer.COLON {
+		return value, nil
+	}
+	if err := r.Lexer.Eat(lexer.COLON); err != nil {
+		return nil, err
+	}
+
+	width, err := r.Expr()
+	if err != nil {
+		return nil, err
+	}
+
+	var precision ast.Node
+	if r.Lexer.GetCurrentToken().TokenType == lexer.COLON {
+		if err := r.Lexer.Eat(lexer.COLON); err != nil {
+			return nil, err
+		}
+		precision, err = r.Expr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ast.NewFormattedArgument(value, width, precision, *token), nil
+}
+
+// arguments: LPAREN (argument (COMMA argument)*)? RPAREN
+func (r *BasicParser) arguments() ([]ast.Node, error) {
+	if err := r.Lexer.Eat(lexer.LPAREN); err != nil {
+		return nil, err
+	}
+
+	var arguments []ast.Node
+	if r.Lexer.GetCurrentToken().TokenType != lexer.RPAREN {
+		for {
+			argument, err := r.argument()
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, argument)
+
+			if r.Lexer.GetCurrentToken().TokenType != lexer.COMMA {
+				break
+			}
+			if err := r.Lexer.Eat(lexer.COMMA); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := r.Lexer.Eat(lexer.RPAREN); err != nil {
+		return nil, err
+	}
+	return arguments, nil
+}
+
+// inheritedCall: INHERITED ID arguments?
+func (r *BasicParser) inheritedCall() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.INHERITED); err != nil {
+		return nil, err
+	}
+
+	name := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.ID); err != nil {
+		return nil, err
+	}
+
+	var arguments []ast.Node
+	if r.Lexer.GetCurrentToken().TokenType == lexer.LPAREN {
+		var err error
+		if arguments, err = r.arguments(); err != nil {
+			return nil, err
+		}
+	}
+	return ast.NewInheritedCall(name.TokenValue, arguments, *token), nil
+}
+
+// procedureCall: ID arguments?
+func (r *BasicParser) procedureCall(name ast.Var) (ast.Node, error) {
+	var arguments []ast.Node
+	if r.Lexer.GetCurrentToken().TokenType == lexer.LPAREN {
+		var err error
+		if arguments, err = r.arguments(); err != nil {
+			return nil, err
+		}
+	}
+
+	return ast.NewProcedureCall(arguments, name.GetToken()), nil
+}
+
 // statementList: statement | statement SEMI statementList
 func (r *BasicParser) statementList() ([]ast.Node, error) {
 	node, err := r.statement()
@@ -201,20 +469,14 @@ func (r *BasicParser) statementList() ([]ast.Node, error) {
 	results = append(results, node)
 
 	for r.Lexer.GetCurrentToken().TokenType == lexer.SEMICOLON {
-		r.Lexer.Eat(lexer.SEMICOLON)
-		statement, err := r.statement()
-		if err != nil {
+		if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
 			return nil, err
 		}
-		results = append(results, statement)
-	}
-
-	if r.Lexer.GetCurrentToken().TokenType == lexer.ID {
-		node, err := r.variable()
+		statement, err := r.statement()
 		if err != nil {
 			return nil, err
 		}
-		results = append(results, node)
+		results = append(results, statement)
 	}
 
 	return results, nil
@@ -222,20 +484,29 @@ func (r *BasicParser) statementList() ([]ast.Node, error) {
 
 // compound: BEGIN statementList END
 func (r *BasicParser) compound() (ast.Node, error) {
-	r.Lexer.Eat(lexer.BEGIN)
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.BEGIN); err != nil {
+		return nil, err
+	}
+
 	nodes, err := r.statementList()
 	if err != nil {
 		return nil, err
 	}
 
-	r.Lexer.Eat(lexer.END)
+	endToken := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.END); err != nil {
+		return nil, err
+	}
 
-	token := r.Lexer.GetCurrentToken()
 	compound := ast.NewCompound(nodes, *token)
+	compound.End = endToken.End
 	return compound, nil
 }
 
-// statement: compound | assignment | empty
+// statement: compound | assignment | procedureCall | methodCall | inheritedCall | withStatement | tryStatement
+//	| raiseStatement | ifStatement | whileStatement | repeatStatement | forStatement | gotoStatement
+//	| labeledStatement | empty
 func (r *BasicParser) statement() (ast.Node, error) {
 	currentToken := r.Lexer.GetCurrentToken()
 	var result ast.Node
@@ -246,8 +517,99 @@ func (r *BasicParser) statement() (ast.Node, error) {
 			return nil, err
 		}
 		result = node
+	} else if currentToken.TokenType == lexer.WITH {
+		node, err := r.withStatement()
+		if err != nil {
+			return nil, err
+		}
+		result = node
+	} else if currentToken.TokenType == lexer.GOTO {
+		node, err := r.gotoStatement()
+		if err != nil {
+			return nil, err
+		}
+		result = node
+	} else if currentToken.TokenType == lexer.INTEGER {
+		if err := r.Lexer.Eat(lexer.INTEGER); err != nil {
+			return nil, err
+		}
+		node, err := r.labeledStatement(*currentToken)
+		if err != nil {
+			return nil, err
+		}
+		result = node
+	} else if currentToken.TokenType == lexer.IF {
+		node, err := r.ifStatement()
+		if err != nil {
+			return nil, err
+		}
+		result = node
+	} else if currentToken.TokenType == lexer.WHILE {
+		node, err := r.whileStatement()
+		if err != nil {
+			return nil, err
+		}
+		result = node
+	} else if currentToken.TokenType == lexer.REPEAT {
+		node, err := r.repeatStatement()
+		if err != nil {
+			return nil, err
+		}
+		result = node
+	} else if currentToken.TokenType == lexer.FOR {
+		node, err := r.forStatement()
+		if err != nil {
+			return nil, err
+		}
+		result = node
+	} else if currentToken.TokenType == lexer.TRY {
+		node, err := r.tryStatement()
+		if err != nil {
+			return nil, err
+		}
+		result = node
+	} else if currentToken.TokenType == lexer.RAISE {
+		node, err := r.raiseStatement()
+		if err != nil {
+			return nil, err
+		}
+		result = node
+	} else if currentToken.TokenType == lexer.INHERITED {
+		node, err := r.inheritedCall()
+		if err != nil {
+			return nil, err
+		}
+		result = node
+	} else if currentToken.TokenType == lexer.LPAREN {
+		// a parenthesized object, as in (x AS TClass).Method
+		left, err := r.factor()
+		if err != nil {
+			return nil, err
+		}
+		node, err := r.selectorStatement(left)
+		if err != nil {
+			return nil, err
+		}
+		result = node
 	} else if currentToken.TokenType == lexer.ID {
-		node, err := r.assignment()
+		left, err := r.variable()
+		if err != nil {
+			return nil, err
+		}
+
+		var node ast.Node
+		if r.isValidToken(*r.Lexer.GetCurrentToken(), lexer.LBRACKET, lexer.DOT, lexer.CARET) {
+			if left, err = r.selectors(left); err != nil {
+				return nil, err
+			}
+			node, err = r.selectorStatement(left)
+		} else if r.Lexer.GetCurrentToken().TokenType == lexer.ASSIGN {
+			node, err = r.assignment(left)
+		} else if r.Lexer.GetCurrentToken().TokenType == lexer.COLON {
+			node, err = r.labeledStatement(*currentToken)
+		} else {
+			node, err = r.procedureCall(left.(ast.Var))
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -260,92 +622,1196 @@ func (r *BasicParser) statement() (ast.Node, error) {
 	return result, nil
 }
 
-// program: compound DOT
-func (r *BasicParser) program() (ast.Node, error) {
-	r.Lexer.Eat(lexer.PROGRAM)
-	varNode, err := r.variable()
-	programName := varNode.GetToken().TokenValue
+// selectorStatement: methodCall | assignment, the variable or method the
+// statement starts with being already parsed as left. A method is called
+// without arguments like a field is selected.
+func (r *BasicParser) selectorStatement(left ast.Node) (ast.Node, error) {
+	if r.Lexer.GetCurrentToken().TokenType == lexer.ASSIGN {
+		return r.assignment(left)
+	}
+	switch v := left.(type) {
+	case ast.MethodCall:
+		return v, nil
+	case ast.FieldAccess:
+		return ast.NewMethodCall(v.Value, nil, v.GetToken()), nil
+	}
+	return r.assignment(left)
+}
 
-	err = r.Lexer.Eat(lexer.SEMICOLON)
-	if err != nil {
+// labeledStatement: (INTEGER | ID) COLON statement, the label being
+// already eaten
+func (r *BasicParser) labeledStatement(label lexer.BasicToken) (ast.Node, error) {
+	if err := r.Lexer.Eat(lexer.COLON); err != nil {
 		return nil, err
 	}
-
-	block, err := r.block()
+	statement, err := r.statement()
 	if err != nil {
-		return nil, err	
+		return nil, err
 	}
-
-	program := ast.NewProgram(programName, block.(ast.Block), *r.Lexer.GetCurrentToken())
-	r.Lexer.Eat(lexer.DOT)
-	return program, nil
+	return ast.NewLabeledStatement(statement, label), nil
 }
 
-// INTEGER | REAL
-func (r *BasicParser) typeSpec() (ast.Node, error) {
+// gotoStatement: GOTO (INTEGER | ID)
+func (r *BasicParser) gotoStatement() (ast.Node, error) {
 	token := r.Lexer.GetCurrentToken()
-	if token.TokenType == lexer.INTEGER_DECLARAION {
-		err := r.Lexer.Eat(lexer.INTEGER_DECLARAION)
-		if err != nil {
-			return nil, err
-		}
-		return ast.NewTypeSpec(*token), nil
-	} else if token.TokenType == lexer.REAL_DECLARATION {
-		err := r.Lexer.Eat(lexer.REAL_DECLARATION)
-		if err != nil {
-			return nil, err
-		}
-		return ast.NewTypeSpec(*token), nil
+	if err := r.Lexer.Eat(lexer.GOTO); err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("Unknown type specification %v", token.TokenType)
+	label := r.Lexer.GetCurrentToken()
+	if err := r.eatLabel(); err != nil {
+		return nil, err
+	}
+	return ast.NewGotoStatement(label.Text(), *token), nil
 }
 
+// eatLabel eats a label, which is either a number or an identifier
+func (r *BasicParser) eatLabel() error {
+	if r.Lexer.GetCurrentToken().TokenType == lexer.INTEGER {
+		return r.Lexer.Eat(lexer.INTEGER)
+	}
+	return r.Lexer.Eat(lexer.ID)
+}
 
-func (r *BasicParser) block() (ast.Node, error) {
-	declarationNodes, err := r.declarations()
-	if err != nil {
+// ifStatement: IF expr THEN statement (ELSE statement)?
+func (r *BasicParser) ifStatement() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.IF); err != nil {
 		return nil, err
 	}
 
-	compoundNode, err := r.compound()
+	condition, err := r.Expr()
 	if err != nil {
 		return nil, err
 	}
-
-
-	var castedDeclarations []ast.VarDeclaration
+	if err := r.Lexer.Eat(lexer.THEN); err != nil {
+		return nil, err
+	}
+	then, err := r.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	var otherwise ast.Node
+	if r.Lexer.GetCurrentToken().TokenType == lexer.ELSE {
+		if err := r.Lexer.Eat(lexer.ELSE); err != nil {
+			return nil, err
+		}
+		if otherwise, err = r.statement(); err != nil {
+			return nil, err
+		}
+	}
+	return ast.NewIfStatement(condition, then, otherwise, *token), nil
+}
+
+// whileStatement: WHILE expr DO statement
+func (r *BasicParser) whileStatement() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.WHILE); err != nil {
+		return nil, err
+	}
+
+	condition, err := r.Expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Lexer.Eat(lexer.DO); err != nil {
+		return nil, err
+	}
+	body, err := r.statement()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewWhileStatement(condition, body, *token), nil
+}
+
+// repeatStatement: REPEAT statementList UNTIL expr
+func (r *BasicParser) repeatStatement() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.REPEAT); err != nil {
+		return nil, err
+	}
+
+	body, err := r.statementList()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Lexer.Eat(lexer.UNTIL); err != nil {
+		return nil, err
+	}
+	condition, err := r.Expr()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewRepeatStatement(body, condition, *token), nil
+}
+
+// forStatement: FOR variable ASSIGN expr (TO | DOWNTO) expr DO statement
+func (r *BasicParser) forStatement() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.FOR); err != nil {
+		return nil, err
+	}
+
+	variable, err := r.variable()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Lexer.Eat(lexer.ASSIGN); err != nil {
+		return nil, err
+	}
+	start, err := r.Expr()
+	if err != nil {
+		return nil, err
+	}
+
+	downto := r.Lexer.GetCurrentToken().TokenType == lexer.DOWNTO
+	if downto {
+		err = r.Lexer.Eat(lexer.DOWNTO)
+	} else {
+		err = r.Lexer.Eat(lexer.TO)
+	}
+	if err != nil {
+		return nil, err
+	}
+	stop, err := r.Expr()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.Lexer.Eat(lexer.DO); err != nil {
+		return nil, err
+	}
+	body, err := r.statement()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewForStatement(variable, start, stop, downto, body, *token), nil
+}
+
+// tryStatement: TRY statementList (EXCEPT (exceptionHandlers | statementList) | FINALLY statementList) END
+func (r *BasicParser) tryStatement() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.TRY); err != nil {
+		return nil, err
+	}
+
+	body, err := r.statementList()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Lexer.GetCurrentToken().TokenType == lexer.FINALLY {
+		if err := r.Lexer.Eat(lexer.FINALLY); err != nil {
+			return nil, err
+		}
+		finally, err := r.statementList()
+		if err != nil {
+			return nil, err
+		}
+
+		endToken := r.Lexer.GetCurrentToken()
+		if err := r.Lexer.Eat(lexer.END); err != nil {
+			return nil, err
+		}
+		node := ast.NewTryFinally(body, finally, *token)
+		node.End = endToken.End
+		return node, nil
+	}
+
+	if err := r.Lexer.Eat(lexer.EXCEPT); err != nil {
+		return nil, err
+	}
+
+	var handlers []ast.ExceptionHandler
+	var defaultStatements []ast.Node
+	if r.Lexer.GetCurrentToken().TokenType == lexer.ON {
+		if handlers, err = r.exceptionHandlers(); err != nil {
+			return nil, err
+		}
+		if r.Lexer.GetCurrentToken().TokenType == lexer.ELSE {
+			if err := r.Lexer.Eat(lexer.ELSE); err != nil {
+				return nil, err
+			}
+			if defaultStatements, err = r.statementList(); err != nil {
+				return nil, err
+			}
+		}
+	} else if defaultStatements, err = r.statementList(); err != nil {
+		return nil, err
+	}
+
+	endToken := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.END); err != nil {
+		return nil, err
+	}
+	node := ast.NewTryExcept(body, handlers, defaultStatements, *token)
+	node.End = endToken.End
+	return node, nil
+}
+
+// exceptionHandlers: exceptionHandler (SEMICOLON exceptionHandler)* SEMICOLON?
+func (r *BasicParser) exceptionHandlers() ([]ast.ExceptionHandler, error) {
+	var handlers []ast.ExceptionHandler
+	for r.Lexer.GetCurrentToken().TokenType == lexer.ON {
+		handler, err := r.exceptionHandler()
+		if err != nil {
+			return nil, err
+		}
+		handlers = append(handlers, handler)
+
+		if r.Lexer.GetCurrentToken().TokenType != lexer.SEMICOLON {
+			break
+		}
+		if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
+			return nil, err
+		}
+	}
+	return handlers, nil
+}
+
+// exceptionHandler: ON (ID COLON)? ID DO statement
+func (r *BasicParser) exceptionHandler() (ast.ExceptionHandler, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.ON); err != nil {
+		return ast.ExceptionHandler{}, err
+	}
+
+	var variable ast.Node
+	class := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.ID); err != nil {
+		return ast.ExceptionHandler{}, err
+	}
+	if r.Lexer.GetCurrentToken().TokenType == lexer.COLON {
+		name, err := ast.NewVar(*class)
+		if err != nil {
+			return ast.ExceptionHandler{}, err
+		}
+		variable = name
+
+		if err := r.Lexer.Eat(lexer.COLON); err != nil {
+			return ast.ExceptionHandler{}, err
+		}
+		class = r.Lexer.GetCurrentToken()
+		if err := r.Lexer.Eat(lexer.ID); err != nil {
+			return ast.ExceptionHandler{}, err
+		}
+	}
+
+	if err := r.Lexer.Eat(lexer.DO); err != nil {
+		return ast.ExceptionHandler{}, err
+	}
+	body, err := r.statement()
+	if err != nil {
+		return ast.ExceptionHandler{}, err
+	}
+	return ast.NewExceptionHandler(variable, ast.NewTypeSpec(*class), body, *token), nil
+}
+
+// raiseStatement: RAISE expr?
+func (r *BasicParser) raiseStatement() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.RAISE); err != nil {
+		return nil, err
+	}
+
+	if r.isValidToken(*r.Lexer.GetCurrentToken(), lexer.SEMICOLON, lexer.END, lexer.EXCEPT, lexer.FINALLY, lexer.ELSE, lexer.EOF) {
+		return ast.NewRaiseStatement(nil, *token), nil
+	}
+	value, err := r.Expr()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewRaiseStatement(value, *token), nil
+}
+
+// withStatement: WITH variable selectors (COMMA variable selectors)* DO statement
+func (r *BasicParser) withStatement() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.WITH); err != nil {
+		return nil, err
+	}
+
+	var records []ast.Node
+	for {
+		if r.Lexer.GetCurrentToken().TokenType != lexer.ID {
+			return nil, fmt.Errorf("Variable identifier expected, got %v", r.Lexer.GetCurrentToken().TokenType)
+		}
+		record, err := r.variable()
+		if err != nil {
+			return nil, err
+		}
+		if record, err = r.selectors(record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+
+		if r.Lexer.GetCurrentToken().TokenType != lexer.COMMA {
+			break
+		}
+		if err := r.Lexer.Eat(lexer.COMMA); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.Lexer.Eat(lexer.DO); err != nil {
+		return nil, err
+	}
+	body, err := r.statement()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewWithStatement(records, body, *token), nil
+}
+
+// program: PROGRAM ID SEMICOLON usesClause? block DOT
+func (r *BasicParser) program() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.PROGRAM); err != nil {
+		return nil, err
+	}
+
+	varNode, err := r.variable()
+	if err != nil {
+		return nil, err
+	}
+	programName := varNode.GetToken().TokenValue
+
+	err = r.Lexer.Eat(lexer.SEMICOLON)
+	if err != nil {
+		return nil, err
+	}
+
+	uses, err := r.usesClause()
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := r.block()
+	if err != nil {
+		return nil, err	
+	}
+
+	program := ast.NewProgram(programName, block.(ast.Block), *token)
+	program.Uses = uses
+	if err := r.Lexer.Eat(lexer.DOT); err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+// usesClause: USES ID (COMMA ID)* SEMICOLON | empty
+func (r *BasicParser) usesClause() ([]ast.Var, error) {
+	if r.Lexer.GetCurrentToken().TokenType != lexer.USES {
+		return nil, nil
+	}
+	if err := r.Lexer.Eat(lexer.USES); err != nil {
+		return nil, err
+	}
+
+	var units []ast.Var
+	for {
+		name, err := ast.NewVar(*r.Lexer.GetCurrentToken())
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Lexer.Eat(lexer.ID); err != nil {
+			return nil, err
+		}
+		units = append(units, name)
+
+		if r.Lexer.GetCurrentToken().TokenType != lexer.COMMA {
+			break
+		}
+		if err := r.Lexer.Eat(lexer.COMMA); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
+		return nil, err
+	}
+	return units, nil
+}
+
+// unit: UNIT ID SEMICOLON
+//	INTERFACE usesClause? sections routineHeading*
+//	IMPLEMENTATION usesClause? declarations
+//	(INITIALIZATION statementList (FINALIZATION statementList)? | BEGIN statementList)? END DOT
+func (r *BasicParser) unit() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.UNIT); err != nil {
+		return nil, err
+	}
+
+	nameToken := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.ID); err != nil {
+		return nil, err
+	}
+	if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
+		return nil, err
+	}
+
+	interfaceToken := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.INTERFACE); err != nil {
+		return nil, err
+	}
+	interfaceUses, err := r.usesClause()
+	if err != nil {
+		return nil, err
+	}
+	interfaceNodes, err := r.sections()
+	if err != nil {
+		return nil, err
+	}
+	var headings []ast.RoutineHeading
+	for r.isValidToken(*r.Lexer.GetCurrentToken(), lexer.PROCEDURE, lexer.FUNCTION) {
+		heading, err := r.routineHeading()
+		if err != nil {
+			return nil, err
+		}
+		headings = append(headings, heading)
+	}
+
+	implementationToken := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.IMPLEMENTATION); err != nil {
+		return nil, err
+	}
+	implementationUses, err := r.usesClause()
+	if err != nil {
+		return nil, err
+	}
+	implementationNodes, err := r.declarations()
+	if err != nil {
+		return nil, err
+	}
+
+	initialization := ast.NewCompound(nil, *r.Lexer.GetCurrentToken())
+	var finalization []ast.Node
+	if r.isValidToken(*r.Lexer.GetCurrentToken(), lexer.INITIALIZATION, lexer.BEGIN) {
+		startsFinalization := r.Lexer.GetCurrentToken().TokenType == lexer.INITIALIZATION
+		if err := r.Lexer.Eat(r.Lexer.GetCurrentToken().TokenType); err != nil {
+			return nil, err
+		}
+		if initialization.Children, err = r.statementList(); err != nil {
+			return nil, err
+		}
+
+		if startsFinalization && r.Lexer.GetCurrentToken().TokenType == lexer.FINALIZATION {
+			if err := r.Lexer.Eat(lexer.FINALIZATION); err != nil {
+				return nil, err
+			}
+			if finalization, err = r.statementList(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	endToken := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.END); err != nil {
+		return nil, err
+	}
+	initialization.End = endToken.End
+	if err := r.Lexer.Eat(lexer.DOT); err != nil {
+		return nil, err
+	}
+
+	interfaceBlock, err := r.newBlock(interfaceNodes, ast.NewCompound(nil, *interfaceToken), *interfaceToken)
+	if err != nil {
+		return nil, err
+	}
+	implementation, err := r.newBlock(implementationNodes, initialization, *implementationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	unit := ast.NewUnit(nameToken.TokenValue, interfaceBlock, headings, implementation, *token)
+	unit.InterfaceUses = interfaceUses
+	unit.ImplementationUses = implementationUses
+	unit.Finalization = finalization
+	unit.End = endToken.End
+	return unit, nil
+}
+
+// routineHeading: (PROCEDURE | FUNCTION) ID signature SEMICOLON
+func (r *BasicParser) routineHeading() (ast.RoutineHeading, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(token.TokenType); err != nil {
+		return ast.RoutineHeading{}, err
+	}
+
+	name, err := ast.NewVar(*r.Lexer.GetCurrentToken())
+	if err != nil {
+		return ast.RoutineHeading{}, err
+	}
+	if err := r.Lexer.Eat(lexer.ID); err != nil {
+		return ast.RoutineHeading{}, err
+	}
+
+	params, result, err := r.signature(token.TokenType == lexer.FUNCTION)
+	if err != nil {
+		return ast.RoutineHeading{}, err
+	}
+	if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
+		return ast.RoutineHeading{}, err
+	}
+	return ast.NewRoutineHeading(name, params, result, *token), nil
+}
+
+// typeSpec: INTEGER | REAL | CHAR | STRING | BOOLEAN | ID | arrayType | recordType | classType | setType | pointerType
+//	| proceduralType | enumType | subrange
+func (r *BasicParser) typeSpec() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if token.TokenType == lexer.CLASS {
+		return r.classType()
+	}
+	if r.isValidToken(*token, lexer.PROCEDURE, lexer.FUNCTION) {
+		return r.proceduralType()
+	}
+	if token.TokenType == lexer.CARET {
+		return r.pointerType()
+	}
+	if token.TokenType == lexer.ARRAY {
+		return r.arrayType()
+	}
+	if token.TokenType == lexer.SET {
+		return r.setType()
+	}
+	if token.TokenType == lexer.RECORD {
+		return r.recordType()
+	}
+	if token.TokenType == lexer.LPAREN {
+		return r.enumType()
+	}
+	if r.isValidToken(*token, lexer.INTEGER_DECLARAION, lexer.REAL_DECLARATION, lexer.CHAR_DECLARATION, lexer.STRING_DECLARATION, lexer.BOOLEAN_DECLARATION) {
+		err := r.Lexer.Eat(token.TokenType)
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewTypeSpec(*token), nil
+	}
+	if r.isValidToken(*token, lexer.ID, lexer.INTEGER, lexer.REAL, lexer.STRING_LITERAL, lexer.PLUS, lexer.MINUS) {
+		return r.subrangeOrTypeName()
+	}
+
+	return nil, fmt.Errorf("Unknown type specification %v", token.TokenType)
+}
+
+// subrangeOrTypeName: subrange | ID
+//
+// Both may start with an identifier, e.g. Red..Blue and Color, so the
+// lower bound is parsed first and taken as the type name if no DOTDOT
+// follows it.
+func (r *BasicParser) subrangeOrTypeName() (ast.Node, error) {
+	low, err := r.simpleExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	token := r.Lexer.GetCurrentToken()
+	if token.TokenType != lexer.DOTDOT {
+		name, ok := low.(ast.Var)
+		if !ok {
+			return nil, fmt.Errorf("Unknown type specification %v", token.TokenType)
+		}
+		return ast.NewTypeSpec(name.GetToken()), nil
+	}
+	if err := r.Lexer.Eat(lexer.DOTDOT); err != nil {
+		return nil, err
+	}
+
+	high, err := r.simpleExpression()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewSubrange(low, high, *token), nil
+}
+
+// pointerType: CARET (INTEGER | REAL | CHAR | STRING | BOOLEAN | ID)
+func (r *BasicParser) pointerType() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.CARET); err != nil {
+		return nil, err
+	}
+
+	target := r.Lexer.GetCurrentToken()
+	if !r.isValidToken(*target, lexer.ID, lexer.INTEGER_DECLARAION, lexer.REAL_DECLARATION, lexer.CHAR_DECLARATION, lexer.STRING_DECLARATION, lexer.BOOLEAN_DECLARATION) {
+		return nil, fmt.Errorf("Type identifier expected, got %v", target.TokenType)
+	}
+	if err := r.Lexer.Eat(target.TokenType); err != nil {
+		return nil, err
+	}
+	return ast.NewPointerType(ast.NewTypeSpec(*target), *token), nil
+}
+
+// proceduralType: PROCEDURE formalParams? | FUNCTION formalParams? COLON typeSpec
+func (r *BasicParser) proceduralType() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(token.TokenType); err != nil {
+		return nil, err
+	}
+
+	params, result, err := r.signature(token.TokenType == lexer.FUNCTION)
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewProceduralType(params, result, *token), nil
+}
+
+// signature: formalParams? (COLON typeSpec)?, the result type being
+// required for functions only
+func (r *BasicParser) signature(function bool) ([]ast.Param, ast.Node, error) {
+	var params []ast.Param
+	if r.Lexer.GetCurrentToken().TokenType == lexer.LPAREN {
+		var err error
+		if params, err = r.formalParams(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if !function {
+		return params, nil, nil
+	}
+
+	if err := r.Lexer.Eat(lexer.COLON); err != nil {
+		return nil, nil, err
+	}
+	result, err := r.typeSpec()
+	if err != nil {
+		return nil, nil, err
+	}
+	return params, result, nil
+}
+
+// formalParams: LPAREN formalParam (SEMICOLON formalParam)* RPAREN
+func (r *BasicParser) formalParams() ([]ast.Param, error) {
+	if err := r.Lexer.Eat(lexer.LPAREN); err != nil {
+		return nil, err
+	}
+
+	var params []ast.Param
+	for {
+		group, err := r.formalParam()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, group...)
+
+		if r.Lexer.GetCurrentToken().TokenType != lexer.SEMICOLON {
+			break
+		}
+		if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.Lexer.Eat(lexer.RPAREN); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// formalParam: VAR? ID (COMMA ID)* COLON typeSpec | (PROCEDURE | FUNCTION) ID signature
+func (r *BasicParser) formalParam() ([]ast.Param, error) {
+	token := r.Lexer.GetCurrentToken()
+	if r.isValidToken(*token, lexer.PROCEDURE, lexer.FUNCTION) {
+		if err := r.Lexer.Eat(token.TokenType); err != nil {
+			return nil, err
+		}
+		name, err := ast.NewVar(*r.Lexer.GetCurrentToken())
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Lexer.Eat(lexer.ID); err != nil {
+			return nil, err
+		}
+
+		params, result, err := r.signature(token.TokenType == lexer.FUNCTION)
+		if err != nil {
+			return nil, err
+		}
+		return []ast.Param{ast.NewParam(name, ast.NewProceduralType(params, result, *token), *token)}, nil
+	}
+
+	if token.TokenType == lexer.VAR {
+		if err := r.Lexer.Eat(lexer.VAR); err != nil {
+			return nil, err
+		}
+	}
+
+	declarations, err := r.varDeclaration()
+	if err != nil {
+		return nil, err
+	}
+
+	var params []ast.Param
+	for _, v := range declarations {
+		declaration := v.(ast.VarDeclaration)
+		paramToken := declaration.GetToken()
+		if token.TokenType == lexer.VAR {
+			paramToken = *token
+		}
+		params = append(params, ast.NewParam(declaration.Variable, declaration.TypeSpec, paramToken))
+	}
+	return params, nil
+}
+
+// routineDeclaration: (PROCEDURE | FUNCTION | CONSTRUCTOR | DESTRUCTOR) (ID DOT)? ID signature SEMICOLON block SEMICOLON,
+// the implementation of a method naming its class first
+func (r *BasicParser) routineDeclaration() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(token.TokenType); err != nil {
+		return nil, err
+	}
+
+	nameToken := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.ID); err != nil {
+		return nil, err
+	}
+	var class ast.Node
+	if r.Lexer.GetCurrentToken().TokenType == lexer.DOT {
+		if err := r.Lexer.Eat(lexer.DOT); err != nil {
+			return nil, err
+		}
+		class = ast.NewTypeSpec(*nameToken)
+		nameToken = r.Lexer.GetCurrentToken()
+		if err := r.Lexer.Eat(lexer.ID); err != nil {
+			return nil, err
+		}
+	}
+	name, err := ast.NewVar(*nameToken)
+	if err != nil {
+		return nil, err
+	}
+
+	params, result, err := r.signature(token.TokenType == lexer.FUNCTION)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
+		return nil, err
+	}
+
+	block, err := r.block()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
+		return nil, err
+	}
+	routine := ast.NewRoutineDeclaration(name, params, result, block.(ast.Block), *token)
+	routine.Class = class
+	return routine, nil
+}
+
+// keywords starting a method heading or implementation
+var methodKeywords = []lexer.TokenType{
+	lexer.PROCEDURE,
+	lexer.FUNCTION,
+	lexer.CONSTRUCTOR,
+	lexer.DESTRUCTOR,
+}
+
+// classType: CLASS (LPAREN ID RPAREN)? (varDeclaration SEMICOLON)* methodHeading* END
+func (r *BasicParser) classType() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.CLASS); err != nil {
+		return nil, err
+	}
+
+	var parent ast.Node
+	if r.Lexer.GetCurrentToken().TokenType == lexer.LPAREN {
+		if err := r.Lexer.Eat(lexer.LPAREN); err != nil {
+			return nil, err
+		}
+		name := r.Lexer.GetCurrentToken()
+		if err := r.Lexer.Eat(lexer.ID); err != nil {
+			return nil, err
+		}
+		if err := r.Lexer.Eat(lexer.RPAREN); err != nil {
+			return nil, err
+		}
+		parent = ast.NewTypeSpec(*name)
+	}
+
+	var fields []ast.VarDeclaration
+	for r.Lexer.GetCurrentToken().TokenType == lexer.ID {
+		declarations, err := r.varDeclaration()
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range declarations {
+			fields = append(fields, v.(ast.VarDeclaration))
+		}
+		if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
+			return nil, err
+		}
+	}
+
+	var methods []ast.MethodHeading
+	for r.isValidToken(*r.Lexer.GetCurrentToken(), methodKeywords...) {
+		method, err := r.methodHeading()
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+
+	endToken := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.END); err != nil {
+		return nil, err
+	}
+
+	class := ast.NewClassType(parent, fields, methods, *token)
+	class.End = endToken.End
+	return class, nil
+}
+
+// methodHeading: (PROCEDURE | FUNCTION | CONSTRUCTOR | DESTRUCTOR) ID signature SEMICOLON ((VIRTUAL | OVERRIDE) SEMICOLON)?
+func (r *BasicParser) methodHeading() (ast.MethodHeading, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(token.TokenType); err != nil {
+		return ast.MethodHeading{}, err
+	}
+
+	name, err := ast.NewVar(*r.Lexer.GetCurrentToken())
+	if err != nil {
+		return ast.MethodHeading{}, err
+	}
+	if err := r.Lexer.Eat(lexer.ID); err != nil {
+		return ast.MethodHeading{}, err
+	}
+
+	params, result, err := r.signature(token.TokenType == lexer.FUNCTION)
+	if err != nil {
+		return ast.MethodHeading{}, err
+	}
+	if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
+		return ast.MethodHeading{}, err
+	}
+
+	method := ast.NewMethodHeading(name, params, result, *token)
+	if directive := r.Lexer.GetCurrentToken(); r.isValidToken(*directive, lexer.VIRTUAL, lexer.OVERRIDE) {
+		if err := r.Lexer.Eat(directive.TokenType); err != nil {
+			return ast.MethodHeading{}, err
+		}
+		method.Virtual = directive.TokenType == lexer.VIRTUAL
+		method.Override = directive.TokenType == lexer.OVERRIDE
+		if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
+			return ast.MethodHeading{}, err
+		}
+	}
+	return method, nil
+}
+
+// setType: SET OF typeSpec
+func (r *BasicParser) setType() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.SET); err != nil {
+		return nil, err
+	}
+	if err := r.Lexer.Eat(lexer.OF); err != nil {
+		return nil, err
+	}
+
+	element, err := r.typeSpec()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewSetType(element, *token), nil
+}
+
+// enumType: LPAREN ID (COMMA ID)* RPAREN
+func (r *BasicParser) enumType() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.LPAREN); err != nil {
+		return nil, err
+	}
+
+	var values []ast.Var
+	for {
+		value, err := ast.NewVar(*r.Lexer.GetCurrentToken())
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Lexer.Eat(lexer.ID); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		if r.Lexer.GetCurrentToken().TokenType != lexer.COMMA {
+			break
+		}
+		if err := r.Lexer.Eat(lexer.COMMA); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.Lexer.Eat(lexer.RPAREN); err != nil {
+		return nil, err
+	}
+	return ast.NewEnumType(values, *token), nil
+}
+
+// arrayType: ARRAY LBRACKET typeSpec (COMMA typeSpec)* RBRACKET OF typeSpec
+func (r *BasicParser) arrayType() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.ARRAY); err != nil {
+		return nil, err
+	}
+	if err := r.Lexer.Eat(lexer.LBRACKET); err != nil {
+		return nil, err
+	}
+
+	var ranges []ast.Node
+	for {
+		indexType, err := r.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, indexType)
+
+		if r.Lexer.GetCurrentToken().TokenType != lexer.COMMA {
+			break
+		}
+		if err := r.Lexer.Eat(lexer.COMMA); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.Lexer.Eat(lexer.RBRACKET); err != nil {
+		return nil, err
+	}
+	if err := r.Lexer.Eat(lexer.OF); err != nil {
+		return nil, err
+	}
+
+	element, err := r.typeSpec()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewArrayType(ranges, element, *token), nil
+}
+
+// recordType: RECORD (varDeclaration (SEMICOLON varDeclaration)* SEMICOLON?)? END
+func (r *BasicParser) recordType() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.RECORD); err != nil {
+		return nil, err
+	}
+
+	var fields []ast.VarDeclaration
+	for r.Lexer.GetCurrentToken().TokenType == lexer.ID {
+		declarations, err := r.varDeclaration()
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range declarations {
+			fields = append(fields, v.(ast.VarDeclaration))
+		}
+
+		if r.Lexer.GetCurrentToken().TokenType != lexer.SEMICOLON {
+			break
+		}
+		if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
+			return nil, err
+		}
+	}
+
+	endToken := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.END); err != nil {
+		return nil, err
+	}
+
+	record := ast.NewRecordType(fields, *token)
+	record.End = endToken.End
+	return record, nil
+}
+
+func (r *BasicParser) block() (ast.Node, error) {
+	token := r.Lexer.GetCurrentToken()
+	declarationNodes, err := r.declarations()
+	if err != nil {
+		return nil, err
+	}
+
+	compoundNode, err := r.compound()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.newBlock(declarationNodes, compoundNode.(ast.Compound), *token)
+}
+
+// newBlock returns the block declaring declarationNodes and running compound
+func (r *BasicParser) newBlock(declarationNodes []ast.Node, compound ast.Compound, token lexer.BasicToken) (ast.Block, error) {
+	var castedLabels []ast.LabelDeclaration
+	var declarations []ast.Node
 	for _, v := range declarationNodes {
-		casted, ok := v.(ast.VarDeclaration)
-		if !ok {
-			return nil, fmt.Errorf("Cannot cast %v to variable declaration", v)
+		switch casted := v.(type) {
+		case ast.LabelDeclaration:
+			castedLabels = append(castedLabels, casted)
+		case ast.ConstDeclaration, ast.TypeDeclaration, ast.VarDeclaration, ast.RoutineDeclaration:
+			declarations = append(declarations, casted)
+		default:
+			return ast.Block{}, fmt.Errorf("Cannot cast %v to declaration", v)
 		}
-		castedDeclarations = append(castedDeclarations, casted)
 	}
 
-	node := ast.NewBlock(castedDeclarations, compoundNode.(ast.Compound), *r.Lexer.GetCurrentToken())
+	node := ast.NewBlock(declarations, compound, token)
+	node.Labels = castedLabels
 	return node, nil
 }
 
-//declarations: VAR (varDeclaration SEMICOLON)+ | empty
+//declarations: (LABEL label (COMMA label)* SEMICOLON)? (section | routineDeclaration)* | empty
 func (r *BasicParser) declarations() ([]ast.Node, error) {
 	var declarations []ast.Node
 
-	if r.Lexer.GetCurrentToken().TokenType == lexer.VAR {
-		r.Lexer.Eat(lexer.VAR)
-		for r.Lexer.GetCurrentToken().TokenType == lexer.ID {
+	if r.Lexer.GetCurrentToken().TokenType == lexer.LABEL {
+		if err := r.Lexer.Eat(lexer.LABEL); err != nil {
+			return nil, err
+		}
+		for {
+			label := r.Lexer.GetCurrentToken()
+			if err := r.eatLabel(); err != nil {
+				return nil, err
+			}
+			declarations = append(declarations, ast.NewLabelDeclaration(*label))
+
+			if r.Lexer.GetCurrentToken().TokenType != lexer.COMMA {
+				break
+			}
+			if err := r.Lexer.Eat(lexer.COMMA); err != nil {
+				return nil, err
+			}
+		}
+		if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		var nodes []ast.Node
+		var err error
+		switch token := r.Lexer.GetCurrentToken(); {
+		case r.isValidToken(*token, lexer.CONST, lexer.TYPE, lexer.VAR):
+			nodes, err = r.section()
+		case r.isValidToken(*token, methodKeywords...):
+			var declaration ast.Node
+			declaration, err = r.routineDeclaration()
+			nodes = []ast.Node{declaration}
+		default:
+			return declarations, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		declarations = append(declarations, nodes...)
+	}
+}
+
+// sections: section*
+func (r *BasicParser) sections() ([]ast.Node, error) {
+	var declarations []ast.Node
+	for r.isValidToken(*r.Lexer.GetCurrentToken(), lexer.CONST, lexer.TYPE, lexer.VAR) {
+		section, err := r.section()
+		if err != nil {
+			return nil, err
+		}
+		declarations = append(declarations, section...)
+	}
+	return declarations, nil
+}
+
+// section: CONST (constDeclaration SEMICOLON)+ | TYPE (typeDeclaration SEMICOLON)+ | VAR (varDeclaration SEMICOLON)+
+func (r *BasicParser) section() ([]ast.Node, error) {
+	keyword := r.Lexer.GetCurrentToken().TokenType
+	if err := r.Lexer.Eat(keyword); err != nil {
+		return nil, err
+	}
+
+	var declarations []ast.Node
+	for r.Lexer.GetCurrentToken().TokenType == lexer.ID {
+		switch keyword {
+		case lexer.CONST:
+			declaration, err := r.constDeclaration()
+			if err != nil {
+				return nil, err
+			}
+			declarations = append(declarations, declaration)
+		case lexer.TYPE:
+			declaration, err := r.typeDeclaration()
+			if err != nil {
+				return nil, err
+			}
+			declarations = append(declarations, declaration)
+		default:
 			declaration, err := r.varDeclaration()
 			if err != nil {
 				return nil, err
 			}
 			declarations = append(declarations, declaration...)
-			r.Lexer.Eat(lexer.SEMICOLON)
+		}
+		if err := r.Lexer.Eat(lexer.SEMICOLON); err != nil {
+			return nil, err
 		}
 	}
-
 	return declarations, nil
 }
 
+// constDeclaration: ID (COLON typeSpec)? EQUAL expr
+func (r *BasicParser) constDeclaration() (ast.Node, error) {
+	name, err := ast.NewVar(*r.Lexer.GetCurrentToken())
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Lexer.Eat(lexer.ID); err != nil {
+		return nil, err
+	}
+
+	var typeNode ast.Node
+	if r.Lexer.GetCurrentToken().TokenType == lexer.COLON {
+		if err := r.Lexer.Eat(lexer.COLON); err != nil {
+			return nil, err
+		}
+		if typeNode, err = r.typeSpec(); err != nil {
+			return nil, err
+		}
+	}
+
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.EQUAL); err != nil {
+		return nil, err
+	}
+
+	value, err := r.Expr()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewConstDeclaration(name, typeNode, value, *token), nil
+}
+
+// typeDeclaration: ID EQUAL typeSpec
+func (r *BasicParser) typeDeclaration() (ast.Node, error) {
+	name, err := ast.NewVar(*r.Lexer.GetCurrentToken())
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Lexer.Eat(lexer.ID); err != nil {
+		return nil, err
+	}
+
+	token := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.EQUAL); err != nil {
+		return nil, err
+	}
+
+	typeNode, err := r.typeSpec()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewTypeDeclaration(name, typeNode, *token), nil
+}
+
 // varDeclaration: ID (COMMA ID)* COLON typeSpec
 func (r *BasicParser) varDeclaration() ([]ast.Node, error) {
 	var varNodes []ast.Var
@@ -361,16 +1827,24 @@ func (r *BasicParser) varDeclaration() ([]ast.Node, error) {
 
 	varNodes = append(varNodes, firstVar)
 	for r.Lexer.GetCurrentToken().TokenType == lexer.COMMA {
-		r.Lexer.Eat(lexer.COMMA)
+		if err := r.Lexer.Eat(lexer.COMMA); err != nil {
+			return nil, err
+		}
 		varNode, err := ast.NewVar(*r.Lexer.GetCurrentToken())
 		if err != nil {
 			return nil, err
 		}
 
 		varNodes = append(varNodes, varNode)
-		r.Lexer.Eat(lexer.ID)
+		if err := r.Lexer.Eat(lexer.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	colonToken := r.Lexer.GetCurrentToken()
+	if err := r.Lexer.Eat(lexer.COLON); err != nil {
+		return nil, err
 	}
-	r.Lexer.Eat(lexer.COLON)
 
 	typeNode, err := r.typeSpec()
 	if err != nil {
@@ -379,25 +1853,68 @@ func (r *BasicParser) varDeclaration() ([]ast.Node, error) {
 	
 	var declarations []ast.Node
 	for _, v := range varNodes {
-		d := ast.NewVarDeclaration(v, typeNode.(ast.TypeSpec), *r.Lexer.GetCurrentToken())
+		d := ast.NewVarDeclaration(v, typeNode, *colonToken)
 		declarations = append(declarations, d)
 	}
 
 	return declarations, nil
 }
 
+// syntaxError attributes err to the current token unless it already
+// carries a position
+func (r *BasicParser) syntaxError(err error) error {
+	var lexerError lexer.LexerError
+	var parserError ParserError
+	if errors.As(err, &lexerError) || errors.As(err, &parserError) {
+		return err
+	}
+
+	return ParserError{
+		Pos:     r.Lexer.GetCurrentToken().Pos,
+		Message: err.Error(),
+	}
+}
+
+func (r *BasicParser) expectEOF() error {
+	token := r.Lexer.GetCurrentToken()
+	if token.TokenType != lexer.EOF {
+		return r.syntaxError(fmt.Errorf("EOF expected, got %v instead", token.TokenType))
+	}
+	return nil
+}
+
+// Parse parses a program, or a unit if the input starts with UNIT
 func (r *BasicParser) Parse() (ast.Node, error) {
-	node, err := r.program()
+	parse := r.program
+	if r.Lexer.GetCurrentToken().TokenType == lexer.UNIT {
+		parse = r.unit
+	}
+
+	node, err := parse()
 	if err != nil {
-		return nil, err
+		return nil, r.syntaxError(err)
 	}
 
-	if r.Lexer.GetCurrentToken().TokenType != lexer.EOF {
-		return nil, fmt.Errorf("EOF expected, got %v instead", r.Lexer.GetCurrentToken())
+	if err := r.expectEOF(); err != nil {
+		return nil, err
 	}
 	return node, nil
 }
 
+// ParseStatements parses input consisting of a statement list only, as
+// typed into the REPL.
+func (r *BasicParser) ParseStatements() ([]ast.Node, error) {
+	nodes, err := r.statementList()
+	if err != nil {
+		return nil, r.syntaxError(err)
+	}
+
+	if err := r.expectEOF(); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
 func NewParser(lexer lexer.BasicLexer) (*BasicParser, error) {
 	interpreter := BasicParser{
 		Lexer: &lexer,
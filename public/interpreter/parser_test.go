@@ -246,7 +246,7 @@ func TestBasicParser_varDeclaration(t *testing.T) {
 		require.IsType(t, ast.VarDeclaration{}, numberNode)
 		casted := numberNode.(ast.VarDeclaration)
 		require.Equal(t, "number", casted.Variable.Value)
-		require.Equal(t, "INTEGER", casted.TypeSpec.Value)
+		require.Equal(t, "INTEGER", casted.TypeSpec.(ast.TypeSpec).Value)
 	
 	})
 
@@ -267,7 +267,7 @@ func TestBasicParser_varDeclaration(t *testing.T) {
 		require.IsType(t, ast.VarDeclaration{}, numberNode)
 		casted := numberNode.(ast.VarDeclaration)
 		require.Equal(t, "number", casted.Variable.Value)
-		require.Equal(t, "REAL", casted.TypeSpec.Value)
+		require.Equal(t, "REAL", casted.TypeSpec.(ast.TypeSpec).Value)
 	})
 }
 
@@ -289,19 +289,560 @@ func TestBasicParser_declarations(t *testing.T) {
 
 		casted := nodes[0].(ast.VarDeclaration)
 		require.Equal(t, "number", casted.Variable.Value)
-		require.Equal(t, "INTEGER", casted.TypeSpec.Value)
+		require.Equal(t, "INTEGER", casted.TypeSpec.(ast.TypeSpec).Value)
 
 		casted = nodes[1].(ast.VarDeclaration)
 		require.Equal(t, "a", casted.Variable.Value)
-		require.Equal(t, "REAL", casted.TypeSpec.Value)
+		require.Equal(t, "REAL", casted.TypeSpec.(ast.TypeSpec).Value)
 	
 		casted = nodes[2].(ast.VarDeclaration)
 		require.Equal(t, "b", casted.Variable.Value)
-		require.Equal(t, "REAL", casted.TypeSpec.Value)
+		require.Equal(t, "REAL", casted.TypeSpec.(ast.TypeSpec).Value)
 
 		casted = nodes[3].(ast.VarDeclaration)
 		require.Equal(t, "c", casted.Variable.Value)
-		require.Equal(t, "REAL", casted.TypeSpec.Value)
+		require.Equal(t, "REAL", casted.TypeSpec.(ast.TypeSpec).Value)
 	})
 }
 
+
+func TestBasicParser_procedureCall(t *testing.T) {
+	parse := func(t *testing.T, text string) ast.ProcedureCall {
+		parser, err := NewParser(lexer.NewLexer(text))
+		require.NoError(t, err)
+		nodes, err := parser.ParseStatements()
+		require.NoError(t, err)
+		require.Len(t, nodes, 1)
+		require.IsType(t, ast.ProcedureCall{}, nodes[0])
+		return nodes[0].(ast.ProcedureCall)
+	}
+
+	t.Run("Without arguments", func(t *testing.T) {
+		call := parse(t, "writeln")
+		require.Equal(t, "writeln", call.Name)
+		require.Empty(t, call.Arguments)
+
+		require.Empty(t, parse(t, "WRITELN()").Arguments)
+	})
+
+	t.Run("Formatted arguments", func(t *testing.T) {
+		call := parse(t, "WRITE(a, b : 5, c + 1 : w : 2)")
+		require.Len(t, call.Arguments, 3)
+		require.IsType(t, ast.Var{}, call.Arguments[0])
+
+		width := call.Arguments[1].(ast.FormattedArgument)
+		require.Equal(t, intNode(5).Value, width.Width.(ast.IntNode).Value)
+		require.Nil(t, width.Precision)
+
+		precision := call.Arguments[2].(ast.FormattedArgument)
+		require.IsType(t, ast.BinaryOperation{}, precision.Value)
+		require.IsType(t, ast.Var{}, precision.Width)
+		require.Equal(t, 2, precision.Precision.(ast.IntNode).Value)
+	})
+
+	t.Run("Unterminated argument list", func(t *testing.T) {
+		parser, err := NewParser(lexer.NewLexer("WRITE(a, b"))
+		require.NoError(t, err)
+		_, err = parser.ParseStatements()
+		require.ErrorAs(t, err, &ParserError{})
+	})
+}
+
+func TestBasicParser_Expr(t *testing.T) {
+	parse := func(t *testing.T, text string) ast.Node {
+		parser, err := NewParser(lexer.NewLexer(text))
+		require.NoError(t, err)
+		node, err := parser.Expr()
+		require.NoError(t, err)
+		return node
+	}
+
+	t.Run("Relational operators bind loosest", func(t *testing.T) {
+		node := parse(t, "a + 1 <= b * 2")
+		require.Equal(t, lexer.LESS_EQUAL, node.GetToken().TokenType)
+		require.Equal(t, lexer.PLUS, node.(ast.BinaryOperation).Left.GetToken().TokenType)
+		require.Equal(t, lexer.MUL, node.(ast.BinaryOperation).Right.GetToken().TokenType)
+	})
+
+	t.Run("Indexing", func(t *testing.T) {
+		node := parse(t, "s[i + 1] + 'x'")
+		index := node.(ast.BinaryOperation).Left.(ast.Index)
+		require.Equal(t, "s", index.Value.(ast.Var).Value)
+		require.Len(t, index.Indices, 1)
+		require.Equal(t, "x", node.(ast.BinaryOperation).Right.(ast.StringNode).Value)
+
+		nested := parse(t, "a[1, 2][3]").(ast.Index)
+		require.Len(t, nested.Indices, 1)
+		require.Len(t, nested.Value.(ast.Index).Indices, 2)
+	})
+}
+
+func TestBasicParser_arrayType(t *testing.T) {
+	t.Run("Several ranges", func(t *testing.T) {
+		parser, err := NewParser(lexer.NewLexer("grid: ARRAY[1..3, 'a'..'z'] OF ARRAY[-1..1] OF REAL;"))
+		require.NoError(t, err)
+		nodes, err := parser.varDeclaration()
+		require.NoError(t, err)
+
+		array := nodes[0].(ast.VarDeclaration).TypeSpec.(ast.ArrayType)
+		require.Len(t, array.Ranges, 2)
+		require.Equal(t, 3, array.Ranges[0].(ast.Subrange).High.(ast.IntNode).Value)
+		require.Equal(t, "z", array.Ranges[1].(ast.Subrange).High.(ast.StringNode).Value)
+
+		element := array.Element.(ast.ArrayType)
+		require.IsType(t, ast.UnaryOperation{}, element.Ranges[0].(ast.Subrange).Low)
+		require.Equal(t, "REAL", element.Element.(ast.TypeSpec).Value)
+	})
+
+	t.Run("Indexed assignment", func(t *testing.T) {
+		parser, err := NewParser(lexer.NewLexer("a[i, 2] := 1"))
+		require.NoError(t, err)
+		nodes, err := parser.ParseStatements()
+		require.NoError(t, err)
+		require.IsType(t, ast.Index{}, nodes[0].(ast.AssignOperation).Left)
+	})
+
+	t.Run("Missing range", func(t *testing.T) {
+		parser, err := NewParser(lexer.NewLexer("PROGRAM p; VAR a: ARRAY[10] OF INTEGER; BEGIN END."))
+		require.NoError(t, err)
+		_, err = parser.Parse()
+		require.ErrorAs(t, err, &ParserError{})
+	})
+}
+
+func TestBasicParser_records(t *testing.T) {
+	parser, err := NewParser(lexer.NewLexer(`
+		PROGRAM p;
+		VAR r: RECORD a, b: INTEGER; inner: RECORD c: CHAR; END END;
+		BEGIN
+			r.inner.c := 'x';
+			WITH r, inner DO a := b
+		END.
+	`))
+	require.NoError(t, err)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+
+	block := node.(ast.Program).Block
+	record := block.Vars()[0].TypeSpec.(ast.RecordType)
+	require.Len(t, record.Fields, 3)
+	require.Equal(t, "c", record.Fields[2].TypeSpec.(ast.RecordType).Fields[0].Variable.Value)
+
+	assignment := block.Compound.Children[0].(ast.AssignOperation)
+	field := assignment.Left.(ast.FieldAccess)
+	require.Equal(t, "c", field.Field)
+	require.Equal(t, "inner", field.Value.(ast.FieldAccess).Field)
+
+	with := block.Compound.Children[1].(ast.WithStatement)
+	require.Len(t, with.Records, 2)
+	require.IsType(t, ast.AssignOperation{}, with.Body)
+}
+
+func TestBasicParser_types(t *testing.T) {
+	parser, err := NewParser(lexer.NewLexer(`
+		PROGRAM p;
+		TYPE
+			Color = (Red, Green, Blue);
+			Digit = 0..9;
+			Number = Digit;
+			Counts = ARRAY[Color, Red..Green] OF Number;
+		VAR c: Color; d: -1..+1;
+		BEGIN
+		END.
+	`))
+	require.NoError(t, err)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+
+	block := node.(ast.Program).Block
+	require.Len(t, block.Types(), 4)
+	require.Len(t, block.Vars(), 2)
+
+	require.Equal(t, "Color", block.Types()[0].Name.Value)
+	enum := block.Types()[0].TypeSpec.(ast.EnumType)
+	require.Len(t, enum.Values, 3)
+	require.Equal(t, "Blue", enum.Values[2].Value)
+
+	require.IsType(t, ast.Subrange{}, block.Types()[1].TypeSpec)
+	require.Equal(t, "Digit", block.Types()[2].TypeSpec.(ast.TypeSpec).Value)
+
+	array := block.Types()[3].TypeSpec.(ast.ArrayType)
+	require.Equal(t, "Color", array.Ranges[0].(ast.TypeSpec).Value)
+	require.IsType(t, ast.Subrange{}, array.Ranges[1])
+
+	require.Equal(t, "Color", block.Vars()[0].TypeSpec.(ast.TypeSpec).Value)
+	require.IsType(t, ast.Subrange{}, block.Vars()[1].TypeSpec)
+}
+
+func TestBasicParser_constants(t *testing.T) {
+	parser, err := NewParser(lexer.NewLexer(`
+		PROGRAM p;
+		CONST Max = 10; Half: REAL = Max / 2;
+		TYPE Index = 1..Max;
+		BEGIN
+		END.
+	`))
+	require.NoError(t, err)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+
+	block := node.(ast.Program).Block
+	require.Len(t, block.Constants(), 2)
+	require.Len(t, block.Types(), 1)
+
+	require.Equal(t, "Max", block.Constants()[0].Name.Value)
+	require.Nil(t, block.Constants()[0].TypeSpec)
+	require.Equal(t, 10, block.Constants()[0].Value.(ast.IntNode).Value)
+
+	require.Equal(t, "REAL", block.Constants()[1].TypeSpec.(ast.TypeSpec).Value)
+	require.IsType(t, ast.BinaryOperation{}, block.Constants()[1].Value)
+}
+
+func TestBasicParser_sectionOrder(t *testing.T) {
+	parser, err := NewParser(lexer.NewLexer(`
+		PROGRAM p;
+		TYPE Small = 1..9;
+		CONST Max: Small = 5;
+		VAR i: INTEGER;
+		PROCEDURE P; BEGIN END;
+		VAR j: Small;
+		TYPE Big = INTEGER;
+		BEGIN END.
+	`))
+	require.NoError(t, err)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+
+	declarations := node.(ast.Program).Block.Declarations
+	require.Len(t, declarations, 6)
+	require.IsType(t, ast.TypeDeclaration{}, declarations[0])
+	require.IsType(t, ast.ConstDeclaration{}, declarations[1])
+	require.IsType(t, ast.VarDeclaration{}, declarations[2])
+	require.IsType(t, ast.RoutineDeclaration{}, declarations[3])
+	require.Equal(t, "j", declarations[4].(ast.VarDeclaration).Variable.Value)
+	require.Equal(t, "Big", declarations[5].(ast.TypeDeclaration).Name.Value)
+}
+
+func TestBasicParser_sets(t *testing.T) {
+	parser, err := NewParser(lexer.NewLexer(`
+		PROGRAM p;
+		TYPE Letters = SET OF 'a'..'z';
+		VAR l: Letters; b: BOOLEAN;
+		BEGIN
+			b := 'a' IN l + ['b', 'd'..'f']
+		END.
+	`))
+	require.NoError(t, err)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+
+	block := node.(ast.Program).Block
+	set := block.Types()[0].TypeSpec.(ast.SetType)
+	require.IsType(t, ast.Subrange{}, set.Element)
+
+	in := block.Compound.Children[0].(ast.AssignOperation).Right.(ast.BinaryOperation)
+	require.Equal(t, lexer.IN, in.GetToken().TokenType)
+
+	union := in.Right.(ast.BinaryOperation)
+	constructor := union.Right.(ast.SetConstructor)
+	require.Len(t, constructor.Elements, 2)
+	require.IsType(t, ast.StringNode{}, constructor.Elements[0])
+	require.IsType(t, ast.Subrange{}, constructor.Elements[1])
+}
+
+func TestBasicParser_pointers(t *testing.T) {
+	parser, err := NewParser(lexer.NewLexer(`
+		PROGRAM p;
+		TYPE PNode = ^TNode;
+		VAR p: PNode;
+		BEGIN
+			p^.next^ := NIL
+		END.
+	`))
+	require.NoError(t, err)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+
+	block := node.(ast.Program).Block
+	pointer := block.Types()[0].TypeSpec.(ast.PointerType)
+	require.Equal(t, "TNode", pointer.Target.(ast.TypeSpec).Value)
+
+	assignment := block.Compound.Children[0].(ast.AssignOperation)
+	require.IsType(t, ast.NilNode{}, assignment.Right)
+	dereference := assignment.Left.(ast.Dereference)
+	field := dereference.Value.(ast.FieldAccess)
+	require.Equal(t, "next", field.Field)
+	require.IsType(t, ast.Dereference{}, field.Value)
+
+	parser, err = NewParser(lexer.NewLexer("PROGRAM p; TYPE P = ^ARRAY[1..2] OF INTEGER; BEGIN END."))
+	require.NoError(t, err)
+	_, err = parser.Parse()
+	require.ErrorContains(t, err, "Type identifier expected, got ARRAY")
+}
+
+func TestBasicParser_routines(t *testing.T) {
+	parser, err := NewParser(lexer.NewLexer(`
+		PROGRAM p;
+		TYPE TCompare = FUNCTION(a, b: INTEGER): INTEGER;
+		PROCEDURE Sort(VAR x, y: INTEGER; FUNCTION less(a, b: INTEGER): BOOLEAN);
+		BEGIN
+		END;
+		FUNCTION Answer: INTEGER;
+			PROCEDURE Nested;
+			BEGIN
+			END;
+		BEGIN
+			Answer := 42
+		END;
+		BEGIN
+		END.
+	`))
+	require.NoError(t, err)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+
+	block := node.(ast.Program).Block
+	procedural := block.Types()[0].TypeSpec.(ast.ProceduralType)
+	require.Len(t, procedural.Params, 2)
+	require.Equal(t, "INTEGER", procedural.Result.(ast.TypeSpec).Value)
+
+	require.Len(t, block.Routines(), 2)
+	sort := block.Routines()[0]
+	require.False(t, sort.IsFunction())
+	require.Nil(t, sort.Result)
+	require.Len(t, sort.Params, 3)
+	require.True(t, sort.Params[0].IsVar())
+	require.True(t, sort.Params[1].IsVar())
+	require.False(t, sort.Params[2].IsVar())
+	less := sort.Params[2].TypeSpec.(ast.ProceduralType)
+	require.Equal(t, "BOOLEAN", less.Result.(ast.TypeSpec).Value)
+
+	answer := block.Routines()[1]
+	require.True(t, answer.IsFunction())
+	require.Empty(t, answer.Params)
+	require.Equal(t, "Nested", answer.Block.Routines()[0].Name.Value)
+}
+
+func TestBasicParser_exceptions(t *testing.T) {
+	parser, err := NewParser(lexer.NewLexer(`
+		PROGRAM p;
+		BEGIN
+			TRY
+				RAISE Exception.Create('failed')
+			EXCEPT
+				ON E: EDivByZero DO RAISE;
+				ON Exception DO
+			ELSE
+				x := 1
+			END;
+			TRY
+			FINALLY
+				x := 2
+			END
+		END.
+	`))
+	require.NoError(t, err)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+
+	statements := node.(ast.Program).Block.Compound.Children
+	tryExcept := statements[0].(ast.TryExcept)
+	raise := tryExcept.Body[0].(ast.RaiseStatement)
+	create := raise.Value.(ast.MethodCall)
+	require.Equal(t, "Exception", create.Value.(ast.Var).Value)
+	require.Equal(t, "Create", create.Name)
+	require.Len(t, create.Arguments, 1)
+
+	require.Len(t, tryExcept.Handlers, 2)
+	require.Equal(t, "E", tryExcept.Handlers[0].Variable.(ast.Var).Value)
+	require.Equal(t, "EDivByZero", tryExcept.Handlers[0].Class.Value)
+	require.Nil(t, tryExcept.Handlers[0].Body.(ast.RaiseStatement).Value)
+	require.Nil(t, tryExcept.Handlers[1].Variable)
+	require.Len(t, tryExcept.Default, 1)
+
+	tryFinally := statements[1].(ast.TryFinally)
+	require.Len(t, tryFinally.Finally, 1)
+	require.Equal(t, 15, tryFinally.End.Line)
+}
+
+func TestBasicParser_controlFlow(t *testing.T) {
+	parser, err := NewParser(lexer.NewLexer(`
+		PROGRAM p;
+		BEGIN
+			IF a THEN IF b THEN x := 1 ELSE x := 2;
+			WHILE a DO Break;
+			REPEAT x := 1; Continue UNTIL a;
+			FOR i := 10 DOWNTO 1 DO Exit(i)
+		END.
+	`))
+	require.NoError(t, err)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+
+	statements := node.(ast.Program).Block.Compound.Children
+	outer := statements[0].(ast.IfStatement)
+	require.Nil(t, outer.Else)
+	require.NotNil(t, outer.Then.(ast.IfStatement).Else)
+
+	while := statements[1].(ast.WhileStatement)
+	require.Equal(t, "Break", while.Body.(ast.ProcedureCall).Name)
+
+	repeat := statements[2].(ast.RepeatStatement)
+	require.Len(t, repeat.Body, 2)
+	require.Equal(t, "a", repeat.Condition.(ast.Var).Value)
+
+	loop := statements[3].(ast.ForStatement)
+	require.True(t, loop.Downto)
+	require.Equal(t, 10, loop.Start.(ast.IntNode).Value)
+	require.Len(t, loop.Body.(ast.ProcedureCall).Arguments, 1)
+}
+
+func TestBasicParser_labels(t *testing.T) {
+	parser, err := NewParser(lexer.NewLexer(`
+		PROGRAM p;
+		LABEL 10, done;
+		BEGIN
+			10: x := 1;
+			done: ;
+			GOTO 10
+		END.
+	`))
+	require.NoError(t, err)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+
+	block := node.(ast.Program).Block
+	require.Len(t, block.Labels, 2)
+	require.Equal(t, "done", block.Labels[1].Name)
+
+	statements := block.Compound.Children
+	labeled := statements[0].(ast.LabeledStatement)
+	require.Equal(t, "10", labeled.Label)
+	require.IsType(t, ast.AssignOperation{}, labeled.Statement)
+	require.IsType(t, ast.NoOp{}, statements[1].(ast.LabeledStatement).Statement)
+	require.Equal(t, "10", statements[2].(ast.GotoStatement).Label)
+}
+
+func TestBasicParser_classes(t *testing.T) {
+	parser, err := NewParser(lexer.NewLexer(`
+		PROGRAM p;
+		TYPE
+			TShape = CLASS(TObject)
+				x, y: INTEGER;
+				CONSTRUCTOR Create(ax: INTEGER);
+				FUNCTION Area: REAL; VIRTUAL;
+			END;
+		CONSTRUCTOR TShape.Create(ax: INTEGER);
+		BEGIN
+			INHERITED Create;
+			x := ax
+		END;
+		BEGIN
+			s := TShape.Create(1);
+			s.Free;
+			b := s IS TShape;
+			(s AS TShape).x := 2
+		END.
+	`))
+	require.NoError(t, err)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+
+	block := node.(ast.Program).Block
+	class := block.Types()[0].TypeSpec.(ast.ClassType)
+	require.Equal(t, "TObject", class.Parent.(ast.TypeSpec).Value)
+	require.Len(t, class.Fields, 2)
+	require.Len(t, class.Methods, 2)
+	require.Equal(t, lexer.CONSTRUCTOR, class.Methods[0].GetToken().TokenType)
+	require.True(t, class.Methods[1].Virtual)
+	require.NotNil(t, class.Methods[1].Result)
+
+	routine := block.Routines()[0]
+	require.Equal(t, "TShape", routine.Class.(ast.TypeSpec).Value)
+	require.Equal(t, "Create", routine.Name.Value)
+	require.Equal(t, "Create", routine.Block.Compound.Children[0].(ast.InheritedCall).Name)
+
+	statements := block.Compound.Children
+	require.Equal(t, "Create", statements[0].(ast.AssignOperation).Right.(ast.MethodCall).Name)
+	require.Equal(t, "Free", statements[1].(ast.MethodCall).Name)
+	require.Equal(t, lexer.IS, statements[2].(ast.AssignOperation).Right.GetToken().TokenType)
+	access := statements[3].(ast.AssignOperation).Left.(ast.FieldAccess)
+	require.Equal(t, lexer.AS, access.Value.GetToken().TokenType)
+}
+
+func TestBasicParser_units(t *testing.T) {
+	t.Run("Unit sections", func(t *testing.T) {
+		parser, err := NewParser(lexer.NewLexer(`
+			UNIT Shapes;
+			INTERFACE
+			USES Math, Strings;
+			CONST Pi = 3.14;
+			VAR count: INTEGER;
+			FUNCTION Area(r: REAL): REAL;
+			PROCEDURE Reset;
+			IMPLEMENTATION
+			USES Helpers;
+			VAR hidden: INTEGER;
+			FUNCTION Area(r: REAL): REAL;
+			BEGIN
+				Area := Pi * r * r
+			END;
+			PROCEDURE Reset;
+			BEGIN
+				count := 0
+			END;
+			INITIALIZATION
+				count := 1
+			FINALIZATION
+				count := 0;
+				hidden := 0
+			END.
+		`))
+		require.NoError(t, err)
+		node, err := parser.Parse()
+		require.NoError(t, err)
+
+		unit := node.(ast.Unit)
+		require.Equal(t, "Shapes", unit.Name)
+		require.Len(t, unit.InterfaceUses, 2)
+		require.Equal(t, "Strings", unit.InterfaceUses[1].Value)
+		require.Len(t, unit.Interface.Constants(), 1)
+		require.Len(t, unit.Interface.Vars(), 1)
+		require.Len(t, unit.Headings, 2)
+		require.True(t, unit.Headings[0].IsFunction())
+		require.False(t, unit.Headings[1].IsFunction())
+		require.Equal(t, "Helpers", unit.ImplementationUses[0].Value)
+		require.Len(t, unit.Implementation.Vars(), 1)
+		require.Len(t, unit.Implementation.Routines(), 2)
+		require.Len(t, unit.Implementation.Compound.Children, 1)
+		require.Len(t, unit.Finalization, 2)
+	})
+
+	t.Run("BEGIN starts the initialization", func(t *testing.T) {
+		parser, err := NewParser(lexer.NewLexer(`UNIT u; INTERFACE IMPLEMENTATION BEGIN x := 1 END.`))
+		require.NoError(t, err)
+		node, err := parser.Parse()
+		require.NoError(t, err)
+		require.Len(t, node.(ast.Unit).Implementation.Compound.Children, 1)
+		require.Empty(t, node.(ast.Unit).Finalization)
+	})
+
+	t.Run("Program USES clause", func(t *testing.T) {
+		parser, err := NewParser(lexer.NewLexer(`PROGRAM p; USES a, b; BEGIN END.`))
+		require.NoError(t, err)
+		node, err := parser.Parse()
+		require.NoError(t, err)
+
+		uses := node.(ast.Program).Uses
+		require.Len(t, uses, 2)
+		require.Equal(t, "a", uses[0].Value)
+		require.Equal(t, "b", uses[1].Value)
+	})
+
+	t.Run("Routine bodies are not allowed in the interface", func(t *testing.T) {
+		parser, err := NewParser(lexer.NewLexer(`UNIT u; INTERFACE PROCEDURE p; BEGIN END; IMPLEMENTATION END.`))
+		require.NoError(t, err)
+		_, err = parser.Parse()
+		require.ErrorAs(t, err, &ParserError{})
+	})
+}
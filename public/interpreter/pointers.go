@@ -0,0 +1,245 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+)
+
+// pointerType is ^Target. The target is kept by name as it may be declared
+// after the pointer type, e.g. in PNode = ^TNode; TNode = RECORD ... END.
+type pointerType struct {
+	Name   string
+	Target string
+}
+
+func (r pointerType) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return "^" + r.Target
+}
+
+// pointerValue is the address of a heapCell, NIL if Address is 0. NIL
+// itself has no Type, it is converted to the type of the variable
+// receiving it.
+type pointerValue struct {
+	Type    pointerType
+	Address int
+}
+
+func (r pointerValue) String() string {
+	if r.Address == 0 {
+		return "NIL"
+	}
+	return fmt.Sprintf("^%d", r.Address)
+}
+
+// heapCell is a variable allocated by New, Node being the call
+type heapCell struct {
+	Value    any
+	Type     dataType
+	Disposed bool
+	Node     ast.Node
+}
+
+// pointerTarget returns the type of the variables t points to
+func (r *EvaluatorVisitor) pointerTarget(t pointerType) dataType {
+	if declared, ok := r.typeDef(strings.ToUpper(t.Target)); ok {
+		return declared
+	}
+	return simpleType(strings.ToUpper(t.Target))
+}
+
+// cell returns the heap cell pointer points to, failing for NIL and for
+// disposed cells
+func (r *EvaluatorVisitor) cell(node ast.Node, pointer any) (*heapCell, error) {
+	value, ok := pointer.(pointerValue)
+	if !ok {
+		return nil, newRuntimeError(node, "Cannot dereference a value of type %v", typeName(pointer))
+	}
+	if value.Address == 0 {
+		return nil, newKindError(node, AccessViolation, "Dereference of NIL pointer")
+	}
+
+	cell := r.heap[value.Address-1]
+	if cell.Disposed {
+		return nil, newKindError(node, InvalidPointer, "Dereference of disposed pointer")
+	}
+	return cell, nil
+}
+
+func (r *EvaluatorVisitor) visitDereference(node ast.Dereference) (any, error) {
+	pointer, err := r.Visit(node.Value)
+	if err != nil {
+		return nil, err
+	}
+	cell, err := r.cell(node, pointer)
+	if err != nil {
+		return nil, err
+	}
+	return r.cellValue(node, cell)
+}
+
+// cellValue returns the value of the variable cell, which may have been
+// disposed since it was looked up
+func (r *EvaluatorVisitor) cellValue(node ast.Node, cell *heapCell) (any, error) {
+	if cell.Disposed {
+		return nil, newKindError(node, InvalidPointer, "Dereference of disposed pointer")
+	}
+	if cell.Value == nil {
+		return nil, newRuntimeError(node, "Pointer target is not initialized")
+	}
+	return cell.Value, nil
+}
+
+// dereferenceReference returns a reference to the variable the pointer
+// node.Value points to. The pointer is only evaluated once, so that WITH p^
+// keeps referring to the same variable.
+func (r *EvaluatorVisitor) dereferenceReference(node ast.Dereference) (*reference, error) {
+	pointer, err := r.Visit(node.Value)
+	if err != nil {
+		return nil, err
+	}
+	cell, err := r.cell(node, pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reference{
+		get: func() (any, error) {
+			return r.cellValue(node, cell)
+		},
+		set: func(value any) error {
+			if cell.Disposed {
+				return newKindError(node, InvalidPointer, "Dereference of disposed pointer")
+			}
+			converted, err := r.convert(node, value, cell.Type)
+			if err != nil {
+				return err
+			}
+			cell.Value = converted
+			return nil
+		},
+		declared: cell.Type,
+		typeName: baseType(cell.Type).String(),
+	}, nil
+}
+
+// newPointer implements New(p), making p point to a new variable of the
+// type it points to
+func (r *EvaluatorVisitor) newPointer(node ast.ProcedureCall) error {
+	if len(node.Arguments) != 1 {
+		return newRuntimeError(node, "Wrong number of arguments for '%v'", node.Name)
+	}
+	variable, err := r.referenceTo(node.Arguments[0])
+	if err != nil {
+		return err
+	}
+
+	declared, ok := baseType(variable.declared).(pointerType)
+	if !ok {
+		return newRuntimeError(node.Arguments[0], "Pointer type expected, got %v", variable.typeName)
+	}
+
+	target := r.pointerTarget(declared)
+	r.heap = append(r.heap, &heapCell{Value: newValue(target), Type: target, Node: node})
+	return variable.set(pointerValue{Type: declared, Address: len(r.heap)})
+}
+
+// dispose implements Dispose(p), releasing the variable p points to. Any
+// later use of it through p or a copy of p fails.
+func (r *EvaluatorVisitor) dispose(node ast.ProcedureCall) error {
+	if len(node.Arguments) != 1 {
+		return newRuntimeError(node, "Wrong number of arguments for '%v'", node.Name)
+	}
+	value, err := r.Visit(node.Arguments[0])
+	if err != nil {
+		return err
+	}
+
+	pointer, ok := value.(pointerValue)
+	if !ok {
+		return newRuntimeError(node.Arguments[0], "Pointer type expected, got %v", typeName(value))
+	}
+	if pointer.Address == 0 {
+		return nil
+	}
+
+	cell := r.heap[pointer.Address-1]
+	if cell.Disposed {
+		return newKindError(node, InvalidPointer, "Pointer disposed twice")
+	}
+	cell.Disposed = true
+	cell.Value = nil
+	return nil
+}
+
+// convertPointer makes the pointer value fit a variable of type declared,
+// which it does if both point to the same type or value is NIL
+func (r *EvaluatorVisitor) convertPointer(node ast.Node, value any, declared pointerType) (any, error) {
+	pointer, ok := value.(pointerValue)
+	if !ok || (pointer.Type.Target != "" && r.pointerTarget(pointer.Type).String() != r.pointerTarget(declared).String()) {
+		return nil, newRuntimeError(node, "Incompatible types: got %v expected %v", typeName(value), declared)
+	}
+	pointer.Type = declared
+	return pointer, nil
+}
+
+// comparePointers evaluates = and <> on two pointers
+func (r *EvaluatorVisitor) comparePointers(node ast.BinaryOperation, left pointerValue, right pointerValue) (any, error) {
+	switch node.GetToken().TokenType {
+	case lexer.EQUAL:
+		return left.Address == right.Address, nil
+	case lexer.NOT_EQUAL:
+		return left.Address != right.Address, nil
+	}
+	return nil, newRuntimeError(node, "Operator %v is not defined for %v and %v", node.GetToken().Text(), typeName(left), typeName(right))
+}
+
+// Assigned(p): whether the pointer, object or procedural variable p is not
+// NIL. Procedural variables of functions are not called.
+func builtinAssigned(args []any) (any, error) {
+	value, err := args[0].(*reference).get()
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case pointerValue:
+		return v.Address != 0, nil
+	case *objectValue:
+		return true, nil
+	case routineValue:
+		return v.Declaration != nil, nil
+	}
+	return nil, fmt.Errorf("Incompatible type for argument 1: got %v expected a pointer, object or procedural variable", typeName(value))
+}
+
+// reportLeaks writes the allocations that were never disposed to Leaks
+func (r *EvaluatorVisitor) reportLeaks() {
+	if r.Leaks == nil {
+		return
+	}
+
+	var leaked []*heapCell
+	for _, v := range r.heap {
+		if !v.Disposed {
+			leaked = append(leaked, v)
+		}
+	}
+	if len(leaked) == 0 {
+		return
+	}
+
+	noun := "allocations"
+	if len(leaked) == 1 {
+		noun = "allocation"
+	}
+	fmt.Fprintf(r.Leaks, "%d unfreed %s:\n", len(leaked), noun)
+	for _, v := range leaked {
+		pos := ast.Pos(v.Node)
+		fmt.Fprintf(r.Leaks, "  %v:%v: %v\n", pos.Line, pos.Column, v.Type)
+	}
+}
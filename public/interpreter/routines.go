@@ -0,0 +1,399 @@
+package interpreter
+
+import (
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+)
+
+// maxCallDepth limits the nesting of routine calls, so that runaway
+// recursion is reported instead of exhausting the Go stack
+const maxCallDepth = 10000
+
+// frame holds the variables, constants, types and routines declared by a
+// running routine, keyed like GloabalScope. Parent is the frame of the
+// routine it is declared in, nil for routines declared in the program.
+type frame struct {
+	values   map[string]any
+	types    map[string]dataType
+	typeDefs map[string]dataType
+	// name of the function whose result variable the frame holds
+	result string
+	// method running in the frame and its Self, nil unless the frame is
+	// the one of a method
+	method *classMethod
+	self   *objectValue
+	// path of the file of the unit the frame holds the declarations of,
+	// empty for other frames
+	unit   string
+	parent *frame
+}
+
+func newFrame(parent *frame) *frame {
+	return &frame{
+		values:   map[string]any{},
+		types:    map[string]dataType{},
+		typeDefs: map[string]dataType{},
+		parent:   parent,
+	}
+}
+
+// declares reports whether name is declared in the frame, a variable
+// being declared even before it is assigned
+func (r *frame) declares(name string) bool {
+	_, isValue := r.values[name]
+	_, isTyped := r.types[name]
+	return isValue || isTyped
+}
+
+// global returns the frame of the program
+func (r *EvaluatorVisitor) global() *frame {
+	return &frame{values: r.GloabalScope, types: r.types, typeDefs: r.typeDefs}
+}
+
+// scope returns the frame the declarations being evaluated go to
+func (r *EvaluatorVisitor) scope() *frame {
+	if r.frame != nil {
+		return r.frame
+	}
+	return r.global()
+}
+
+// frameOf returns the innermost frame declaring name, the global one if
+// none does. Variables of the REPL are never declared.
+func (r *EvaluatorVisitor) frameOf(name string) *frame {
+	for f := r.frame; f != nil; f = f.parent {
+		if f.declares(name) {
+			return f
+		}
+	}
+	return r.global()
+}
+
+// typeDef returns the type declared as name in a TYPE section
+func (r *EvaluatorVisitor) typeDef(name string) (dataType, bool) {
+	for f := r.frame; f != nil; f = f.parent {
+		if declared, ok := f.typeDefs[name]; ok {
+			return declared, true
+		}
+	}
+	declared, ok := r.typeDefs[name]
+	return declared, ok
+}
+
+// procParam is a parameter of a procedural type
+type procParam struct {
+	Name  string
+	Type  dataType
+	IsVar bool
+}
+
+// procType is PROCEDURE(params) or FUNCTION(params): Result, the type of
+// a routine. Result is nil for procedures. Name is set if the type was
+// declared in a TYPE section.
+type procType struct {
+	Name   string
+	Params []procParam
+	Result dataType
+}
+
+func (r procType) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.signature()
+}
+
+// signature returns the type without its name and the names of its
+// parameters, two procedural types being compatible if their signatures
+// are the same
+func (r procType) signature() string {
+	params := make([]string, len(r.Params))
+	for i, v := range r.Params {
+		params[i] = typeSignature(v.Type)
+		if v.IsVar {
+			params[i] = "VAR " + params[i]
+		}
+	}
+
+	result := "PROCEDURE"
+	if r.Result != nil {
+		result = "FUNCTION"
+	}
+	if len(params) > 0 {
+		result += "(" + strings.Join(params, "; ") + ")"
+	}
+	if r.Result != nil {
+		result += ": " + typeSignature(r.Result)
+	}
+	return result
+}
+
+// typeSignature returns t as it appears in a signature
+func typeSignature(t dataType) string {
+	if proc, ok := t.(procType); ok {
+		return proc.signature()
+	}
+	return t.String()
+}
+
+// routineValue is a procedure or function, which may be stored in a
+// procedural variable. Frame is the frame the routine was declared in,
+// whose variables the routine can use. Declaration is nil for NIL.
+type routineValue struct {
+	Declaration *ast.RoutineDeclaration
+	Frame       *frame
+	Type        procType
+}
+
+func (r routineValue) String() string {
+	if r.Declaration == nil {
+		return "NIL"
+	}
+	return r.Declaration.Name.Value
+}
+
+// compareRoutines evaluates = and <> on two procedural values or one and
+// NIL. Routines are equal if they are the same declaration run in the same
+// frame.
+func (r *EvaluatorVisitor) compareRoutines(node ast.BinaryOperation, left any, right any) (any, error) {
+	leftRoutine, leftOk := asRoutine(left)
+	rightRoutine, rightOk := asRoutine(right)
+	if leftOk && rightOk {
+		equal := leftRoutine.Declaration == rightRoutine.Declaration && (leftRoutine.Declaration == nil || leftRoutine.Frame == rightRoutine.Frame)
+		switch node.GetToken().TokenType {
+		case lexer.EQUAL:
+			return equal, nil
+		case lexer.NOT_EQUAL:
+			return !equal, nil
+		}
+	}
+	return nil, newRuntimeError(node, "Operator %v is not defined for %v and %v", node.GetToken().Text(), typeName(left), typeName(right))
+}
+
+// asRoutine returns value as a procedural value, NIL being one that
+// refers to no routine
+func asRoutine(value any) (routineValue, bool) {
+	switch v := value.(type) {
+	case routineValue:
+		return v, true
+	case pointerValue:
+		return routineValue{}, v.Address == 0 && v.Type.Target == ""
+	}
+	return routineValue{}, false
+}
+
+// routineType returns the type of a routine taking params and returning
+// a value of the type result, nil for procedures
+func (r *EvaluatorVisitor) routineType(params []ast.Param, result ast.Node) (procType, error) {
+	var t procType
+	for _, v := range params {
+		paramType, err := r.resolveType(v.TypeSpec)
+		if err != nil {
+			return procType{}, err
+		}
+		t.Params = append(t.Params, procParam{Name: v.Variable.Value, Type: paramType, IsVar: v.IsVar()})
+	}
+
+	if result != nil {
+		resultType, err := r.resolveType(result)
+		if err != nil {
+			return procType{}, err
+		}
+		t.Result = resultType
+	}
+	return t, nil
+}
+
+func (r *EvaluatorVisitor) visitRoutineDeclaration(node ast.RoutineDeclaration) (any, error) {
+	if node.Class != nil {
+		return nil, r.implementMethod(node)
+	}
+
+	t, err := r.routineType(node.Params, node.Result)
+	if err != nil {
+		return nil, err
+	}
+
+	r.scope().values[strings.ToUpper(node.Name.Value)] = routineValue{Declaration: &node, Frame: r.frame, Type: t}
+	return nil, nil
+}
+
+// routine returns the value of the routine or procedural variable called
+// name, false if there is none and name may denote a builtin. The result
+// variable of a function does not hide the function, so that it can call
+// itself.
+func (r *EvaluatorVisitor) routine(name string) (any, bool) {
+	name = strings.ToUpper(name)
+	for f := r.frame; f != nil; f = f.parent {
+		if f.result != name && f.declares(name) {
+			return f.values[name], true
+		}
+	}
+
+	value, ok := r.GloabalScope[name]
+	_, isTyped := r.types[name]
+	return value, ok || isTyped
+}
+
+// IsProcedure reports whether name denotes a procedure, declared by the
+// program or builtin, which can only be called by a statement
+func (r *EvaluatorVisitor) IsProcedure(name string) bool {
+	if value, ok := r.routine(name); ok {
+		if variable, isReference := value.(*reference); isReference {
+			value, _ = variable.get()
+		}
+		routine, isRoutine := value.(routineValue)
+		return isRoutine && routine.Type.Result == nil
+	}
+
+	routine, isBuiltin := builtinRoutines[strings.ToUpper(name)]
+	return isOutputProcedure(name) || isInputProcedure(name) || isMemoryProcedure(name) ||
+		isControlProcedure(name) || (isBuiltin && !routine.function)
+}
+
+// valueFor evaluates node as a value for a variable of type t. A routine
+// name stands for the routine itself if t is procedural, rather than for
+// a call of it.
+func (r *EvaluatorVisitor) valueFor(node ast.Node, t dataType) (any, error) {
+	if _, ok := t.(procType); ok {
+		if _, isVar := node.(ast.Var); isVar {
+			variable, err := r.referenceTo(node)
+			if err != nil {
+				return nil, err
+			}
+			return variable.get()
+		}
+	}
+	return r.Visit(node)
+}
+
+// call runs the routine value called name with arguments and returns
+// the result of a function, nil for a procedure
+func (r *EvaluatorVisitor) call(node ast.Node, name string, value any, arguments []ast.Node) (any, error) {
+	if variable, ok := value.(*reference); ok {
+		var err error
+		if value, err = variable.get(); err != nil {
+			return nil, err
+		}
+	}
+	if method, ok := value.(boundMethod); ok {
+		return r.callMethod(node, method.Object, method.Method, arguments)
+	}
+
+	routine, ok := value.(routineValue)
+	switch {
+	case value == nil:
+		return nil, newRuntimeError(node, "var %v is not initialized", name)
+	case !ok:
+		return nil, newRuntimeError(node, "'%v' is not a routine", name)
+	case routine.Declaration == nil:
+		return nil, newRuntimeError(node, "Call through NIL procedural variable '%v'", name)
+	}
+	return r.run(node, name, routine.Declaration, routine.Type, newFrame(routine.Frame), arguments)
+}
+
+// run passes arguments to the parameters of the routine declaration of
+// type t in the frame callee and runs its block
+func (r *EvaluatorVisitor) run(node ast.Node, name string, declaration *ast.RoutineDeclaration, t procType, callee *frame, arguments []ast.Node) (any, error) {
+	switch {
+	case len(arguments) != len(t.Params):
+		return nil, newRuntimeError(node, "Wrong number of arguments for '%v'", name)
+	case r.depth >= maxCallDepth:
+		return nil, newKindError(node, StackOverflow, "Stack overflow")
+	}
+
+	for i, param := range t.Params {
+		if err := r.bind(callee, param, arguments[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	resultName := strings.ToUpper(declaration.Name.Value)
+	if t.Result != nil {
+		callee.result = resultName
+		callee.types[resultName] = t.Result
+		if result := newValue(t.Result); result != nil {
+			callee.values[resultName] = result
+		}
+	}
+
+	caller, withs := r.frame, r.withs
+	r.frame, r.withs = callee, nil
+	r.depth++
+	defer func() {
+		r.frame, r.withs = caller, withs
+		r.depth--
+	}()
+
+	_, err := r.visitBlock(declaration.Block)
+	switch err.(type) {
+	case nil, exitSignal:
+	case breakSignal, continueSignal, gotoSignal:
+		return nil, runtimeErrorAt(node, err)
+	default:
+		if len(r.units) > 0 {
+			err = inUnit(callee.unitPath(), err)
+		}
+		return nil, err
+	}
+	if t.Result == nil {
+		return nil, nil
+	}
+
+	result, ok := callee.values[resultName]
+	if !ok {
+		return nil, newRuntimeError(node, "Result of function '%v' is not set", declaration.Name.Value)
+	}
+	return result, nil
+}
+
+// bind passes argument to param in the frame of the called routine. A
+// VAR parameter refers to the variable argument, the value of any other
+// argument is copied.
+func (r *EvaluatorVisitor) bind(callee *frame, param procParam, argument ast.Node) error {
+	name := strings.ToUpper(param.Name)
+	if param.IsVar {
+		variable, err := r.referenceTo(argument)
+		if err != nil {
+			return err
+		}
+		if variable.declared != nil && typeSignature(variable.declared) != typeSignature(param.Type) {
+			return newRuntimeError(argument, "Incompatible types: got %v expected %v", variable.declared, param.Type)
+		}
+		callee.values[name] = variable
+		return nil
+	}
+
+	value, err := r.valueFor(argument, param.Type)
+	if err != nil {
+		return err
+	}
+	converted, err := r.convert(argument, value, param.Type)
+	if err != nil {
+		return err
+	}
+	callee.types[name] = param.Type
+	if converted != nil {
+		callee.values[name] = converted
+	}
+	return nil
+}
+
+// convertRoutine makes the routine value fit a procedural variable of the
+// declared type, which it does if the signatures are the same. NIL fits
+// any procedural variable.
+func (r *EvaluatorVisitor) convertRoutine(node ast.Node, value any, declared procType) (any, error) {
+	switch v := value.(type) {
+	case routineValue:
+		if v.Type.signature() == declared.signature() {
+			return v, nil
+		}
+	case pointerValue:
+		if v.Address == 0 && v.Type.Target == "" {
+			return routineValue{Type: declared}, nil
+		}
+	}
+	return nil, newRuntimeError(node, "Incompatible types: got %v expected %v", typeName(value), declared)
+}
@@ -0,0 +1,2224 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+)
+
+// SemanticAnalyzer checks declarations and the use of identifiers before a
+// program is evaluated.
+type SemanticAnalyzer struct {
+	CurrentScope *ScopedSymbolTable
+	// number of enclosing exception handlers, a bare RAISE is only
+	// allowed in one
+	handlers int
+	// number of enclosing loops of the routine being checked, Break and
+	// Continue are only allowed in one
+	loops int
+	// result type of the function being checked, nil in procedures and
+	// the program
+	result Symbol
+	// class of the method being checked, nil outside of methods
+	class *ClassTypeSymbol
+	// Units finds the units used by the program, none can be used if it
+	// is nil
+	Units *Units
+	// scopes holding what the units checked so far export, keyed by their
+	// upper case name, nil while a unit is being checked
+	units map[string]*ScopedSymbolTable
+	// scope of the IMPLEMENTATION section of the unit being checked and
+	// the routines declared by its INTERFACE section and not implemented
+	// yet
+	implementation *ScopedSymbolTable
+	headings       map[string]RoutineSymbol
+	// units whose IMPLEMENTATION section is left to check and the number
+	// of INTERFACE sections being checked
+	pending    []*pendingUnit
+	interfaces int
+}
+
+func (r *SemanticAnalyzer) visitProgram(node ast.Program) error {
+	enclosingScope := r.CurrentScope
+	defer func() {
+		r.CurrentScope = enclosingScope
+	}()
+
+	if err := checkUses(node.Uses); err != nil {
+		return err
+	}
+	uses, err := r.useUnits(node.Uses, r.CurrentScope)
+	if err != nil {
+		return err
+	}
+	r.CurrentScope = NewScopedSymbolTable("global", uses.ScopeLevel+1, uses)
+	return r.visitBlock(node.Block)
+}
+
+// checkUses reports a unit named twice by the USES clauses of a file
+func checkUses(clauses ...[]ast.Var) error {
+	seen := map[string]bool{}
+	for _, uses := range clauses {
+		for _, v := range uses {
+			key := strings.ToUpper(v.Value)
+			if seen[key] {
+				return newSemanticError(v, "Duplicate identifier '%v' found", v.Value)
+			}
+			seen[key] = true
+		}
+	}
+	return nil
+}
+
+// useUnits returns a scope enclosed by enclosing holding what the units
+// named by a USES clause export, a unit hiding what the ones before it
+// export
+func (r *SemanticAnalyzer) useUnits(uses []ast.Var, enclosing *ScopedSymbolTable) (*ScopedSymbolTable, error) {
+	scope := NewScopedSymbolTable("uses", enclosing.ScopeLevel+1, enclosing)
+	for _, v := range uses {
+		exports, err := r.useUnit(v)
+		if err != nil {
+			return nil, err
+		}
+		for name, symbol := range exports.symbols {
+			scope.symbols[name] = symbol
+		}
+	}
+	return scope, nil
+}
+
+// useUnit checks the unit name unless this happened before and returns
+// the scope holding what it exports. Errors in the file of the unit are
+// reported as UnitError.
+func (r *SemanticAnalyzer) useUnit(name ast.Var) (*ScopedSymbolTable, error) {
+	key := strings.ToUpper(name.Value)
+	if exports, ok := r.units[key]; ok {
+		if exports == nil {
+			return nil, newSemanticError(name, "Circular unit reference to '%v'", name.Value)
+		}
+		return exports, nil
+	}
+
+	var file *unitFile
+	var err error
+	if r.Units != nil {
+		file, err = r.Units.load(name.Value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, newSemanticError(name, "Can't find unit '%v'", name.Value)
+	}
+
+	r.units[key] = nil
+	unit, err := r.visitInterface(file.node)
+	if err != nil {
+		delete(r.units, key)
+		return nil, inUnit(file.path, err)
+	}
+	unit.path = file.path
+	r.units[key] = unit.exports
+	r.pending = append(r.pending, unit)
+	if err := r.visitPending(); err != nil {
+		return nil, err
+	}
+	return unit.exports, nil
+}
+
+// pendingUnit is a unit whose INTERFACE section is checked and whose
+// IMPLEMENTATION section is not yet
+type pendingUnit struct {
+	path string
+	node ast.Unit
+	// scopes of the units named by the INTERFACE section and of the
+	// INTERFACE section itself
+	uses    *ScopedSymbolTable
+	exports *ScopedSymbolTable
+	// routines declared by the INTERFACE section
+	headings map[string]RoutineSymbol
+}
+
+// visitUnit checks a unit, then the IMPLEMENTATION sections of the units
+// it uses
+func (r *SemanticAnalyzer) visitUnit(node ast.Unit) error {
+	unit, err := r.visitInterface(node)
+	if err != nil {
+		return err
+	}
+	if err := r.visitImplementation(unit); err != nil {
+		return err
+	}
+	return r.visitPending()
+}
+
+// visitPending checks the IMPLEMENTATION sections of the pending units
+// once no INTERFACE section is being checked, so that the units a unit
+// uses in its INTERFACE section may use it in their IMPLEMENTATION section
+func (r *SemanticAnalyzer) visitPending() error {
+	for r.interfaces == 0 && len(r.pending) > 0 {
+		unit := r.pending[0]
+		r.pending = r.pending[1:]
+		if err := r.visitImplementation(unit); err != nil {
+			return inUnit(unit.path, err)
+		}
+	}
+	return nil
+}
+
+// visitInterface checks the INTERFACE section of a unit, where only the
+// units named by its USES clause are visible
+func (r *SemanticAnalyzer) visitInterface(node ast.Unit) (*pendingUnit, error) {
+	enclosingScope := r.CurrentScope
+	r.interfaces++
+	defer func() {
+		r.CurrentScope = enclosingScope
+		r.interfaces--
+	}()
+
+	if err := checkUses(node.InterfaceUses, node.ImplementationUses); err != nil {
+		return nil, err
+	}
+	builtins := r.CurrentScope
+	for builtins.EnclosingScope != nil {
+		builtins = builtins.EnclosingScope
+	}
+	uses, err := r.useUnits(node.InterfaceUses, builtins)
+	if err != nil {
+		return nil, err
+	}
+	unit := &pendingUnit{
+		node:     node,
+		uses:     uses,
+		exports:  NewScopedSymbolTable(node.Name, uses.ScopeLevel+1, uses),
+		headings: map[string]RoutineSymbol{},
+	}
+	r.CurrentScope = unit.exports
+	if err := r.visitDeclarations(node.Interface); err != nil {
+		return nil, err
+	}
+
+	for _, v := range node.Headings {
+		name := v.Name.Value
+		if _, ok := r.CurrentScope.Lookup(name, true); ok {
+			return nil, newSemanticError(v.Name, "Duplicate identifier '%v' found", name)
+		}
+		routineType, err := r.routineTypeSymbol(v.Params, v.Result)
+		if err != nil {
+			return nil, err
+		}
+		heading := RoutineSymbol{Name: name, Type: routineType}
+		r.CurrentScope.Insert(heading)
+		unit.headings[strings.ToUpper(name)] = heading
+	}
+	return unit, nil
+}
+
+// visitImplementation checks the IMPLEMENTATION section of a unit in a
+// scope enclosed by the one of its INTERFACE section, which encloses the
+// units named by both USES clauses meanwhile
+func (r *SemanticAnalyzer) visitImplementation(unit *pendingUnit) error {
+	enclosingScope, implementation, headings := r.CurrentScope, r.implementation, r.headings
+	defer func() {
+		r.CurrentScope, r.implementation, r.headings = enclosingScope, implementation, headings
+	}()
+
+	node := unit.node
+	implementationUses, err := r.useUnits(node.ImplementationUses, unit.uses)
+	if err != nil {
+		return err
+	}
+	unit.exports.EnclosingScope = implementationUses
+	defer func() {
+		unit.exports.EnclosingScope = unit.uses
+	}()
+	r.headings = unit.headings
+	r.implementation = NewScopedSymbolTable(node.Name, unit.exports.ScopeLevel+1, unit.exports)
+	r.CurrentScope = r.implementation
+	if err := r.visitBlock(node.Implementation); err != nil {
+		return err
+	}
+	if err := r.visitStatements(node.Finalization); err != nil {
+		return err
+	}
+
+	for _, v := range node.Headings {
+		if _, ok := r.headings[strings.ToUpper(v.Name.Value)]; ok {
+			return newSemanticError(v.Name, "Routine '%v' of unit %v is not implemented", v.Name.Value, node.Name)
+		}
+	}
+	return r.checkMethodsImplemented(node.Interface)
+}
+
+func (r *SemanticAnalyzer) visitBlock(node ast.Block) error {
+	for _, v := range node.Labels {
+		if err := r.visitLabelDeclaration(v); err != nil {
+			return err
+		}
+	}
+	if err := r.visitDeclarations(node); err != nil {
+		return err
+	}
+	if err := r.checkMethodsImplemented(node); err != nil {
+		return err
+	}
+	if err := r.checkLabels(node.Compound); err != nil {
+		return err
+	}
+	return r.visitCompound(node.Compound)
+}
+
+// visitDeclarations checks the declarations of a block in source order
+func (r *SemanticAnalyzer) visitDeclarations(node ast.Block) error {
+	for _, v := range node.Declarations {
+		var err error
+		switch declaration := v.(type) {
+		case ast.ConstDeclaration:
+			err = r.visitConstDeclaration(declaration)
+		case ast.TypeDeclaration:
+			err = r.visitTypeDeclaration(declaration)
+		case ast.VarDeclaration:
+			err = r.visitVarDeclaration(declaration)
+		case ast.RoutineDeclaration:
+			err = r.visitRoutineDeclaration(declaration)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return r.checkPointerTargets(node)
+}
+
+// checkPointerTargets checks that the types pointers declared in the block
+// point to exist, which is only known once all types are declared
+func (r *SemanticAnalyzer) checkPointerTargets(node ast.Block) error {
+	var err error
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case ast.Compound, ast.RoutineDeclaration:
+			return false
+		case ast.PointerType:
+			if err == nil {
+				_, err = r.typeSymbol(v.Target)
+			}
+		}
+		return err == nil
+	})
+	return err
+}
+
+// visitRoutineDeclaration declares a routine and checks its body in a
+// scope holding the parameters and, for a function, the result variable.
+// A routine of the IMPLEMENTATION section of a unit implements the one of
+// the same name its INTERFACE section declares, if any.
+func (r *SemanticAnalyzer) visitRoutineDeclaration(node ast.RoutineDeclaration) error {
+	if node.Class != nil {
+		return r.visitMethodImplementation(node)
+	}
+
+	name := node.Name.Value
+	if _, ok := r.CurrentScope.Lookup(name, true); ok {
+		return newSemanticError(node.Name, "Duplicate identifier '%v' found", name)
+	}
+
+	routineType, err := r.routineTypeSymbol(node.Params, node.Result)
+	if err != nil {
+		return err
+	}
+	if heading, declared := r.headings[strings.ToUpper(name)]; declared && r.CurrentScope == r.implementation {
+		if routineType.Signature() != heading.Type.Signature() {
+			return newSemanticError(node.Name, "Implementation of '%v' does not match its declaration in the interface", name)
+		}
+		delete(r.headings, strings.ToUpper(name))
+	}
+	r.CurrentScope.Insert(RoutineSymbol{Name: name, Type: routineType})
+
+	routineScope := NewScopedSymbolTable(name, r.CurrentScope.ScopeLevel+1, r.CurrentScope)
+	loops, result := r.loops, r.result
+	r.CurrentScope, r.loops, r.result = routineScope, 0, routineType.Result
+	defer func() {
+		r.CurrentScope, r.loops, r.result = routineScope.EnclosingScope, loops, result
+	}()
+
+	if routineType.Result != nil {
+		r.CurrentScope.Insert(VarSymbol{Name: name, Type: routineType.Result, Result: true})
+	}
+	for i, v := range routineType.Params {
+		if _, ok := r.CurrentScope.Lookup(v.Name, true); ok {
+			return newSemanticError(node.Params[i].Variable, "Duplicate identifier '%v' found", v.Name)
+		}
+		r.CurrentScope.Insert(VarSymbol{Name: v.Name, Type: v.Type})
+	}
+	return r.visitBlock(node.Block)
+}
+
+// routineTypeSymbol returns the type of a routine taking params and
+// returning a value of the type result, nil for procedures
+func (r *SemanticAnalyzer) routineTypeSymbol(params []ast.Param, result ast.Node) (ProceduralTypeSymbol, error) {
+	var routineType ProceduralTypeSymbol
+	for _, v := range params {
+		paramType, err := r.typeSymbol(v.TypeSpec)
+		if err != nil {
+			return ProceduralTypeSymbol{}, err
+		}
+		routineType.Params = append(routineType.Params, ParamSymbol{Name: v.Variable.Value, Type: paramType, IsVar: v.IsVar()})
+	}
+
+	if result != nil {
+		resultType, err := r.typeSymbol(result)
+		if err != nil {
+			return ProceduralTypeSymbol{}, err
+		}
+		routineType.Result = resultType
+	}
+	return routineType, nil
+}
+
+func (r *SemanticAnalyzer) visitVarDeclaration(node ast.VarDeclaration) error {
+	typeSymbol, err := r.typeSymbol(node.TypeSpec)
+	if err != nil {
+		return err
+	}
+
+	varName := node.Variable.Value
+	if _, ok := r.CurrentScope.Lookup(varName, true); ok {
+		return newSemanticError(node.Variable, "Duplicate identifier '%v' found", varName)
+	}
+
+	r.CurrentScope.Insert(VarSymbol{Name: varName, Type: typeSymbol})
+	return nil
+}
+
+// visitConstDeclaration evaluates a constant. The value of a typed
+// constant is converted to its type, an untyped one has the type of its
+// value.
+func (r *SemanticAnalyzer) visitConstDeclaration(node ast.ConstDeclaration) error {
+	name := node.Name.Value
+	if _, ok := r.CurrentScope.Lookup(name, true); ok {
+		return newSemanticError(node.Name, "Duplicate identifier '%v' found", name)
+	}
+
+	value, err := r.constant(node.Value)
+	if err != nil {
+		return err
+	}
+
+	constType := r.valueType(value)
+	if node.TypeSpec != nil {
+		if constType, err = r.typeSymbol(node.TypeSpec); err != nil {
+			return err
+		}
+		if value, err = r.convertConstant(node.Value, value, constType); err != nil {
+			return err
+		}
+	}
+
+	r.CurrentScope.Insert(ConstSymbol{Name: name, Type: constType, Value: value})
+	return nil
+}
+
+// convertConstant makes the constant value fit the type t like an
+// assignment does
+func (r *SemanticAnalyzer) convertConstant(node ast.Node, value any, t Symbol) (any, error) {
+	base := baseSymbol(t)
+	name := typeName(value)
+	if set, ok := t.(SetTypeSymbol); ok {
+		return r.convertSetConstant(node, value, set)
+	}
+
+	switch {
+	case name == base.GetName():
+	case name == "INTEGER" && base.GetName() == "REAL":
+		value = float64(value.(int))
+	case name == "CHAR" && base.GetName() == "STRING":
+		value = string([]byte{value.(byte)})
+	default:
+		return nil, newSemanticError(node, "Incompatible types: got %v expected %v", name, t.GetName())
+	}
+
+	if subrange, ok := t.(SubrangeTypeSymbol); ok {
+		n, _ := ordinalValue(value)
+		low, _ := ordinalValue(subrange.Low)
+		high, _ := ordinalValue(subrange.High)
+		if n < low || n > high {
+			return nil, newSemanticError(node, "Range check error: %v out of range %v..%v", FormatValue(value), FormatValue(subrange.Low), FormatValue(subrange.High))
+		}
+	}
+	return value, nil
+}
+
+// convertSetConstant checks that the elements of the constant set value
+// belong to the element type of t
+func (r *SemanticAnalyzer) convertSetConstant(node ast.Node, value any, t SetTypeSymbol) (any, error) {
+	set, ok := value.(setValue)
+	element, low, high, _ := ordinalSymbolBounds(t.Element)
+	if !ok || (set.Element != nil && set.Element.String() != element.GetName()) {
+		return nil, newSemanticError(node, "Incompatible types: got %v expected %v", typeName(value), t.GetName())
+	}
+
+	lowOrdinal, _ := ordinalValue(low)
+	highOrdinal, _ := ordinalValue(high)
+	for _, n := range set.elements() {
+		if n < lowOrdinal || n > highOrdinal {
+			return nil, newSemanticError(node, "Range check error: set element %v out of range %v..%v", set.element(n), FormatValue(low), FormatValue(high))
+		}
+	}
+	return set, nil
+}
+
+// valueType returns the type of a constant value
+func (r *SemanticAnalyzer) valueType(value any) Symbol {
+	switch v := value.(type) {
+	case enumValue:
+		return EnumTypeSymbol{Type: v.Type}
+	case setValue:
+		if v.Element == nil {
+			return SetTypeSymbol{Name: "SET"}
+		}
+		return SetTypeSymbol{Element: r.valueType(ordinalSample(v.Element))}
+	}
+	return r.builtinType(typeName(value))
+}
+
+// visitTypeDeclaration declares the name of a TYPE declaration. A type
+// constructor gets the name, e.g. in Point = RECORD ... END, while a type
+// name, as in Number = INTEGER, makes the name an alias.
+func (r *SemanticAnalyzer) visitTypeDeclaration(node ast.TypeDeclaration) error {
+	name := node.Name.Value
+	if _, ok := r.CurrentScope.Lookup(name, true); ok {
+		return newSemanticError(node.Name, "Duplicate identifier '%v' found", name)
+	}
+	if class, isClass := node.TypeSpec.(ast.ClassType); isClass {
+		return r.visitClassType(name, class)
+	}
+
+	typeSymbol, err := r.typeSymbol(node.TypeSpec)
+	if err != nil {
+		return err
+	}
+
+	if _, isAlias := node.TypeSpec.(ast.TypeSpec); !isAlias {
+		switch v := typeSymbol.(type) {
+		case ArrayTypeSymbol:
+			v.Name = name
+			typeSymbol = v
+		case RecordTypeSymbol:
+			v.Name = name
+			typeSymbol = v
+		case SubrangeTypeSymbol:
+			v.Name = name
+			typeSymbol = v
+		case SetTypeSymbol:
+			v.Name = name
+			typeSymbol = v
+		case PointerTypeSymbol:
+			v.Name = name
+			typeSymbol = v
+		case ProceduralTypeSymbol:
+			v.Name = name
+			typeSymbol = v
+		case EnumTypeSymbol:
+			v.Type.Name = name
+		}
+	}
+	r.CurrentScope.Insert(TypeAliasSymbol{Name: name, Type: typeSymbol})
+	return nil
+}
+
+// typeSymbol returns the type described by a type specification
+func (r *SemanticAnalyzer) typeSymbol(node ast.Node) (Symbol, error) {
+	switch n := node.(type) {
+	case ast.TypeSpec:
+		typeSymbol, ok := r.CurrentScope.Lookup(n.Value, false)
+		if !ok {
+			return nil, newSemanticError(n, "Unknown type '%v'", n.Value)
+		}
+		switch v := typeSymbol.(type) {
+		case BuiltinTypeSymbol, *ClassTypeSymbol:
+			return v, nil
+		case TypeAliasSymbol:
+			return v.Type, nil
+		}
+		return nil, newSemanticError(n, "'%v' is not a type", n.Value)
+
+	case ast.ArrayType:
+		return r.arrayTypeSymbol(n)
+	case ast.RecordType:
+		return r.recordTypeSymbol(n)
+	case ast.EnumType:
+		return r.enumTypeSymbol(n)
+	case ast.Subrange:
+		return r.subrangeTypeSymbol(n)
+	case ast.SetType:
+		return r.setTypeSymbol(n)
+	case ast.PointerType:
+		return PointerTypeSymbol{Target: n.Target.GetToken().Text()}, nil
+	case ast.ProceduralType:
+		return r.routineTypeSymbol(n.Params, n.Result)
+	case ast.ClassType:
+		return nil, newSemanticError(node, "Class types have to be declared in a TYPE section")
+	}
+	return nil, newSemanticError(node, "Type expected")
+}
+
+// setTypeSymbol checks that the elements of SET OF T have the ordinal
+// numbers 0..maxSetOrdinal
+func (r *SemanticAnalyzer) setTypeSymbol(node ast.SetType) (Symbol, error) {
+	element, err := r.typeSymbol(node.Element)
+	if err != nil {
+		return nil, err
+	}
+
+	_, low, high, ok := ordinalSymbolBounds(element)
+	if ok {
+		lowOrdinal, _ := ordinalValue(low)
+		highOrdinal, _ := ordinalValue(high)
+		ok = lowOrdinal >= 0 && highOrdinal <= maxSetOrdinal
+	}
+	if !ok {
+		return nil, newSemanticError(node.Element, "Illegal set element type %v", element.GetName())
+	}
+	return SetTypeSymbol{Element: element}, nil
+}
+
+// enumTypeSymbol declares the values of an enumeration as constants.
+// Variables declared together, as in VAR a, b: (x, y), share the type.
+func (r *SemanticAnalyzer) enumTypeSymbol(node ast.EnumType) (Symbol, error) {
+	result := EnumTypeSymbol{Type: &enumType{}}
+	for _, v := range node.Values {
+		result.Type.Values = append(result.Type.Values, v.Value)
+	}
+
+	if symbol, ok := r.CurrentScope.Lookup(node.Values[0].Value, true); ok {
+		if first, isConst := symbol.(ConstSymbol); isConst && first.Type.GetName() == result.GetName() {
+			return first.Type, nil
+		}
+	}
+
+	for i, v := range node.Values {
+		if _, ok := r.CurrentScope.Lookup(v.Value, true); ok {
+			return nil, newSemanticError(v, "Duplicate identifier '%v' found", v.Value)
+		}
+		r.CurrentScope.Insert(ConstSymbol{Name: v.Value, Type: result, Value: enumValue{Type: result.Type, Ordinal: i}})
+	}
+	return result, nil
+}
+
+func (r *SemanticAnalyzer) subrangeTypeSymbol(node ast.Subrange) (SubrangeTypeSymbol, error) {
+	low, err := r.constant(node.Low)
+	if err != nil {
+		return SubrangeTypeSymbol{}, err
+	}
+	high, err := r.constant(node.High)
+	if err != nil {
+		return SubrangeTypeSymbol{}, err
+	}
+
+	lowOrdinal, err := ordinalValue(low)
+	if err != nil {
+		return SubrangeTypeSymbol{}, newSemanticError(node.Low, "%v", err)
+	}
+	highOrdinal, err := ordinalValue(high)
+	if err != nil {
+		return SubrangeTypeSymbol{}, newSemanticError(node.High, "%v", err)
+	}
+	if typeName(low) != typeName(high) {
+		return SubrangeTypeSymbol{}, newSemanticError(node, "Incompatible types: got %v expected %v", typeName(high), typeName(low))
+	}
+	if lowOrdinal > highOrdinal {
+		return SubrangeTypeSymbol{}, newSemanticError(node, "High range limit < low range limit")
+	}
+
+	return SubrangeTypeSymbol{Low: low, High: high, Base: r.valueType(low)}, nil
+}
+
+// ordinalSymbolBounds returns the type of the values of the ordinal type t
+// and its first and last value. INTEGER is not bounded this way as it is too
+// large to index an array.
+func ordinalSymbolBounds(t Symbol) (Symbol, any, any, bool) {
+	switch v := t.(type) {
+	case BuiltinTypeSymbol:
+		switch v.Name {
+		case "CHAR":
+			return v, byte(0), byte(math.MaxUint8), true
+		case "BOOLEAN":
+			return v, false, true, true
+		}
+	case EnumTypeSymbol:
+		return v, enumValue{Type: v.Type}, enumValue{Type: v.Type, Ordinal: len(v.Type.Values) - 1}, true
+	case SubrangeTypeSymbol:
+		return v.Base, v.Low, v.High, true
+	}
+	return nil, nil, nil, false
+}
+
+// baseSymbol returns the type the values of t belong to, which is t itself
+// unless it is a subrange
+func baseSymbol(t Symbol) Symbol {
+	if subrange, ok := t.(SubrangeTypeSymbol); ok {
+		return subrange.Base
+	}
+	return t
+}
+
+func (r *SemanticAnalyzer) recordTypeSymbol(node ast.RecordType) (Symbol, error) {
+	var result RecordTypeSymbol
+	for _, v := range node.Fields {
+		if _, ok := result.Field(v.Variable.Value); ok {
+			return nil, newSemanticError(v.Variable, "Duplicate identifier '%v' found", v.Variable.Value)
+		}
+
+		fieldType, err := r.typeSymbol(v.TypeSpec)
+		if err != nil {
+			return nil, err
+		}
+		result.Fields = append(result.Fields, VarSymbol{Name: v.Variable.Value, Type: fieldType})
+	}
+	return result, nil
+}
+
+// arrayTypeSymbol returns ARRAY[r1, r2] OF T as ARRAY[r1] OF ARRAY[r2] OF T
+func (r *SemanticAnalyzer) arrayTypeSymbol(node ast.ArrayType) (Symbol, error) {
+	result, err := r.typeSymbol(node.Element)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(node.Ranges) - 1; i >= 0; i-- {
+		indexType, err := r.typeSymbol(node.Ranges[i])
+		if err != nil {
+			return nil, err
+		}
+		index, low, high, ok := ordinalSymbolBounds(indexType)
+		if !ok {
+			return nil, newSemanticError(node.Ranges[i], "Index range expected")
+		}
+
+		result = ArrayTypeSymbol{
+			Low:     low,
+			High:    high,
+			Index:   index,
+			Element: result,
+		}
+	}
+	return result, nil
+}
+
+// constant evaluates an expression whose value has to be known before the
+// program runs, such as an array bound. It may use other constants and
+// call builtin functions.
+func (r *SemanticAnalyzer) constant(node ast.Node) (any, error) {
+	evaluator := NewEvaluatorVisitor(strings.NewReader(""), io.Discard)
+
+	var err error
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case nil, ast.IntNode, ast.RealNode, ast.StringNode, ast.UnaryOperation, ast.BinaryOperation, ast.SetConstructor, ast.Subrange:
+			return err == nil
+		case ast.Var:
+			if symbol, ok := r.CurrentScope.Lookup(v.Value, false); ok {
+				if constant, isConst := symbol.(ConstSymbol); isConst {
+					evaluator.GloabalScope[strings.ToUpper(v.Value)] = constant.Value
+					return err == nil
+				}
+			}
+		case ast.FunctionCall:
+			if routine, ok := builtinRoutines[strings.ToUpper(v.Name)]; ok && routine.function {
+				return err == nil
+			}
+		}
+		if err == nil {
+			err = newSemanticError(n, "Constant expression expected")
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := evaluator.Visit(node)
+	var runtimeError RuntimeError
+	if errors.As(err, &runtimeError) {
+		return nil, SemanticError{Pos: runtimeError.Pos, Message: runtimeError.Message}
+	}
+	return value, err
+}
+
+func (r *SemanticAnalyzer) visitCompound(node ast.Compound) error {
+	for _, v := range node.Children {
+		if err := r.visit(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SemanticAnalyzer) visitAssign(node ast.AssignOperation) error {
+	target, err := r.typeOf(node.Left)
+	if err == nil && isProceduralSymbol(target) {
+		if err := r.checkRoutineValue(node.Right, target.(ProceduralTypeSymbol)); err != nil {
+			return err
+		}
+	} else if err := r.visit(node.Right); err != nil {
+		return err
+	}
+
+	if !r.isVariableReference(node.Left) {
+		return newSemanticError(node.Left, "Cannot assign to %v", node.Left.GetToken().Text())
+	}
+	if err := r.visit(node.Left); err != nil {
+		return err
+	}
+	return r.checkAssignable(node.Right, target)
+}
+
+// checkAssignable checks that the value of node can be stored in a
+// variable of type target, unless the type of either cannot be told
+func (r *SemanticAnalyzer) checkAssignable(node ast.Node, target Symbol) error {
+	valueType, err := r.typeOf(node)
+	if err != nil || target == nil || valueType == nil {
+		return err
+	}
+
+	ok, err := r.assignable(node, target, valueType)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newSemanticError(node, "Incompatible types: got %v expected %v", valueType.GetName(), target.GetName())
+	}
+	return nil
+}
+
+// assignable reports whether a value of type value fits a variable of type
+// target: an INTEGER fits a REAL and a CHAR a STRING, arrays, records and
+// enumerations have to be of the same type, pointers have to point to the
+// same type and objects have to be of a class descending from the one of
+// the variable, NIL fitting any pointer and class. Procedural values are
+// checked by checkRoutineValue.
+func (r *SemanticAnalyzer) assignable(node ast.Node, target Symbol, value Symbol) (bool, error) {
+	target, value = baseSymbol(target), baseSymbol(value)
+	switch t := target.(type) {
+	case BuiltinTypeSymbol:
+		_, ok := value.(BuiltinTypeSymbol)
+		return ok && matchesParam(t.Name, value, false, false), nil
+	case ArrayTypeSymbol, RecordTypeSymbol, EnumTypeSymbol:
+		return target.GetName() == value.GetName(), nil
+	case SetTypeSymbol:
+		v, ok := value.(SetTypeSymbol)
+		return ok && (v.Element == nil || baseSymbol(v.Element).GetName() == baseSymbol(t.Element).GetName()), nil
+	case PointerTypeSymbol:
+		v, ok := value.(PointerTypeSymbol)
+		if !ok || v.Target == "" {
+			return ok, nil
+		}
+		return r.samePointerTargets(node, t, v)
+	case *ClassTypeSymbol:
+		return matchesClass(t, value, false), nil
+	}
+	return true, nil
+}
+
+// samePointerTargets reports whether two typed pointers point to values
+// of the same type
+func (r *SemanticAnalyzer) samePointerTargets(node ast.Node, left Symbol, right Symbol) (bool, error) {
+	leftTarget, err := r.pointerTarget(node, left)
+	if err != nil {
+		return false, err
+	}
+	rightTarget, err := r.pointerTarget(node, right)
+	if err != nil {
+		return false, err
+	}
+	return leftTarget.GetName() == rightTarget.GetName(), nil
+}
+
+// isVariableReference reports whether node denotes a variable or an
+// element of one, that can be assigned to or passed to a VAR parameter.
+// Unknown identifiers are reported when node is visited.
+func (r *SemanticAnalyzer) isVariableReference(node ast.Node) bool {
+	switch n := node.(type) {
+	case ast.Var:
+		symbol, ok := r.CurrentScope.Lookup(n.Value, false)
+		if !ok {
+			return true
+		}
+		_, isVar := symbol.(VarSymbol)
+		return isVar
+	case ast.Index:
+		return r.isVariableReference(n.Value)
+	case ast.FieldAccess:
+		// objects are shared, the fields of any of them are variables
+		if container, _ := r.typeOf(n.Value); container != nil {
+			if class, ok := container.(*ClassTypeSymbol); ok {
+				_, isField := class.Field(n.Field)
+				return isField
+			}
+		}
+		return r.isVariableReference(n.Value)
+	case ast.Dereference:
+		return true
+	}
+	return false
+}
+
+func (r *SemanticAnalyzer) visitIndex(node ast.Index) error {
+	if err := r.visit(node.Value); err != nil {
+		return err
+	}
+	for _, v := range node.Indices {
+		if err := r.visit(v); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.typeOf(node)
+	return err
+}
+
+func (r *SemanticAnalyzer) visitFieldAccess(node ast.FieldAccess) error {
+	if _, ok := r.classNamed(node.Value); ok {
+		return r.visitMethodCall(ast.NewMethodCall(node.Value, nil, node.GetToken()))
+	}
+	if err := r.visit(node.Value); err != nil {
+		return err
+	}
+
+	_, err := r.typeOf(node)
+	return err
+}
+
+// fieldType returns the type of the named field of a record or an object
+// of type container
+func (r *SemanticAnalyzer) fieldType(node ast.Node, container Symbol, name string) (Symbol, error) {
+	if class, ok := container.(*ClassTypeSymbol); ok {
+		return r.memberType(node, class, name)
+	}
+
+	record, ok := container.(RecordTypeSymbol)
+	if !ok {
+		return nil, newSemanticError(node, "Cannot access field '%v' of a value of type %v", name, container.GetName())
+	}
+
+	field, ok := record.Field(name)
+	if !ok {
+		return nil, newSemanticError(node, "Unknown record field '%v'", name)
+	}
+	return field.Type, nil
+}
+
+// visitWithStatement checks the body in a scope holding the fields of the
+// records, later records hiding the fields of earlier ones
+func (r *SemanticAnalyzer) visitWithStatement(node ast.WithStatement) error {
+	enclosingScope := r.CurrentScope
+	defer func() {
+		r.CurrentScope = enclosingScope
+	}()
+
+	for _, v := range node.Records {
+		if !r.isVariableReference(v) {
+			return newSemanticError(v, "Variable identifier expected")
+		}
+		if err := r.visit(v); err != nil {
+			return err
+		}
+
+		recordType, err := r.typeOf(v)
+		if err != nil {
+			return err
+		}
+		record, ok := recordType.(RecordTypeSymbol)
+		if !ok {
+			return newSemanticError(v, "Record type expected")
+		}
+
+		r.CurrentScope = NewScopedSymbolTable("with", r.CurrentScope.ScopeLevel+1, r.CurrentScope)
+		for _, field := range record.Fields {
+			r.CurrentScope.Insert(field)
+		}
+	}
+	return r.visit(node.Body)
+}
+
+// elementType returns the type of the elements of an array or string of
+// type container selected by index, the index expression node of type
+// indexType. Unknown types are nil.
+func (r *SemanticAnalyzer) elementType(node ast.Node, container Symbol, indexType Symbol) (Symbol, error) {
+	expected := r.builtinType("INTEGER")
+	result := r.builtinType("CHAR")
+	if array, ok := container.(ArrayTypeSymbol); ok {
+		expected, result = array.Index, array.Element
+	} else if container.GetName() != "STRING" {
+		return nil, newSemanticError(node, "Cannot index a value of type %v", container.GetName())
+	}
+
+	if indexType != nil && baseSymbol(indexType).GetName() != expected.GetName() {
+		return nil, newSemanticError(node, "Incompatible types: got %v expected %v", indexType.GetName(), expected.GetName())
+	}
+	return result, nil
+}
+
+func (r *SemanticAnalyzer) visitVar(node ast.Var) error {
+	symbol, ok := r.CurrentScope.Lookup(node.Value, false)
+	if !ok {
+		return newSemanticError(node, "Identifier not found '%v'", node.Value)
+	}
+	switch v := symbol.(type) {
+	case VarSymbol, ConstSymbol:
+		return nil
+	case RoutineSymbol:
+		if v.Type.Result != nil {
+			return r.visitCall(node, node.Value, v.Type, nil)
+		}
+	case *MethodSymbol:
+		if v.Type.Result != nil {
+			return r.visitCall(node, node.Value, v.Type, nil)
+		}
+	}
+	return newSemanticError(node, "'%v' is not a variable", node.Value)
+}
+
+// isOutputProcedure reports whether name is one of the procedures accepting
+// value:width:precision arguments
+func isOutputProcedure(name string) bool {
+	return strings.EqualFold(name, "WRITE") || strings.EqualFold(name, "WRITELN")
+}
+
+// isInputProcedure reports whether name is one of the procedures assigning
+// to their arguments
+func isInputProcedure(name string) bool {
+	return strings.EqualFold(name, "READ") || strings.EqualFold(name, "READLN")
+}
+
+// isMemoryProcedure reports whether name is one of the procedures
+// allocating and releasing the variables pointers point to
+func isMemoryProcedure(name string) bool {
+	return strings.EqualFold(name, "NEW") || strings.EqualFold(name, "DISPOSE")
+}
+
+func (r *SemanticAnalyzer) visitProcedureCall(node ast.ProcedureCall) error {
+	symbol, ok := r.lookupRoutine(node.Name)
+	if !ok {
+		return newSemanticError(node, "Identifier not found '%v'", node.Name)
+	}
+	if routineType, isRoutine := routineTypeOf(symbol); isRoutine {
+		return r.visitCall(node, node.Name, routineType, node.Arguments)
+	}
+	if _, isProcedure := symbol.(BuiltinProcedureSymbol); !isProcedure {
+		return newSemanticError(node, "'%v' is not a procedure", node.Name)
+	}
+	if isMemoryProcedure(node.Name) {
+		return r.visitMemoryProcedure(node)
+	}
+	if isControlProcedure(node.Name) {
+		return r.visitControlProcedure(node)
+	}
+	return r.visitArguments(node, node.Name, node.Arguments)
+}
+
+// visitMemoryProcedure checks New(p) and Dispose(p), p having to be a
+// pointer and, for New, a variable
+func (r *SemanticAnalyzer) visitMemoryProcedure(node ast.ProcedureCall) error {
+	if len(node.Arguments) != 1 {
+		return newSemanticError(node, "Wrong number of arguments for '%v'", node.Name)
+	}
+	argument := node.Arguments[0]
+	if strings.EqualFold(node.Name, "NEW") && !r.isVariableReference(argument) {
+		return newSemanticError(argument, "Variable identifier expected")
+	}
+	if err := r.visit(argument); err != nil {
+		return err
+	}
+
+	argumentType, err := r.typeOf(argument)
+	if err != nil || argumentType == nil {
+		return err
+	}
+	if pointer, ok := baseSymbol(argumentType).(PointerTypeSymbol); !ok || pointer.Target == "" {
+		return newSemanticError(argument, "Pointer type expected, got %v", argumentType.GetName())
+	}
+	return nil
+}
+
+// pointerTarget returns the type of the variables pointer points to
+func (r *SemanticAnalyzer) pointerTarget(node ast.Node, pointer Symbol) (Symbol, error) {
+	target, ok := baseSymbol(pointer).(PointerTypeSymbol)
+	if !ok || target.Target == "" {
+		return nil, newSemanticError(node, "Cannot dereference a value of type %v", pointer.GetName())
+	}
+	return r.typeSymbol(ast.NewTypeSpec(lexer.BasicToken{TokenType: lexer.ID, TokenValue: target.Target, Pos: node.GetToken().Pos}))
+}
+
+func (r *SemanticAnalyzer) visitFunctionCall(node ast.FunctionCall) error {
+	symbol, ok := r.lookupRoutine(node.Name)
+	if !ok {
+		return newSemanticError(node, "Identifier not found '%v'", node.Name)
+	}
+	if routineType, isRoutine := routineTypeOf(symbol); isRoutine {
+		if routineType.Result == nil {
+			return newSemanticError(node, "'%v' is not a function", node.Name)
+		}
+		return r.visitCall(node, node.Name, routineType, node.Arguments)
+	}
+	if _, isFunction := symbol.(BuiltinFunctionSymbol); !isFunction {
+		return newSemanticError(node, "'%v' is not a function", node.Name)
+	}
+	return r.visitArguments(node, node.Name, node.Arguments)
+}
+
+// lookupRoutine looks up the routine a call refers to. The result
+// variables of the enclosing functions are skipped, so that a function can
+// call itself.
+func (r *SemanticAnalyzer) lookupRoutine(name string) (Symbol, bool) {
+	for scope := r.CurrentScope; scope != nil; scope = scope.EnclosingScope {
+		symbol, ok := scope.Lookup(name, true)
+		if result, isVar := symbol.(VarSymbol); !ok || (isVar && result.Result) {
+			continue
+		}
+		return symbol, true
+	}
+	return nil, false
+}
+
+// routineTypeOf returns the type of a symbol that can be called like a
+// routine declared by the program, which procedural variables can
+func routineTypeOf(symbol Symbol) (ProceduralTypeSymbol, bool) {
+	switch v := symbol.(type) {
+	case RoutineSymbol:
+		return v.Type, true
+	case *MethodSymbol:
+		return v.Type, true
+	case VarSymbol:
+		routineType, ok := v.Type.(ProceduralTypeSymbol)
+		return routineType, ok
+	}
+	return ProceduralTypeSymbol{}, false
+}
+
+func isProceduralSymbol(t Symbol) bool {
+	_, ok := t.(ProceduralTypeSymbol)
+	return ok
+}
+
+// visitCall checks the arguments of a call to a routine of type
+// routineType: their number, that VAR parameters receive variables and
+// that their types match the parameters
+func (r *SemanticAnalyzer) visitCall(node ast.Node, name string, routineType ProceduralTypeSymbol, arguments []ast.Node) error {
+	if len(arguments) != len(routineType.Params) {
+		return newSemanticError(node, "Wrong number of arguments for '%v'", name)
+	}
+
+	for i, v := range arguments {
+		param := routineType.Params[i]
+		if _, isFormatted := v.(ast.FormattedArgument); isFormatted {
+			return newSemanticError(v, "Format specifiers are only allowed in WRITE, WRITELN and Str")
+		}
+		if param.IsVar && !r.isVariableReference(v) {
+			return newSemanticError(v, "Variable identifier expected")
+		}
+		if routineType, ok := param.Type.(ProceduralTypeSymbol); ok {
+			if err := r.checkRoutineValue(v, routineType); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := r.visit(v); err != nil {
+			return err
+		}
+		argumentType, err := r.typeOf(v)
+		if err != nil {
+			return err
+		}
+		if class, ok := param.Type.(*ClassTypeSymbol); ok {
+			if !matchesClass(class, argumentType, param.IsVar) {
+				return newSemanticError(v, "Incompatible types: got %v expected %v", argumentType.GetName(), param.Type.GetName())
+			}
+			continue
+		}
+		if !matchesParam(baseSymbol(param.Type).GetName(), argumentType, param.IsVar, false) {
+			return newSemanticError(v, "Incompatible types: got %v expected %v", argumentType.GetName(), param.Type.GetName())
+		}
+	}
+	return nil
+}
+
+// checkRoutineValue checks that node can be stored in a procedural
+// variable of type t: it has to be NIL, a routine or a procedural value of
+// the same signature. A routine name stands for the routine rather than
+// for a call of it.
+func (r *SemanticAnalyzer) checkRoutineValue(node ast.Node, t ProceduralTypeSymbol) error {
+	var valueType Symbol
+	if variable, ok := node.(ast.Var); ok {
+		if symbol, ok := r.CurrentScope.Lookup(variable.Value, false); ok {
+			if routine, isRoutine := symbol.(RoutineSymbol); isRoutine {
+				valueType = routine.Type
+			}
+		}
+	}
+
+	if valueType == nil {
+		if err := r.visit(node); err != nil {
+			return err
+		}
+		var err error
+		if valueType, err = r.typeOf(node); err != nil || valueType == nil {
+			return err
+		}
+	}
+
+	if pointer, ok := valueType.(PointerTypeSymbol); ok && pointer.Target == "" {
+		return nil
+	}
+	if routineType, ok := valueType.(ProceduralTypeSymbol); !ok || routineType.Signature() != t.Signature() {
+		return newSemanticError(node, "Incompatible types: got %v expected %v", valueType.GetName(), t.GetName())
+	}
+	return nil
+}
+
+// visitArguments checks the arguments of a call to the builtin routine
+// name: their number, that VAR parameters receive variables and that only
+// output arguments are formatted
+func (r *SemanticAnalyzer) visitArguments(node ast.Node, name string, arguments []ast.Node) error {
+	routine, isRoutine := builtinRoutines[strings.ToUpper(name)]
+	if isRoutine && (len(arguments) < routine.minArgs || (routine.maxArgs >= 0 && len(arguments) > routine.maxArgs)) {
+		return newSemanticError(node, "Wrong number of arguments for '%v'", name)
+	}
+
+	for i, v := range arguments {
+		if (isInputProcedure(name) || routine.isVarParam(i)) && !r.isVariableReference(v) {
+			return newSemanticError(v, "Variable identifier expected")
+		}
+
+		formatted, isFormatted := v.(ast.FormattedArgument)
+		if !isFormatted {
+			if err := r.visit(v); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !isOutputProcedure(name) && !routine.isFormatParam(i) {
+			return newSemanticError(formatted, "Format specifiers are only allowed in WRITE, WRITELN and Str")
+		}
+		if err := r.visitFormattedArgument(formatted); err != nil {
+			return err
+		}
+	}
+
+	if isRoutine {
+		_, err := r.resolve(node, name, routine, arguments)
+		return err
+	}
+	return nil
+}
+
+// builtinType returns the symbol of a predeclared type
+func (r *SemanticAnalyzer) builtinType(name string) Symbol {
+	symbol, _ := r.CurrentScope.Lookup(name, false)
+	return symbol
+}
+
+// typeOf returns the type of an expression, nil if it cannot be told
+// before running the program
+func (r *SemanticAnalyzer) typeOf(node ast.Node) (Symbol, error) {
+	switch n := node.(type) {
+	case ast.IntNode:
+		return r.builtinType("INTEGER"), nil
+	case ast.RealNode:
+		return r.builtinType("REAL"), nil
+	case ast.StringNode:
+		if len(n.Value) == 1 {
+			return r.builtinType("CHAR"), nil
+		}
+		return r.builtinType("STRING"), nil
+	case ast.Var:
+		if symbol, ok := r.CurrentScope.Lookup(n.Value, false); ok {
+			switch v := symbol.(type) {
+			case VarSymbol:
+				return v.Type, nil
+			case ConstSymbol:
+				return v.Type, nil
+			case RoutineSymbol:
+				return v.Type.Result, nil
+			case *MethodSymbol:
+				return v.Type.Result, nil
+			}
+		}
+	case ast.Index:
+		valueType, err := r.typeOf(n.Value)
+		for _, v := range n.Indices {
+			if err != nil || valueType == nil {
+				return nil, err
+			}
+
+			indexType, err := r.typeOf(v)
+			if err != nil {
+				return nil, err
+			}
+			if valueType, err = r.elementType(v, valueType, indexType); err != nil {
+				return nil, err
+			}
+		}
+		return valueType, err
+	case ast.FieldAccess:
+		if class, ok := r.classNamed(n.Value); ok {
+			return class, nil
+		}
+		recordType, err := r.typeOf(n.Value)
+		if err != nil || recordType == nil {
+			return nil, err
+		}
+		return r.fieldType(n, recordType, n.Field)
+	case ast.UnaryOperation:
+		return r.typeOf(n.Right)
+	case ast.BinaryOperation:
+		return r.binaryOperationType(n)
+	case ast.FormattedArgument:
+		return r.typeOf(n.Value)
+	case ast.SetConstructor:
+		return r.setConstructorType(n)
+	case ast.NilNode:
+		return PointerTypeSymbol{}, nil
+	case ast.MethodCall:
+		return r.methodCallType(n)
+	case ast.InheritedCall:
+		if method, err := r.inheritedMethod(n); err == nil {
+			return method.Type.Result, nil
+		}
+	case ast.Dereference:
+		pointer, err := r.typeOf(n.Value)
+		if err != nil || pointer == nil {
+			return nil, err
+		}
+		return r.pointerTarget(n, pointer)
+	case ast.FunctionCall:
+		if symbol, ok := r.lookupRoutine(n.Name); ok {
+			if routineType, isRoutine := routineTypeOf(symbol); isRoutine {
+				return routineType.Result, nil
+			}
+		}
+		if routine, ok := builtinRoutines[strings.ToUpper(n.Name)]; ok {
+			return r.resolve(n, n.Name, routine, n.Arguments)
+		}
+	}
+	return nil, nil
+}
+
+// setConstructorType returns the type of [a, b..c], nil for the empty set
+// and if the type of an element cannot be told
+func (r *SemanticAnalyzer) setConstructorType(node ast.SetConstructor) (Symbol, error) {
+	var element Symbol
+	for _, v := range node.Elements {
+		bounds := []ast.Node{v}
+		if subrange, ok := v.(ast.Subrange); ok {
+			bounds = []ast.Node{subrange.Low, subrange.High}
+		}
+
+		for _, bound := range bounds {
+			boundType, err := r.typeOf(bound)
+			if err != nil || boundType == nil {
+				return nil, err
+			}
+			boundType = baseSymbol(boundType)
+			if _, _, _, ok := ordinalSymbolBounds(boundType); !ok && boundType.GetName() != "INTEGER" {
+				return nil, newSemanticError(bound, "Ordinal expression expected")
+			}
+			if element != nil && element.GetName() != boundType.GetName() {
+				return nil, newSemanticError(bound, "Incompatible types: got %v expected %v", boundType.GetName(), element.GetName())
+			}
+			element = boundType
+		}
+	}
+
+	if element == nil {
+		return nil, nil
+	}
+	return SetTypeSymbol{Element: element}, nil
+}
+
+func (r *SemanticAnalyzer) binaryOperationType(node ast.BinaryOperation) (Symbol, error) {
+	operation := node.GetToken().TokenType
+	if operation == lexer.AS {
+		if class, ok := r.classNamed(node.Right); ok {
+			return class, nil
+		}
+		return nil, nil
+	}
+	left, err := r.typeOf(node.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := r.typeOf(node.Right)
+	if err != nil {
+		return nil, err
+	}
+	left, right = operandType(left), operandType(right)
+
+	if leftSet, ok := left.(SetTypeSymbol); ok && operation != lexer.IN {
+		if rightSet, ok := right.(SetTypeSymbol); ok {
+			left, err = r.commonSetType(node, leftSet, rightSet)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	leftPointer, leftIsPointer := left.(PointerTypeSymbol)
+	rightPointer, rightIsPointer := right.(PointerTypeSymbol)
+	if leftIsPointer && rightIsPointer && leftPointer.Target != "" && rightPointer.Target != "" {
+		same, err := r.samePointerTargets(node, left, right)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			return nil, newSemanticError(node, "Incompatible types: got %v expected %v", right.GetName(), left.GetName())
+		}
+	}
+
+	if left == nil || right == nil {
+		switch {
+		case isRelational(operation), operation == lexer.IN:
+			return r.builtinType("BOOLEAN"), nil
+		case operation == lexer.FLOAT_DIV:
+			return r.builtinType("REAL"), nil
+		}
+		return nil, nil
+	}
+
+	result := operationType(operation, baseSymbol(left), baseSymbol(right))
+	if result == "" {
+		return nil, newSemanticError(node, "Operator %v is not defined for %v and %v", node.GetToken().Text(), left.GetName(), right.GetName())
+	}
+	if result == "SET" {
+		return left, nil
+	}
+	return r.builtinType(result), nil
+}
+
+// operandType returns the type of the value of an operand of type t, the
+// result type for procedural variables of functions without parameters,
+// which are called
+func operandType(t Symbol) Symbol {
+	if routine, ok := t.(ProceduralTypeSymbol); ok && routine.Result != nil && len(routine.Params) == 0 {
+		return routine.Result
+	}
+	return t
+}
+
+// operationType returns the name of the type of the result of the binary
+// operation on values of the types left and right, SET for the type of
+// left, empty if the operation is not defined for them. Sets are already
+// known to have elements of the same type and typed pointers to point to
+// the same type.
+func operationType(operation lexer.TokenType, left Symbol, right Symbol) string {
+	leftName, rightName := left.GetName(), right.GetName()
+	isText := func(name string) bool { return name == "STRING" || name == "CHAR" }
+	isNumber := func(name string) bool { return name == "INTEGER" || name == "REAL" }
+	_, leftIsSet := left.(SetTypeSymbol)
+	_, rightIsSet := right.(SetTypeSymbol)
+	_, leftIsPointer := left.(PointerTypeSymbol)
+	_, rightIsPointer := right.(PointerTypeSymbol)
+	_, leftIsEnum := left.(EnumTypeSymbol)
+	isObject := func(t Symbol) bool {
+		pointer, isPointer := t.(PointerTypeSymbol)
+		_, isClass := t.(*ClassTypeSymbol)
+		return isClass || (isPointer && pointer.Target == "")
+	}
+
+	switch {
+	case operation == lexer.IN:
+		set, ok := right.(SetTypeSymbol)
+		if ok && (set.Element == nil || baseSymbol(set.Element).GetName() == leftName) {
+			return "BOOLEAN"
+		}
+	case leftIsSet && rightIsSet:
+		switch operation {
+		case lexer.PLUS, lexer.MINUS, lexer.MUL:
+			return "SET"
+		case lexer.EQUAL, lexer.NOT_EQUAL, lexer.LESS_EQUAL, lexer.GREATER_EQUAL:
+			return "BOOLEAN"
+		}
+	case isProceduralSymbol(left) || isProceduralSymbol(right):
+		isRoutine := func(t Symbol) bool {
+			pointer, isPointer := t.(PointerTypeSymbol)
+			return isProceduralSymbol(t) || (isPointer && pointer.Target == "")
+		}
+		sameSignature := !isProceduralSymbol(left) || !isProceduralSymbol(right) ||
+			left.(ProceduralTypeSymbol).Signature() == right.(ProceduralTypeSymbol).Signature()
+		if (operation == lexer.EQUAL || operation == lexer.NOT_EQUAL) && isRoutine(left) && isRoutine(right) && sameSignature {
+			return "BOOLEAN"
+		}
+	case leftIsPointer && rightIsPointer, isObject(left) && isObject(right):
+		if operation == lexer.EQUAL || operation == lexer.NOT_EQUAL {
+			return "BOOLEAN"
+		}
+	case isRelational(operation):
+		if (isText(leftName) && isText(rightName)) || (isNumber(leftName) && isNumber(rightName)) ||
+			(leftName == "BOOLEAN" && rightName == "BOOLEAN") || (leftIsEnum && leftName == rightName) {
+			return "BOOLEAN"
+		}
+	case operation == lexer.PLUS && isText(leftName) && isText(rightName):
+		return "STRING"
+	case operation == lexer.FLOAT_DIV && isNumber(leftName) && isNumber(rightName):
+		return "REAL"
+	case leftName == "INTEGER" && rightName == "INTEGER":
+		return "INTEGER"
+	case isNumber(leftName) && isNumber(rightName) && operation != lexer.INTEGER_DIV:
+		return "REAL"
+	}
+	return ""
+}
+
+// commonSetType returns the type of the result of an operation on sets of
+// types left and right, whose elements have to be of the same type
+func (r *SemanticAnalyzer) commonSetType(node ast.Node, left SetTypeSymbol, right SetTypeSymbol) (Symbol, error) {
+	switch {
+	case left.Element == nil:
+		return right, nil
+	case right.Element == nil:
+		return left, nil
+	case baseSymbol(left.Element).GetName() != baseSymbol(right.Element).GetName():
+		return nil, newSemanticError(node, "Incompatible types: got %v expected %v", right.GetName(), left.GetName())
+	}
+	return left, nil
+}
+
+func isSetSymbol(t Symbol) bool {
+	_, ok := t.(SetTypeSymbol)
+	return ok
+}
+
+// matchesParam reports whether an argument of type argument can be passed
+// to a parameter of the named type. Unless exact is set an INTEGER may be
+// passed as REAL and a CHAR as STRING, but not to a VAR parameter.
+func matchesParam(param string, argument Symbol, isVar bool, exact bool) bool {
+	if argument == nil {
+		return true
+	}
+
+	argument = baseSymbol(argument)
+	name := argument.GetName()
+	switch {
+	case param == name:
+		return true
+	case param == anyOrdinal:
+		_, isEnum := argument.(EnumTypeSymbol)
+		return isEnum || name == "INTEGER" || name == "CHAR" || name == "BOOLEAN"
+	case param == anyReference:
+		_, isPointer := argument.(PointerTypeSymbol)
+		_, isClass := argument.(*ClassTypeSymbol)
+		return isPointer || isClass || isProceduralSymbol(argument)
+	case exact || isVar:
+		return false
+	}
+	return (param == "REAL" && name == "INTEGER") || (param == "STRING" && name == "CHAR")
+}
+
+// resolve chooses the overload of a builtin routine matching the types of
+// arguments, preferring exact matches, and returns its result type. The
+// result type is nil for procedures and if it cannot be told.
+func (r *SemanticAnalyzer) resolve(node ast.Node, name string, routine builtinRoutine, arguments []ast.Node) (Symbol, error) {
+	if len(routine.signatures) == 0 {
+		if routine.result == "" {
+			return nil, nil
+		}
+		return r.builtinType(routine.result), nil
+	}
+
+	types := make([]Symbol, len(arguments))
+	for i, v := range arguments {
+		argumentType, err := r.typeOf(v)
+		if err != nil {
+			return nil, err
+		}
+		types[i] = argumentType
+	}
+
+	for _, exact := range []bool{true, false} {
+		var matches []signature
+		for _, candidate := range routine.signatures {
+			if len(candidate.params) != len(types) {
+				continue
+			}
+
+			matched := true
+			for i, param := range candidate.params {
+				matched = matched && matchesParam(param, types[i], routine.isVarParam(i), exact)
+			}
+			if matched {
+				matches = append(matches, candidate)
+			}
+		}
+
+		if len(matches) == 0 {
+			continue
+		}
+		if len(matches) > 1 || matches[0].result == "" {
+			return nil, nil
+		}
+		if matches[0].result == sameAsArgument {
+			return types[0], nil
+		}
+		return r.builtinType(matches[0].result), nil
+	}
+
+	typeNames := make([]string, len(types))
+	for i, v := range types {
+		typeNames[i] = v.GetName()
+	}
+	return nil, newSemanticError(node, "Incompatible types in call to '%v': got (%v)", name, strings.Join(typeNames, ", "))
+}
+
+func (r *SemanticAnalyzer) visitFormattedArgument(node ast.FormattedArgument) error {
+	if err := r.visit(node.Value); err != nil {
+		return err
+	}
+	if err := r.visit(node.Width); err != nil {
+		return err
+	}
+	if node.Precision != nil {
+		return r.visit(node.Precision)
+	}
+	return nil
+}
+
+func (r *SemanticAnalyzer) visit(node ast.Node) error {
+	switch n := node.(type) {
+	case ast.Program:
+		return r.visitProgram(n)
+	case ast.Unit:
+		return r.visitUnit(n)
+	case ast.Block:
+		return r.visitBlock(n)
+	case ast.VarDeclaration:
+		return r.visitVarDeclaration(n)
+	case ast.ConstDeclaration:
+		return r.visitConstDeclaration(n)
+	case ast.TypeDeclaration:
+		return r.visitTypeDeclaration(n)
+	case ast.RoutineDeclaration:
+		return r.visitRoutineDeclaration(n)
+	case ast.Compound:
+		return r.visitCompound(n)
+	case ast.AssignOperation:
+		return r.visitAssign(n)
+	case ast.Var:
+		return r.visitVar(n)
+	case ast.BinaryOperation:
+		if operation := n.GetToken().TokenType; operation == lexer.IS || operation == lexer.AS {
+			return r.visitTypeTest(n)
+		}
+		if err := r.visit(n.Left); err != nil {
+			return err
+		}
+		if err := r.visit(n.Right); err != nil {
+			return err
+		}
+		_, err := r.typeOf(n)
+		return err
+	case ast.UnaryOperation:
+		if err := r.visit(n.Right); err != nil {
+			return err
+		}
+		operand, err := r.typeOf(n.Right)
+		if err != nil || operand == nil {
+			return err
+		}
+		if name := baseSymbol(operand).GetName(); name != "INTEGER" && name != "REAL" {
+			return newSemanticError(n, "Operator %v is not defined for %v", n.GetToken().Text(), operand.GetName())
+		}
+		return nil
+	case ast.ProcedureCall:
+		return r.visitProcedureCall(n)
+	case ast.FunctionCall:
+		return r.visitFunctionCall(n)
+	case ast.Index:
+		return r.visitIndex(n)
+	case ast.FieldAccess:
+		return r.visitFieldAccess(n)
+	case ast.WithStatement:
+		return r.visitWithStatement(n)
+	case ast.SetConstructor:
+		for _, v := range n.Elements {
+			if err := r.visit(v); err != nil {
+				return err
+			}
+		}
+		_, err := r.typeOf(n)
+		return err
+	case ast.Subrange:
+		if err := r.visit(n.Low); err != nil {
+			return err
+		}
+		return r.visit(n.High)
+	case ast.Dereference:
+		if err := r.visit(n.Value); err != nil {
+			return err
+		}
+		_, err := r.typeOf(n)
+		return err
+	case ast.MethodCall:
+		return r.visitMethodCall(n)
+	case ast.InheritedCall:
+		method, err := r.inheritedMethod(n)
+		if err != nil {
+			return err
+		}
+		return r.visitCall(n, n.Name, method.Type, n.Arguments)
+	case ast.TryExcept:
+		return r.visitTryExcept(n)
+	case ast.TryFinally:
+		if err := r.visitStatements(n.Body); err != nil {
+			return err
+		}
+		return r.visitStatements(n.Finally)
+	case ast.RaiseStatement:
+		return r.visitRaiseStatement(n)
+	case ast.IfStatement:
+		if err := r.visitCondition(n.Condition); err != nil {
+			return err
+		}
+		if err := r.visit(n.Then); err != nil {
+			return err
+		}
+		if n.Else != nil {
+			return r.visit(n.Else)
+		}
+		return nil
+	case ast.WhileStatement:
+		if err := r.visitCondition(n.Condition); err != nil {
+			return err
+		}
+		return r.visitLoopBody(n.Body)
+	case ast.RepeatStatement:
+		if err := r.visitLoopBody(n.Body...); err != nil {
+			return err
+		}
+		return r.visitCondition(n.Condition)
+	case ast.ForStatement:
+		return r.visitForStatement(n)
+	case ast.LabeledStatement:
+		return r.visit(n.Statement)
+	case ast.IntNode, ast.RealNode, ast.StringNode, ast.NilNode, ast.NoOp, ast.TypeSpec, ast.GotoStatement:
+		return nil
+	}
+
+	return fmt.Errorf("Cannot analyze node of unknown type %T", node)
+}
+
+// Analyze checks the tree rooted at node and returns a SemanticError for
+// the first problem found.
+func (r *SemanticAnalyzer) Analyze(node ast.Node) error {
+	return r.visit(node)
+}
+
+func NewSemanticAnalyzer() *SemanticAnalyzer {
+	return &SemanticAnalyzer{
+		CurrentScope: newBuiltinsScope(),
+		units:        map[string]*ScopedSymbolTable{},
+	}
+}
+
+// visitStatements checks a statement list
+func (r *SemanticAnalyzer) visitStatements(nodes []ast.Node) error {
+	for _, v := range nodes {
+		if err := r.visit(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visitTryExcept checks each handler in a scope holding its exception
+// variable
+func (r *SemanticAnalyzer) visitTryExcept(node ast.TryExcept) error {
+	if err := r.visitStatements(node.Body); err != nil {
+		return err
+	}
+
+	r.handlers++
+	defer func() {
+		r.handlers--
+	}()
+
+	for _, v := range node.Handlers {
+		class, err := r.typeSymbol(v.Class)
+		if err != nil {
+			return err
+		}
+		if !r.isException(class) {
+			return newSemanticError(v.Class, "Exception class type expected, got %v", class.GetName())
+		}
+
+		if err := r.visitHandler(v, class); err != nil {
+			return err
+		}
+	}
+	return r.visitStatements(node.Default)
+}
+
+func (r *SemanticAnalyzer) visitHandler(node ast.ExceptionHandler, class Symbol) error {
+	if node.Variable == nil {
+		return r.visit(node.Body)
+	}
+
+	handlerScope := NewScopedSymbolTable("on", r.CurrentScope.ScopeLevel+1, r.CurrentScope)
+	r.CurrentScope = handlerScope
+	defer func() {
+		r.CurrentScope = handlerScope.EnclosingScope
+	}()
+
+	r.CurrentScope.Insert(VarSymbol{Name: node.Variable.GetToken().Text(), Type: class})
+	return r.visit(node.Body)
+}
+
+// visitRaiseStatement checks that an exception is raised, a bare RAISE
+// raising the exception being handled again
+func (r *SemanticAnalyzer) visitRaiseStatement(node ast.RaiseStatement) error {
+	if node.Value == nil {
+		if r.handlers == 0 {
+			return newSemanticError(node, "RAISE without an exception outside of an exception handler")
+		}
+		return nil
+	}
+
+	if err := r.visit(node.Value); err != nil {
+		return err
+	}
+	valueType, err := r.typeOf(node.Value)
+	if err != nil {
+		return err
+	}
+	if valueType != nil && !r.isException(valueType) {
+		return newSemanticError(node.Value, "Exception expected, got %v", valueType.GetName())
+	}
+	return nil
+}
+
+// visitControlProcedure checks Break and Continue, which are only allowed
+// in loops, Exit, taking the result of a function, and Halt(code)
+func (r *SemanticAnalyzer) visitControlProcedure(node ast.ProcedureCall) error {
+	name := strings.ToUpper(node.Name)
+	switch {
+	case (name == "BREAK" || name == "CONTINUE") && len(node.Arguments) == 0:
+		if r.loops == 0 {
+			return newSemanticError(node, "%v outside of a loop", node.Name)
+		}
+		return nil
+
+	case name == "EXIT" && len(node.Arguments) == 0:
+		return nil
+	case name == "EXIT" && len(node.Arguments) == 1:
+		if r.result == nil {
+			return newSemanticError(node, "Exit with a value outside of a function")
+		}
+		return r.visitCall(node, node.Name, ProceduralTypeSymbol{Params: []ParamSymbol{{Type: r.result}}}, node.Arguments)
+
+	case name == "HALT" && len(node.Arguments) <= 1:
+		code := ProceduralTypeSymbol{Params: []ParamSymbol{{Type: r.builtinType("INTEGER")}}}
+		if len(node.Arguments) == 0 {
+			code.Params = nil
+		}
+		return r.visitCall(node, node.Name, code, node.Arguments)
+	}
+	return newSemanticError(node, "Wrong number of arguments for '%v'", node.Name)
+}
+
+// visitCondition checks the BOOLEAN expression controlling a statement
+func (r *SemanticAnalyzer) visitCondition(node ast.Node) error {
+	if err := r.visit(node); err != nil {
+		return err
+	}
+	conditionType, err := r.typeOf(node)
+	if err != nil {
+		return err
+	}
+	if !matchesParam("BOOLEAN", conditionType, false, true) {
+		return newSemanticError(node, "Incompatible types: got %v expected BOOLEAN", conditionType.GetName())
+	}
+	return nil
+}
+
+// visitLoopBody checks the statements repeated by a loop
+func (r *SemanticAnalyzer) visitLoopBody(body ...ast.Node) error {
+	r.loops++
+	defer func() {
+		r.loops--
+	}()
+	return r.visitStatements(body)
+}
+
+// visitForStatement checks that the control variable is a variable of an
+// ordinal type the bounds are compatible with
+func (r *SemanticAnalyzer) visitForStatement(node ast.ForStatement) error {
+	variable, ok := node.Variable.(ast.Var)
+	if !ok || !r.isVariableReference(variable) {
+		return newSemanticError(node.Variable, "Variable identifier expected")
+	}
+	if err := r.visit(variable); err != nil {
+		return err
+	}
+	variableType, err := r.typeOf(variable)
+	if err != nil {
+		return err
+	}
+	if !matchesParam(anyOrdinal, variableType, false, false) {
+		return newSemanticError(variable, "Ordinal type expected, got %v", variableType.GetName())
+	}
+
+	for _, v := range []ast.Node{node.Start, node.Stop} {
+		if err := r.visit(v); err != nil {
+			return err
+		}
+		boundType, err := r.typeOf(v)
+		if err != nil {
+			return err
+		}
+		if variableType != nil && !matchesParam(baseSymbol(variableType).GetName(), boundType, false, true) {
+			return newSemanticError(v, "Incompatible types: got %v expected %v", boundType.GetName(), variableType.GetName())
+		}
+	}
+	return r.visitLoopBody(node.Body)
+}
+
+// predeclared returns the symbol the interpreter provides for name, even
+// if the program hides it
+func (r *SemanticAnalyzer) predeclared(name string) Symbol {
+	scope := r.CurrentScope
+	for scope.EnclosingScope != nil {
+		scope = scope.EnclosingScope
+	}
+	symbol, _ := scope.Lookup(name, true)
+	return symbol
+}
+
+// isException reports whether t is a class descending from Exception
+func (r *SemanticAnalyzer) isException(t Symbol) bool {
+	class, ok := t.(*ClassTypeSymbol)
+	return ok && class.Descends(r.predeclared("Exception").(*ClassTypeSymbol))
+}
+
+// matchesClass reports whether an argument of type argument can be passed
+// to a parameter of the class param: an object of a descending class or
+// NIL, but only an object of the class itself to a VAR parameter
+func matchesClass(param *ClassTypeSymbol, argument Symbol, isVar bool) bool {
+	switch v := argument.(type) {
+	case nil:
+		return true
+	case *ClassTypeSymbol:
+		return v == param || (!isVar && v.Descends(param))
+	case PointerTypeSymbol:
+		return !isVar && v.Target == ""
+	}
+	return false
+}
+
+// classNamed returns the class node names, unless a variable hides it
+func (r *SemanticAnalyzer) classNamed(node ast.Node) (*ClassTypeSymbol, bool) {
+	variable, ok := node.(ast.Var)
+	if !ok {
+		return nil, false
+	}
+	symbol, _ := r.CurrentScope.Lookup(variable.Value, false)
+	if alias, isAlias := symbol.(TypeAliasSymbol); isAlias {
+		symbol = alias.Type
+	}
+	class, ok := symbol.(*ClassTypeSymbol)
+	return class, ok
+}
+
+// visitClassType declares the class name before its fields and methods
+// are checked, so that they can refer to the class. A class cannot declare
+// a member named like an inherited one, except for a constructor and for
+// a method overriding a virtual one of the same kind and signature.
+func (r *SemanticAnalyzer) visitClassType(name string, node ast.ClassType) error {
+	parent := r.predeclared("TObject").(*ClassTypeSymbol)
+	if node.Parent != nil {
+		parentType, err := r.typeSymbol(node.Parent)
+		if err != nil {
+			return err
+		}
+		class, ok := parentType.(*ClassTypeSymbol)
+		if !ok {
+			return newSemanticError(node.Parent, "Class type expected, got %v", parentType.GetName())
+		}
+		parent = class
+	}
+
+	class := &ClassTypeSymbol{Name: name, Parent: parent}
+	r.CurrentScope.Insert(TypeAliasSymbol{Name: name, Type: class})
+	for _, v := range node.Fields {
+		if _, ok := class.Field(v.Variable.Value); ok {
+			return newSemanticError(v.Variable, "Duplicate identifier '%v' found", v.Variable.Value)
+		}
+		if _, ok := class.Method(v.Variable.Value); ok {
+			return newSemanticError(v.Variable, "Duplicate identifier '%v' found", v.Variable.Value)
+		}
+
+		fieldType, err := r.typeSymbol(v.TypeSpec)
+		if err != nil {
+			return err
+		}
+		class.Fields = append(class.Fields, VarSymbol{Name: v.Variable.Value, Type: fieldType})
+	}
+
+	for _, v := range node.Methods {
+		methodType, err := r.routineTypeSymbol(v.Params, v.Result)
+		if err != nil {
+			return err
+		}
+		method := &MethodSymbol{Name: v.Name.Value, Kind: v.GetToken().TokenType, Type: methodType, Virtual: v.Virtual || v.Override, Class: class}
+		if err := r.checkMethodHeading(v, method); err != nil {
+			return err
+		}
+		class.Methods = append(class.Methods, method)
+	}
+	return nil
+}
+
+// checkMethodHeading checks that the method the heading node declares can
+// be added to the members of its class
+func (r *SemanticAnalyzer) checkMethodHeading(node ast.MethodHeading, method *MethodSymbol) error {
+	class := method.Class
+	if _, ok := class.Field(method.Name); ok {
+		return newSemanticError(node.Name, "Duplicate identifier '%v' found", method.Name)
+	}
+	for _, v := range class.Methods {
+		if strings.EqualFold(v.Name, method.Name) {
+			return newSemanticError(node.Name, "Duplicate identifier '%v' found", method.Name)
+		}
+	}
+	if method.Kind == lexer.CONSTRUCTOR && method.Virtual {
+		return newSemanticError(node, "Constructor '%v' cannot be virtual", method.Name)
+	}
+
+	inherited, isInherited := class.Parent.Method(method.Name)
+	switch {
+	case node.Override:
+		if !isInherited || !inherited.Virtual || inherited.Kind != method.Kind || inherited.Type.Signature() != method.Type.Signature() {
+			return newSemanticError(node.Name, "There is no virtual method '%v' to override", method.Name)
+		}
+	case isInherited && method.Kind != lexer.CONSTRUCTOR:
+		return newSemanticError(node.Name, "Duplicate identifier '%v' found", method.Name)
+	}
+	return nil
+}
+
+// visitMethodImplementation checks the implementation of a method, which
+// has to match its declaration in a class declared in the same block, or
+// in the INTERFACE section of the unit whose IMPLEMENTATION it is in. The
+// body is checked in a scope holding the members of the class, enclosing
+// the one holding Self, the result variable and the parameters.
+func (r *SemanticAnalyzer) visitMethodImplementation(node ast.RoutineDeclaration) error {
+	className := node.Class.GetToken().Text()
+	classType, err := r.typeSymbol(node.Class)
+	if err != nil {
+		return err
+	}
+	class, ok := classType.(*ClassTypeSymbol)
+	if !ok {
+		return newSemanticError(node.Class, "Class type expected, got %v", classType.GetName())
+	}
+
+	var method *MethodSymbol
+	for _, v := range class.Methods {
+		if strings.EqualFold(v.Name, node.Name.Value) {
+			method = v
+		}
+	}
+	_, local := r.CurrentScope.Lookup(className, true)
+	if !local && r.CurrentScope == r.implementation {
+		_, local = r.implementation.EnclosingScope.Lookup(className, true)
+	}
+	if method == nil || !local {
+		return newSemanticError(node.Name, "Method '%v' is not declared by %v", node.Name.Value, class.GetName())
+	}
+	if method.Implemented {
+		return newSemanticError(node.Name, "Duplicate identifier '%v' found", node.Name.Value)
+	}
+
+	methodType, err := r.routineTypeSymbol(node.Params, node.Result)
+	if err != nil {
+		return err
+	}
+	if node.GetToken().TokenType != method.Kind || methodType.Signature() != method.Type.Signature() {
+		return newSemanticError(node.Name, "Implementation of '%v' does not match its declaration in %v", node.Name.Value, class.GetName())
+	}
+	method.Implemented = true
+
+	enclosingScope := r.CurrentScope
+	loops, result, enclosingClass := r.loops, r.result, r.class
+	defer func() {
+		r.CurrentScope, r.loops, r.result, r.class = enclosingScope, loops, result, enclosingClass
+	}()
+
+	r.CurrentScope = NewScopedSymbolTable(className, r.CurrentScope.ScopeLevel+1, r.CurrentScope)
+	var ancestors []*ClassTypeSymbol
+	for c := class; c != nil; c = c.Parent {
+		ancestors = append(ancestors, c)
+	}
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		for _, v := range ancestors[i].Fields {
+			r.CurrentScope.Insert(v)
+		}
+		for _, v := range ancestors[i].Methods {
+			r.CurrentScope.Insert(v)
+		}
+	}
+
+	r.CurrentScope = NewScopedSymbolTable(node.Name.Value, r.CurrentScope.ScopeLevel+1, r.CurrentScope)
+	r.loops, r.result, r.class = 0, methodType.Result, class
+	r.CurrentScope.Insert(VarSymbol{Name: "Self", Type: class})
+	if methodType.Result != nil {
+		r.CurrentScope.Insert(VarSymbol{Name: node.Name.Value, Type: methodType.Result, Result: true})
+	}
+	for i, v := range methodType.Params {
+		if _, ok := r.CurrentScope.Lookup(v.Name, true); ok {
+			return newSemanticError(node.Params[i].Variable, "Duplicate identifier '%v' found", v.Name)
+		}
+		r.CurrentScope.Insert(VarSymbol{Name: v.Name, Type: v.Type})
+	}
+	return r.visitBlock(node.Block)
+}
+
+// checkMethodsImplemented checks that the block implements the methods of
+// the classes it declares
+func (r *SemanticAnalyzer) checkMethodsImplemented(node ast.Block) error {
+	for _, v := range node.Types() {
+		classNode, isClass := v.TypeSpec.(ast.ClassType)
+		if !isClass {
+			continue
+		}
+		class, _ := r.classNamed(v.Name)
+		for i, method := range class.Methods {
+			if !method.Implemented {
+				return newSemanticError(classNode.Methods[i], "Method '%v' of %v is not implemented", method.Name, class.GetName())
+			}
+		}
+	}
+	return nil
+}
+
+// memberType returns the type of the named field of an object of class
+// or the result of its method name, which takes no arguments then
+func (r *SemanticAnalyzer) memberType(node ast.Node, class *ClassTypeSymbol, name string) (Symbol, error) {
+	if field, ok := class.Field(name); ok {
+		return field.Type, nil
+	}
+	method, ok := class.Method(name)
+	if !ok {
+		return nil, newSemanticError(node, "Unknown field '%v' of %v", name, class.GetName())
+	}
+	if method.Kind == lexer.CONSTRUCTOR {
+		return nil, newSemanticError(node, "Constructor '%v' has to be called on a class", method.Name)
+	}
+	if len(method.Type.Params) != 0 {
+		return nil, newSemanticError(node, "Wrong number of arguments for '%v'", method.Name)
+	}
+	return method.Type.Result, nil
+}
+
+// calledMethod returns the method a method call refers to and, for a
+// constructor called on a class, the class. The method is nil if the type
+// of the object cannot be told.
+func (r *SemanticAnalyzer) calledMethod(node ast.MethodCall) (*MethodSymbol, *ClassTypeSymbol, error) {
+	if class, ok := r.classNamed(node.Value); ok {
+		method, ok := class.Method(node.Name)
+		if !ok {
+			return nil, nil, newSemanticError(node, "Unknown method '%v'", node.Name)
+		}
+		if method.Kind != lexer.CONSTRUCTOR {
+			return nil, nil, newSemanticError(node, "'%v' is not a constructor", node.Name)
+		}
+		return method, class, nil
+	}
+
+	valueType, err := r.typeOf(node.Value)
+	if err != nil || valueType == nil {
+		return nil, nil, err
+	}
+	class, ok := valueType.(*ClassTypeSymbol)
+	if !ok {
+		return nil, nil, newSemanticError(node, "Unknown method '%v'", node.Name)
+	}
+	method, ok := class.Method(node.Name)
+	if !ok {
+		return nil, nil, newSemanticError(node, "Unknown method '%v'", node.Name)
+	}
+	if method.Kind == lexer.CONSTRUCTOR {
+		return nil, nil, newSemanticError(node, "Constructor '%v' has to be called on a class", method.Name)
+	}
+	return method, nil, nil
+}
+
+// visitMethodCall checks a call of a constructor on a class or of a
+// method of an object
+func (r *SemanticAnalyzer) visitMethodCall(node ast.MethodCall) error {
+	if _, isClass := r.classNamed(node.Value); !isClass {
+		if err := r.visit(node.Value); err != nil {
+			return err
+		}
+	}
+
+	method, _, err := r.calledMethod(node)
+	if err != nil {
+		return err
+	}
+	if method == nil {
+		return r.visitStatements(node.Arguments)
+	}
+	return r.visitCall(node, node.Name, method.Type, node.Arguments)
+}
+
+// methodCallType returns the type of the object a constructor creates or
+// the result of a method
+func (r *SemanticAnalyzer) methodCallType(node ast.MethodCall) (Symbol, error) {
+	method, class, err := r.calledMethod(node)
+	switch {
+	case err != nil || method == nil:
+		return nil, err
+	case class != nil:
+		return class, nil
+	}
+	return method.Type.Result, nil
+}
+
+// inheritedMethod returns the method INHERITED calls, the one the
+// ancestors of the class of the method being checked declare
+func (r *SemanticAnalyzer) inheritedMethod(node ast.InheritedCall) (*MethodSymbol, error) {
+	if r.class == nil {
+		return nil, newSemanticError(node, "INHERITED outside of a method")
+	}
+	method, ok := r.class.Parent.Method(node.Name)
+	if !ok {
+		return nil, newSemanticError(node, "Unknown method '%v'", node.Name)
+	}
+	return method, nil
+}
+
+// visitTypeTest checks object IS Class and object AS Class, the object
+// having to be of a class type
+func (r *SemanticAnalyzer) visitTypeTest(node ast.BinaryOperation) error {
+	if err := r.visit(node.Left); err != nil {
+		return err
+	}
+	valueType, err := r.typeOf(node.Left)
+	if err != nil {
+		return err
+	}
+
+	class, ok := r.classNamed(node.Right)
+	if !ok {
+		return newSemanticError(node.Right, "Class type expected, got %v", node.Right.GetToken().Text())
+	}
+	if _, isClass := valueType.(*ClassTypeSymbol); valueType != nil && !isClass && !matchesClass(class, valueType, false) {
+		return newSemanticError(node, "Operator %v is not defined for %v and %v", node.GetToken().Text(), valueType.GetName(), class.GetName())
+	}
+	return nil
+}
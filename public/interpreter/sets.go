@@ -0,0 +1,243 @@
+package interpreter
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+)
+
+// maxSetOrdinal is the highest ordinal number a set element may have
+const maxSetOrdinal = 255
+
+// setType is SET OF Element, Element being an ordinal type whose values
+// have the ordinal numbers 0..maxSetOrdinal
+type setType struct {
+	Name    string
+	Element dataType
+}
+
+func (r setType) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if r.Element == nil {
+		return "SET"
+	}
+	return fmt.Sprintf("SET OF %v", r.Element)
+}
+
+// setValue holds the ordinal numbers of the elements of a set as bits.
+// Element is the type the elements belong to, nil for the empty set [],
+// which is compatible with every set.
+type setValue struct {
+	Element dataType
+	Bits    [(maxSetOrdinal + 1) / 64]uint64
+}
+
+func (r setValue) contains(n int) bool {
+	return n >= 0 && n <= maxSetOrdinal && r.Bits[n/64]&(1<<(n%64)) != 0
+}
+
+func (r *setValue) add(n int) {
+	r.Bits[n/64] |= 1 << (n % 64)
+}
+
+func (r setValue) isEmpty() bool {
+	return r.Bits == setValue{}.Bits
+}
+
+// elements returns the ordinal numbers of the elements in ascending order
+func (r setValue) elements() []int {
+	var result []int
+	for i, word := range r.Bits {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			result = append(result, i*64+bit)
+			word &^= 1 << bit
+		}
+	}
+	return result
+}
+
+// element returns the text of the element with the ordinal number n
+func (r setValue) element(n int) string {
+	value, err := withOrdinal(ordinalSample(r.Element), n)
+	if err != nil {
+		return fmt.Sprint(n)
+	}
+	return FormatValue(value)
+}
+
+// String returns the set like a constructor, e.g. ['a', 'c'..'e']
+func (r setValue) String() string {
+	var parts []string
+
+	elements := r.elements()
+	for start := 0; start < len(elements); {
+		end := start + 1
+		for end < len(elements) && elements[end] == elements[end-1]+1 {
+			end++
+		}
+
+		if end-start > 2 {
+			parts = append(parts, r.element(elements[start])+".."+r.element(elements[end-1]))
+		} else {
+			for _, v := range elements[start:end] {
+				parts = append(parts, r.element(v))
+			}
+		}
+		start = end
+	}
+	return fmt.Sprintf("[%v]", strings.Join(parts, ", "))
+}
+
+// setElementBounds checks that t can be the element type of a set and
+// returns the ordinal numbers of its first and last value
+func setElementBounds(t dataType) (int, int, error) {
+	_, low, high, ok := ordinalBounds(t)
+	if !ok || low < 0 || high > maxSetOrdinal {
+		return 0, 0, fmt.Errorf("Illegal set element type %v", t)
+	}
+	return low, high, nil
+}
+
+// commonElement returns the element type of the result of an operation on
+// the sets left and right, failing if they are not compatible
+func commonElement(left setValue, right setValue) (dataType, bool) {
+	switch {
+	case left.Element == nil:
+		return right.Element, true
+	case right.Element == nil:
+		return left.Element, true
+	}
+	return left.Element, left.Element.String() == right.Element.String()
+}
+
+func (r *EvaluatorVisitor) visitSetConstructor(node ast.SetConstructor) (any, error) {
+	var result setValue
+	for _, v := range node.Elements {
+		lowNode, highNode := v, v
+		if subrange, ok := v.(ast.Subrange); ok {
+			lowNode, highNode = subrange.Low, subrange.High
+		}
+
+		low, err := r.Visit(lowNode)
+		if err != nil {
+			return nil, err
+		}
+		high := low
+		if highNode != lowNode {
+			if high, err = r.Visit(highNode); err != nil {
+				return nil, err
+			}
+		}
+
+		lowOrdinal, err := ordinalValue(low)
+		if err != nil {
+			return nil, runtimeErrorAt(lowNode, err)
+		}
+		highOrdinal, err := ordinalValue(high)
+		if err != nil {
+			return nil, runtimeErrorAt(highNode, err)
+		}
+
+		for _, element := range []any{low, high} {
+			if result.Element != nil && result.Element.String() != typeName(element) {
+				return nil, newRuntimeError(v, "Incompatible types: got %v expected %v", typeName(element), result.Element)
+			}
+			result.Element = valueType(element)
+		}
+
+		// a range whose high bound is below the low one is empty
+		if lowOrdinal > highOrdinal {
+			continue
+		}
+		if lowOrdinal < 0 || highOrdinal > maxSetOrdinal {
+			return nil, newKindError(v, RangeCheckError, "Range check error: set elements must be in 0..%d", maxSetOrdinal)
+		}
+		for n := lowOrdinal; n <= highOrdinal; n++ {
+			result.add(n)
+		}
+	}
+	return result, nil
+}
+
+// membership evaluates element IN set
+func (r *EvaluatorVisitor) membership(node ast.BinaryOperation, element any, set any) (any, error) {
+	value, ok := set.(setValue)
+	if !ok {
+		return nil, newRuntimeError(node, "Operator IN is not defined for %v and %v", typeName(element), typeName(set))
+	}
+	n, err := ordinalValue(element)
+	if err != nil {
+		return nil, runtimeErrorAt(node, err)
+	}
+	if value.Element != nil && value.Element.String() != typeName(element) {
+		return nil, newRuntimeError(node, "Incompatible types: got %v expected %v", typeName(element), value.Element)
+	}
+	return value.contains(n), nil
+}
+
+// setOperation evaluates the union +, the difference -, the intersection *
+// and the comparisons of two sets, <= and >= testing for subsets and
+// supersets
+func (r *EvaluatorVisitor) setOperation(node ast.BinaryOperation, left setValue, right setValue) (any, error) {
+	element, ok := commonElement(left, right)
+	if !ok {
+		return nil, newRuntimeError(node, "Incompatible types: got %v expected %v", typeName(right), typeName(left))
+	}
+
+	result := setValue{Element: element}
+	for i := range result.Bits {
+		switch node.GetToken().TokenType {
+		case lexer.PLUS:
+			result.Bits[i] = left.Bits[i] | right.Bits[i]
+		case lexer.MINUS:
+			result.Bits[i] = left.Bits[i] &^ right.Bits[i]
+		case lexer.MUL:
+			result.Bits[i] = left.Bits[i] & right.Bits[i]
+		case lexer.LESS_EQUAL:
+			result.Bits[i] = left.Bits[i] &^ right.Bits[i]
+		case lexer.GREATER_EQUAL:
+			result.Bits[i] = right.Bits[i] &^ left.Bits[i]
+		case lexer.EQUAL, lexer.NOT_EQUAL:
+			result.Bits[i] = left.Bits[i] ^ right.Bits[i]
+		default:
+			return nil, newRuntimeError(node, "Operator %v is not defined for %v and %v", node.GetToken().Text(), typeName(left), typeName(right))
+		}
+	}
+
+	switch node.GetToken().TokenType {
+	case lexer.LESS_EQUAL, lexer.GREATER_EQUAL, lexer.EQUAL:
+		// nothing is left of the difference if the relation holds
+		return result.isEmpty(), nil
+	case lexer.NOT_EQUAL:
+		return !result.isEmpty(), nil
+	}
+	return result, nil
+}
+
+// convertSet makes the set value fit a variable of type declared,
+// checking its elements against the element type
+func (r *EvaluatorVisitor) convertSet(node ast.Node, value any, declared setType) (any, error) {
+	set, ok := value.(setValue)
+	element := baseType(declared.Element)
+	if !ok || (set.Element != nil && set.Element.String() != element.String()) {
+		return nil, newRuntimeError(node, "Incompatible types: got %v expected %v", typeName(value), declared)
+	}
+
+	low, high, err := setElementBounds(declared.Element)
+	if err != nil {
+		return nil, runtimeErrorAt(node, err)
+	}
+	set.Element = element
+	for _, n := range set.elements() {
+		if n < low || n > high {
+			return nil, newKindError(node, RangeCheckError, "Range check error: set element %v out of range %v..%v", set.element(n), set.element(low), set.element(high))
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,422 @@
+package interpreter
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+)
+
+type Symbol interface {
+	GetName() string
+}
+
+type BuiltinTypeSymbol struct {
+	Name string
+}
+
+func (r BuiltinTypeSymbol) GetName() string {
+	return r.Name
+}
+
+// ArrayTypeSymbol is ARRAY[Low..High] OF Element, Index is the type of
+// the bounds. Name is set if the type was declared in a TYPE section.
+type ArrayTypeSymbol struct {
+	Name    string
+	Low     any
+	High    any
+	Index   Symbol
+	Element Symbol
+}
+
+func (r ArrayTypeSymbol) GetName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("ARRAY[%v..%v] OF %v", FormatValue(r.Low), FormatValue(r.High), r.Element.GetName())
+}
+
+// RecordTypeSymbol is RECORD field: type; ... END.
+type RecordTypeSymbol struct {
+	Name   string
+	Fields []VarSymbol
+}
+
+func (r RecordTypeSymbol) GetName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	fields := make([]string, len(r.Fields))
+	for i, v := range r.Fields {
+		fields[i] = fmt.Sprintf("%v: %v", v.Name, v.Type.GetName())
+	}
+	return fmt.Sprintf("RECORD %v END", strings.Join(fields, "; "))
+}
+
+// Field returns the named field
+func (r RecordTypeSymbol) Field(name string) (VarSymbol, bool) {
+	for _, v := range r.Fields {
+		if strings.EqualFold(v.Name, name) {
+			return v, true
+		}
+	}
+	return VarSymbol{}, false
+}
+
+// EnumTypeSymbol is an enumeration, its values are ConstSymbols
+type EnumTypeSymbol struct {
+	Type *enumType
+}
+
+func (r EnumTypeSymbol) GetName() string {
+	return r.Type.String()
+}
+
+// SubrangeTypeSymbol is Low..High of the ordinal type Base
+type SubrangeTypeSymbol struct {
+	Name string
+	Low  any
+	High any
+	Base Symbol
+}
+
+func (r SubrangeTypeSymbol) GetName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("%v..%v", FormatValue(r.Low), FormatValue(r.High))
+}
+
+// SetTypeSymbol is SET OF Element
+type SetTypeSymbol struct {
+	Name    string
+	Element Symbol
+}
+
+func (r SetTypeSymbol) GetName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return "SET OF " + r.Element.GetName()
+}
+
+// PointerTypeSymbol is ^Target, the target type is looked up by name when
+// needed as it may be declared after the pointer type. NIL is a pointer
+// without a Target.
+type PointerTypeSymbol struct {
+	Name   string
+	Target string
+}
+
+func (r PointerTypeSymbol) GetName() string {
+	switch {
+	case r.Name != "":
+		return r.Name
+	case r.Target == "":
+		return "Pointer"
+	}
+	return "^" + r.Target
+}
+
+// ClassTypeSymbol is a CLASS, Parent is nil for TObject only. Fields and
+// Methods are the ones the class declares, not the inherited ones.
+type ClassTypeSymbol struct {
+	Name    string
+	Parent  *ClassTypeSymbol
+	Fields  []VarSymbol
+	Methods []*MethodSymbol
+}
+
+func (r *ClassTypeSymbol) GetName() string {
+	return r.Name
+}
+
+// Field returns the named field declared by the class or an ancestor
+func (r *ClassTypeSymbol) Field(name string) (VarSymbol, bool) {
+	for c := r; c != nil; c = c.Parent {
+		for _, v := range c.Fields {
+			if strings.EqualFold(v.Name, name) {
+				return v, true
+			}
+		}
+	}
+	return VarSymbol{}, false
+}
+
+// Method returns the named method declared by the class or, if it does
+// not declare one, by its closest ancestor declaring it
+func (r *ClassTypeSymbol) Method(name string) (*MethodSymbol, bool) {
+	for c := r; c != nil; c = c.Parent {
+		for _, v := range c.Methods {
+			if strings.EqualFold(v.Name, name) {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Descends reports whether the class is ancestor or derived from it
+func (r *ClassTypeSymbol) Descends(ancestor *ClassTypeSymbol) bool {
+	for c := r; c != nil; c = c.Parent {
+		if c == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// MethodSymbol is a method declared by Class, Kind being the keyword it is
+// declared with. Implemented is set once its implementation is checked.
+type MethodSymbol struct {
+	Name        string
+	Kind        lexer.TokenType
+	Type        ProceduralTypeSymbol
+	Virtual     bool
+	Class       *ClassTypeSymbol
+	Implemented bool
+}
+
+func (r *MethodSymbol) GetName() string {
+	return r.Name
+}
+
+// LabelSymbol is a label declared in a LABEL section, named by the key
+// labelKey returns
+type LabelSymbol struct {
+	Name string
+}
+
+func (r LabelSymbol) GetName() string {
+	return r.Name
+}
+
+// ParamSymbol is a parameter of a procedural type
+type ParamSymbol struct {
+	Name  string
+	Type  Symbol
+	IsVar bool
+}
+
+// ProceduralTypeSymbol is PROCEDURE(params) or FUNCTION(params): Result,
+// the type of a routine. Result is nil for procedures.
+type ProceduralTypeSymbol struct {
+	Name   string
+	Params []ParamSymbol
+	Result Symbol
+}
+
+func (r ProceduralTypeSymbol) GetName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Signature()
+}
+
+// Signature returns the type without its name and the names of its
+// parameters, two procedural types being compatible if their signatures
+// are the same
+func (r ProceduralTypeSymbol) Signature() string {
+	params := make([]string, len(r.Params))
+	for i, v := range r.Params {
+		params[i] = symbolSignature(v.Type)
+		if v.IsVar {
+			params[i] = "VAR " + params[i]
+		}
+	}
+
+	result := "PROCEDURE"
+	if r.Result != nil {
+		result = "FUNCTION"
+	}
+	if len(params) > 0 {
+		result += "(" + strings.Join(params, "; ") + ")"
+	}
+	if r.Result != nil {
+		result += ": " + symbolSignature(r.Result)
+	}
+	return result
+}
+
+// symbolSignature returns the type t as it appears in a signature
+func symbolSignature(t Symbol) string {
+	if proc, ok := t.(ProceduralTypeSymbol); ok {
+		return proc.Signature()
+	}
+	return t.GetName()
+}
+
+// RoutineSymbol is a procedure or function declared by the program
+type RoutineSymbol struct {
+	Name string
+	Type ProceduralTypeSymbol
+}
+
+func (r RoutineSymbol) GetName() string {
+	return r.Name
+}
+
+// TypeAliasSymbol is a name declared in a TYPE section for the existing
+// type Type
+type TypeAliasSymbol struct {
+	Name string
+	Type Symbol
+}
+
+func (r TypeAliasSymbol) GetName() string {
+	return r.Name
+}
+
+// ConstSymbol is a constant declared in a CONST section, a value of an
+// enumeration or one of TRUE and FALSE
+type ConstSymbol struct {
+	Name  string
+	Type  Symbol
+	Value any
+}
+
+func (r ConstSymbol) GetName() string {
+	return r.Name
+}
+
+// VarSymbol is a variable, a parameter or, if Result is set, the result
+// variable of a function, named like the function
+type VarSymbol struct {
+	Name   string
+	Type   Symbol
+	Result bool
+}
+
+func (r VarSymbol) GetName() string {
+	return r.Name
+}
+
+// BuiltinProcedureSymbol is a procedure provided by the interpreter, such
+// as WRITELN.
+type BuiltinProcedureSymbol struct {
+	Name string
+}
+
+func (r BuiltinProcedureSymbol) GetName() string {
+	return r.Name
+}
+
+// BuiltinFunctionSymbol is a function provided by the interpreter, such as
+// Length.
+type BuiltinFunctionSymbol struct {
+	Name string
+}
+
+func (r BuiltinFunctionSymbol) GetName() string {
+	return r.Name
+}
+
+// ScopedSymbolTable holds the symbols declared in a single scope. Names are
+// case-insensitive, as everywhere in Pascal.
+type ScopedSymbolTable struct {
+	ScopeName      string
+	ScopeLevel     int
+	EnclosingScope *ScopedSymbolTable
+	symbols        map[string]Symbol
+}
+
+func (r *ScopedSymbolTable) Insert(symbol Symbol) {
+	r.symbols[strings.ToUpper(symbol.GetName())] = symbol
+}
+
+// Lookup searches name in this scope and, unless currentScopeOnly is set,
+// in the enclosing ones.
+func (r *ScopedSymbolTable) Lookup(name string, currentScopeOnly bool) (Symbol, bool) {
+	symbol, ok := r.symbols[strings.ToUpper(name)]
+	if ok {
+		return symbol, true
+	}
+
+	if currentScopeOnly || r.EnclosingScope == nil {
+		return nil, false
+	}
+	return r.EnclosingScope.Lookup(name, false)
+}
+
+func NewScopedSymbolTable(name string, level int, enclosingScope *ScopedSymbolTable) *ScopedSymbolTable {
+	return &ScopedSymbolTable{
+		ScopeName:      name,
+		ScopeLevel:     level,
+		EnclosingScope: enclosingScope,
+		symbols:        map[string]Symbol{},
+	}
+}
+
+func newBuiltinsScope() *ScopedSymbolTable {
+	scope := NewScopedSymbolTable("builtins", 0, nil)
+	scope.Insert(BuiltinTypeSymbol{Name: "INTEGER"})
+	scope.Insert(BuiltinTypeSymbol{Name: "REAL"})
+	scope.Insert(BuiltinTypeSymbol{Name: "CHAR"})
+	scope.Insert(BuiltinTypeSymbol{Name: "STRING"})
+	scope.Insert(BuiltinTypeSymbol{Name: "BOOLEAN"})
+	scope.Insert(ConstSymbol{Name: "TRUE", Type: BuiltinTypeSymbol{Name: "BOOLEAN"}, Value: true})
+	scope.Insert(ConstSymbol{Name: "FALSE", Type: BuiltinTypeSymbol{Name: "BOOLEAN"}, Value: false})
+	scope.Insert(BuiltinProcedureSymbol{Name: "WRITE"})
+	scope.Insert(BuiltinProcedureSymbol{Name: "WRITELN"})
+	scope.Insert(BuiltinProcedureSymbol{Name: "READ"})
+	scope.Insert(BuiltinProcedureSymbol{Name: "READLN"})
+	scope.Insert(BuiltinProcedureSymbol{Name: "NEW"})
+	scope.Insert(BuiltinProcedureSymbol{Name: "DISPOSE"})
+	scope.Insert(BuiltinProcedureSymbol{Name: "BREAK"})
+	scope.Insert(BuiltinProcedureSymbol{Name: "CONTINUE"})
+	scope.Insert(BuiltinProcedureSymbol{Name: "EXIT"})
+	scope.Insert(BuiltinProcedureSymbol{Name: "HALT"})
+	classes := map[*classType]*ClassTypeSymbol{}
+	for _, v := range predeclaredClasses {
+		scope.Insert(predeclaredClassSymbol(v, classes))
+	}
+	for name, routine := range builtinRoutines {
+		if routine.function {
+			scope.Insert(BuiltinFunctionSymbol{Name: name})
+		} else {
+			scope.Insert(BuiltinProcedureSymbol{Name: name})
+		}
+	}
+	return scope
+}
+
+// predeclaredClassSymbol returns the symbol of a class the interpreter
+// provides, classes holding the symbols already made so that every class
+// has a single one
+func predeclaredClassSymbol(class *classType, classes map[*classType]*ClassTypeSymbol) *ClassTypeSymbol {
+	if symbol, ok := classes[class]; ok {
+		return symbol
+	}
+
+	symbol := &ClassTypeSymbol{Name: class.Name}
+	classes[class] = symbol
+	inherited := 0
+	if class.Parent != nil {
+		symbol.Parent = predeclaredClassSymbol(class.Parent, classes)
+		inherited = len(class.Parent.Fields)
+	}
+	for _, v := range class.Fields[inherited:] {
+		symbol.Fields = append(symbol.Fields, VarSymbol{Name: v.Name, Type: BuiltinTypeSymbol{Name: v.Type.String()}})
+	}
+	for _, name := range slices.Sorted(maps.Keys(class.Methods)) {
+		method := class.Methods[name]
+		methodType := ProceduralTypeSymbol{}
+		for _, v := range method.Type.Params {
+			methodType.Params = append(methodType.Params, ParamSymbol{Name: v.Name, Type: BuiltinTypeSymbol{Name: v.Type.String()}})
+		}
+		if method.Type.Result != nil {
+			methodType.Result = BuiltinTypeSymbol{Name: method.Type.Result.String()}
+		}
+		symbol.Methods = append(symbol.Methods, &MethodSymbol{
+			Name:        method.Name,
+			Kind:        method.Kind,
+			Type:        methodType,
+			Virtual:     method.Virtual,
+			Class:       symbol,
+			Implemented: true,
+		})
+	}
+	return symbol
+}
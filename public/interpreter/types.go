@@ -0,0 +1,570 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+)
+
+// dataType describes the values a variable holds at runtime. String
+// returns the type as it is written in Pascal, for messages.
+type dataType interface {
+	String() string
+}
+
+// simpleType is one of the predeclared types, e.g. INTEGER
+type simpleType string
+
+func (r simpleType) String() string {
+	return string(r)
+}
+
+// arrayType is ARRAY[Low..High] OF Element. Low and High are the ordinal
+// numbers of the bounds, Index is the type they belong to. Name is set if
+// the type was declared in a TYPE section.
+type arrayType struct {
+	Name    string
+	Low     int
+	High    int
+	Index   dataType
+	Element dataType
+}
+
+// bound returns the text of the ordinal n of the index type, e.g. 'a'
+func (r arrayType) bound(n int) string {
+	value, err := withOrdinal(ordinalSample(r.Index), n)
+	if err != nil {
+		return fmt.Sprint(n)
+	}
+	return FormatValue(value)
+}
+
+func (r arrayType) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("ARRAY[%v..%v] OF %v", r.bound(r.Low), r.bound(r.High), r.Element)
+}
+
+// arrayValue holds the elements of an ARRAY, an element is nil until it is
+// assigned. Arrays are copied on assignment.
+type arrayValue struct {
+	Type     arrayType
+	Elements []any
+}
+
+type recordField struct {
+	Name string
+	Type dataType
+}
+
+// recordType is RECORD name: type; ... END
+type recordType struct {
+	Name   string
+	Fields []recordField
+}
+
+func (r recordType) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	fields := make([]string, len(r.Fields))
+	for i, v := range r.Fields {
+		fields[i] = fmt.Sprintf("%v: %v", v.Name, v.Type)
+	}
+	return fmt.Sprintf("RECORD %v END", strings.Join(fields, "; "))
+}
+
+// field returns the position of the named field, -1 if there is none
+func (r recordType) field(name string) int {
+	for i, v := range r.Fields {
+		if strings.EqualFold(v.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// enumType is the enumeration (Values[0], ...). Enumerations declared in
+// a TYPE section have a Name.
+type enumType struct {
+	Name   string
+	Values []string
+}
+
+func (r *enumType) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("(%v)", strings.Join(r.Values, ", "))
+}
+
+// enumValue is the value of Type with the ordinal number Ordinal
+type enumValue struct {
+	Type    *enumType
+	Ordinal int
+}
+
+// subrangeType is Low..High of the ordinal type Base, Low and High being
+// ordinal numbers. Values of a subrange are values of Base.
+type subrangeType struct {
+	Name string
+	Base dataType
+	Low  int
+	High int
+}
+
+// bound returns the text of the ordinal n of the base type
+func (r subrangeType) bound(n int) string {
+	value, err := withOrdinal(ordinalSample(r.Base), n)
+	if err != nil {
+		return fmt.Sprint(n)
+	}
+	return FormatValue(value)
+}
+
+func (r subrangeType) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("%v..%v", r.bound(r.Low), r.bound(r.High))
+}
+
+// named returns t as the type declared as name in a TYPE section
+func named(t dataType, name string) dataType {
+	switch v := t.(type) {
+	case arrayType:
+		v.Name = name
+		return v
+	case recordType:
+		v.Name = name
+		return v
+	case subrangeType:
+		v.Name = name
+		return v
+	case setType:
+		v.Name = name
+		return v
+	case pointerType:
+		v.Name = name
+		return v
+	case procType:
+		v.Name = name
+		return v
+	case *enumType:
+		v.Name = name
+	}
+	return t
+}
+
+// baseType returns the type the values of t belong to, which is t itself
+// unless it is a subrange
+func baseType(t dataType) dataType {
+	if subrange, ok := t.(subrangeType); ok {
+		return subrange.Base
+	}
+	return t
+}
+
+// ordinalBounds returns the type of the values of the ordinal type t and
+// the ordinal numbers of its first and last value. INTEGER is not bounded
+// this way as it is too large to index an array.
+func ordinalBounds(t dataType) (dataType, int, int, bool) {
+	switch v := t.(type) {
+	case simpleType:
+		switch v {
+		case simpleType("CHAR"):
+			return v, 0, math.MaxUint8, true
+		case simpleType("BOOLEAN"):
+			return v, 0, 1, true
+		}
+	case *enumType:
+		return v, 0, len(v.Values) - 1, true
+	case subrangeType:
+		return v.Base, v.Low, v.High, true
+	}
+	return nil, 0, 0, false
+}
+
+// valueType returns the type of an ordinal value
+func valueType(value any) dataType {
+	if v, ok := value.(enumValue); ok {
+		return v.Type
+	}
+	return simpleType(typeName(value))
+}
+
+// recordValue holds the fields of a RECORD in the order of declaration, a
+// field is nil until it is assigned. Records are copied on assignment.
+type recordValue struct {
+	Type   recordType
+	Fields []any
+}
+
+// newValue returns the initial value of a variable of type t: nil, unless
+// t has elements or fields that have to exist before they can be assigned
+func newValue(t dataType) any {
+	switch v := t.(type) {
+	case arrayType:
+		value := &arrayValue{Type: v, Elements: make([]any, v.High-v.Low+1)}
+		for i := range value.Elements {
+			value.Elements[i] = newValue(v.Element)
+		}
+		return value
+
+	case recordType:
+		value := &recordValue{Type: v, Fields: make([]any, len(v.Fields))}
+		for i, field := range v.Fields {
+			value.Fields[i] = newValue(field.Type)
+		}
+		return value
+	}
+	return nil
+}
+
+// copyValue returns a copy of value not sharing any elements or fields
+// with it
+func copyValue(value any) any {
+	switch v := value.(type) {
+	case *arrayValue:
+		result := &arrayValue{Type: v.Type, Elements: make([]any, len(v.Elements))}
+		for i, element := range v.Elements {
+			result.Elements[i] = copyValue(element)
+		}
+		return result
+
+	case *recordValue:
+		result := &recordValue{Type: v.Type, Fields: make([]any, len(v.Fields))}
+		for i, field := range v.Fields {
+			result.Fields[i] = copyValue(field)
+		}
+		return result
+	}
+	return value
+}
+
+// ordinalSample returns a value of the ordinal type t, to be passed to
+// withOrdinal
+func ordinalSample(t dataType) any {
+	switch v := t.(type) {
+	case *enumType:
+		return enumValue{Type: v}
+	case subrangeType:
+		return ordinalSample(v.Base)
+	}
+
+	switch t {
+	case simpleType("CHAR"):
+		return byte(0)
+	case simpleType("BOOLEAN"):
+		return false
+	}
+	return 0
+}
+
+// offset returns the position of the element of container at index,
+// checking the index against the bounds of the array or string
+func (r *EvaluatorVisitor) offset(node ast.Node, container any, index any) (int, error) {
+	switch v := container.(type) {
+	case string:
+		position, ok := index.(int)
+		if !ok {
+			return 0, newRuntimeError(node, "Incompatible types: got %v expected INTEGER", typeName(index))
+		}
+		if position < 1 || position > len(v) {
+			return 0, newKindError(node, RangeCheckError, "Index %d out of range 1..%d", position, len(v))
+		}
+		return position - 1, nil
+
+	case *arrayValue:
+		if typeName(index) != v.Type.Index.String() {
+			return 0, newRuntimeError(node, "Incompatible types: got %v expected %v", typeName(index), v.Type.Index)
+		}
+		position, err := ordinalValue(index)
+		if err != nil {
+			return 0, runtimeErrorAt(node, err)
+		}
+		if position < v.Type.Low || position > v.Type.High {
+			return 0, newKindError(node, RangeCheckError, "Index %v out of range %v..%v", FormatValue(index), v.Type.bound(v.Type.Low), v.Type.bound(v.Type.High))
+		}
+		return position - v.Type.Low, nil
+	}
+	return 0, newRuntimeError(node, "Cannot index a value of type %v", typeName(container))
+}
+
+// element returns the element of container at index
+func (r *EvaluatorVisitor) element(node ast.Node, container any, index any) (any, error) {
+	position, err := r.offset(node, container, index)
+	if err != nil {
+		return nil, err
+	}
+
+	if text, ok := container.(string); ok {
+		return text[position], nil
+	}
+	value := container.(*arrayValue).Elements[position]
+	if value == nil {
+		return nil, newRuntimeError(node, "Element %v is not initialized", FormatValue(index))
+	}
+	return value, nil
+}
+
+// elementReference returns a reference to the element at the index denoted
+// by node of the array or string container refers to
+func (r *EvaluatorVisitor) elementReference(container *reference, node ast.Node) (*reference, error) {
+	index, err := r.Visit(node)
+	if err != nil {
+		return nil, err
+	}
+	value, err := container.get()
+	if err != nil {
+		return nil, err
+	}
+	position, err := r.offset(node, value, index)
+	if err != nil {
+		return nil, err
+	}
+
+	if array, ok := value.(*arrayValue); ok {
+		return &reference{
+			get: func() (any, error) {
+				return r.element(node, array, index)
+			},
+			set: func(element any) error {
+				converted, err := r.convert(node, element, array.Type.Element)
+				if err != nil {
+					return err
+				}
+				array.Elements[position] = converted
+				return nil
+			},
+			declared: array.Type.Element,
+			typeName: baseType(array.Type.Element).String(),
+		}, nil
+	}
+
+	// a character of a string is assigned by replacing the whole string
+	return &reference{
+		get: func() (any, error) {
+			current, err := container.get()
+			if err != nil {
+				return nil, err
+			}
+			return r.element(node, current, index)
+		},
+		set: func(element any) error {
+			c, ok := element.(byte)
+			if !ok {
+				return newRuntimeError(node, "Incompatible types: got %v expected CHAR", typeName(element))
+			}
+
+			current, err := container.get()
+			if err != nil {
+				return err
+			}
+			text := current.(string)
+			return container.set(text[:position] + string([]byte{c}) + text[position+1:])
+		},
+		declared: simpleType("CHAR"),
+		typeName: "CHAR",
+	}, nil
+}
+
+// fieldReference returns a reference to the named field of the record
+// container refers to
+func (r *EvaluatorVisitor) fieldReference(node ast.Node, container *reference, name string) (*reference, error) {
+	value, err := container.get()
+	if err != nil {
+		return nil, err
+	}
+	if isObject(value) {
+		return r.memberReference(node, value, name)
+	}
+	record, position, err := r.recordField(node, value, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reference{
+		get: func() (any, error) {
+			return r.field(node, record, name)
+		},
+		set: func(field any) error {
+			converted, err := r.convert(node, field, record.Type.Fields[position].Type)
+			if err != nil {
+				return err
+			}
+			record.Fields[position] = converted
+			return nil
+		},
+		declared: record.Type.Fields[position].Type,
+		typeName: baseType(record.Type.Fields[position].Type).String(),
+	}, nil
+}
+
+// recordField returns value as a record and the position of its field name
+func (r *EvaluatorVisitor) recordField(node ast.Node, value any, name string) (*recordValue, int, error) {
+	record, ok := value.(*recordValue)
+	if !ok {
+		return nil, 0, newRuntimeError(node, "Cannot access field '%v' of a value of type %v", name, typeName(value))
+	}
+
+	position := record.Type.field(name)
+	if position < 0 {
+		return nil, 0, newRuntimeError(node, "Unknown record field '%v'", name)
+	}
+	return record, position, nil
+}
+
+// field returns the named field of the record or object value
+func (r *EvaluatorVisitor) field(node ast.Node, value any, name string) (any, error) {
+	if isObject(value) {
+		return r.member(node, value, name)
+	}
+
+	record, position, err := r.recordField(node, value, name)
+	if err != nil {
+		return nil, err
+	}
+
+	field := record.Fields[position]
+	if field == nil {
+		return nil, newRuntimeError(node, "Field '%v' is not initialized", name)
+	}
+	return field, nil
+}
+
+// withField returns a reference to the field of the innermost record
+// opened by WITH named like variable, nil if there is none
+func (r *EvaluatorVisitor) withField(variable ast.Var) (*reference, error) {
+	for i := len(r.withs) - 1; i >= 0; i-- {
+		value, err := r.withs[i].get()
+		if err != nil {
+			return nil, err
+		}
+		if record, ok := value.(*recordValue); ok && record.Type.field(variable.Value) >= 0 {
+			return r.fieldReference(variable, r.withs[i], variable.Value)
+		}
+	}
+	return nil, nil
+}
+
+// resolveType returns the runtime type described by a type specification
+func (r *EvaluatorVisitor) resolveType(node ast.Node) (dataType, error) {
+	switch n := node.(type) {
+	case ast.TypeSpec:
+		if declared, ok := r.typeDef(strings.ToUpper(n.Value)); ok {
+			return declared, nil
+		}
+		if class, ok := predeclaredClasses[strings.ToUpper(n.Value)]; ok {
+			return class, nil
+		}
+		return simpleType(strings.ToUpper(n.Value)), nil
+	case ast.ArrayType:
+		return r.resolveArrayType(n)
+	case ast.RecordType:
+		var result recordType
+		for _, v := range n.Fields {
+			fieldType, err := r.resolveType(v.TypeSpec)
+			if err != nil {
+				return nil, err
+			}
+			result.Fields = append(result.Fields, recordField{Name: v.Variable.Value, Type: fieldType})
+		}
+		return result, nil
+	case ast.EnumType:
+		return r.resolveEnumType(n), nil
+	case ast.Subrange:
+		return r.resolveSubrange(n)
+	case ast.SetType:
+		element, err := r.resolveType(n.Element)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := setElementBounds(element); err != nil {
+			return nil, runtimeErrorAt(n.Element, err)
+		}
+		return setType{Element: element}, nil
+	case ast.PointerType:
+		return pointerType{Target: n.Target.GetToken().Text()}, nil
+	case ast.ProceduralType:
+		return r.routineType(n.Params, n.Result)
+	case ast.ClassType:
+		return nil, newRuntimeError(node, "Class types have to be declared in a TYPE section")
+	}
+	return nil, newRuntimeError(node, "Unknown type specification %T", node)
+}
+
+// resolveEnumType declares the values of an enumeration as constants.
+// Variables declared together, as in VAR a, b: (x, y), share the type.
+func (r *EvaluatorVisitor) resolveEnumType(node ast.EnumType) dataType {
+	result := &enumType{}
+	for _, v := range node.Values {
+		result.Values = append(result.Values, v.Value)
+	}
+
+	scope := r.scope()
+	if first, ok := scope.values[strings.ToUpper(result.Values[0])].(enumValue); ok && first.Type.Name == "" && first.Type.String() == result.String() {
+		return first.Type
+	}
+	for i, v := range result.Values {
+		scope.values[strings.ToUpper(v)] = enumValue{Type: result, Ordinal: i}
+	}
+	return result
+}
+
+func (r *EvaluatorVisitor) resolveSubrange(node ast.Subrange) (subrangeType, error) {
+	low, err := r.Visit(node.Low)
+	if err != nil {
+		return subrangeType{}, err
+	}
+	high, err := r.Visit(node.High)
+	if err != nil {
+		return subrangeType{}, err
+	}
+
+	lowOrdinal, err := ordinalValue(low)
+	if err != nil {
+		return subrangeType{}, runtimeErrorAt(node.Low, err)
+	}
+	highOrdinal, err := ordinalValue(high)
+	if err != nil {
+		return subrangeType{}, runtimeErrorAt(node.High, err)
+	}
+	if typeName(low) != typeName(high) {
+		return subrangeType{}, newRuntimeError(node, "Incompatible types: got %v expected %v", typeName(high), typeName(low))
+	}
+	if lowOrdinal > highOrdinal {
+		return subrangeType{}, newRuntimeError(node, "High range limit < low range limit")
+	}
+	return subrangeType{Base: valueType(low), Low: lowOrdinal, High: highOrdinal}, nil
+}
+
+// resolveArrayType turns ARRAY[r1, r2] OF T into ARRAY[r1] OF ARRAY[r2] OF T
+func (r *EvaluatorVisitor) resolveArrayType(node ast.ArrayType) (dataType, error) {
+	result, err := r.resolveType(node.Element)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(node.Ranges) - 1; i >= 0; i-- {
+		indexType, err := r.resolveType(node.Ranges[i])
+		if err != nil {
+			return nil, err
+		}
+		index, low, high, ok := ordinalBounds(indexType)
+		if !ok {
+			return nil, newRuntimeError(node.Ranges[i], "Index range expected")
+		}
+
+		result = arrayType{
+			Low:     low,
+			High:    high,
+			Index:   index,
+			Element: result,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,336 @@
+package interpreter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+)
+
+// Units finds and parses the units named by USES clauses. The unit Name is
+// read from the file name.pas, or Name.pas as written, in the first
+// directory of SearchPath holding one. Every unit is parsed once, so the
+// SemanticAnalyzer and the EvaluatorVisitor of a program should share it.
+type Units struct {
+	SearchPath []string
+	// conditional symbols defined while units are parsed
+	Defines []string
+	// units parsed so far, keyed by their upper case name
+	parsed map[string]*unitFile
+}
+
+// unitFile is a parsed unit and the path of its file
+type unitFile struct {
+	path string
+	node ast.Unit
+}
+
+func NewUnits(searchPath ...string) *Units {
+	return &Units{
+		SearchPath: searchPath,
+		parsed:     map[string]*unitFile{},
+	}
+}
+
+// find returns the path of the file of the unit name, false if there is
+// none in the search path
+func (r *Units) find(name string) (string, bool) {
+	for _, dir := range r.SearchPath {
+		for _, file := range []string{strings.ToLower(name) + ".pas", name + ".pas"} {
+			path := filepath.Join(dir, file)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// load returns the unit name, nil if it can't be found. Errors in the file
+// of the unit are reported as UnitError.
+func (r *Units) load(name string) (*unitFile, error) {
+	key := strings.ToUpper(name)
+	if unit, ok := r.parsed[key]; ok {
+		return unit, nil
+	}
+
+	path, ok := r.find(name)
+	if !ok {
+		return nil, nil
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, UnitError{Path: path, Err: err}
+	}
+
+	lxr := lexer.NewLexer(string(source))
+	lxr.Define(r.Defines...)
+	parser, err := NewParser(lxr)
+	if err != nil {
+		return nil, UnitError{Path: path, Err: err}
+	}
+	tree, err := parser.Parse()
+	if err != nil {
+		return nil, UnitError{Path: path, Err: err}
+	}
+
+	node, isUnit := tree.(ast.Unit)
+	switch {
+	case !isUnit:
+		return nil, UnitError{Path: path, Err: newSemanticError(tree, "Unit expected, got a program")}
+	case !strings.EqualFold(node.Name, name):
+		return nil, UnitError{Path: path, Err: newSemanticError(node, "Unit '%v' found instead of '%v'", node.Name, name)}
+	}
+
+	unit := &unitFile{path: path, node: node}
+	r.parsed[key] = unit
+	return unit, nil
+}
+
+// inUnit attributes a semantic or runtime error to the file at path,
+// unless it already is attributed to a file
+func inUnit(path string, err error) error {
+	var unitError UnitError
+	var semanticError SemanticError
+	var runtimeError RuntimeError
+	switch {
+	case errors.As(err, &unitError):
+		return err
+	case errors.As(err, &semanticError), errors.As(err, &runtimeError):
+		return UnitError{Path: path, Err: err}
+	}
+	return err
+}
+
+// unitInstance is a unit whose declarations are evaluated
+type unitInstance struct {
+	path string
+	node ast.Unit
+	// frame holding every declaration of the unit, enclosed by the one
+	// holding what the units it uses export
+	frame *frame
+	uses  *frame
+	// the declarations of the INTERFACE section, variables being
+	// references to the ones in frame
+	exports *frame
+}
+
+// loadUnit evaluates the INTERFACE section of the unit name, after doing
+// so for the units it uses, unless this happened before. Its
+// IMPLEMENTATION section is evaluated and its INITIALIZATION section run
+// once no INTERFACE section is being evaluated, so that the units it uses
+// in its INTERFACE section may use it in their IMPLEMENTATION section.
+func (r *EvaluatorVisitor) loadUnit(name ast.Var) (*unitInstance, error) {
+	key := strings.ToUpper(name.Value)
+	if instance, ok := r.units[key]; ok {
+		if instance == nil {
+			return nil, newRuntimeError(name, "Circular unit reference to '%v'", name.Value)
+		}
+		return instance, nil
+	}
+
+	var file *unitFile
+	var err error
+	if r.Units != nil {
+		file, err = r.Units.load(name.Value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, newRuntimeError(name, "Can't find unit '%v'", name.Value)
+	}
+
+	r.units[key] = nil
+	instance, err := r.evaluateInterface(file)
+	if err != nil {
+		delete(r.units, key)
+		return nil, inUnit(file.path, err)
+	}
+	r.units[key] = instance
+	r.pending = append(r.pending, instance)
+	if err := r.evaluatePending(); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// evaluatePending evaluates the IMPLEMENTATION sections of the pending
+// units unless an INTERFACE section is being evaluated
+func (r *EvaluatorVisitor) evaluatePending() error {
+	for r.interfaces == 0 && len(r.pending) > 0 {
+		instance := r.pending[0]
+		r.pending = r.pending[1:]
+		if err := r.evaluateImplementation(instance); err != nil {
+			return inUnit(instance.path, err)
+		}
+	}
+	return nil
+}
+
+// evaluateInterface declares the INTERFACE section of the unit in a frame
+// of its own, which encloses the declarations of the units it uses, and
+// collects what it exports
+func (r *EvaluatorVisitor) evaluateInterface(file *unitFile) (*unitInstance, error) {
+	r.interfaces++
+	defer func() {
+		r.interfaces--
+	}()
+
+	node := file.node
+	uses := newFrame(nil)
+	if err := r.useUnits(node.InterfaceUses, uses); err != nil {
+		return nil, err
+	}
+
+	instance := &unitInstance{path: file.path, node: node, frame: newFrame(uses), uses: uses, exports: newFrame(nil)}
+	instance.frame.unit = file.path
+	caller, withs := r.frame, r.withs
+	r.frame, r.withs = instance.frame, nil
+	defer func() {
+		r.frame, r.withs = caller, withs
+	}()
+
+	if err := r.declare(node.Interface); err != nil {
+		return nil, err
+	}
+	for name, value := range instance.frame.values {
+		instance.exports.values[name] = value
+	}
+	for name, declared := range instance.frame.typeDefs {
+		instance.exports.typeDefs[name] = declared
+	}
+	for _, v := range node.Interface.Vars() {
+		name := strings.ToUpper(v.Variable.Value)
+		instance.exports.values[name] = r.unitVariable(instance, v)
+	}
+
+	// the routines are exported before the IMPLEMENTATION section is
+	// evaluated, which keeps them when it declares them again
+	routines := node.Implementation.Routines()
+	for _, heading := range node.Headings {
+		i := slices.IndexFunc(routines, func(v ast.RoutineDeclaration) bool {
+			return v.Class == nil && strings.EqualFold(v.Name.Value, heading.Name.Value)
+		})
+		if i < 0 {
+			continue
+		}
+		t, err := r.routineType(heading.Params, heading.Result)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.ToUpper(heading.Name.Value)
+		instance.exports.values[name] = routineValue{Declaration: &routines[i], Frame: instance.frame, Type: t}
+	}
+	return instance, nil
+}
+
+// evaluateImplementation declares the IMPLEMENTATION section of the unit
+// and runs its initialization
+func (r *EvaluatorVisitor) evaluateImplementation(instance *unitInstance) error {
+	caller, withs := r.frame, r.withs
+	r.frame, r.withs = instance.frame, nil
+	defer func() {
+		r.frame, r.withs = caller, withs
+	}()
+
+	node := instance.node
+	if err := r.useUnits(node.ImplementationUses, instance.uses); err != nil {
+		return err
+	}
+	if err := r.declare(node.Implementation); err != nil {
+		return err
+	}
+	for _, v := range node.Headings {
+		name := strings.ToUpper(v.Name.Value)
+		instance.frame.values[name] = instance.exports.values[name]
+	}
+
+	_, err := r.visitCompound(node.Implementation.Compound)
+	if _, exited := err.(exitSignal); !exited && err != nil {
+		return err
+	}
+	r.initialized = append(r.initialized, instance)
+	return nil
+}
+
+// unitVariable returns a reference to the variable of the unit declared
+// by node, through which its users access it
+func (r *EvaluatorVisitor) unitVariable(instance *unitInstance, node ast.VarDeclaration) *reference {
+	name := strings.ToUpper(node.Variable.Value)
+	declared := instance.frame.types[name]
+	return &reference{
+		get: func() (any, error) {
+			value, ok := instance.frame.values[name]
+			if !ok {
+				return nil, inUnit(instance.path, newRuntimeError(node.Variable, "var %v is not initialized", node.Variable.Value))
+			}
+			return value, nil
+		},
+		set: func(value any) error {
+			converted, err := r.convert(node.Variable, value, declared)
+			if err != nil {
+				return err
+			}
+			instance.frame.values[name] = converted
+			return nil
+		},
+		declared: declared,
+		typeName: baseType(declared).String(),
+	}
+}
+
+// useUnits evaluates the units named by a USES clause and makes what they
+// export visible in target, a unit hiding what the ones before it export
+func (r *EvaluatorVisitor) useUnits(uses []ast.Var, target *frame) error {
+	for _, v := range uses {
+		instance, err := r.loadUnit(v)
+		if err != nil {
+			return err
+		}
+		for name, value := range instance.exports.values {
+			target.values[name] = value
+			delete(target.types, name)
+		}
+		for name, declared := range instance.exports.typeDefs {
+			target.typeDefs[name] = declared
+		}
+	}
+	return nil
+}
+
+// finalizeUnits runs the FINALIZATION sections of the initialized units in
+// the reverse order of their initialization
+func (r *EvaluatorVisitor) finalizeUnits() error {
+	caller := r.frame
+	defer func() {
+		r.frame = caller
+	}()
+
+	for len(r.initialized) > 0 {
+		instance := r.initialized[len(r.initialized)-1]
+		r.initialized = r.initialized[:len(r.initialized)-1]
+
+		r.frame = instance.frame
+		err := r.statements(instance.node.Finalization)
+		if _, exited := err.(exitSignal); !exited && err != nil {
+			return inUnit(instance.path, err)
+		}
+	}
+	return nil
+}
+
+// unitPath returns the path of the unit f belongs to, empty for frames of
+// the program
+func (r *frame) unitPath() string {
+	for ; r != nil; r = r.parent {
+		if r.unit != "" {
+			return r.unit
+		}
+	}
+	return ""
+}
@@ -1,186 +1,775 @@
 package interpreter
 
 import (
+	"bufio"
+	"cmp"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
 	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
 )
 
+// EvaluatorVisitor executes a tree. INTEGER values are represented as int,
+// REAL values as float64, CHAR values as byte, STRING values as string and
+// BOOLEAN values as bool.
 type EvaluatorVisitor struct {
 	GloabalScope map[string]any
+	// Input is read by READ and READLN
+	Input *bufio.Reader
+	// Output receives everything the program writes
+	Output io.Writer
+	// declared types of the variables, keyed like GloabalScope
+	types map[string]dataType
+	// types declared in TYPE sections, keyed like GloabalScope
+	typeDefs map[string]dataType
+	// Leaks receives a report of the variables allocated by New and never
+	// disposed when the program ends, if it is set
+	Leaks io.Writer
+	// records opened by the enclosing WITH statements, innermost last
+	withs []*reference
+	// variables allocated by New, the address of a variable is its
+	// position plus one
+	heap []*heapCell
+	// frame of the running routine, nil in the main program
+	frame *frame
+	// number of routine calls in progress
+	depth int
+	// exceptions being handled, innermost last, raised again by RAISE
+	handling []error
+	// Units finds the units used by the program, none can be used if it
+	// is nil
+	Units *Units
+	// units evaluated so far, keyed like GloabalScope, nil while a unit
+	// is being evaluated
+	units map[string]*unitInstance
+	// units whose INITIALIZATION section ran, in order
+	initialized []*unitInstance
+	// units whose IMPLEMENTATION section is left to evaluate and the
+	// number of INTERFACE sections being evaluated
+	pending    []*unitInstance
+	interfaces int
 }
 
-func (r *EvaluatorVisitor) visitOperationNode(node ast.BinaryOperation) (int, error) {
+func (r *EvaluatorVisitor) visitOperationNode(node ast.BinaryOperation) (any, error) {
 	operation := node.GetToken().TokenType
+	if operation == lexer.IS || operation == lexer.AS {
+		return r.typeTest(node)
+	}
 
 	left, err := r.Visit(node.Left)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	right, err := r.Visit(node.Right)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if operation == lexer.MINUS {
-		return left - right, nil
-	} else if operation == lexer.PLUS {
+	if operation == lexer.IN {
+		return r.membership(node, left, right)
+	}
+	leftSet, leftIsSet := left.(setValue)
+	rightSet, rightIsSet := right.(setValue)
+	if leftIsSet && rightIsSet {
+		return r.setOperation(node, leftSet, rightSet)
+	}
+	leftPointer, leftIsPointer := left.(pointerValue)
+	rightPointer, rightIsPointer := right.(pointerValue)
+	if leftIsPointer && rightIsPointer {
+		return r.comparePointers(node, leftPointer, rightPointer)
+	}
+	_, leftIsObject := left.(*objectValue)
+	_, rightIsObject := right.(*objectValue)
+	if leftIsObject || rightIsObject {
+		return r.compareObjects(node, left, right)
+	}
+	_, leftIsRoutine := left.(routineValue)
+	_, rightIsRoutine := right.(routineValue)
+	if leftIsRoutine || rightIsRoutine {
+		return r.compareRoutines(node, left, right)
+	}
+
+	if isRelational(operation) {
+		return r.compare(node, left, right)
+	}
+
+	leftString, leftIsString := toString(left)
+	rightString, rightIsString := toString(right)
+	if operation == lexer.PLUS && leftIsString && rightIsString {
+		return leftString + rightString, nil
+	}
+
+	leftInt, leftIsInt := left.(int)
+	rightInt, rightIsInt := right.(int)
+	if leftIsInt && rightIsInt && operation != lexer.FLOAT_DIV {
+		return r.integerOperation(node, leftInt, rightInt)
+	}
+
+	leftReal, leftOk := toReal(left)
+	rightReal, rightOk := toReal(right)
+	if !leftOk || !rightOk {
+		return nil, newRuntimeError(node, "Operator %v is not defined for %v and %v", node.GetToken().Text(), typeName(left), typeName(right))
+	}
+
+	switch operation {
+	case lexer.PLUS:
+		return leftReal + rightReal, nil
+	case lexer.MINUS:
+		return leftReal - rightReal, nil
+	case lexer.MUL:
+		return leftReal * rightReal, nil
+	case lexer.FLOAT_DIV:
+		if rightReal == 0 {
+			return nil, newKindError(node, DivisionByZero, "Division by zero")
+		}
+		return leftReal / rightReal, nil
+	case lexer.INTEGER_DIV:
+		return nil, newRuntimeError(node, "Operator DIV is not defined for REAL operands")
+	}
+
+	return nil, fmt.Errorf("Cannot evaluate BinaryOperation node %v", node)
+}
+
+func (r *EvaluatorVisitor) integerOperation(node ast.BinaryOperation, left int, right int) (any, error) {
+	switch node.GetToken().TokenType {
+	case lexer.PLUS:
 		return left + right, nil
-	} else if operation == lexer.MUL {
+	case lexer.MINUS:
+		return left - right, nil
+	case lexer.MUL:
 		return left * right, nil
-	} else if operation == lexer.INTEGER_DIV {
-		return left / right, nil
-	} else if operation == lexer.FLOAT_DIV {
+	case lexer.INTEGER_DIV:
+		if right == 0 {
+			return nil, newKindError(node, DivisionByZero, "Division by zero")
+		}
 		return left / right, nil
 	}
 
-	return 0, fmt.Errorf("Cannot evaluate BinaryOperation node %v", node)
+	return nil, fmt.Errorf("Cannot evaluate BinaryOperation node %v", node)
+}
+
+func isRelational(operation lexer.TokenType) bool {
+	for _, v := range relationalOperators {
+		if v == operation {
+			return true
+		}
+	}
+	return false
 }
 
+// compare evaluates a relational operation on two numbers, two strings or
+// characters, or two booleans
+func (r *EvaluatorVisitor) compare(node ast.BinaryOperation, left any, right any) (any, error) {
+	var order int
+
+	leftString, leftIsString := toString(left)
+	rightString, rightIsString := toString(right)
+	leftReal, leftIsReal := toReal(left)
+	rightReal, rightIsReal := toReal(right)
+	leftBool, leftIsBool := left.(bool)
+	rightBool, rightIsBool := right.(bool)
+	leftEnum, leftIsEnum := left.(enumValue)
+	rightEnum, rightIsEnum := right.(enumValue)
+
+	switch {
+	case leftIsString && rightIsString:
+		order = strings.Compare(leftString, rightString)
+	case leftIsReal && rightIsReal:
+		leftInt, leftIsInt := left.(int)
+		rightInt, rightIsInt := right.(int)
+		if leftIsInt && rightIsInt {
+			order = cmp.Compare(leftInt, rightInt)
+		} else {
+			order = cmp.Compare(leftReal, rightReal)
+		}
+	case leftIsBool && rightIsBool:
+		order = cmp.Compare(ordinal(leftBool), ordinal(rightBool))
+	case leftIsEnum && rightIsEnum && typeName(left) == typeName(right):
+		order = cmp.Compare(leftEnum.Ordinal, rightEnum.Ordinal)
+	default:
+		return nil, newRuntimeError(node, "Operator %v is not defined for %v and %v", node.GetToken().Text(), typeName(left), typeName(right))
+	}
 
-func (r *EvaluatorVisitor) visitUnaryNode(node ast.UnaryOperation) (int, error) {
+	switch node.GetToken().TokenType {
+	case lexer.EQUAL:
+		return order == 0, nil
+	case lexer.NOT_EQUAL:
+		return order != 0, nil
+	case lexer.LESS:
+		return order < 0, nil
+	case lexer.LESS_EQUAL:
+		return order <= 0, nil
+	case lexer.GREATER:
+		return order > 0, nil
+	case lexer.GREATER_EQUAL:
+		return order >= 0, nil
+	}
+	return nil, fmt.Errorf("Cannot evaluate BinaryOperation node %v", node)
+}
+
+func (r *EvaluatorVisitor) visitUnaryNode(node ast.UnaryOperation) (any, error) {
 	operation := node.GetToken().TokenType
 
 	right, err := r.Visit(node.Right)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if operation == lexer.PLUS {
-		return +right, nil
-	} else if operation == lexer.MINUS {
-		return -right, nil
+	switch value := right.(type) {
+	case int:
+		if operation == lexer.PLUS {
+			return +value, nil
+		} else if operation == lexer.MINUS {
+			return -value, nil
+		}
+	case float64:
+		if operation == lexer.PLUS {
+			return +value, nil
+		} else if operation == lexer.MINUS {
+			return -value, nil
+		}
+	default:
+		return nil, newRuntimeError(node, "Operator %v is not defined for %v", node.GetToken().Text(), typeName(right))
 	}
 
-	return 0, fmt.Errorf("Cannot evaluate UnaryOperation node %v", node)
+	return nil, fmt.Errorf("Cannot evaluate UnaryOperation node %v", node)
 }
 
-func (r *EvaluatorVisitor) visitIntNode(node ast.IntNode) (int, error) {
+func (r *EvaluatorVisitor) visitIntNode(node ast.IntNode) (any, error) {
 	return node.Value, nil
 }
 
-func (r *EvaluatorVisitor) visitRealNode(node ast.RealNode) (float64, error) {
+func (r *EvaluatorVisitor) visitRealNode(node ast.RealNode) (any, error) {
 	return node.Value, nil
 }
 
-func (r *EvaluatorVisitor) visitCompound(node ast.Compound) (int, error) {
-	for _, v := range node.Children {
-		r.Visit(v)
+// visitStringNode returns a CHAR for literals of a single character
+func (r *EvaluatorVisitor) visitStringNode(node ast.StringNode) (any, error) {
+	if len(node.Value) == 1 {
+		return node.Value[0], nil
 	}
-	return 0, nil
+	return node.Value, nil
 }
 
-func (r *EvaluatorVisitor) visitNoOp(node ast.NoOp) (int, error) {
-	return 0, nil 
+// visitIndex selects an element of an array or a character of a string,
+// a[i, j] is a[i][j]
+func (r *EvaluatorVisitor) visitIndex(node ast.Index) (any, error) {
+	value, err := r.Visit(node.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range node.Indices {
+		index, err := r.Visit(v)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = r.element(v, value, index); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
 }
 
-func (r *EvaluatorVisitor) visitAssign(node ast.AssignOperation) (int, error) {
-	varName := node.Left.(ast.Var).Value
-	rightValue, err := r.Visit(node.Right)
+// visitFieldAccess selects a field of a record or an object, a method
+// being called instead. A constructor of a class creates an object.
+func (r *EvaluatorVisitor) visitFieldAccess(node ast.FieldAccess) (any, error) {
+	if class, ok := r.className(node.Value); ok {
+		return r.construct(node, class, node.Field, nil)
+	}
+
+	value, err := r.Visit(node.Value)
 	if err != nil {
-		return ErrorCode, nil
+		return nil, err
+	}
+	return r.field(node, value, node.Field)
+}
+
+// visitWithStatement opens the fields of the records for the body. The
+// records are selected once, before the body runs.
+func (r *EvaluatorVisitor) visitWithStatement(node ast.WithStatement) (any, error) {
+	depth := len(r.withs)
+	defer func() {
+		r.withs = r.withs[:depth]
+	}()
+
+	for _, v := range node.Records {
+		record, err := r.referenceTo(v)
+		if err != nil {
+			return nil, err
+		}
+		value, err := record.get()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := value.(*recordValue); !ok {
+			return nil, newRuntimeError(v, "Record expected, got %v", typeName(value))
+		}
+		r.withs = append(r.withs, record)
 	}
-	r.GloabalScope[varName] = rightValue
-	return 0, nil
+	return r.Visit(node.Body)
 }
 
-func (r *EvaluatorVisitor) visitVar(node ast.Var) (int, error) {
-	varName := node.Value
-	varValue, ok := r.GloabalScope[varName]
+func (r *EvaluatorVisitor) visitCompound(node ast.Compound) (any, error) {
+	return nil, r.statements(node.Children)
+}
+
+func (r *EvaluatorVisitor) visitNoOp(node ast.NoOp) (any, error) {
+	return nil, nil
+}
+
+func (r *EvaluatorVisitor) visitAssign(node ast.AssignOperation) (any, error) {
+	variable, err := r.referenceTo(node.Left)
+	if err != nil {
+		return nil, err
+	}
+
+	rightValue, err := r.valueFor(node.Right, variable.declared)
+	if err != nil {
+		return nil, err
+	}
+	return nil, variable.set(rightValue)
+}
+
+// assign stores value in variable, converted to its declared type. Errors
+// are reported at node.
+func (r *EvaluatorVisitor) assign(node ast.Node, variable ast.Var, value any) error {
+	varName := strings.ToUpper(variable.Value)
+	scope := r.frameOf(varName)
+	if parameter, ok := scope.values[varName].(*reference); ok {
+		return parameter.set(value)
+	}
+
+	converted, err := r.convert(node, value, scope.types[varName])
+	if err != nil {
+		return err
+	}
+	scope.values[varName] = converted
+	return nil
+}
+
+// reference is a variable passed to a VAR parameter
+type reference struct {
+	get func() (any, error)
+	set func(value any) error
+	// declared type of the variable, nil if unknown
+	declared dataType
+	// name of the base type of declared, empty if unknown
+	typeName string
+}
+
+// referenceTo returns a reference to the variable denoted by node, which
+// may be an element of an array, a field of a record or a character of a
+// string
+func (r *EvaluatorVisitor) referenceTo(node ast.Node) (*reference, error) {
+	switch n := node.(type) {
+	case ast.Index:
+		result, err := r.referenceTo(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range n.Indices {
+			if result, err = r.elementReference(result, v); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+
+	case ast.FieldAccess:
+		record, err := r.referenceTo(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		return r.fieldReference(n, record, n.Field)
+
+	case ast.Dereference:
+		return r.dereferenceReference(n)
+
+	case ast.MethodCall, ast.FunctionCall, ast.BinaryOperation:
+		// an object is shared, so the fields of one returned by a call or
+		// cast by AS are variables as well
+		value, err := r.Visit(n)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := value.(*objectValue); !ok {
+			return nil, newRuntimeError(node, "Variable identifier expected")
+		}
+		return &reference{
+			get: func() (any, error) {
+				return value, nil
+			},
+			set: func(any) error {
+				return newRuntimeError(node, "Variable identifier expected")
+			},
+		}, nil
+	}
+
+	variable, ok := node.(ast.Var)
 	if !ok {
-		return ErrorCode, fmt.Errorf("var %v is not initialized", varName)
+		return nil, newRuntimeError(node, "Variable identifier expected")
+	}
+	if field, err := r.withField(variable); field != nil || err != nil {
+		return field, err
 	}
-	return varValue.(int), nil
+
+	name := strings.ToUpper(variable.Value)
+	scope := r.frameOf(name)
+	if parameter, ok := scope.values[name].(*reference); ok {
+		return parameter, nil
+	}
+	return &reference{
+		get: func() (any, error) {
+			return r.variable(variable)
+		},
+		set: func(value any) error {
+			return r.assign(variable, variable, value)
+		},
+		declared: scope.types[name],
+		typeName: r.variableType(variable),
+	}, nil
 }
 
-func (r *EvaluatorVisitor) visitProgram(node ast.Program) (int, error) {
-	return r.visitBlock(node.Block)
+// convert makes value fit a variable of the declared type, a nil type
+// accepts any value. Arrays are copied.
+func (r *EvaluatorVisitor) convert(node ast.Node, value any, declaredType dataType) (any, error) {
+	switch t := declaredType.(type) {
+	case arrayType, recordType, *enumType:
+		if declaredType.String() != typeName(value) {
+			return nil, newRuntimeError(node, "Incompatible types: got %v expected %v", typeName(value), declaredType)
+		}
+		return copyValue(value), nil
+
+	case subrangeType:
+		converted, err := r.convert(node, value, t.Base)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := ordinalValue(converted); n < t.Low || n > t.High {
+			return nil, newKindError(node, RangeCheckError, "Range check error: %v out of range %v..%v", FormatValue(converted), t.bound(t.Low), t.bound(t.High))
+		}
+		return converted, nil
+
+	case setType:
+		return r.convertSet(node, value, t)
+
+	case pointerType:
+		return r.convertPointer(node, value, t)
+
+	case procType:
+		return r.convertRoutine(node, value, t)
+
+	case *classType:
+		return r.convertObject(node, value, t)
+	}
+
+	switch declaredType {
+	case simpleType("INTEGER"):
+		if _, ok := value.(int); !ok {
+			return nil, newRuntimeError(node, "Incompatible types: got %v expected INTEGER", typeName(value))
+		}
+	case simpleType("REAL"):
+		converted, ok := toReal(value)
+		if !ok {
+			return nil, newRuntimeError(node, "Incompatible types: got %v expected REAL", typeName(value))
+		}
+		return converted, nil
+	case simpleType("CHAR"):
+		if _, ok := value.(byte); !ok {
+			return nil, newRuntimeError(node, "Incompatible types: got %v expected CHAR", typeName(value))
+		}
+	case simpleType("BOOLEAN"):
+		if _, ok := value.(bool); !ok {
+			return nil, newRuntimeError(node, "Incompatible types: got %v expected BOOLEAN", typeName(value))
+		}
+	case simpleType("STRING"):
+		switch v := value.(type) {
+		case byte:
+			return string([]byte{v}), nil
+		case string:
+			return v, nil
+		}
+		return nil, newRuntimeError(node, "Incompatible types: got %v expected STRING", typeName(value))
+	}
+	return copyValue(value), nil
 }
 
-func (r *EvaluatorVisitor) visitBlock(node ast.Block) (int, error) {
-	for _, v := range node.Declarations {
-		r.visitVarDeclaration(v)
+func (r *EvaluatorVisitor) visitVar(node ast.Var) (any, error) {
+	if field, err := r.withField(node); field != nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return field.get()
 	}
-	return r.visitCompound(node.Compound)
+
+	value, err := r.variable(node)
+	if err != nil {
+		return nil, err
+	}
+	if routine, ok := value.(routineValue); ok && routine.Type.Result != nil && len(routine.Type.Params) == 0 {
+		return r.call(node, node.Value, routine, nil)
+	}
+	if method, ok := value.(boundMethod); ok && method.Method.Type.Result != nil && len(method.Method.Type.Params) == 0 {
+		return r.callMethod(node, method.Object, method.Method, nil)
+	}
+	return value, nil
+}
+
+// variable returns the value of a variable, ignoring the records opened
+// by WITH. A routine name stands for the routine.
+func (r *EvaluatorVisitor) variable(node ast.Var) (any, error) {
+	name := strings.ToUpper(node.Value)
+	varValue, ok := r.frameOf(name).values[name]
+	if !ok {
+		return nil, newRuntimeError(node, "var %v is not initialized", node.Value)
+	}
+	if parameter, ok := varValue.(*reference); ok {
+		return parameter.get()
+	}
+	return varValue, nil
 }
 
-func (r *EvaluatorVisitor) visitVarDeclaration(node ast.VarDeclaration) (int, error) {
-	return 0, nil
+// visitProgram runs the program, which Exit ends as if it were finished,
+// after initializing the units it uses. Their finalization runs however
+// the program ends, leaks are reported when Halt stops it too.
+func (r *EvaluatorVisitor) visitProgram(node ast.Program) (any, error) {
+	err := r.useUnits(node.Uses, r.global())
+	if err == nil {
+		_, err = r.visitBlock(node.Block)
+	}
+	if _, exited := err.(exitSignal); exited {
+		err = nil
+	}
+	if finalizationErr := r.finalizeUnits(); err == nil {
+		err = finalizationErr
+	}
+	if _, halted := err.(haltSignal); err != nil && !halted {
+		return nil, err
+	}
+	r.reportLeaks()
+	return nil, err
 }
 
-func (r *EvaluatorVisitor) visitTypeSpec(node ast.TypeSpec) (int, error) {
-	return 0, nil
+func (r *EvaluatorVisitor) visitBlock(node ast.Block) (any, error) {
+	if err := r.declare(node); err != nil {
+		return nil, err
+	}
+	return r.visitCompound(node.Compound)
 }
 
-//TODO update visits for new ast nodes
-func (r *EvaluatorVisitor) Visit(node ast.Node) (int, error) {
-	castedOpNode, ok := node.(ast.BinaryOperation)
-	if ok {
-		return r.visitOperationNode(castedOpNode)
+// declare evaluates the declarations of a block in source order
+func (r *EvaluatorVisitor) declare(node ast.Block) error {
+	for _, v := range node.Declarations {
+		if _, err := r.Visit(v); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	castedIntNode, ok := node.(ast.IntNode)
-	if ok {
-		return r.visitIntNode(castedIntNode)
+func (r *EvaluatorVisitor) visitVarDeclaration(node ast.VarDeclaration) (any, error) {
+	declaredType, err := r.resolveType(node.TypeSpec)
+	if err != nil {
+		return nil, err
 	}
 
-	castedUnaryNode, ok := node.(ast.UnaryOperation)
-	if ok {
-		return r.visitUnaryNode(castedUnaryNode)
+	name := strings.ToUpper(node.Variable.Value)
+	scope := r.scope()
+	scope.types[name] = declaredType
+	if value := newValue(declaredType); value != nil {
+		scope.values[name] = value
+	} else {
+		// the name may denote a variable of a unit used by the program
+		delete(scope.values, name)
 	}
+	return nil, nil
+}
 
-	// castedRealNode, ok := node.(ast.RealNode)
-	// if ok {
-	// 	return r.visitRealNode(castedRealNode)
-	// }
+// visitConstDeclaration stores the value of a constant, converted to its
+// type if it is typed
+func (r *EvaluatorVisitor) visitConstDeclaration(node ast.ConstDeclaration) (any, error) {
+	value, err := r.Visit(node.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.TypeSpec != nil {
+		declaredType, err := r.resolveType(node.TypeSpec)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = r.convert(node.Value, value, declaredType); err != nil {
+			return nil, err
+		}
+	}
+	r.scope().values[strings.ToUpper(node.Name.Value)] = value
+	return nil, nil
+}
 
-	castedVarNode, ok := node.(ast.Var)
-	if ok {
-		return r.visitVar(castedVarNode)
+// visitTypeDeclaration makes the name of a TYPE declaration denote the
+// type, the type itself is named after it unless it is an alias
+func (r *EvaluatorVisitor) visitTypeDeclaration(node ast.TypeDeclaration) (any, error) {
+	if class, isClass := node.TypeSpec.(ast.ClassType); isClass {
+		return nil, r.declareClass(node.Name.Value, class)
 	}
 
-	castedVarDeclarationNode, ok := node.(ast.VarDeclaration)
-	if ok {
-		return r.visitVarDeclaration(castedVarDeclarationNode)
+	declaredType, err := r.resolveType(node.TypeSpec)
+	if err != nil {
+		return nil, err
 	}
 
-	castedCompountNode, ok := node.(ast.Compound)
-	if ok {
-		return r.visitCompound(castedCompountNode)
+	if _, isAlias := node.TypeSpec.(ast.TypeSpec); !isAlias {
+		declaredType = named(declaredType, node.Name.Value)
 	}
+	r.scope().typeDefs[strings.ToUpper(node.Name.Value)] = declaredType
+	return nil, nil
+}
 
-	castedBlockNode, ok := node.(ast.Block)
-	if ok {
-		return r.visitBlock(castedBlockNode)
+func (r *EvaluatorVisitor) visitTypeSpec(node ast.TypeSpec) (any, error) {
+	return nil, nil
+}
+
+// visitProcedureCall calls a routine declared by the program, which hides a
+// builtin of the same name, or a builtin procedure
+func (r *EvaluatorVisitor) visitProcedureCall(node ast.ProcedureCall) (any, error) {
+	if routine, ok := r.routine(node.Name); ok {
+		_, err := r.call(node, node.Name, routine, node.Arguments)
+		return nil, err
 	}
 
-	castedProgramNode, ok := node.(ast.Program)
-	if ok {
-		return r.visitProgram(castedProgramNode)
+	name := strings.ToUpper(node.Name)
+	switch name {
+	case "WRITE":
+		return nil, r.write(node.Arguments, false)
+	case "WRITELN":
+		return nil, r.write(node.Arguments, true)
+	case "READ":
+		return nil, r.read(node.Arguments, false)
+	case "READLN":
+		return nil, r.read(node.Arguments, true)
+	case "NEW":
+		return nil, r.newPointer(node)
+	case "DISPOSE":
+		return nil, r.dispose(node)
+	}
+	if isControlProcedure(name) {
+		return nil, r.controlProcedure(node)
 	}
 
-	castedTypeSpecNode, ok := node.(ast.TypeSpec)
-	if ok {
-		return r.visitTypeSpec(castedTypeSpecNode)
+	if routine, ok := builtinRoutines[name]; ok && !routine.function {
+		_, err := r.callBuiltin(node, routine, node.Arguments)
+		return nil, err
 	}
+	return nil, newRuntimeError(node, "Unknown procedure '%v'", node.Name)
+}
 
-	castedAssignNode, ok := node.(ast.AssignOperation)
-	if ok {
-		return r.visitAssign(castedAssignNode)
+func (r *EvaluatorVisitor) visitFunctionCall(node ast.FunctionCall) (any, error) {
+	if value, ok := r.routine(node.Name); ok {
+		if routine, isRoutine := value.(routineValue); isRoutine && routine.Type.Result == nil {
+			return nil, newRuntimeError(node, "'%v' is not a function", node.Name)
+		}
+		return r.call(node, node.Name, value, node.Arguments)
+	}
+	if routine, ok := builtinRoutines[strings.ToUpper(node.Name)]; ok && routine.function {
+		return r.callBuiltin(node, routine, node.Arguments)
 	}
+	return nil, newRuntimeError(node, "Unknown function '%v'", node.Name)
+}
 
-	castedNoOpNode, ok := node.(ast.NoOp)
-	if ok {
-		return r.visitNoOp(castedNoOpNode)
+// Visit evaluates node and returns its value, nil for statements.
+func (r *EvaluatorVisitor) Visit(node ast.Node) (any, error) {
+	switch n := node.(type) {
+	case ast.BinaryOperation:
+		return r.visitOperationNode(n)
+	case ast.IntNode:
+		return r.visitIntNode(n)
+	case ast.RealNode:
+		return r.visitRealNode(n)
+	case ast.StringNode:
+		return r.visitStringNode(n)
+	case ast.Index:
+		return r.visitIndex(n)
+	case ast.SetConstructor:
+		return r.visitSetConstructor(n)
+	case ast.NilNode:
+		return pointerValue{}, nil
+	case ast.Dereference:
+		return r.visitDereference(n)
+	case ast.FieldAccess:
+		return r.visitFieldAccess(n)
+	case ast.WithStatement:
+		return r.visitWithStatement(n)
+	case ast.UnaryOperation:
+		return r.visitUnaryNode(n)
+	case ast.Var:
+		return r.visitVar(n)
+	case ast.VarDeclaration:
+		return r.visitVarDeclaration(n)
+	case ast.ConstDeclaration:
+		return r.visitConstDeclaration(n)
+	case ast.TypeDeclaration:
+		return r.visitTypeDeclaration(n)
+	case ast.RoutineDeclaration:
+		return r.visitRoutineDeclaration(n)
+	case ast.Compound:
+		return r.visitCompound(n)
+	case ast.Block:
+		return r.visitBlock(n)
+	case ast.Program:
+		return r.visitProgram(n)
+	case ast.Unit:
+		return nil, newRuntimeError(n, "Unit %v can't be run, only used by a program", n.Name)
+	case ast.TypeSpec:
+		return r.visitTypeSpec(n)
+	case ast.AssignOperation:
+		return r.visitAssign(n)
+	case ast.NoOp:
+		return r.visitNoOp(n)
+	case ast.ProcedureCall:
+		return r.visitProcedureCall(n)
+	case ast.FunctionCall:
+		return r.visitFunctionCall(n)
+	case ast.MethodCall:
+		return r.visitMethodCall(n)
+	case ast.InheritedCall:
+		return r.visitInheritedCall(n)
+	case ast.TryExcept:
+		return r.visitTryExcept(n)
+	case ast.TryFinally:
+		return r.visitTryFinally(n)
+	case ast.RaiseStatement:
+		return r.visitRaiseStatement(n)
+	case ast.IfStatement:
+		return r.visitIfStatement(n)
+	case ast.WhileStatement:
+		return r.visitWhileStatement(n)
+	case ast.RepeatStatement:
+		return r.visitRepeatStatement(n)
+	case ast.ForStatement:
+		return r.visitForStatement(n)
+	case ast.LabeledStatement:
+		return r.visitLabeledStatement(n)
+	case ast.GotoStatement:
+		return r.visitGotoStatement(n)
 	}
 
-	return 0, fmt.Errorf("Cannot evaluate node of unknown type %v", node)
+	return nil, fmt.Errorf("Cannot evaluate node of unknown type %T", node)
 }
 
-func NewEvaluatorVisitor() EvaluatorVisitor {
+// NewEvaluatorVisitor creates an evaluator for programs reading input and
+// writing output. An input that already is a *bufio.Reader is used as is,
+// so that its buffer can be shared. The scope starts with the predeclared
+// constants TRUE and FALSE.
+func NewEvaluatorVisitor(input io.Reader, output io.Writer) EvaluatorVisitor {
 	return EvaluatorVisitor{
-		GloabalScope: map[string]any{},
+		GloabalScope: map[string]any{"TRUE": true, "FALSE": false},
+		Input:        bufio.NewReader(input),
+		Output:       output,
+		types:        map[string]dataType{},
+		typeDefs:     map[string]dataType{},
+		units:        map[string]*unitInstance{},
 	}
 }
@@ -0,0 +1,160 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// condition is an open {$IFDEF} or {$IFNDEF} directive
+type condition struct {
+	directive string
+	pos       Position
+	// whether the source of the current branch is compiled
+	active bool
+	// set once the {$ELSE} of the condition is read
+	inElse bool
+}
+
+// Define makes the conditional symbols defined, as {$DEFINE symbol}
+// directives at the start of the text do. It has to be called before the
+// first token is read.
+func (r *BasicLexer) Define(symbols ...string) {
+	if r.defines == nil {
+		r.defines = map[string]bool{}
+	}
+	for _, v := range symbols {
+		r.defines[strings.ToUpper(v)] = true
+	}
+}
+
+// directiveWords splits the text of the directive starting at offset into
+// its upper case name and arguments
+func (r *BasicLexer) directiveWords(offset int) []string {
+	text := r.Text[offset+len("{$"):]
+	if end := strings.IndexByte(text, '}'); end >= 0 {
+		text = text[:end]
+	}
+	return strings.Fields(strings.ToUpper(text))
+}
+
+// directive reads a {$...} compiler directive, which is kept as a comment.
+// DEFINE and UNDEF change the conditional symbols, IFDEF, IFNDEF, ELSE and
+// ENDIF select the source that is compiled; an excluded branch is skipped
+// up to its ELSE or ENDIF. Other directives are ignored.
+func (r *BasicLexer) directive() error {
+	for {
+		start := r.position()
+		words := r.directiveWords(r.Position)
+		if err := r.skipComment(); err != nil {
+			return err
+		}
+		if len(words) == 0 {
+			return nil
+		}
+
+		name := words[0]
+		switch name {
+		case "DEFINE", "UNDEF", "IFDEF", "IFNDEF":
+			if len(words) < 2 {
+				return LexerError{Pos: start, Message: fmt.Sprintf("Conditional symbol expected in {$%v}", name)}
+			}
+		case "ELSE", "ENDIF":
+			if len(r.conditions) == 0 {
+				return LexerError{Pos: start, Message: fmt.Sprintf("{$%v} without {$IFDEF}", name)}
+			}
+		}
+
+		switch name {
+		case "DEFINE":
+			r.Define(words[1])
+		case "UNDEF":
+			delete(r.defines, words[1])
+		case "IFDEF", "IFNDEF":
+			r.conditions = append(r.conditions, condition{
+				directive: name,
+				pos:       start,
+				active:    r.defines[words[1]] == (name == "IFDEF"),
+			})
+		case "ELSE":
+			open := &r.conditions[len(r.conditions)-1]
+			if open.inElse {
+				return LexerError{Pos: start, Message: fmt.Sprintf("Duplicate {$ELSE} of {$%v}", open.directive)}
+			}
+			open.inElse = true
+			open.active = !open.active
+		case "ENDIF":
+			r.conditions = r.conditions[:len(r.conditions)-1]
+		}
+
+		if len(r.conditions) == 0 || r.conditions[len(r.conditions)-1].active {
+			return nil
+		}
+		r.skipBranch()
+		if r.IsReachedEOF {
+			return nil
+		}
+	}
+}
+
+// skipBranch skips the source of an excluded branch up to the {$ELSE} or
+// {$ENDIF} ending it, skipping nested conditions as a whole. The source is
+// kept as a comment so that formatting does not lose it.
+func (r *BasicLexer) skipBranch() {
+	for !r.IsReachedEOF && r.isOnSpace() {
+		r.advance()
+	}
+	start, startOffset := r.position(), r.Position
+	end, endOffset := start, startOffset
+	defer func() {
+		if endOffset > startOffset {
+			r.Comments = append(r.Comments, Comment{Text: r.Text[startOffset:endOffset], Pos: start, End: end})
+		}
+	}()
+
+	depth := 0
+	for !r.IsReachedEOF {
+		switch r.currentRune() {
+		case '{':
+			if r.peekRune() == '$' {
+				var name string
+				if words := r.directiveWords(r.Position); len(words) > 0 {
+					name = words[0]
+				}
+				switch {
+				case name == "IFDEF" || name == "IFNDEF":
+					depth++
+				case depth == 0 && (name == "ELSE" || name == "ENDIF"):
+					return
+				case name == "ENDIF":
+					depth--
+				}
+			}
+			for !r.IsReachedEOF && r.currentRune() != '}' {
+				r.advance()
+			}
+		case '\'':
+			r.advance()
+			for !r.IsReachedEOF && r.currentRune() != '\'' {
+				r.advance()
+			}
+		}
+
+		if !r.IsReachedEOF {
+			space := r.isOnSpace()
+			r.advance()
+			if !space {
+				end, endOffset = r.position(), r.Position
+			}
+		}
+	}
+}
+
+// unterminatedCondition reports the innermost condition left open at the
+// end of the text, if any
+func (r *BasicLexer) unterminatedCondition() error {
+	if len(r.conditions) == 0 {
+		return nil
+	}
+	open := r.conditions[len(r.conditions)-1]
+	return LexerError{Pos: open.pos, Message: fmt.Sprintf("{$%v} without {$ENDIF}", open.directive)}
+}
@@ -2,14 +2,33 @@ package lexer
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"unicode"
 )
 
+// LexerError reports malformed source text.
+type LexerError struct {
+	Pos     Position
+	Message string
+}
+
+func (r LexerError) Error() string {
+	return fmt.Sprintf("%v:%v: syntax error: %v", r.Pos.Line, r.Pos.Column, r.Message)
+}
+
 type BasicLexer struct {
 	Text         string
 	Position     int
 	CurrentToken *BasicToken
 	IsReachedEOF bool
+	Comments     []Comment
+	line         int
+	column       int
+	// conditional symbols defined by Define and {$DEFINE}, upper case
+	defines map[string]bool
+	// conditional directives not closed by {$ENDIF} yet, innermost last
+	conditions []condition
 }
 
 func (r *BasicLexer) currentChar() *byte {
@@ -19,6 +38,13 @@ func (r *BasicLexer) currentChar() *byte {
 
 func (r *BasicLexer) advance() {
 	if !r.IsReachedEOF {
+		if r.Text[r.Position] == '\n' {
+			r.line++
+			r.column = 1
+		} else {
+			r.column++
+		}
+
 		r.Position++
 		if r.Position >= len(r.Text) {
 			r.IsReachedEOF = true
@@ -41,7 +67,15 @@ func (r *BasicLexer) currentRune() rune {
 
 
 func (r *BasicLexer) peekRune() rune {
-	return rune(*r.peek())
+	char := r.peek()
+	if char == nil {
+		return 0
+	}
+	return rune(*char)
+}
+
+func (r *BasicLexer) position() Position {
+	return Position{Line: r.line, Column: r.column}
 }
 
 func (r *BasicLexer) isOnSpace() bool {
@@ -59,11 +93,11 @@ func (r *BasicLexer) parseNumber() BasicToken {
 		r.advance()
 	}
 
-	if r.currentRune() == '.' {
+	if !r.IsReachedEOF && r.currentRune() == '.' && r.peek() != nil && unicode.IsDigit(r.peekRune()) {
 		result += string(*r.currentChar())
 		r.advance()
 
-		for r.currentChar() != nil || unicode.IsDigit(r.currentRune()) {
+		for !r.IsReachedEOF && r.isOnDigit() {
 			result += string(*r.currentChar())
 			r.advance()
 		}
@@ -80,17 +114,104 @@ func (r *BasicLexer) parseNumber() BasicToken {
 	}
 }
 
+// stringLiteral reads a sequence of quoted strings and #nn character codes,
+// e.g. 'it''s'#10, into a single token holding the decoded text
+func (r *BasicLexer) stringLiteral() (BasicToken, error) {
+	var result []byte
+	for !r.IsReachedEOF && (r.currentRune() == '\'' || r.currentRune() == '#') {
+		start := r.position()
+
+		if r.currentRune() == '#' {
+			r.advance()
+			digits := ""
+			for !r.IsReachedEOF && r.isOnDigit() {
+				digits += string(*r.currentChar())
+				r.advance()
+			}
+
+			code, err := strconv.Atoi(digits)
+			if err != nil || code > 255 {
+				return BasicToken{}, LexerError{Pos: start, Message: "Invalid character code"}
+			}
+			result = append(result, byte(code))
+			continue
+		}
+
+		r.advance()
+		for {
+			if r.IsReachedEOF || r.currentRune() == '\n' {
+				return BasicToken{}, LexerError{Pos: start, Message: "Unterminated string literal"}
+			}
+
+			if r.currentRune() == '\'' {
+				r.advance()
+				if r.IsReachedEOF || r.currentRune() != '\'' {
+					break
+				}
+			}
+			result = append(result, *r.currentChar())
+			r.advance()
+		}
+	}
+
+	return BasicToken{
+		TokenType:  STRING_LITERAL,
+		TokenValue: string(result),
+	}, nil
+}
+
+// relational reads = < <= <> > >=
+func (r *BasicLexer) relational() BasicToken {
+	currentRune := r.currentRune()
+	r.advance()
+
+	next := rune(0)
+	if !r.IsReachedEOF {
+		next = r.currentRune()
+	}
+
+	switch {
+	case currentRune == '<' && next == '=':
+		r.advance()
+		return BasicToken{TokenType: LESS_EQUAL}
+	case currentRune == '<' && next == '>':
+		r.advance()
+		return BasicToken{TokenType: NOT_EQUAL}
+	case currentRune == '<':
+		return BasicToken{TokenType: LESS}
+	case currentRune == '>' && next == '=':
+		r.advance()
+		return BasicToken{TokenType: GREATER_EQUAL}
+	case currentRune == '>':
+		return BasicToken{TokenType: GREATER}
+	}
+	return BasicToken{TokenType: EQUAL}
+}
+
 func (r *BasicLexer) skipWhitespace() {
 	if !r.IsReachedEOF && r.isOnSpace() {
 		r.advance()
 	}
 }
 
-func (r *BasicLexer) skipComment() {
-	for r.currentRune() != '}' {
+func (r *BasicLexer) skipComment() error {
+	start := r.position()
+	startOffset := r.Position
+
+	for !r.IsReachedEOF && r.currentRune() != '}' {
 		r.advance()
 	}
+	if r.IsReachedEOF {
+		return LexerError{Pos: start, Message: "Unterminated comment"}
+	}
 	r.advance()
+
+	r.Comments = append(r.Comments, Comment{
+		Text: r.Text[startOffset:r.Position],
+		Pos:  start,
+		End:  r.position(),
+	})
+	return nil
 }
 
 func (r *BasicLexer) handleNoValueToken(symbol rune, token BasicToken) (BasicToken, error) {
@@ -98,7 +219,10 @@ func (r *BasicLexer) handleNoValueToken(symbol rune, token BasicToken) (BasicTok
 		r.advance()
 		return token, nil
 	}
-	return BasicToken{}, fmt.Errorf("Got rune %v, expected %v", r.currentRune(), symbol)
+	return BasicToken{}, LexerError{
+		Pos:     r.position(),
+		Message: fmt.Sprintf("Got rune %q, expected %q", r.currentRune(), symbol),
+	}
 }
 
 func (r *BasicLexer) peek() *byte {
@@ -118,7 +242,7 @@ func (r *BasicLexer) identifier() BasicToken {
 		r.advance()
 	}
 
-	reserved, ok := ReservedKeywords[result]
+	reserved, ok := ReservedKeywords[strings.ToUpper(result)]
 	if ok {
 		return reserved
 	} else {
@@ -136,72 +260,110 @@ func (r *BasicLexer) NextToken() (BasicToken, error) {
 			continue
 		}
 
+		if r.currentRune() == '{' && r.peekRune() == '$' {
+			if err := r.directive(); err != nil {
+				return BasicToken{}, err
+			}
+			continue
+		}
 
-
-		currentRune := r.currentRune()
-
-		if currentRune == '{' {
-			r.advance()
-			r.skipComment()
+		if r.currentRune() == '{' {
+			if err := r.skipComment(); err != nil {
+				return BasicToken{}, err
+			}
 			continue
-		} 
-		
-		if r.isOnDigit() {
-			return r.parseNumber(), nil
-		} else if currentRune == '+' {
-			token := BasicToken{TokenType: PLUS}
-			r.advance()
-			return token, nil
-		} else if currentRune == '-' {
-			token := BasicToken{TokenType: MINUS}
-			r.advance()
-			return token, nil
-		} else if currentRune == '*' {
-			token := BasicToken {TokenType: MUL }
-			r.advance()
-			return token, nil
-		} else if currentRune == '/' {
-			token := BasicToken { TokenType: FLOAT_DIV}
-			r.advance()
-			return token, nil
-		} else if currentRune == '(' {
-			return r.handleNoValueToken('(', BasicToken{TokenType: LPAREN})
-		} else if currentRune == ')' {
-			return r.handleNoValueToken(')', BasicToken{TokenType: RPAREN})
-		} else if currentRune == ':' && r.peekRune() == '=' {
-			r.advance()
-			r.advance()
-			token := BasicToken {TokenType: ASSIGN}
-			return token, nil
-		} else if currentRune == ';' {
-			r.advance()
-			token := BasicToken {TokenType: SEMICOLON}
-			return token, nil
-		} else if currentRune == '.' {
-			r.advance()
-			token := BasicToken{TokenType: DOT}
-			return token, nil
-		} else if unicode.IsLetter(currentRune) {
-			return r.identifier(), nil
-		} else if currentRune == ':' {
-			r.advance()
-			token := BasicToken{ TokenType: COLON }
-			return token, nil
-		} else if currentRune == ',' {
-			r.advance()
-			token := BasicToken{ TokenType: COMMA}
-			return token, nil
-		} else if currentRune == '/' {
-			r.advance()
-			token := BasicToken{ TokenType: FLOAT_DIV }
-			return token, nil
 		}
 
+		start := r.position()
+		token, err := r.scanToken()
+		if err != nil {
+			return BasicToken{}, err
+		}
+
+		token.Pos = start
+		token.End = r.position()
+		return token, nil
+	}
+	if err := r.unterminatedCondition(); err != nil {
+		return BasicToken{}, err
 	}
-	token := BasicToken{TokenType: EOF}
+	token := BasicToken{TokenType: EOF, Pos: r.position(), End: r.position()}
 	return token, nil
 }
 
+func (r *BasicLexer) scanToken() (BasicToken, error) {
+	currentRune := r.currentRune()
+
+	if r.isOnDigit() {
+		return r.parseNumber(), nil
+	} else if currentRune == '+' {
+		token := BasicToken{TokenType: PLUS}
+		r.advance()
+		return token, nil
+	} else if currentRune == '-' {
+		token := BasicToken{TokenType: MINUS}
+		r.advance()
+		return token, nil
+	} else if currentRune == '*' {
+		token := BasicToken {TokenType: MUL }
+		r.advance()
+		return token, nil
+	} else if currentRune == '/' {
+		token := BasicToken { TokenType: FLOAT_DIV}
+		r.advance()
+		return token, nil
+	} else if currentRune == '(' {
+		return r.handleNoValueToken('(', BasicToken{TokenType: LPAREN})
+	} else if currentRune == ')' {
+		return r.handleNoValueToken(')', BasicToken{TokenType: RPAREN})
+	} else if currentRune == ':' && r.peekRune() == '=' {
+		r.advance()
+		r.advance()
+		token := BasicToken {TokenType: ASSIGN}
+		return token, nil
+	} else if currentRune == ';' {
+		r.advance()
+		token := BasicToken {TokenType: SEMICOLON}
+		return token, nil
+	} else if currentRune == '.' && r.peekRune() == '.' {
+		r.advance()
+		r.advance()
+		return BasicToken{TokenType: DOTDOT}, nil
+	} else if currentRune == '.' {
+		r.advance()
+		token := BasicToken{TokenType: DOT}
+		return token, nil
+	} else if unicode.IsLetter(currentRune) {
+		return r.identifier(), nil
+	} else if currentRune == ':' {
+		r.advance()
+		token := BasicToken{ TokenType: COLON }
+		return token, nil
+	} else if currentRune == ',' {
+		r.advance()
+		token := BasicToken{ TokenType: COMMA}
+		return token, nil
+	} else if currentRune == '\'' || currentRune == '#' {
+		return r.stringLiteral()
+	} else if currentRune == '=' || currentRune == '<' || currentRune == '>' {
+		return r.relational(), nil
+	} else if currentRune == '[' {
+		r.advance()
+		return BasicToken{TokenType: LBRACKET}, nil
+	} else if currentRune == ']' {
+		r.advance()
+		return BasicToken{TokenType: RBRACKET}, nil
+	} else if currentRune == '^' {
+		r.advance()
+		return BasicToken{TokenType: CARET}, nil
+	}
+
+	return BasicToken{}, LexerError{
+		Pos:     r.position(),
+		Message: fmt.Sprintf("Unexpected character %q", currentRune),
+	}
+}
+
 func (r *BasicLexer) Eat(tokenType TokenType) (error) {
 	if r.CurrentToken.TokenType == tokenType {
 		token, err := r.NextToken()
@@ -220,6 +382,10 @@ func (r *BasicLexer) GetCurrentToken() *BasicToken {
 	return r.CurrentToken
 }
 
+func (r *BasicLexer) GetComments() []Comment {
+	return r.Comments
+}
+
 func NewLexer(text string) BasicLexer {
 	eof := false
 	if len(text) == 0 {
@@ -231,6 +397,8 @@ func NewLexer(text string) BasicLexer {
 		Position: 0,
 		CurrentToken: nil,
 		IsReachedEOF: eof,
+		line: 1,
+		column: 1,
 	}
 
 	return lexer
@@ -66,6 +66,21 @@ func TestBasicLexer_NextToken(t *testing.T) {
 		require.Equal(t, "5", token.TokenValue)
 	})
 
+	t.Run("'3.14' REAL Expected", func(t *testing.T) {
+		lexer := NewLexer("3.14")
+		token, err := lexer.NextToken()
+		require.NoError(t, err)
+		require.Equal(t, REAL, token.TokenType)
+		require.Equal(t, "3.14", token.TokenValue)
+		require.True(t, lexer.IsReachedEOF)
+	})
+
+	t.Run("'1.' INTEGER followed by DOT", func(t *testing.T) {
+		lexer := NewLexer("1.")
+		expectTokenType(t, &lexer, INTEGER)
+		expectTokenType(t, &lexer, DOT)
+	})
+
 	t.Run("Consecutive calls", func(t *testing.T) {
 		lexer := NewLexer("5 + 3")
 		token, err := lexer.NextToken()
@@ -323,3 +338,276 @@ func TestBasicLexer_Initialize(t *testing.T) {
 		require.Equal(t, EOF, lexer.CurrentToken.TokenType)
 	})
 }
+
+func TestBasicLexer_positions(t *testing.T) {
+	t.Run("Tokens carry line and column", func(t *testing.T) {
+		lexer := NewLexer("BEGIN\n  number := 25\nEND")
+
+		token, err := lexer.NextToken()
+		require.NoError(t, err)
+		require.Equal(t, Position{Line: 1, Column: 1}, token.Pos)
+		require.Equal(t, Position{Line: 1, Column: 6}, token.End)
+
+		token, err = lexer.NextToken()
+		require.NoError(t, err)
+		require.Equal(t, Position{Line: 2, Column: 3}, token.Pos)
+		require.Equal(t, Position{Line: 2, Column: 9}, token.End)
+
+		token, err = lexer.NextToken()
+		require.NoError(t, err)
+		require.Equal(t, Position{Line: 2, Column: 10}, token.Pos)
+
+		token, err = lexer.NextToken()
+		require.NoError(t, err)
+		require.Equal(t, Position{Line: 2, Column: 13}, token.Pos)
+		require.Equal(t, Position{Line: 2, Column: 15}, token.End)
+
+		token, err = lexer.NextToken()
+		require.NoError(t, err)
+		require.Equal(t, END, token.TokenType)
+		require.Equal(t, Position{Line: 3, Column: 1}, token.Pos)
+	})
+}
+
+func TestBasicLexer_comments(t *testing.T) {
+	t.Run("Comments are skipped and recorded", func(t *testing.T) {
+		lexer := NewLexer("{ first }BEGIN\n{ second\n line } END")
+		expectTokenType(t, &lexer, BEGIN)
+		expectTokenType(t, &lexer, END)
+
+		require.Equal(t, []Comment{
+			{Text: "{ first }", Pos: Position{Line: 1, Column: 1}, End: Position{Line: 1, Column: 10}},
+			{Text: "{ second\n line }", Pos: Position{Line: 2, Column: 1}, End: Position{Line: 3, Column: 8}},
+		}, lexer.GetComments())
+	})
+
+	t.Run("Unterminated comment is an error", func(t *testing.T) {
+		lexer := NewLexer("BEGIN { END")
+		expectTokenType(t, &lexer, BEGIN)
+
+		_, err := lexer.NextToken()
+		require.Error(t, err)
+	})
+}
+
+func TestBasicLexer_keywords(t *testing.T) {
+	t.Run("Keywords are case-insensitive", func(t *testing.T) {
+		lexer := NewLexer("program Begin end Integer")
+		expectTokenType(t, &lexer, PROGRAM)
+		expectTokenType(t, &lexer, BEGIN)
+		expectTokenType(t, &lexer, END)
+
+		token, err := lexer.NextToken()
+		require.NoError(t, err)
+		require.Equal(t, INTEGER_DECLARAION, token.TokenType)
+		require.Equal(t, "INTEGER", token.TokenValue)
+	})
+
+	t.Run("Unknown characters are reported", func(t *testing.T) {
+		lexer := NewLexer("@")
+		_, err := lexer.NextToken()
+		require.Error(t, err)
+	})
+}
+
+func TestBasicLexer_strings(t *testing.T) {
+	t.Run("Quotes are doubled and character codes joined", func(t *testing.T) {
+		cases := map[string]string{
+			"'hello'":        "hello",
+			"''":             "",
+			"'it''s'":        "it's",
+			"''''":           "'",
+			"#65":            "A",
+			"'a'#13#10'b'":   "a\r\nb",
+			"#39'quoted'#39": "'quoted'",
+		}
+
+		for source, expected := range cases {
+			lexer := NewLexer(source)
+			token, err := lexer.NextToken()
+			require.NoError(t, err, source)
+			require.Equal(t, STRING_LITERAL, token.TokenType, source)
+			require.Equal(t, expected, token.TokenValue, source)
+			require.True(t, lexer.IsReachedEOF, source)
+		}
+	})
+
+	t.Run("Text quotes the value again", func(t *testing.T) {
+		require.Equal(t, "'it''s'#10", BasicToken{TokenType: STRING_LITERAL, TokenValue: "it's\n"}.Text())
+		require.Equal(t, "''", BasicToken{TokenType: STRING_LITERAL}.Text())
+		require.Equal(t, "#9'x'", QuoteString("\tx"))
+	})
+
+	t.Run("Malformed literals", func(t *testing.T) {
+		for _, source := range []string{"'open", "'line\nbreak'", "#", "#256"} {
+			lexer := NewLexer(source)
+			_, err := lexer.NextToken()
+			require.ErrorAs(t, err, &LexerError{}, source)
+		}
+	})
+}
+
+func TestBasicLexer_relational(t *testing.T) {
+	lexer := NewLexer("= <> < <= > >= [ ] a<b")
+	for _, v := range []TokenType{EQUAL, NOT_EQUAL, LESS, LESS_EQUAL, GREATER, GREATER_EQUAL, LBRACKET, RBRACKET, ID, LESS, ID, EOF} {
+		expectTokenType(t, &lexer, v)
+	}
+}
+
+func TestBasicLexer_ranges(t *testing.T) {
+	lexer := NewLexer("ARRAY[1..10, 'a'..'z'] of Real; x. 1.5..2")
+	for _, v := range []TokenType{ARRAY, LBRACKET, INTEGER, DOTDOT, INTEGER, COMMA, STRING_LITERAL, DOTDOT, STRING_LITERAL, RBRACKET, OF, REAL_DECLARATION, SEMICOLON, ID, DOT, REAL, DOTDOT, INTEGER, EOF} {
+		expectTokenType(t, &lexer, v)
+	}
+}
+
+func TestBasicLexer_records(t *testing.T) {
+	lexer := NewLexer("Record x: integer end; with p.q do p.x")
+	for _, v := range []TokenType{RECORD, ID, COLON, INTEGER_DECLARAION, END, SEMICOLON, WITH, ID, DOT, ID, DO, ID, DOT, ID, EOF} {
+		expectTokenType(t, &lexer, v)
+	}
+}
+
+func TestBasicLexer_types(t *testing.T) {
+	lexer := NewLexer("type Color = (Red, Green); Digit = 0..9;")
+	for _, v := range []TokenType{TYPE, ID, EQUAL, LPAREN, ID, COMMA, ID, RPAREN, SEMICOLON, ID, EQUAL, INTEGER, DOTDOT, INTEGER, SEMICOLON, EOF} {
+		expectTokenType(t, &lexer, v)
+	}
+}
+
+func TestBasicLexer_constants(t *testing.T) {
+	lexer := NewLexer("Const Max = 10; Limit: integer = -Max * 2;")
+	for _, v := range []TokenType{CONST, ID, EQUAL, INTEGER, SEMICOLON, ID, COLON, INTEGER_DECLARAION, EQUAL, MINUS, ID, MUL, INTEGER, SEMICOLON, EOF} {
+		expectTokenType(t, &lexer, v)
+	}
+}
+
+func TestBasicLexer_sets(t *testing.T) {
+	lexer := NewLexer("Set of char; c in ['a'..'z', '_']")
+	for _, v := range []TokenType{SET, OF, CHAR_DECLARATION, SEMICOLON, ID, IN, LBRACKET, STRING_LITERAL, DOTDOT, STRING_LITERAL, COMMA, STRING_LITERAL, RBRACKET, EOF} {
+		expectTokenType(t, &lexer, v)
+	}
+}
+
+func TestBasicLexer_pointers(t *testing.T) {
+	lexer := NewLexer("p: ^Node; p^.next := nil")
+	for _, v := range []TokenType{ID, COLON, CARET, ID, SEMICOLON, ID, CARET, DOT, ID, ASSIGN, NIL, EOF} {
+		expectTokenType(t, &lexer, v)
+	}
+}
+
+func TestBasicLexer_routines(t *testing.T) {
+	lexer := NewLexer("Procedure P(var x: Integer); function F: Real;")
+	for _, v := range []TokenType{PROCEDURE, ID, LPAREN, VAR, ID, COLON, INTEGER_DECLARAION, RPAREN, SEMICOLON, FUNCTION, ID, COLON, REAL_DECLARATION, SEMICOLON, EOF} {
+		expectTokenType(t, &lexer, v)
+	}
+}
+
+func TestBasicLexer_exceptions(t *testing.T) {
+	lexer := NewLexer("try raise except on E: Exception do else raise end; try finally end")
+	for _, v := range []TokenType{TRY, RAISE, EXCEPT, ON, ID, COLON, ID, DO, ELSE, RAISE, END, SEMICOLON, TRY, FINALLY, END, EOF} {
+		expectTokenType(t, &lexer, v)
+	}
+}
+
+func TestBasicLexer_controlFlow(t *testing.T) {
+	lexer := NewLexer("if a then else while do repeat until for i := 1 to 2 downto")
+	for _, v := range []TokenType{IF, ID, THEN, ELSE, WHILE, DO, REPEAT, UNTIL, FOR, ID, ASSIGN, INTEGER, TO, INTEGER, DOWNTO, EOF} {
+		expectTokenType(t, &lexer, v)
+	}
+}
+
+func TestBasicLexer_labels(t *testing.T) {
+	lexer := NewLexer("label 10, done; 10: goto done")
+	for _, v := range []TokenType{LABEL, INTEGER, COMMA, ID, SEMICOLON, INTEGER, COLON, GOTO, ID, EOF} {
+		expectTokenType(t, &lexer, v)
+	}
+}
+
+func TestBasicLexer_classes(t *testing.T) {
+	lexer := NewLexer("type TCircle = class(TShape) constructor Create; override; end; inherited Create; s is TCircle; (s as TCircle); destructor virtual")
+	for _, v := range []TokenType{
+		TYPE, ID, EQUAL, CLASS, LPAREN, ID, RPAREN, CONSTRUCTOR, ID, SEMICOLON, OVERRIDE, SEMICOLON, END, SEMICOLON,
+		INHERITED, ID, SEMICOLON, ID, IS, ID, SEMICOLON, LPAREN, ID, AS, ID, RPAREN, SEMICOLON, DESTRUCTOR, VIRTUAL, EOF,
+	} {
+		expectTokenType(t, &lexer, v)
+	}
+}
+
+func TestBasicLexer_units(t *testing.T) {
+	lexer := NewLexer("unit Shapes; interface uses Math; implementation initialization finalization end.")
+	for _, v := range []TokenType{
+		UNIT, ID, SEMICOLON, INTERFACE, USES, ID, SEMICOLON, IMPLEMENTATION, INITIALIZATION, FINALIZATION, END, DOT, EOF,
+	} {
+		expectTokenType(t, &lexer, v)
+	}
+}
+
+// identifiers returns the names of the identifiers lexer reads up to EOF
+func identifiers(t *testing.T, lexer *BasicLexer) []string {
+	t.Helper()
+	var names []string
+	for {
+		token, err := lexer.NextToken()
+		require.NoError(t, err)
+		if token.TokenType == EOF {
+			return names
+		}
+		names = append(names, token.TokenValue)
+	}
+}
+
+func TestBasicLexer_directives(t *testing.T) {
+	t.Run("Branches are selected by defined symbols", func(t *testing.T) {
+		source := "{$IFDEF DEBUG} a {$ELSE} b {$ENDIF} {$IFNDEF debug} c {$ELSE} d {$ENDIF}"
+
+		lexer := NewLexer(source)
+		require.Equal(t, []string{"b", "c"}, identifiers(t, &lexer))
+
+		lexer = NewLexer(source)
+		lexer.Define("Debug")
+		require.Equal(t, []string{"a", "d"}, identifiers(t, &lexer))
+	})
+
+	t.Run("DEFINE and UNDEF", func(t *testing.T) {
+		lexer := NewLexer("{$DEFINE x} {$IFDEF X} a {$ENDIF} {$UNDEF x} {$IFDEF x} b {$ENDIF}")
+		require.Equal(t, []string{"a"}, identifiers(t, &lexer))
+	})
+
+	t.Run("Excluded branches skip nested conditions, strings and comments", func(t *testing.T) {
+		lexer := NewLexer("{$IFDEF A} a {$IFDEF B} b {$ELSE} c {$ENDIF} '{$ENDIF}' { {$ELSE} } {$ELSE} d {$ENDIF} e")
+		require.Equal(t, []string{"d", "e"}, identifiers(t, &lexer))
+	})
+
+	t.Run("Directives and excluded source are kept as comments", func(t *testing.T) {
+		lexer := NewLexer("{$IFDEF A}\n  a;\n  b\n{$ENDIF}\n{$R+} c")
+		require.Equal(t, []string{"c"}, identifiers(t, &lexer))
+
+		var texts []string
+		for _, v := range lexer.GetComments() {
+			texts = append(texts, v.Text)
+		}
+		require.Equal(t, []string{"{$IFDEF A}", "a;\n  b", "{$ENDIF}", "{$R+}"}, texts)
+		require.Equal(t, Position{Line: 2, Column: 3}, lexer.GetComments()[1].Pos)
+		require.Equal(t, Position{Line: 3, Column: 4}, lexer.GetComments()[1].End)
+	})
+
+	t.Run("Malformed conditions", func(t *testing.T) {
+		for _, source := range []string{
+			"{$IFDEF A} a",
+			"{$IFNDEF A} a {$ELSE}",
+			"{$ENDIF}",
+			"{$ELSE}",
+			"{$IFDEF A} {$ELSE} {$ELSE} {$ENDIF}",
+			"{$IFDEF}",
+			"{$DEFINE A",
+		} {
+			lexer := NewLexer(source)
+			var err error
+			for token := (BasicToken{}); err == nil && token.TokenType != EOF; {
+				token, err = lexer.NextToken()
+			}
+			require.ErrorAs(t, err, &LexerError{}, source)
+		}
+	})
+}
@@ -3,9 +3,54 @@ package lexer
 var ReservedKeywords map[string]BasicToken = map[string]BasicToken{
 	"PROGRAM":   {TokenType: PROGRAM},
 	"VAR":   {TokenType: VAR},
+	"TYPE":  {TokenType: TYPE},
+	"CONST": {TokenType: CONST},
 	"DIV":   {TokenType: INTEGER_DIV},
 	"INTEGER":   {TokenType: INTEGER_DECLARAION, TokenValue: "INTEGER" },
 	"REAL":   {TokenType: REAL_DECLARATION, TokenValue: "REAL" },
+	"CHAR":   {TokenType: CHAR_DECLARATION, TokenValue: "CHAR" },
+	"STRING":   {TokenType: STRING_DECLARATION, TokenValue: "STRING" },
+	"BOOLEAN":   {TokenType: BOOLEAN_DECLARATION, TokenValue: "BOOLEAN" },
+	"ARRAY": {TokenType: ARRAY},
+	"OF":    {TokenType: OF},
+	"SET":   {TokenType: SET},
+	"IN":    {TokenType: IN},
+	"NIL":   {TokenType: NIL},
+	"PROCEDURE": {TokenType: PROCEDURE},
+	"FUNCTION":  {TokenType: FUNCTION},
+	"TRY":     {TokenType: TRY},
+	"EXCEPT":  {TokenType: EXCEPT},
+	"FINALLY": {TokenType: FINALLY},
+	"RAISE":   {TokenType: RAISE},
+	"ON":      {TokenType: ON},
+	"ELSE":    {TokenType: ELSE},
+	"IF":      {TokenType: IF},
+	"THEN":    {TokenType: THEN},
+	"WHILE":   {TokenType: WHILE},
+	"REPEAT":  {TokenType: REPEAT},
+	"UNTIL":   {TokenType: UNTIL},
+	"FOR":     {TokenType: FOR},
+	"TO":      {TokenType: TO},
+	"DOWNTO":  {TokenType: DOWNTO},
+	"LABEL":   {TokenType: LABEL},
+	"GOTO":    {TokenType: GOTO},
+	"CLASS":       {TokenType: CLASS},
+	"CONSTRUCTOR": {TokenType: CONSTRUCTOR},
+	"DESTRUCTOR":  {TokenType: DESTRUCTOR},
+	"VIRTUAL":     {TokenType: VIRTUAL},
+	"OVERRIDE":    {TokenType: OVERRIDE},
+	"INHERITED":   {TokenType: INHERITED},
+	"IS":          {TokenType: IS},
+	"AS":          {TokenType: AS},
+	"UNIT":           {TokenType: UNIT},
+	"INTERFACE":      {TokenType: INTERFACE},
+	"IMPLEMENTATION": {TokenType: IMPLEMENTATION},
+	"USES":           {TokenType: USES},
+	"INITIALIZATION": {TokenType: INITIALIZATION},
+	"FINALIZATION":   {TokenType: FINALIZATION},
+	"RECORD": {TokenType: RECORD},
+	"WITH":  {TokenType: WITH},
+	"DO":    {TokenType: DO},
 	"BEGIN": {TokenType: BEGIN},
 	"END":   {TokenType: END},
 }
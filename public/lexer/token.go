@@ -1,4 +1,9 @@
-package lexer 
+package lexer
+
+import (
+	"fmt"
+	"strings"
+)
 
 
 type TokenType int
@@ -26,17 +31,316 @@ const (
 	COMMA
 	FLOAT_DIV
 	INTEGER_DIV
+	CHAR_DECLARATION
+	STRING_DECLARATION
+	STRING_LITERAL
+	BOOLEAN_DECLARATION
+	EQUAL
+	NOT_EQUAL
+	LESS
+	LESS_EQUAL
+	GREATER
+	GREATER_EQUAL
+	LBRACKET
+	RBRACKET
+	DOTDOT
+	ARRAY
+	OF
+	RECORD
+	WITH
+	DO
+	TYPE
+	CONST
+	SET
+	IN
+	CARET
+	NIL
+	PROCEDURE
+	FUNCTION
+	TRY
+	EXCEPT
+	FINALLY
+	RAISE
+	ON
+	ELSE
+	IF
+	THEN
+	WHILE
+	REPEAT
+	UNTIL
+	FOR
+	TO
+	DOWNTO
+	LABEL
+	GOTO
+	CLASS
+	CONSTRUCTOR
+	DESTRUCTOR
+	VIRTUAL
+	OVERRIDE
+	INHERITED
+	IS
+	AS
+	UNIT
+	INTERFACE
+	IMPLEMENTATION
+	USES
+	INITIALIZATION
+	FINALIZATION
 )
 
+// Position is a 1-based line and column in the source text.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (r Position) IsValid() bool {
+	return r.Line > 0
+}
+
+func (r Position) Before(other Position) bool {
+	return r.Line < other.Line || (r.Line == other.Line && r.Column < other.Column)
+}
+
+var tokenTypeNames = map[TokenType]string{
+	INTEGER:            "INTEGER",
+	MINUS:              "MINUS",
+	PLUS:               "PLUS",
+	MUL:                "MUL",
+	LPAREN:             "LPAREN",
+	RPAREN:             "RPAREN",
+	BEGIN:              "BEGIN",
+	END:                "END",
+	DOT:                "DOT",
+	ASSIGN:             "ASSIGN",
+	SEMICOLON:          "SEMICOLON",
+	ID:                 "ID",
+	EOF:                "EOF",
+	PROGRAM:            "PROGRAM",
+	VAR:                "VAR",
+	REAL:               "REAL",
+	REAL_DECLARATION:   "REAL_DECLARATION",
+	INTEGER_DECLARAION: "INTEGER_DECLARAION",
+	COLON:              "COLON",
+	COMMA:              "COMMA",
+	FLOAT_DIV:          "FLOAT_DIV",
+	INTEGER_DIV:        "INTEGER_DIV",
+	CHAR_DECLARATION:   "CHAR_DECLARATION",
+	STRING_DECLARATION: "STRING_DECLARATION",
+	STRING_LITERAL:      "STRING_LITERAL",
+	BOOLEAN_DECLARATION: "BOOLEAN_DECLARATION",
+	EQUAL:               "EQUAL",
+	NOT_EQUAL:           "NOT_EQUAL",
+	LESS:                "LESS",
+	LESS_EQUAL:          "LESS_EQUAL",
+	GREATER:             "GREATER",
+	GREATER_EQUAL:       "GREATER_EQUAL",
+	LBRACKET:            "LBRACKET",
+	RBRACKET:            "RBRACKET",
+	DOTDOT:              "DOTDOT",
+	ARRAY:               "ARRAY",
+	OF:                  "OF",
+	RECORD:              "RECORD",
+	WITH:                "WITH",
+	DO:                  "DO",
+	TYPE:                "TYPE",
+	CONST:               "CONST",
+	SET:                 "SET",
+	IN:                  "IN",
+	CARET:               "CARET",
+	NIL:                 "NIL",
+	PROCEDURE:           "PROCEDURE",
+	FUNCTION:            "FUNCTION",
+	TRY:                 "TRY",
+	EXCEPT:              "EXCEPT",
+	FINALLY:             "FINALLY",
+	RAISE:               "RAISE",
+	ON:                  "ON",
+	ELSE:                "ELSE",
+	IF:                  "IF",
+	THEN:                "THEN",
+	WHILE:               "WHILE",
+	REPEAT:              "REPEAT",
+	UNTIL:               "UNTIL",
+	FOR:                 "FOR",
+	TO:                  "TO",
+	DOWNTO:              "DOWNTO",
+	LABEL:               "LABEL",
+	GOTO:                "GOTO",
+	CLASS:               "CLASS",
+	CONSTRUCTOR:         "CONSTRUCTOR",
+	DESTRUCTOR:          "DESTRUCTOR",
+	VIRTUAL:             "VIRTUAL",
+	OVERRIDE:            "OVERRIDE",
+	INHERITED:           "INHERITED",
+	IS:                  "IS",
+	AS:                  "AS",
+	UNIT:                "UNIT",
+	INTERFACE:           "INTERFACE",
+	IMPLEMENTATION:      "IMPLEMENTATION",
+	USES:                "USES",
+	INITIALIZATION:      "INITIALIZATION",
+	FINALIZATION:        "FINALIZATION",
+}
+
+func (r TokenType) String() string {
+	name, ok := tokenTypeNames[r]
+	if !ok {
+		return fmt.Sprintf("TokenType(%d)", int(r))
+	}
+	return name
+}
+
+// TokenTypeByName is the inverse of TokenType.String
+func TokenTypeByName(name string) (TokenType, bool) {
+	for k, v := range tokenTypeNames {
+		if v == name {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
 type BasicToken struct {
 	TokenType TokenType
 	TokenValue string
+	// Pos is the position of the first character of the token, End the
+	// position right after the last one
+	Pos Position
+	End Position
+}
+
+// Comment is a { } comment, Text includes the braces. Compiler directives
+// and the source of branches excluded by them are kept as comments too.
+type Comment struct {
+	Text string
+	Pos  Position
+	End  Position
+}
+
+var tokenSymbols = map[TokenType]string{
+	MINUS:       "-",
+	PLUS:        "+",
+	MUL:         "*",
+	LPAREN:      "(",
+	RPAREN:      ")",
+	BEGIN:       "BEGIN",
+	END:         "END",
+	DOT:         ".",
+	ASSIGN:      ":=",
+	SEMICOLON:   ";",
+	PROGRAM:     "PROGRAM",
+	VAR:         "VAR",
+	COLON:       ":",
+	COMMA:       ",",
+	FLOAT_DIV:   "/",
+	INTEGER_DIV: "DIV",
+	EQUAL:         "=",
+	NOT_EQUAL:     "<>",
+	LESS:          "<",
+	LESS_EQUAL:    "<=",
+	GREATER:       ">",
+	GREATER_EQUAL: ">=",
+	LBRACKET:      "[",
+	RBRACKET:      "]",
+	DOTDOT:        "..",
+	ARRAY:         "ARRAY",
+	OF:            "OF",
+	RECORD:        "RECORD",
+	WITH:          "WITH",
+	DO:            "DO",
+	TYPE:          "TYPE",
+	CONST:         "CONST",
+	SET:           "SET",
+	IN:            "IN",
+	CARET:         "^",
+	NIL:           "NIL",
+	PROCEDURE:     "PROCEDURE",
+	FUNCTION:      "FUNCTION",
+	TRY:           "TRY",
+	EXCEPT:        "EXCEPT",
+	FINALLY:       "FINALLY",
+	RAISE:         "RAISE",
+	ON:            "ON",
+	ELSE:          "ELSE",
+	IF:            "IF",
+	THEN:          "THEN",
+	WHILE:         "WHILE",
+	REPEAT:        "REPEAT",
+	UNTIL:         "UNTIL",
+	FOR:           "FOR",
+	TO:            "TO",
+	DOWNTO:        "DOWNTO",
+	LABEL:         "LABEL",
+	GOTO:          "GOTO",
+	CLASS:         "CLASS",
+	CONSTRUCTOR:   "CONSTRUCTOR",
+	DESTRUCTOR:    "DESTRUCTOR",
+	VIRTUAL:       "VIRTUAL",
+	OVERRIDE:      "OVERRIDE",
+	INHERITED:     "INHERITED",
+	IS:            "IS",
+	AS:            "AS",
+	UNIT:          "UNIT",
+	INTERFACE:     "INTERFACE",
+	IMPLEMENTATION: "IMPLEMENTATION",
+	USES:          "USES",
+	INITIALIZATION: "INITIALIZATION",
+	FINALIZATION:  "FINALIZATION",
+}
+
+// QuoteString returns value as a Pascal string literal. Characters outside
+// printable ASCII are written as #nn codes, e.g. 'it''s'#10.
+func QuoteString(value string) string {
+	if value == "" {
+		return "''"
+	}
+
+	var result strings.Builder
+	quoted := false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < ' ' || c > '~' {
+			if quoted {
+				result.WriteByte('\'')
+				quoted = false
+			}
+			fmt.Fprintf(&result, "#%d", c)
+			continue
+		}
+
+		if !quoted {
+			result.WriteByte('\'')
+			quoted = true
+		}
+		if c == '\'' {
+			result.WriteByte('\'')
+		}
+		result.WriteByte(c)
+	}
+	if quoted {
+		result.WriteByte('\'')
+	}
+	return result.String()
+}
+
+// Text returns the token as it is spelled in source, keywords upper-cased.
+func (r BasicToken) Text() string {
+	if r.TokenType == STRING_LITERAL {
+		return QuoteString(r.TokenValue)
+	}
+	if r.TokenValue != "" {
+		return r.TokenValue
+	}
+	return tokenSymbols[r.TokenType]
 }
 
 func (r BasicToken) HasValue() bool {
 	return r.TokenType == INTEGER ||
 		r.TokenType == ID ||
-		r.TokenType == REAL
+		r.TokenType == REAL ||
+		r.TokenType == STRING_LITERAL
 }
 
 
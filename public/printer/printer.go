@@ -0,0 +1,1308 @@
+// Package printer turns AST nodes back into canonically formatted Pascal
+// source, optionally interleaved with the comments of the original text.
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+)
+
+const indentation = "  "
+
+var binaryOperators = map[lexer.TokenType]string{
+	lexer.PLUS:          "+",
+	lexer.MINUS:         "-",
+	lexer.MUL:           "*",
+	lexer.FLOAT_DIV:     "/",
+	lexer.INTEGER_DIV:   "DIV",
+	lexer.EQUAL:         "=",
+	lexer.NOT_EQUAL:     "<>",
+	lexer.LESS:          "<",
+	lexer.LESS_EQUAL:    "<=",
+	lexer.GREATER:       ">",
+	lexer.GREATER_EQUAL: ">=",
+	lexer.IN:            "IN",
+	lexer.IS:            "IS",
+	lexer.AS:            "AS",
+}
+
+var unaryOperators = map[lexer.TokenType]string{
+	lexer.PLUS:  "+",
+	lexer.MINUS: "-",
+}
+
+// Binding strength of operators, the higher the tighter.
+const (
+	lowestPrecedence         = 0
+	relationalPrecedence     = 1
+	additivePrecedence       = 2
+	multiplicativePrecedence = 3
+	unaryPrecedence          = 4
+)
+
+var precedences = map[lexer.TokenType]int{
+	lexer.PLUS:          additivePrecedence,
+	lexer.MINUS:         additivePrecedence,
+	lexer.MUL:           multiplicativePrecedence,
+	lexer.FLOAT_DIV:     multiplicativePrecedence,
+	lexer.INTEGER_DIV:   multiplicativePrecedence,
+	lexer.EQUAL:         relationalPrecedence,
+	lexer.NOT_EQUAL:     relationalPrecedence,
+	lexer.LESS:          relationalPrecedence,
+	lexer.LESS_EQUAL:    relationalPrecedence,
+	lexer.GREATER:       relationalPrecedence,
+	lexer.GREATER_EQUAL: relationalPrecedence,
+	lexer.IN:            relationalPrecedence,
+	lexer.IS:            relationalPrecedence,
+	lexer.AS:            multiplicativePrecedence,
+}
+
+// CommentedNode bundles a node with the comments of the source it was
+// parsed from, so that Fprint can emit them next to the nodes they belong
+// to.
+type CommentedNode struct {
+	Node     ast.Node
+	Comments []lexer.Comment
+	// Source is the text Node was parsed from, if known. Its blank lines
+	// are kept; without it, elements on lines that are not adjacent are
+	// taken to be separated by a blank line.
+	Source []byte
+}
+
+type printer struct {
+	output   bytes.Buffer
+	indent   int
+	comments []lexer.Comment
+	// source line of the last printed element, used to keep blank lines
+	lastLine int
+	// lines of the source, nil if it is unknown
+	lines []string
+	// set while printing the block of a routine, whose nested routines are
+	// indented
+	inRoutine bool
+}
+
+func (r *printer) write(parts ...string) {
+	for _, v := range parts {
+		r.output.WriteString(v)
+	}
+}
+
+func (r *printer) newline() {
+	r.output.WriteByte('\n')
+	r.output.WriteString(strings.Repeat(indentation, r.indent))
+}
+
+// lineBefore starts the line of an element found at pos in the source,
+// keeping a single blank line if the source separated it from the previous
+// element by one or more.
+func (r *printer) lineBefore(pos lexer.Position) {
+	if r.output.Len() == 0 {
+		r.output.WriteString(strings.Repeat(indentation, r.indent))
+		return
+	}
+
+	if r.lastLine > 0 && pos.IsValid() && r.blankLineBefore(pos.Line) {
+		r.output.WriteByte('\n')
+	}
+	r.newline()
+}
+
+// blankLineBefore reports whether the source has a blank line between the
+// last printed element and line. A line holding only a separator, like a
+// semicolon, is not blank.
+func (r *printer) blankLineBefore(line int) bool {
+	if r.lines == nil {
+		return line > r.lastLine+1
+	}
+	for i := r.lastLine; i < line-1 && i < len(r.lines); i++ {
+		if strings.TrimSpace(r.lines[i]) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *printer) printed(end lexer.Position) {
+	if end.IsValid() {
+		r.lastLine = end.Line
+	}
+}
+
+// leadingComments prints every pending comment found before pos, each on
+// its own line.
+func (r *printer) leadingComments(pos lexer.Position) {
+	for len(r.comments) > 0 && pos.IsValid() && r.comments[0].Pos.Before(pos) {
+		r.comment()
+	}
+}
+
+func (r *printer) comment() {
+	comment := r.comments[0]
+	r.comments = r.comments[1:]
+
+	r.lineBefore(comment.Pos)
+	r.write(comment.Text)
+	r.printed(comment.End)
+}
+
+// trailingComments appends pending comments that started on the given
+// source line before the next element at next to the current output line.
+func (r *printer) trailingComments(line int, next lexer.Position) {
+	for len(r.comments) > 0 && line > 0 && r.comments[0].Pos.Line == line {
+		if next.IsValid() && !r.comments[0].Pos.Before(next) {
+			return
+		}
+
+		r.write(" ", r.comments[0].Text)
+		r.printed(r.comments[0].End)
+		r.comments = r.comments[1:]
+	}
+}
+
+func (r *printer) remainingComments() {
+	for len(r.comments) > 0 {
+		r.comment()
+	}
+}
+
+func (r *printer) node(node ast.Node) error {
+	switch n := node.(type) {
+	case ast.Program:
+		return r.program(n)
+	case ast.Unit:
+		return r.unit(n)
+	case ast.Block:
+		return r.block(n)
+	case ast.VarDeclaration:
+		return r.declarationGroup([]ast.VarDeclaration{n})
+	case ast.ConstDeclaration:
+		return r.constDeclaration(n)
+	case ast.TypeDeclaration:
+		return r.typeDeclaration(n)
+	case ast.RoutineDeclaration:
+		return r.routine(n, lexer.Position{})
+	case ast.TypeSpec, ast.ArrayType, ast.RecordType, ast.EnumType, ast.Subrange, ast.SetType, ast.PointerType, ast.ProceduralType, ast.ClassType:
+		return r.typeSpec(n)
+	}
+	return r.statement(node)
+}
+
+// PROGRAM name; block.
+func (r *printer) program(node ast.Program) error {
+	pos := node.GetToken().Pos
+	r.leadingComments(pos)
+	r.lineBefore(pos)
+	r.write("PROGRAM ", node.Name, ";")
+	r.printed(pos)
+	r.trailingComments(pos.Line, firstPos(node.Uses, ast.Pos(node.Block)))
+	r.uses(node.Uses, ast.Pos(node.Block))
+
+	if err := r.block(node.Block); err != nil {
+		return err
+	}
+	r.write(".")
+	r.trailingComments(node.Block.Compound.End.Line, lexer.Position{})
+	r.remainingComments()
+	r.write("\n")
+	return nil
+}
+
+// UNIT Name; INTERFACE sections headings IMPLEMENTATION declarations
+// (INITIALIZATION statements (FINALIZATION statements)?)? END.
+func (r *printer) unit(node ast.Unit) error {
+	pos := node.GetToken().Pos
+	r.leadingComments(pos)
+	r.lineBefore(pos)
+	r.write("UNIT ", node.Name, ";")
+	r.printed(pos)
+
+	interfacePos := node.Interface.GetToken().Pos
+	implementationPos := node.Implementation.GetToken().Pos
+	r.trailingComments(pos.Line, interfacePos)
+	r.keyword("INTERFACE", interfacePos, firstPos(node.InterfaceUses, firstPos(node.Headings, implementationPos)))
+	r.uses(node.InterfaceUses, firstPos(node.Headings, implementationPos))
+	if err := r.declarationSections(node.Interface, r.unknownPosition(), firstPos(node.Headings, implementationPos)); err != nil {
+		return err
+	}
+	for i, v := range node.Headings {
+		headingPos := ast.Pos(v)
+		r.leadingComments(headingPos)
+		r.lineBefore(headingPos)
+		r.write(routineKeyword(v.GetToken().TokenType, v.Result), " ", v.Name.Value)
+		if err := r.signature(v.Params, v.Result); err != nil {
+			return err
+		}
+		r.write(";")
+
+		next := implementationPos
+		if i+1 < len(node.Headings) {
+			next = ast.Pos(node.Headings[i+1])
+		}
+		headingEnd := ast.End(v)
+		r.trailingComments(headingEnd.Line, next)
+		r.printed(headingEnd)
+	}
+
+	initialization := node.Implementation.Compound
+	initializationPos := initialization.GetToken().Pos
+	r.keyword("IMPLEMENTATION", implementationPos, firstPos(node.ImplementationUses, initializationPos))
+	r.uses(node.ImplementationUses, initializationPos)
+	if err := r.declarationSections(node.Implementation, r.unknownPosition(), initializationPos); err != nil {
+		return err
+	}
+
+	r.leadingComments(initializationPos)
+	r.lineBefore(initializationPos)
+	if len(initialization.Children) > 0 || len(node.Finalization) > 0 {
+		if err := r.statementList("INITIALIZATION", initializationPos, initialization.Children, firstPos(node.Finalization, node.End)); err != nil {
+			return err
+		}
+		if len(node.Finalization) > 0 {
+			if err := r.statementList("FINALIZATION", r.unknownPosition(), node.Finalization, node.End); err != nil {
+				return err
+			}
+		}
+	}
+	r.write("END.")
+	r.trailingComments(node.End.Line, lexer.Position{})
+	r.remainingComments()
+	r.write("\n")
+	return nil
+}
+
+// keyword prints a keyword found at pos on a line of its own, followed by
+// the element at next
+func (r *printer) keyword(keyword string, pos lexer.Position, next lexer.Position) {
+	r.leadingComments(pos)
+	r.lineBefore(pos)
+	r.write(keyword)
+	r.printed(pos)
+	r.trailingComments(pos.Line, next)
+}
+
+// USES Name, ...; followed by the element at next
+func (r *printer) uses(units []ast.Var, next lexer.Position) {
+	if len(units) == 0 {
+		return
+	}
+
+	pos := ast.Pos(units[0])
+	r.leadingComments(pos)
+	r.lineBefore(pos)
+	names := make([]string, len(units))
+	for i, v := range units {
+		names[i] = v.Value
+	}
+	r.write("USES ", strings.Join(names, ", "), ";")
+
+	end := ast.End(units[len(units)-1])
+	r.trailingComments(end.Line, next)
+	r.printed(end)
+}
+
+func (r *printer) block(node ast.Block) error {
+	pos := node.Compound.GetToken().Pos
+	// only the position of the keyword starting the block is known
+	if err := r.declarationSections(node, node.GetToken().Pos, pos); err != nil {
+		return err
+	}
+
+	r.leadingComments(pos)
+	r.lineBefore(pos)
+	return r.compound(node.Compound)
+}
+
+// declarationSections prints the declarations of a block, consecutive
+// ones of the same kind in one section, the first section keyword being
+// found at keywordPos, followed by the element at pos
+func (r *printer) declarationSections(node ast.Block, keywordPos lexer.Position, pos lexer.Position) error {
+	first := true
+	if len(node.Labels) > 0 {
+		r.labels(keywordPos, node.Labels)
+		first = false
+	}
+
+	declarations := node.Declarations
+	for start := 0; start < len(declarations); {
+		end := start + 1
+		for end < len(declarations) && reflect.TypeOf(declarations[end]) == reflect.TypeOf(declarations[start]) {
+			end++
+		}
+		next := firstPos(declarations[end:], pos)
+
+		_, isRoutine := declarations[start].(ast.RoutineDeclaration)
+		if !first && !isRoutine {
+			keywordPos = r.unknownPosition()
+		}
+		first = false
+
+		var err error
+		switch declarations[start].(type) {
+		case ast.ConstDeclaration:
+			err = r.section("CONST", keywordPos, declarations[start:end], next)
+		case ast.TypeDeclaration:
+			err = r.section("TYPE", keywordPos, declarations[start:end], next)
+		case ast.VarDeclaration:
+			err = r.declarations(keywordPos, castNodes[ast.VarDeclaration](declarations[start:end]), next)
+		case ast.RoutineDeclaration:
+			err = r.routines(castNodes[ast.RoutineDeclaration](declarations[start:end]), next)
+		default:
+			err = fmt.Errorf("Cannot print %T as a declaration", declarations[start])
+		}
+		if err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// LABEL label, ...;
+func (r *printer) labels(pos lexer.Position, labels []ast.LabelDeclaration) {
+	r.leadingComments(pos)
+	r.lineBefore(pos)
+	names := make([]string, len(labels))
+	for i, v := range labels {
+		names[i] = v.Name
+	}
+	r.write("LABEL ", strings.Join(names, ", "), ";")
+	r.printed(ast.End(labels[len(labels)-1]))
+}
+
+// routines prints the routines declared in a block, indented if the block
+// is the one of a routine
+func (r *printer) routines(routines []ast.RoutineDeclaration, next lexer.Position) error {
+	if r.inRoutine {
+		r.indent++
+		defer func() {
+			r.indent--
+		}()
+	}
+
+	for i, v := range routines {
+		routineNext := next
+		if i+1 < len(routines) {
+			routineNext = ast.Pos(routines[i+1])
+		}
+		if err := r.routine(v, routineNext); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PROCEDURE Name(params); block; or FUNCTION Name(params): TYPE; block;
+// A method is named Class.Name and may be a CONSTRUCTOR or a DESTRUCTOR.
+func (r *printer) routine(node ast.RoutineDeclaration, next lexer.Position) error {
+	pos := node.GetToken().Pos
+	r.leadingComments(pos)
+	r.lineBefore(pos)
+	r.write(routineKeyword(node.GetToken().TokenType, node.Result), " ")
+	if node.Class != nil {
+		if err := r.typeSpec(node.Class); err != nil {
+			return err
+		}
+		r.write(".")
+	}
+	r.write(node.Name.Value)
+	if err := r.signature(node.Params, node.Result); err != nil {
+		return err
+	}
+	r.write(";")
+	r.printed(pos)
+	r.trailingComments(pos.Line, ast.Pos(node.Block))
+
+	enclosing := r.inRoutine
+	r.inRoutine = true
+	err := r.block(node.Block)
+	r.inRoutine = enclosing
+	if err != nil {
+		return err
+	}
+
+	r.write(";")
+	r.trailingComments(node.Block.Compound.End.Line, next)
+	r.printed(node.Block.Compound.End)
+	return nil
+}
+
+// routineKeyword returns the keyword a routine or a method declared with
+// the keyword tokenType returning result starts with
+func routineKeyword(tokenType lexer.TokenType, result ast.Node) string {
+	switch {
+	case tokenType == lexer.CONSTRUCTOR:
+		return "CONSTRUCTOR"
+	case tokenType == lexer.DESTRUCTOR:
+		return "DESTRUCTOR"
+	case result != nil:
+		return "FUNCTION"
+	}
+	return "PROCEDURE"
+}
+
+// Name(params); or Name(params): TYPE; followed by VIRTUAL; or OVERRIDE;
+func (r *printer) methodHeading(node ast.MethodHeading) error {
+	r.write(routineKeyword(node.GetToken().TokenType, node.Result), " ", node.Name.Value)
+	if err := r.signature(node.Params, node.Result); err != nil {
+		return err
+	}
+	r.write(";")
+	if node.Virtual {
+		r.write(" VIRTUAL;")
+	}
+	if node.Override {
+		r.write(" OVERRIDE;")
+	}
+	return nil
+}
+
+// signature prints (params) unless there are none and, for a function,
+// the result type
+func (r *printer) signature(params []ast.Param, result ast.Node) error {
+	if len(params) > 0 {
+		r.write("(")
+		for start := 0; start < len(params); {
+			end := start + 1
+			for end < len(params) && sameParamGroup(params[end], params[start]) {
+				end++
+			}
+
+			if start > 0 {
+				r.write("; ")
+			}
+			if err := r.paramGroup(params[start:end]); err != nil {
+				return err
+			}
+			start = end
+		}
+		r.write(")")
+	}
+
+	if result != nil {
+		r.write(": ")
+		return r.typeSpec(result)
+	}
+	return nil
+}
+
+// isProceduralParam reports whether param is written ISO style, as in
+// FUNCTION f(x: REAL): REAL
+func isProceduralParam(param ast.Param) bool {
+	_, ok := param.TypeSpec.(ast.ProceduralType)
+	tokenType := param.GetToken().TokenType
+	return ok && (tokenType == lexer.PROCEDURE || tokenType == lexer.FUNCTION)
+}
+
+// sameParamGroup reports whether two parameters are printed as one group,
+// which they are if they were declared together
+func sameParamGroup(a ast.Param, b ast.Param) bool {
+	return !isProceduralParam(a) && reflect.DeepEqual(a.GetToken(), b.GetToken()) && reflect.DeepEqual(a.TypeSpec, b.TypeSpec)
+}
+
+// a, b: TYPE, VAR a, b: TYPE or a procedural parameter
+func (r *printer) paramGroup(group []ast.Param) error {
+	if isProceduralParam(group[0]) {
+		procedural := group[0].TypeSpec.(ast.ProceduralType)
+		if procedural.Result != nil {
+			r.write("FUNCTION ", group[0].Variable.Value)
+		} else {
+			r.write("PROCEDURE ", group[0].Variable.Value)
+		}
+		return r.signature(procedural.Params, procedural.Result)
+	}
+
+	if group[0].IsVar() {
+		r.write("VAR ")
+	}
+	for i, v := range group {
+		if i > 0 {
+			r.write(", ")
+		}
+		r.write(v.Variable.Value)
+	}
+	r.write(": ")
+	return r.typeSpec(group[0].TypeSpec)
+}
+
+// firstPos returns the position of the first declaration, otherwise if
+// there is none
+func firstPos[T ast.Node](declarations []T, otherwise lexer.Position) lexer.Position {
+	if len(declarations) == 0 {
+		return otherwise
+	}
+	return ast.Pos(declarations[0])
+}
+
+// castNodes returns declarations, all of type T
+func castNodes[T ast.Node](declarations []ast.Node) []T {
+	result := make([]T, len(declarations))
+	for i, v := range declarations {
+		result[i] = v.(T)
+	}
+	return result
+}
+
+// unknownPosition is the position of a keyword the tree does not record,
+// no blank line is kept before it
+func (r *printer) unknownPosition() lexer.Position {
+	r.lastLine = 0
+	return lexer.Position{}
+}
+
+// sameType reports whether two declarations are printed as one group.
+// Declarations parsed from source only share their type specification if
+// they were declared together.
+func sameType(a ast.VarDeclaration, b ast.VarDeclaration) bool {
+	return reflect.DeepEqual(a.TypeSpec, b.TypeSpec)
+}
+
+// Consecutive declarations sharing the same type specification are printed
+// as a single `a, b: TYPE;` group.
+func (r *printer) declarations(pos lexer.Position, declarations []ast.VarDeclaration, next lexer.Position) error {
+	if len(declarations) == 0 {
+		return nil
+	}
+
+	r.leadingComments(pos)
+	r.lineBefore(pos)
+	r.write("VAR")
+	r.printed(pos)
+	r.trailingComments(pos.Line, ast.Pos(declarations[0]))
+
+	r.indent++
+	for start := 0; start < len(declarations); {
+		end := start + 1
+		for end < len(declarations) && sameType(declarations[end], declarations[start]) {
+			end++
+		}
+
+		groupPos := ast.Pos(declarations[start])
+		r.leadingComments(groupPos)
+		r.lineBefore(groupPos)
+		if err := r.declarationGroup(declarations[start:end]); err != nil {
+			return err
+		}
+		r.write(";")
+
+		groupNext := next
+		if end < len(declarations) {
+			groupNext = ast.Pos(declarations[end])
+		}
+		groupEnd := ast.End(declarations[end-1])
+		r.trailingComments(groupEnd.Line, groupNext)
+		r.printed(groupEnd)
+		start = end
+	}
+	r.indent--
+	return nil
+}
+
+// CONST or TYPE section, one declaration per line
+func (r *printer) section(keyword string, pos lexer.Position, declarations []ast.Node, next lexer.Position) error {
+	r.leadingComments(pos)
+	r.lineBefore(pos)
+	r.write(keyword)
+	r.printed(pos)
+	r.trailingComments(pos.Line, ast.Pos(declarations[0]))
+
+	r.indent++
+	for i, v := range declarations {
+		declarationPos := ast.Pos(v)
+		r.leadingComments(declarationPos)
+		r.lineBefore(declarationPos)
+		if err := r.node(v); err != nil {
+			return err
+		}
+		r.write(";")
+
+		declarationNext := next
+		if i+1 < len(declarations) {
+			declarationNext = ast.Pos(declarations[i+1])
+		}
+		declarationEnd := ast.End(v)
+		r.trailingComments(declarationEnd.Line, declarationNext)
+		r.printed(declarationEnd)
+	}
+	r.indent--
+	return nil
+}
+
+// Name = value or, for a typed constant, Name: type = value
+func (r *printer) constDeclaration(node ast.ConstDeclaration) error {
+	r.write(node.Name.Value)
+	if node.TypeSpec != nil {
+		r.write(": ")
+		if err := r.typeSpec(node.TypeSpec); err != nil {
+			return err
+		}
+	}
+	r.write(" = ")
+	return r.expression(node.Value, lowestPrecedence)
+}
+
+func (r *printer) typeDeclaration(node ast.TypeDeclaration) error {
+	r.write(node.Name.Value, " = ")
+	return r.typeSpec(node.TypeSpec)
+}
+
+func (r *printer) declarationGroup(group []ast.VarDeclaration) error {
+	for i, v := range group {
+		if i > 0 {
+			r.write(", ")
+		}
+		r.write(v.Variable.Value)
+	}
+	r.write(": ")
+	return r.typeSpec(group[0].TypeSpec)
+}
+
+// typeSpec prints a type name, a subrange low..high, an enumeration
+// (a, b), ARRAY[index, ...] OF element, SET OF element, a pointer ^T, a
+// procedural type FUNCTION(a: TYPE): TYPE or a record on a single line,
+// RECORD a, b: TYPE; c: TYPE END. A class has its fields and methods
+// indented on lines of their own.
+func (r *printer) typeSpec(node ast.Node) error {
+	switch n := node.(type) {
+	case ast.TypeSpec:
+		r.write(n.Value)
+		return nil
+
+	case ast.ArrayType:
+		r.write("ARRAY[")
+		for i, v := range n.Ranges {
+			if i > 0 {
+				r.write(", ")
+			}
+			if err := r.typeSpec(v); err != nil {
+				return err
+			}
+		}
+		r.write("] OF ")
+		return r.typeSpec(n.Element)
+
+	case ast.Subrange:
+		return r.expression(n, lowestPrecedence)
+
+	case ast.SetType:
+		r.write("SET OF ")
+		return r.typeSpec(n.Element)
+
+	case ast.PointerType:
+		r.write("^")
+		return r.typeSpec(n.Target)
+
+	case ast.ProceduralType:
+		if n.Result != nil {
+			r.write("FUNCTION")
+		} else {
+			r.write("PROCEDURE")
+		}
+		return r.signature(n.Params, n.Result)
+
+	case ast.EnumType:
+		r.write("(")
+		for i, v := range n.Values {
+			if i > 0 {
+				r.write(", ")
+			}
+			r.write(v.Value)
+		}
+		r.write(")")
+		return nil
+
+	case ast.RecordType:
+		r.write("RECORD")
+		for start := 0; start < len(n.Fields); {
+			end := start + 1
+			for end < len(n.Fields) && sameType(n.Fields[end], n.Fields[start]) {
+				end++
+			}
+
+			if start > 0 {
+				r.write(";")
+			}
+			r.write(" ")
+			if err := r.declarationGroup(n.Fields[start:end]); err != nil {
+				return err
+			}
+			start = end
+		}
+		r.write(" END")
+		return nil
+
+	case ast.ClassType:
+		return r.classType(n)
+	}
+	return fmt.Errorf("Cannot print type specification %T", node)
+}
+
+// CLASS(Parent) fields methods END
+func (r *printer) classType(node ast.ClassType) error {
+	r.write("CLASS")
+	if node.Parent != nil {
+		r.write("(")
+		if err := r.typeSpec(node.Parent); err != nil {
+			return err
+		}
+		r.write(")")
+	}
+	if len(node.Fields) == 0 && len(node.Methods) == 0 {
+		r.write(" END")
+		return nil
+	}
+
+	r.indent++
+	for start := 0; start < len(node.Fields); {
+		end := start + 1
+		for end < len(node.Fields) && sameType(node.Fields[end], node.Fields[start]) {
+			end++
+		}
+
+		r.newline()
+		if err := r.declarationGroup(node.Fields[start:end]); err != nil {
+			return err
+		}
+		r.write(";")
+		start = end
+	}
+	for _, v := range node.Methods {
+		r.newline()
+		if err := r.methodHeading(v); err != nil {
+			return err
+		}
+	}
+	r.indent--
+	r.newline()
+	r.write("END")
+	return nil
+}
+
+// BEGIN statement (; statement)* END
+func (r *printer) compound(node ast.Compound) error {
+	if err := r.statementList("BEGIN", node.GetToken().Pos, node.Children, node.End); err != nil {
+		return err
+	}
+	r.write("END")
+	r.printed(node.End)
+	return nil
+}
+
+// keyword followed by statement (; statement)* indented on lines of their
+// own, end being the position of the element following the statements.
+// The next line is started for the element. The position of keyword may
+// be unknown, in which case no blank line follows it.
+func (r *printer) statementList(keyword string, pos lexer.Position, statements []ast.Node, end lexer.Position) error {
+	children := statements
+	if len(children) > 0 {
+		if _, ok := children[len(children)-1].(ast.NoOp); ok {
+			children = children[:len(children)-1]
+		}
+	}
+
+	// position of the element following child i, end for the last one
+	next := func(i int) lexer.Position {
+		if i+1 < len(children) {
+			if pos := ast.Pos(children[i+1]); pos.IsValid() {
+				return pos
+			}
+		}
+		return end
+	}
+
+	r.write(keyword)
+	r.lastLine = 0
+	r.printed(pos)
+	r.trailingComments(pos.Line, next(-1))
+	r.indent++
+
+	for i, v := range children {
+		pos := ast.Pos(v)
+		r.leadingComments(pos)
+		r.lineBefore(pos)
+		if err := r.statement(v); err != nil {
+			return err
+		}
+		if i < len(children)-1 || len(children) < len(statements) {
+			r.write(";")
+		}
+
+		end := ast.End(v)
+		r.trailingComments(end.Line, next(i))
+		r.printed(end)
+	}
+
+	r.leadingComments(end)
+	r.indent--
+	r.newline()
+	return nil
+}
+
+func (r *printer) statement(node ast.Node) error {
+	switch n := node.(type) {
+	case ast.Compound:
+		return r.compound(n)
+	case ast.AssignOperation:
+		if err := r.expression(n.Left, lowestPrecedence); err != nil {
+			return err
+		}
+		r.write(" := ")
+		return r.expression(n.Right, lowestPrecedence)
+	case ast.ProcedureCall:
+		return r.procedureCall(n)
+	case ast.WithStatement:
+		return r.withStatement(n)
+	case ast.IfStatement:
+		return r.ifStatement(n)
+	case ast.WhileStatement:
+		r.write("WHILE ")
+		if err := r.expression(n.Condition, lowestPrecedence); err != nil {
+			return err
+		}
+		r.write(" DO")
+		return r.body(n.Body)
+	case ast.RepeatStatement:
+		if err := r.statementList("REPEAT", n.GetToken().Pos, n.Body, ast.Pos(n.Condition)); err != nil {
+			return err
+		}
+		r.write("UNTIL ")
+		return r.expression(n.Condition, lowestPrecedence)
+	case ast.ForStatement:
+		return r.forStatement(n)
+	case ast.LabeledStatement:
+		r.write(n.Label, ":")
+		if _, ok := n.Statement.(ast.NoOp); ok {
+			return nil
+		}
+		r.write(" ")
+		return r.statement(n.Statement)
+	case ast.GotoStatement:
+		r.write("GOTO ", n.Label)
+		return nil
+	case ast.TryExcept:
+		return r.tryExcept(n)
+	case ast.TryFinally:
+		if err := r.statementList("TRY", n.GetToken().Pos, n.Body, firstPos(n.Finally, n.End)); err != nil {
+			return err
+		}
+		if err := r.statementList("FINALLY", lexer.Position{}, n.Finally, n.End); err != nil {
+			return err
+		}
+		r.write("END")
+		return nil
+	case ast.InheritedCall:
+		return r.inheritedCall(n)
+	case ast.MethodCall:
+		// a call without arguments needs no parentheses as a statement
+		if len(n.Arguments) > 0 {
+			return r.expression(n, lowestPrecedence)
+		}
+		if err := r.expression(n.Value, unaryPrecedence); err != nil {
+			return err
+		}
+		r.write(".", n.Name)
+		return nil
+	case ast.RaiseStatement:
+		r.write("RAISE")
+		if n.Value == nil {
+			return nil
+		}
+		r.write(" ")
+		return r.expression(n.Value, lowestPrecedence)
+	case ast.NoOp:
+		return nil
+	}
+	return r.expression(node, lowestPrecedence)
+}
+
+// IF condition THEN statement ELSE statement, an IF following ELSE on the
+// same line
+func (r *printer) ifStatement(node ast.IfStatement) error {
+	r.write("IF ")
+	if err := r.expression(node.Condition, lowestPrecedence); err != nil {
+		return err
+	}
+	r.write(" THEN")
+	if err := r.body(node.Then); err != nil {
+		return err
+	}
+	if node.Else == nil {
+		return nil
+	}
+
+	if _, ok := node.Then.(ast.Compound); ok {
+		r.write(" ")
+	} else {
+		r.newline()
+	}
+	r.write("ELSE")
+	if elseIf, ok := node.Else.(ast.IfStatement); ok {
+		r.write(" ")
+		return r.ifStatement(elseIf)
+	}
+	return r.body(node.Else)
+}
+
+// FOR variable := start TO stop DO statement
+func (r *printer) forStatement(node ast.ForStatement) error {
+	r.write("FOR ")
+	if err := r.expression(node.Variable, lowestPrecedence); err != nil {
+		return err
+	}
+	r.write(" := ")
+	if err := r.expression(node.Start, lowestPrecedence); err != nil {
+		return err
+	}
+	if node.Downto {
+		r.write(" DOWNTO ")
+	} else {
+		r.write(" TO ")
+	}
+	if err := r.expression(node.Stop, lowestPrecedence); err != nil {
+		return err
+	}
+	r.write(" DO")
+	return r.body(node.Body)
+}
+
+// TRY statements EXCEPT (handler (; handler)* [ELSE statements] |
+// statements) END
+func (r *printer) tryExcept(node ast.TryExcept) error {
+	next := firstPos(node.Default, node.End)
+	if len(node.Handlers) > 0 {
+		next = node.Handlers[0].GetToken().Pos
+	}
+	if err := r.statementList("TRY", node.GetToken().Pos, node.Body, next); err != nil {
+		return err
+	}
+
+	if len(node.Handlers) == 0 {
+		if err := r.statementList("EXCEPT", lexer.Position{}, node.Default, node.End); err != nil {
+			return err
+		}
+		r.write("END")
+		return nil
+	}
+
+	r.write("EXCEPT")
+	r.lastLine = 0
+	r.indent++
+	for i, v := range node.Handlers {
+		pos := v.GetToken().Pos
+		r.leadingComments(pos)
+		r.lineBefore(pos)
+		if err := r.exceptionHandler(v); err != nil {
+			return err
+		}
+		if i < len(node.Handlers)-1 {
+			r.write(";")
+		}
+		r.printed(ast.End(v))
+	}
+	r.indent--
+	r.newline()
+
+	if len(node.Default) > 0 {
+		if err := r.statementList("ELSE", lexer.Position{}, node.Default, node.End); err != nil {
+			return err
+		}
+	}
+	r.write("END")
+	return nil
+}
+
+// ON name: Class DO statement
+func (r *printer) exceptionHandler(node ast.ExceptionHandler) error {
+	r.write("ON ")
+	if node.Variable != nil {
+		r.write(node.Variable.GetToken().Text(), ": ")
+	}
+	if err := r.typeSpec(node.Class); err != nil {
+		return err
+	}
+	r.write(" DO")
+	return r.body(node.Body)
+}
+
+// WITH record, ... DO statement
+func (r *printer) withStatement(node ast.WithStatement) error {
+	r.write("WITH ")
+	for i, v := range node.Records {
+		if i > 0 {
+			r.write(", ")
+		}
+		if err := r.expression(v, lowestPrecedence); err != nil {
+			return err
+		}
+	}
+	r.write(" DO")
+	return r.body(node.Body)
+}
+
+// body prints the statement controlled by WITH and the like, a compound
+// on the same line, other statements indented on the next one
+func (r *printer) body(node ast.Node) error {
+	if _, ok := node.(ast.Compound); ok {
+		r.write(" ")
+		return r.statement(node)
+	}
+	if _, ok := node.(ast.NoOp); ok {
+		return nil
+	}
+
+	r.indent++
+	defer func() {
+		r.indent--
+	}()
+	r.newline()
+	return r.statement(node)
+}
+
+// name(argument, ...), without parentheses if there are no arguments
+func (r *printer) procedureCall(node ast.ProcedureCall) error {
+	r.write(node.Name)
+	if len(node.Arguments) == 0 {
+		return nil
+	}
+	return r.arguments(node.Arguments)
+}
+
+// INHERITED Name(argument, ...), without parentheses if there are no
+// arguments
+func (r *printer) inheritedCall(node ast.InheritedCall) error {
+	r.write("INHERITED ", node.Name)
+	if len(node.Arguments) == 0 {
+		return nil
+	}
+	return r.arguments(node.Arguments)
+}
+
+// (argument, ...)
+func (r *printer) arguments(arguments []ast.Node) error {
+	r.write("(")
+	for i, v := range arguments {
+		if i > 0 {
+			r.write(", ")
+		}
+		if err := r.argument(v); err != nil {
+			return err
+		}
+	}
+	r.write(")")
+	return nil
+}
+
+// value:width:precision
+func (r *printer) argument(node ast.Node) error {
+	formatted, ok := node.(ast.FormattedArgument)
+	if !ok {
+		return r.expression(node, lowestPrecedence)
+	}
+
+	if err := r.expression(formatted.Value, lowestPrecedence); err != nil {
+		return err
+	}
+	r.write(":")
+	if err := r.expression(formatted.Width, lowestPrecedence); err != nil {
+		return err
+	}
+	if formatted.Precision != nil {
+		r.write(":")
+		return r.expression(formatted.Precision, lowestPrecedence)
+	}
+	return nil
+}
+
+// expression prints node, wrapping it in parentheses only when it binds
+// looser than the surrounding operator requires.
+func (r *printer) expression(node ast.Node, precedence int) error {
+	switch n := node.(type) {
+	case ast.IntNode:
+		r.write(strconv.Itoa(n.Value))
+		return nil
+
+	case ast.RealNode:
+		r.write(formatReal(n.Value))
+		return nil
+
+	case ast.StringNode:
+		r.write(lexer.QuoteString(n.Value))
+		return nil
+
+	case ast.Var:
+		r.write(n.Value)
+		return nil
+
+	case ast.FunctionCall:
+		r.write(n.Name)
+		return r.arguments(n.Arguments)
+
+	case ast.MethodCall:
+		if err := r.expression(n.Value, unaryPrecedence); err != nil {
+			return err
+		}
+		r.write(".", n.Name)
+		return r.arguments(n.Arguments)
+
+	case ast.InheritedCall:
+		return r.inheritedCall(n)
+
+	case ast.FieldAccess:
+		if err := r.expression(n.Value, unaryPrecedence); err != nil {
+			return err
+		}
+		r.write(".", n.Field)
+		return nil
+
+	case ast.Index:
+		if err := r.expression(n.Value, unaryPrecedence); err != nil {
+			return err
+		}
+		r.write("[")
+		for i, v := range n.Indices {
+			if i > 0 {
+				r.write(", ")
+			}
+			if err := r.expression(v, lowestPrecedence); err != nil {
+				return err
+			}
+		}
+		r.write("]")
+		return nil
+
+	case ast.NilNode:
+		r.write("NIL")
+		return nil
+
+	case ast.Dereference:
+		if err := r.expression(n.Value, unaryPrecedence); err != nil {
+			return err
+		}
+		r.write("^")
+		return nil
+
+	case ast.SetConstructor:
+		r.write("[")
+		for i, v := range n.Elements {
+			if i > 0 {
+				r.write(", ")
+			}
+			if err := r.expression(v, lowestPrecedence); err != nil {
+				return err
+			}
+		}
+		r.write("]")
+		return nil
+
+	case ast.Subrange:
+		if err := r.expression(n.Low, lowestPrecedence); err != nil {
+			return err
+		}
+		r.write("..")
+		return r.expression(n.High, lowestPrecedence)
+
+	case ast.UnaryOperation:
+		operator, ok := unaryOperators[n.GetToken().TokenType]
+		if !ok {
+			return fmt.Errorf("Cannot print unary operator %v", n.GetToken().TokenType)
+		}
+		r.write(operator)
+
+		// `- -x` is printed as `-(-x)` to keep the signs apart
+		if _, isUnary := n.Right.(ast.UnaryOperation); isUnary {
+			return r.parenthesized(n.Right)
+		}
+		return r.expression(n.Right, unaryPrecedence)
+
+	case ast.BinaryOperation:
+		tokenType := n.GetToken().TokenType
+		operator, ok := binaryOperators[tokenType]
+		if !ok {
+			return fmt.Errorf("Cannot print binary operator %v", tokenType)
+		}
+
+		own := precedences[tokenType]
+		if own < precedence {
+			return r.parenthesized(n)
+		}
+
+		// relational operators do not associate at all
+		left := own
+		if own == relationalPrecedence {
+			left = own + 1
+		}
+		if err := r.expression(n.Left, left); err != nil {
+			return err
+		}
+		r.write(" ", operator, " ")
+		// operators are left-associative, so an equally binding right
+		// operand needs parentheses
+		return r.expression(n.Right, own+1)
+	}
+
+	return fmt.Errorf("Cannot print node of unknown type %T", node)
+}
+
+func (r *printer) parenthesized(node ast.Node) error {
+	r.write("(")
+	if err := r.expression(node, lowestPrecedence); err != nil {
+		return err
+	}
+	r.write(")")
+	return nil
+}
+
+func formatReal(value float64) string {
+	formatted := strconv.FormatFloat(value, 'f', -1, 64)
+	if !strings.Contains(formatted, ".") {
+		formatted += ".0"
+	}
+	return formatted
+}
+
+// sourceLines splits source into lines, nil if there is none
+func sourceLines(source []byte) []string {
+	if source == nil {
+		return nil
+	}
+	return strings.Split(string(source), "\n")
+}
+
+// Fprint writes the canonical Pascal source of node to output. The node
+// must be an ast.Node or a CommentedNode, comments that cannot be placed
+// next to a node are printed after it. Any node can be printed; only a
+// Program or a Unit is terminated with a newline.
+func Fprint(output io.Writer, node any) error {
+	p := printer{}
+
+	var root ast.Node
+	switch n := node.(type) {
+	case CommentedNode:
+		root = n.Node
+		p.comments = n.Comments
+		p.lines = sourceLines(n.Source)
+	case *CommentedNode:
+		root = n.Node
+		p.comments = n.Comments
+		p.lines = sourceLines(n.Source)
+	case ast.Node:
+		root = n
+	default:
+		return fmt.Errorf("Cannot print %T, ast.Node or CommentedNode expected", node)
+	}
+
+	if err := p.node(root); err != nil {
+		return err
+	}
+	p.remainingComments()
+
+	_, err := output.Write(p.output.Bytes())
+	return err
+}
@@ -0,0 +1,571 @@
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/interpreter"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, text string) ast.Node {
+	parser, err := interpreter.NewParser(lexer.NewLexer(text))
+	require.NoError(t, err)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+	return node
+}
+
+func parseExpr(t *testing.T, text string) ast.Node {
+	parser, err := interpreter.NewParser(lexer.NewLexer(text))
+	require.NoError(t, err)
+	node, err := parser.Expr()
+	require.NoError(t, err)
+	return node
+}
+
+func sprint(t *testing.T, node any) string {
+	var output bytes.Buffer
+	require.NoError(t, Fprint(&output, node))
+	return output.String()
+}
+
+func TestFprint(t *testing.T) {
+	t.Run("Program is printed canonically", func(t *testing.T) {
+		node := parse(t, `
+			PROGRAM Part10;
+			VAR
+			   number     : INTEGER;
+			   a, b, c, x : INTEGER;
+			   y          : REAL;
+			BEGIN
+			   BEGIN
+			      number := 2;
+			      a := number;
+			   END;
+			   x := 11;
+			   y := 20 / 7 + 3.14;
+			END.
+		`)
+
+		require.Equal(t, `PROGRAM Part10;
+VAR
+  number: INTEGER;
+  a, b, c, x: INTEGER;
+  y: REAL;
+BEGIN
+  BEGIN
+    number := 2;
+    a := number;
+  END;
+  x := 11;
+  y := 20 / 7 + 3.14;
+END.
+`, sprint(t, node))
+	})
+
+	t.Run("Sections keep their order", func(t *testing.T) {
+		node := parse(t, `
+			PROGRAM p;
+			TYPE Small = 1..9;
+			CONST Max: Small = 5;
+			PROCEDURE P; BEGIN END;
+			VAR i: Small; VAR j: Small;
+			BEGIN END.
+		`)
+
+		require.Equal(t, `PROGRAM p;
+TYPE
+  Small = 1..9;
+CONST
+  Max: Small = 5;
+PROCEDURE P;
+BEGIN
+END;
+VAR
+  i: Small;
+  j: Small;
+BEGIN
+END.
+`, sprint(t, node))
+	})
+
+	t.Run("Empty compound", func(t *testing.T) {
+		require.Equal(t, "PROGRAM empty;\nBEGIN\nEND.\n", sprint(t, parse(t, "PROGRAM empty; BEGIN END.")))
+	})
+
+	t.Run("Expressions keep only required parentheses", func(t *testing.T) {
+		cases := map[string]string{
+			"(1 + 2) * 3":       "(1 + 2) * 3",
+			"((1 * 2)) + 3":     "1 * 2 + 3",
+			"1 - (2 - 3)":       "1 - (2 - 3)",
+			"(1 - 2) - 3":       "1 - 2 - 3",
+			"a DIV (b * c)":     "a DIV (b * c)",
+			"-(a + b)":          "-(a + b)",
+			"-(-3)":             "-(-3)",
+			"5 + -3":            "5 + -3",
+			"(x) / (2.5)":       "x / 2.5",
+			"+a * (-b / (c))":   "+a * (-b / c)",
+			"(a < b) = (c > d)": "(a < b) = (c > d)",
+			"a + 1 <> (b)":      "a + 1 <> b",
+			"s[(i) + 1, j]":     "s[i + 1, j]",
+			"'it''s'#10 + c":    "'it''s'#10 + c",
+		}
+
+		for source, expected := range cases {
+			require.Equal(t, expected, sprint(t, parseExpr(t, source)), source)
+		}
+	})
+
+	t.Run("Records and WITH", func(t *testing.T) {
+		node := parse(t, `
+			PROGRAM p;
+			VAR point: RECORD x, y: REAL; name: STRING END;
+			BEGIN
+				WITH point DO x := 1;
+				WITH point DO BEGIN y := x END
+			END.
+		`)
+
+		require.Equal(t, `PROGRAM p;
+VAR
+  point: RECORD x, y: REAL; name: STRING END;
+BEGIN
+  WITH point DO
+    x := 1;
+  WITH point DO BEGIN
+    y := x
+  END
+END.
+`, sprint(t, node))
+	})
+
+	t.Run("CONST and TYPE sections", func(t *testing.T) {
+		node := parseCommented(t, `
+			PROGRAM p;
+			CONST max = 9;
+			TYPE color = (red, green); { colors }
+			  digit = 0..9;
+			VAR c: color;
+			BEGIN END.
+		`)
+
+		require.Equal(t, `PROGRAM p;
+CONST
+  max = 9;
+TYPE
+  color = (red, green); { colors }
+  digit = 0..9;
+VAR
+  c: color;
+BEGIN
+END.
+`, sprint(t, node))
+	})
+
+	t.Run("Routines are printed after the declarations", func(t *testing.T) {
+		node := parse(t, `
+			program p;
+			var total: integer;
+			procedure Add(var sum: integer; function f(x: integer): integer);
+			  procedure Twice; begin sum := sum + f(2) end;
+			begin Twice; Twice end;
+			begin Add(total, nil) end.
+		`)
+
+		require.Equal(t, `PROGRAM p;
+VAR
+  total: INTEGER;
+PROCEDURE Add(VAR sum: INTEGER; FUNCTION f(x: INTEGER): INTEGER);
+  PROCEDURE Twice;
+  BEGIN
+    sum := sum + f(2)
+  END;
+BEGIN
+  Twice;
+  Twice
+END;
+BEGIN
+  Add(total, NIL)
+END.
+`, sprint(t, node))
+	})
+
+	t.Run("Exception handling statements", func(t *testing.T) {
+		node := parse(t, `
+			program p;
+			begin
+			  try raise Exception.Create('x') except on E: EDivByZero do raise; on Exception do begin end else writeln(1) end;
+			  try writeln(2) finally writeln(3); end;
+			  try except end
+			end.
+		`)
+
+		require.Equal(t, `PROGRAM p;
+BEGIN
+  TRY
+    RAISE Exception.Create('x')
+  EXCEPT
+    ON E: EDivByZero DO
+      RAISE;
+    ON Exception DO BEGIN
+    END
+  ELSE
+    writeln(1)
+  END;
+  TRY
+    writeln(2)
+  FINALLY
+    writeln(3);
+  END;
+  TRY
+  EXCEPT
+  END
+END.
+`, sprint(t, node))
+	})
+
+	t.Run("Conditions and loops", func(t *testing.T) {
+		node := parse(t, `
+			program p;
+			begin
+			  if a = 1 then x := 1 else if a = 2 then begin x := 2 end else x := 3;
+			  while x > 0 do x := x - 1;
+			  repeat x := x + 1; if x > 5 then break until x > 9;
+			  for i := 1 to 3 do begin continue end;
+			  for i := 3 downto 1 do exit
+			end.
+		`)
+
+		require.Equal(t, `PROGRAM p;
+BEGIN
+  IF a = 1 THEN
+    x := 1
+  ELSE IF a = 2 THEN BEGIN
+    x := 2
+  END ELSE
+    x := 3;
+  WHILE x > 0 DO
+    x := x - 1;
+  REPEAT
+    x := x + 1;
+    IF x > 5 THEN
+      break
+  UNTIL x > 9;
+  FOR i := 1 TO 3 DO BEGIN
+    continue
+  END;
+  FOR i := 3 DOWNTO 1 DO
+    exit
+END.
+`, sprint(t, node))
+	})
+
+	t.Run("Labels and GOTO", func(t *testing.T) {
+		node := parse(t, `
+			program p;
+			label 10, done;
+			const n = 3;
+			begin
+			  10: x := x + 1;
+			  if x < n then goto 10;
+			  done:
+			end.
+		`)
+
+		require.Equal(t, `PROGRAM p;
+LABEL 10, done;
+CONST
+  n = 3;
+BEGIN
+  10: x := x + 1;
+  IF x < n THEN
+    GOTO 10;
+  done:
+END.
+`, sprint(t, node))
+	})
+
+	t.Run("Classes and methods", func(t *testing.T) {
+		node := parse(t, `
+			program p;
+			type
+			  tbase = class end;
+			  tshape = class(tbase)
+			    x, y: integer;
+			    constructor create(ax: integer);
+			    function area: real; virtual;
+			    destructor destroy; override;
+			  end;
+			constructor tshape.create(ax: integer);
+			begin inherited create; x := ax end;
+			begin
+			  s := tshape.create(1);
+			  s.free;
+			  (s as tshape).x := 2;
+			  b := s is tshape
+			end.
+		`)
+
+		require.Equal(t, `PROGRAM p;
+TYPE
+  tbase = CLASS END;
+  tshape = CLASS(tbase)
+    x, y: INTEGER;
+    CONSTRUCTOR create(ax: INTEGER);
+    FUNCTION area: REAL; VIRTUAL;
+    DESTRUCTOR destroy; OVERRIDE;
+  END;
+CONSTRUCTOR tshape.create(ax: INTEGER);
+BEGIN
+  INHERITED create;
+  x := ax
+END;
+BEGIN
+  s := tshape.create(1);
+  s.free;
+  (s AS tshape).x := 2;
+  b := s IS tshape
+END.
+`, sprint(t, node))
+	})
+
+	t.Run("Units and USES", func(t *testing.T) {
+		node := parse(t, `
+			unit shapes;
+			interface
+			uses math, strings;
+			const sides = 4;
+			function area(s: real): real;
+			procedure reset;
+			implementation
+			uses helpers;
+			var count: integer;
+			function area(s: real): real;
+			begin area := s * s end;
+			procedure reset;
+			begin count := 0 end;
+			initialization count := 1
+			finalization reset
+			end.
+		`)
+
+		require.Equal(t, `UNIT shapes;
+INTERFACE
+USES math, strings;
+CONST
+  sides = 4;
+FUNCTION area(s: REAL): REAL;
+PROCEDURE reset;
+IMPLEMENTATION
+USES helpers;
+VAR
+  count: INTEGER;
+FUNCTION area(s: REAL): REAL;
+BEGIN
+  area := s * s
+END;
+PROCEDURE reset;
+BEGIN
+  count := 0
+END;
+INITIALIZATION
+  count := 1
+FINALIZATION
+  reset
+END.
+`, sprint(t, node))
+
+		program := parse(t, "program p; uses shapes; begin reset end.")
+		require.Equal(t, "PROGRAM p;\nUSES shapes;\nBEGIN\n  reset\nEND.\n", sprint(t, program))
+	})
+
+	t.Run("Any node can be printed", func(t *testing.T) {
+		program := parse(t, "PROGRAM p; VAR a, b : INTEGER; BEGIN a := 1 END.").(ast.Program)
+
+		require.Equal(t, "VAR\n  a, b: INTEGER;\nBEGIN\n  a := 1\nEND", sprint(t, program.Block))
+		require.Equal(t, "a: INTEGER", sprint(t, program.Block.Declarations[0]))
+		require.Equal(t, "a := 1", sprint(t, program.Block.Compound.Children[0]))
+	})
+
+	t.Run("Parse, print, parse round-trips", func(t *testing.T) {
+		sources := []string{
+			"PROGRAM p; BEGIN END.",
+			"PROGRAM p; VAR a : INTEGER; BEGIN a := -(-(1 + 2)) * 3 DIV 4 END.",
+			`PROGRAM p;
+			VAR x, y : REAL; i : INTEGER;
+			BEGIN
+				BEGIN ; END;
+				x := 1.5 / (y - 2) - (i - (i + 1));
+				i := i
+			END.`,
+			"PROGRAM p; VAR x : REAL; BEGIN WRITELN; WRITE(x, -x : 8, x : 2 + 3 : 1); WRITELN() END.",
+			"PROGRAM p; VAR s : STRING; b : BOOLEAN; BEGIN s := 'a'#9'b'; b := s[1] <= 'c' END.",
+			"PROGRAM p; VAR s : STRING; i : INTEGER; BEGIN i := Length(Copy(s, 1, 2)) + Pos('a', s); Str(i : 4, s); Delete(s, 1, i) END.",
+			"PROGRAM p; VAR a, b : ARRAY [-1..1, 'a'..'c'] OF ARRAY[1..2] OF REAL; BEGIN a[0, 'b'][1 + 1] := 2; b := a END.",
+			"PROGRAM p; VAR p, q : RECORD x, y : INTEGER; inner : RECORD s : STRING END; END; BEGIN p.inner.s := 'a'; WITH p, inner DO BEGIN x := 1; s := s + 'b' END; WITH q DO y := p.x END.",
+			"PROGRAM p; CONST m = 3; h : REAL = m / 2; l = Chr(Ord('a') + m); TYPE i = 1..m; VAR a : ARRAY[i] OF REAL; BEGIN a[m] := h END.",
+			"PROGRAM p; TYPE c = (r, g, b); d = -1..+1; n = d; a = ARRAY[c, 'a'..'z', BOOLEAN] OF RECORD x : r..g END; VAR v : (x, y); w : a; BEGIN v := y END.",
+			"PROGRAM p; TYPE l = SET OF 'a'..'z'; VAR s : l; t : SET OF (x, y); b : BOOLEAN; BEGIN s := ['a', 'c'..'e'] + [] - s * ['b']; b := ('a' IN s) = (x IN t); b := s <= ['a'..Chr(Ord('a') + 1)] END.",
+			"PROGRAM p; TYPE PNode = ^TNode; TNode = RECORD value : INTEGER; next : PNode END; VAR p : PNode; q : ^INTEGER; BEGIN New(p); p^.next := NIL; p^.value := -p^.value; New(q); q^ := p^.value; WITH p^ DO value := 1; Dispose(p) END.",
+			"PROGRAM p; TYPE c = FUNCTION(a, b : INTEGER) : INTEGER; t = PROCEDURE; VAR f : c; PROCEDURE s(VAR x, y : INTEGER; z : REAL; FUNCTION less(a : INTEGER) : BOOLEAN; PROCEDURE done); VAR i : INTEGER; PROCEDURE n; BEGIN i := x END; BEGIN n END; FUNCTION g : INTEGER; BEGIN g := 1 END; BEGIN f := NIL; WRITELN(f(1, g)) END.",
+			"PROGRAM p; VAR i : INTEGER; FUNCTION f : INTEGER; BEGIN REPEAT Exit(1); UNTIL i = 1; f := 0 END; BEGIN IF i = 1 THEN ELSE i := 0; IF i < 2 THEN IF i > 0 THEN Halt(1) ELSE WHILE i < 5 DO BEGIN i := i + 1; Continue END; FOR i := f TO f + 1 DO Break END.",
+			"PROGRAM p; BEGIN TRY TRY RAISE EConvertError.Create('a' + 'b') FINALLY END EXCEPT ON E : Exception DO WRITELN(E.Message); ON EIntError DO ELSE RAISE END; TRY EXCEPT RAISE END END.",
+			"PROGRAM p; TYPE t = CLASS(TObject) a : INTEGER; CONSTRUCTOR c(x : t); PROCEDURE m; VIRTUAL; END; u = CLASS(t) PROCEDURE m; OVERRIDE; END; CONSTRUCTOR t.c(x : t); BEGIN a := (x AS u).a END; PROCEDURE u.m; BEGIN INHERITED m; WRITELN(INHERITED a(1) + 1, Self IS u) END; BEGIN u.c(NIL).m; t.c(NIL).m() END.",
+			"PROGRAM p; LABEL 1, 2, l; VAR i : INTEGER; PROCEDURE q; LABEL 1; BEGIN 1: GOTO 1 END; BEGIN 1: i := i + 1; IF i < 3 THEN GOTO 1; BEGIN 2: ; GOTO l END; l: WHILE i > 0 DO i := i - 1 END.",
+			"PROGRAM p; USES a, b; BEGIN END.",
+			"UNIT u; INTERFACE IMPLEMENTATION END.",
+			"UNIT u; INTERFACE USES a; TYPE t = CLASS PROCEDURE m; END; VAR v : t; FUNCTION f(x : INTEGER) : t; IMPLEMENTATION USES b, c; PROCEDURE t.m; BEGIN END; FUNCTION f(x : INTEGER) : t; BEGIN f := NIL END; INITIALIZATION v := f(1) FINALIZATION v.m; v := NIL END.",
+		}
+
+		for _, source := range sources {
+			first := parse(t, source)
+			printed := sprint(t, first)
+			second := parse(t, printed)
+
+			require.Equal(t, shape(first), shape(second), printed)
+			require.Equal(t, printed, sprint(t, second))
+		}
+	})
+
+	t.Run("Declarations without source positions are grouped by type", func(t *testing.T) {
+		program := parse(t, "PROGRAM p; VAR a : INTEGER; b : INTEGER; BEGIN END.").(ast.Program)
+		require.Contains(t, sprint(t, program), "  a: INTEGER;\n  b: INTEGER;\n")
+
+		generated := ast.NewBlock([]ast.Node{
+			ast.NewVarDeclaration(variable("a"), integerType(), lexer.BasicToken{TokenType: lexer.COLON}),
+			ast.NewVarDeclaration(variable("b"), integerType(), lexer.BasicToken{TokenType: lexer.COLON}),
+		}, ast.NewCompound(nil, lexer.BasicToken{TokenType: lexer.BEGIN}), lexer.BasicToken{TokenType: lexer.VAR})
+		require.Equal(t, "VAR\n  a, b: INTEGER;\nBEGIN\nEND", sprint(t, generated))
+	})
+}
+
+func variable(name string) ast.Var {
+	node, _ := ast.NewVar(lexer.BasicToken{TokenType: lexer.ID, TokenValue: name})
+	return node
+}
+
+func integerType() ast.TypeSpec {
+	return ast.NewTypeSpec(lexer.BasicToken{TokenType: lexer.INTEGER_DECLARAION, TokenValue: "INTEGER"})
+}
+
+// shape describes the structure of a tree while ignoring source positions
+func shape(node ast.Node) []string {
+	var result []string
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			result = append(result, ")")
+			return false
+		}
+		result = append(result, fmt.Sprintf("(%T %v %q", n, n.GetToken().TokenType, n.GetToken().TokenValue))
+		return true
+	})
+	return result
+}
+
+func parseCommented(t *testing.T, text string) CommentedNode {
+	lxr := lexer.NewLexer(text)
+	parser, err := interpreter.NewParser(lxr)
+	require.NoError(t, err)
+	node, err := parser.Parse()
+	require.NoError(t, err)
+	return CommentedNode{Node: node, Comments: parser.Lexer.GetComments(), Source: []byte(text)}
+}
+
+func TestFprint_comments(t *testing.T) {
+	t.Run("Comments stay next to their nodes", func(t *testing.T) {
+		node := parseCommented(t, `{ header }
+program Demo; { the name }
+var
+   { counters }
+   a, b : integer; { two of them }
+
+
+   y : real;
+begin { start }
+   a := 1;   { first }
+
+   { leading }
+   b := a
+   { before end }
+end.
+{ footer }`)
+
+		require.Equal(t, `{ header }
+PROGRAM Demo; { the name }
+VAR
+  { counters }
+  a, b: INTEGER; { two of them }
+
+  y: REAL;
+BEGIN { start }
+  a := 1; { first }
+
+  { leading }
+  b := a
+  { before end }
+END.
+{ footer }
+`, sprint(t, node))
+	})
+
+	t.Run("Comments inside nested compounds", func(t *testing.T) {
+		node := parseCommented(t, `PROGRAM p; BEGIN
+			BEGIN
+				{ inner }
+			END; { after inner }
+		END.`)
+
+		require.Equal(t, `PROGRAM p;
+BEGIN
+  BEGIN
+    { inner }
+  END; { after inner }
+END.
+`, sprint(t, node))
+	})
+
+	t.Run("Formatting is idempotent", func(t *testing.T) {
+		source := `PROGRAM p; { a }
+VAR x : INTEGER;
+BEGIN
+  { b }
+  x := 1 { c }
+END.
+`
+		once := sprint(t, parseCommented(t, source))
+		twice := sprint(t, parseCommented(t, once))
+		require.Equal(t, once, twice)
+	})
+
+	t.Run("Only blank lines separate statements", func(t *testing.T) {
+		node := parseCommented(t, `program p;
+var x: integer;
+begin
+  x := 1
+  ;
+  writeln(x);
+
+  x := 2
+  ;
+
+  writeln(x)
+end.
+`)
+		require.Equal(t, `PROGRAM p;
+VAR
+  x: INTEGER;
+BEGIN
+  x := 1;
+  writeln(x);
+
+  x := 2;
+
+  writeln(x)
+END.
+`, sprint(t, node))
+	})
+}
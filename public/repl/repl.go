@@ -2,48 +2,143 @@ package repl
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
-	"os"
-	//
-	// "github.com/anuarkaliyev23/simple-interpreter-go/public/interpreter"
-	// "github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
+	"io"
+	"strings"
+
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/ast"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/interpreter"
+	"github.com/anuarkaliyev23/simple-interpreter-go/public/lexer"
 )
 
-type Interpreter interface {
-	Interpret() (int, error)
+// Repl evaluates statements and expressions line by line. Variables keep
+// their values between lines, the value of an expression is printed. The
+// output of WRITE and WRITELN goes to Writer as well.
+type Repl struct {
+	Prefix    string
+	Reader    *bufio.Reader
+	Writer    io.Writer
+	Evaluator *interpreter.EvaluatorVisitor
 }
 
-type Repl struct {
-	Prefix string
-	Reader bufio.Reader 
-}
-
-func (r Repl) Iter() error {
-	fmt.Printf("%v", r.Prefix)
-	// text, err := r.Reader.ReadString('\n')
-	// if err != nil {
-	// 	return err
-	// }
-	// // lexer := lexer.NewLexer(text)
-	//
-	// // parser, err := interpreter.NewParser(lexer)
-	// if err != nil {
-	// 	return err
-	// }
-	//
-	//
-	// // result, err := parser.Interpret()
-	// if err != nil {
-	// 	return err
-	// }
-
-	// fmt.Println(result)
+// parseLine returns the expression typed on the line, or the statements if
+// the line is not a single expression. A call of a procedure is a
+// statement, though it reads like an expression.
+func (r *Repl) parseLine(text string) (ast.Node, []ast.Node, error) {
+	parser, err := interpreter.NewParser(lexer.NewLexer(text))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expression, err := parser.Expr()
+	if err == nil && parser.Lexer.GetCurrentToken().TokenType == lexer.EOF && !r.isProcedureCall(expression) {
+		return expression, nil, nil
+	}
+
+	parser, err = interpreter.NewParser(lexer.NewLexer(text))
+	if err != nil {
+		return nil, nil, err
+	}
+	statements, err := parser.ParseStatements()
+	return nil, statements, err
+}
+
+// isProcedureCall reports whether expression names a procedure, with or
+// without arguments
+func (r *Repl) isProcedureCall(expression ast.Node) bool {
+	switch n := expression.(type) {
+	case ast.FunctionCall:
+		return r.Evaluator.IsProcedure(n.Name)
+	case ast.Var:
+		return r.Evaluator.IsProcedure(n.Value)
+	}
+	return false
+}
+
+func (r *Repl) eval(text string) error {
+	expression, statements, err := r.parseLine(text)
+	if err != nil {
+		return err
+	}
+
+	if expression != nil {
+		result, err := r.Evaluator.Visit(expression)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(r.Writer, interpreter.FormatValue(result))
+		return nil
+	}
+
+	for _, v := range statements {
+		if _, err := r.Evaluator.Visit(v); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func NewRepl() Repl {
-	return Repl {
-		Prefix: "calc>",
-		Reader: *bufio.NewReader(os.Stdin),
+// Halted is returned by Iter when the line calls Halt, which ends the
+// session with Code
+type Halted struct {
+	Code int
+}
+
+func (r Halted) Error() string {
+	return fmt.Sprintf("halted with exit code %d", r.Code)
+}
+
+// Iter reads and evaluates a single line. Errors in the line are printed,
+// only a failure to read input, io.EOF included, and Halted are returned.
+func (r *Repl) Iter() error {
+	fmt.Fprint(r.Writer, r.Prefix)
+	text, err := r.Reader.ReadString('\n')
+	if err != nil && (err != io.EOF || text == "") {
+		return err
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	evalErr := r.eval(text)
+	if code, halted := interpreter.HaltCode(evalErr); halted {
+		return Halted{Code: code}
+	}
+	if evalErr != nil {
+		fmt.Fprintln(r.Writer, evalErr)
+	}
+	return nil
+}
+
+// Run iterates until the input is exhausted or Halt is called, and returns
+// the exit code passed to Halt, 0 otherwise.
+func (r *Repl) Run() (int, error) {
+	for {
+		err := r.Iter()
+		var halted Halted
+		switch {
+		case err == io.EOF:
+			fmt.Fprintln(r.Writer)
+			return 0, nil
+		case errors.As(err, &halted):
+			return halted.Code, nil
+		case err != nil:
+			return 0, err
+		}
+	}
+}
+
+func NewRepl(input io.Reader, output io.Writer) Repl {
+	// READ and READLN consume the lines following the one calling them
+	reader := bufio.NewReader(input)
+	evaluator := interpreter.NewEvaluatorVisitor(reader, output)
+	return Repl{
+		Prefix:    "calc> ",
+		Reader:    reader,
+		Writer:    output,
+		Evaluator: &evaluator,
 	}
 }
@@ -0,0 +1,39 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// session runs the lines of input and returns what the REPL printed
+func session(t *testing.T, input string) string {
+	var output strings.Builder
+	repl := NewRepl(strings.NewReader(input), &output)
+	repl.Prefix = ""
+	code, err := repl.Run()
+	require.NoError(t, err)
+	require.Zero(t, code)
+	return output.String()
+}
+
+func TestRepl_Run(t *testing.T) {
+	t.Run("Expressions are printed", func(t *testing.T) {
+		require.Equal(t, "7\n\n", session(t, "x := 3\nx * 2 + 1\n"))
+	})
+
+	t.Run("Procedure calls are statements", func(t *testing.T) {
+		require.Equal(t, "hi\n\n2 3\n\n", session(t, "writeln('hi')\nwriteln\nwriteln(2, ' ', 3)\n"))
+	})
+
+	t.Run("Halt ends the session with its exit code", func(t *testing.T) {
+		var output strings.Builder
+		repl := NewRepl(strings.NewReader("writeln(1)\nhalt(3)\nwriteln(2)\n"), &output)
+		repl.Prefix = ""
+		code, err := repl.Run()
+		require.NoError(t, err)
+		require.Equal(t, 3, code)
+		require.Equal(t, "1\n", output.String())
+	})
+}